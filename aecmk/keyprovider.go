@@ -2,6 +2,7 @@ package aecmk
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"sync"
 	"time"
@@ -38,11 +39,14 @@ func NewCekProvider(provider ColumnEncryptionKeyProvider) *CekProvider {
 }
 
 func (cp *CekProvider) GetDecryptedKey(ctx context.Context, keyPath string, encryptedBytes []byte) (decryptedKey []byte, err error) {
+	// a master key can protect several column encryption keys,
+	// so the cache is keyed by the encrypted key as well
+	cacheKey := keyPath + ":" + hex.EncodeToString(encryptedBytes)
 	cp.mutex.Lock()
-	ev, cachedKey := cp.decryptedKeys[keyPath]
+	ev, cachedKey := cp.decryptedKeys[cacheKey]
 	if cachedKey {
 		if ev.Expiry.Before(time.Now()) {
-			delete(cp.decryptedKeys, keyPath)
+			delete(cp.decryptedKeys, cacheKey)
 			cachedKey = false
 		} else {
 			decryptedKey = ev.Key
@@ -61,10 +65,12 @@ func (cp *CekProvider) GetDecryptedKey(ctx context.Context, keyPath string, encr
 		if duration == nil {
 			duration = &ColumnEncryptionKeyLifetime
 		}
-		expiry := time.Now().Add(*duration)
-		cp.mutex.Lock()
-		cp.decryptedKeys[keyPath] = cekCacheEntry{Expiry: expiry, Key: decryptedKey}
-		cp.mutex.Unlock()
+		if *duration > 0 {
+			expiry := time.Now().Add(*duration)
+			cp.mutex.Lock()
+			cp.decryptedKeys[cacheKey] = cekCacheEntry{Expiry: expiry, Key: decryptedKey}
+			cp.mutex.Unlock()
+		}
 	}
 	return
 }
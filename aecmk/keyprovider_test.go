@@ -0,0 +1,102 @@
+package aecmk
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+// reverseKeyProvider "decrypts" keys by reversing the bytes and counts the calls
+type reverseKeyProvider struct {
+	calls    int
+	lifetime *time.Duration
+}
+
+func (p *reverseKeyProvider) DecryptColumnEncryptionKey(ctx context.Context, masterKeyPath string, encryptionAlgorithm string, encryptedCek []byte) ([]byte, error) {
+	p.calls++
+	res := make([]byte, len(encryptedCek))
+	for i, b := range encryptedCek {
+		res[len(res)-1-i] = b
+	}
+	return res, nil
+}
+
+func (p *reverseKeyProvider) EncryptColumnEncryptionKey(ctx context.Context, masterKeyPath string, encryptionAlgorithm string, cek []byte) ([]byte, error) {
+	return p.DecryptColumnEncryptionKey(ctx, masterKeyPath, encryptionAlgorithm, cek)
+}
+
+func (p *reverseKeyProvider) SignColumnMasterKeyMetadata(ctx context.Context, masterKeyPath string, allowEnclaveComputations bool) ([]byte, error) {
+	return nil, nil
+}
+
+func (p *reverseKeyProvider) VerifyColumnMasterKeyMetadata(ctx context.Context, masterKeyPath string, allowEnclaveComputations bool) (*bool, error) {
+	return nil, nil
+}
+
+func (p *reverseKeyProvider) KeyLifetime() *time.Duration {
+	return p.lifetime
+}
+
+func TestGetDecryptedKeyCache(t *testing.T) {
+	provider := &reverseKeyProvider{}
+	cp := NewCekProvider(provider)
+	ctx := context.Background()
+
+	key1, err := cp.GetDecryptedKey(ctx, "cmk", []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cp.GetDecryptedKey(ctx, "cmk", []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected the key to be decrypted once, got %d calls", provider.calls)
+	}
+
+	// another key protected by the same master key
+	key2, err := cp.GetDecryptedKey(ctx, "cmk", []byte{4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key1, []byte{3, 2, 1}) || !bytes.Equal(key2, []byte{6, 5, 4}) {
+		t.Errorf("unexpected decrypted keys %v and %v", key1, key2)
+	}
+}
+
+func TestGetDecryptedKeyNoCache(t *testing.T) {
+	zero := time.Duration(0)
+	provider := &reverseKeyProvider{lifetime: &zero}
+	cp := NewCekProvider(provider)
+	for i := 0; i < 2; i++ {
+		if _, err := cp.GetDecryptedKey(context.Background(), "cmk", []byte{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected the key to be decrypted on every call, got %d calls", provider.calls)
+	}
+	if len(cp.decryptedKeys) != 0 {
+		t.Errorf("expected no cached keys, got %d", len(cp.decryptedKeys))
+	}
+}
+
+func TestRegisterCekProvider(t *testing.T) {
+	const name = "TEST_REVERSE_PROVIDER"
+	defer delete(globalCekProviderFactoryMap, name)
+
+	if err := RegisterCekProvider(name, &reverseKeyProvider{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterCekProvider(name, &reverseKeyProvider{}); err == nil {
+		t.Error("expected an error registering a provider name twice")
+	}
+	providers := GetGlobalCekProviders()
+	if _, ok := providers[name]; !ok {
+		t.Fatal("registered provider is not returned by GetGlobalCekProviders")
+	}
+	delete(providers, name)
+	if _, ok := globalCekProviderFactoryMap[name]; !ok {
+		t.Error("GetGlobalCekProviders should return a copy of the providers")
+	}
+}
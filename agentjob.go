@@ -0,0 +1,236 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// defaultJobPollInterval is the time WaitForJob waits between two polls.
+const defaultJobPollInterval = 2 * time.Second
+
+// ErrJobNotFound is returned for a SQL Server Agent job that does not exist.
+var ErrJobNotFound = errors.New("mssql: agent job not found")
+
+// JobRunStatus is the run_status of a job or step in msdb.dbo.sysjobhistory.
+type JobRunStatus int
+
+// Run statuses of jobs and steps.
+const (
+	JobFailed     JobRunStatus = 0
+	JobSucceeded  JobRunStatus = 1
+	JobRetry      JobRunStatus = 2
+	JobCanceled   JobRunStatus = 3
+	JobInProgress JobRunStatus = 4
+)
+
+func (s JobRunStatus) String() string {
+	switch s {
+	case JobFailed:
+		return "failed"
+	case JobSucceeded:
+		return "succeeded"
+	case JobRetry:
+		return "retry"
+	case JobCanceled:
+		return "canceled"
+	case JobInProgress:
+		return "in progress"
+	}
+	return "JobRunStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
+// JobStatus is the state of the latest run of a job in the current session
+// of SQL Server Agent, from msdb.dbo.sysjobactivity. The times are in the
+// local time of the server, like datetime columns, and zero when unknown.
+type JobStatus struct {
+	// RequestedAt is the time the run was requested.
+	RequestedAt time.Time
+	// StartedAt and StoppedAt are the times the run started and stopped.
+	StartedAt time.Time
+	StoppedAt time.Time
+	// Running is true when the run started and did not stop yet.
+	Running bool
+	// LastStepID and LastStepName are the last step that was executed.
+	LastStepID   int
+	LastStepName string
+	// Outcome and Message are the outcome of a run that stopped.
+	Outcome JobRunStatus
+	Message string
+}
+
+// JobStepHistory is a row of msdb.dbo.sysjobhistory, the outcome of a step
+// of a run or, with StepID 0, of the whole run.
+type JobStepHistory struct {
+	// InstanceID is the identity of the row, it increases with every row.
+	InstanceID       int64
+	StepID           int
+	StepName         string
+	Status           JobRunStatus
+	RunAt            time.Time
+	Duration         time.Duration
+	RetriesAttempted int
+	Message          string
+}
+
+// StartJob starts the SQL Server Agent job with msdb.dbo.sp_start_job, at
+// the step named step or at the first step when step is empty. The job runs
+// asynchronously on the server, StartJob returns the time of the server
+// before the job was started, to pass to WaitForJob:
+//
+//	requested, err := mssql.StartJob(ctx, db, "nightly etl", "")
+//	...
+//	status, err := mssql.WaitForJob(ctx, db, "nightly etl", requested, 0)
+//	if err == nil && status.Outcome != mssql.JobSucceeded {
+//		history, err := mssql.JobHistory(ctx, db, "nightly etl", 0)
+//		...
+//	}
+func StartJob(ctx context.Context, q Querier, job, step string) (time.Time, error) {
+	query := "set nocount on; declare @requested datetime = getdate(); exec msdb.dbo.sp_start_job @job_name = @p1"
+	args := []interface{}{job}
+	if step != "" {
+		query += ", @step_name = @p2"
+		args = append(args, step)
+	}
+	rows, err := q.QueryContext(ctx, query+"; select @requested", args...)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer rows.Close()
+	var requested time.Time
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = fmt.Errorf("mssql: sp_start_job returned no time for job %s", job)
+		}
+		return time.Time{}, err
+	}
+	if err = rows.Scan(&requested); err != nil {
+		return time.Time{}, err
+	}
+	return requested, rows.Close()
+}
+
+// jobStatusSQL selects the latest activity of a job, in the current session
+// of SQL Server Agent first, and the outcome of the run from the history.
+const jobStatusSQL = `select top (1) a.run_requested_date, a.start_execution_date, a.stop_execution_date,
+	a.last_executed_step_id, s.step_name, h.run_status, h.message
+from msdb.dbo.sysjobs j
+left join msdb.dbo.sysjobactivity a on a.job_id = j.job_id
+left join msdb.dbo.sysjobsteps s on s.job_id = a.job_id and s.step_id = a.last_executed_step_id
+left join msdb.dbo.sysjobhistory h on h.instance_id = a.job_history_id
+where j.name = @p1
+order by a.session_id desc, a.run_requested_date desc`
+
+// GetJobStatus returns the state of the latest run of the SQL Server Agent
+// job. The fields are zero for a job that did not run since SQL Server Agent
+// started. It returns ErrJobNotFound for a job that does not exist.
+func GetJobStatus(ctx context.Context, q Querier, job string) (*JobStatus, error) {
+	rows, err := q.QueryContext(ctx, jobStatusSQL, job)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = ErrJobNotFound
+		}
+		return nil, err
+	}
+	var requested, started, stopped sql.NullTime
+	var stepID, outcome sql.NullInt64
+	var stepName, message sql.NullString
+	if err = rows.Scan(&requested, &started, &stopped, &stepID, &stepName, &outcome, &message); err != nil {
+		return nil, err
+	}
+	status := &JobStatus{
+		RequestedAt:  requested.Time,
+		StartedAt:    started.Time,
+		StoppedAt:    stopped.Time,
+		Running:      started.Valid && !stopped.Valid,
+		LastStepID:   int(stepID.Int64),
+		LastStepName: stepName.String,
+		Message:      message.String,
+	}
+	switch {
+	case status.Running:
+		status.Outcome = JobInProgress
+	case outcome.Valid:
+		status.Outcome = JobRunStatus(outcome.Int64)
+	}
+	return status, rows.Close()
+}
+
+// WaitForJob polls the status of the SQL Server Agent job every interval,
+// every 2 seconds when interval is 0, until a run that was requested at or
+// after requested, the time returned by StartJob, stopped. It returns the
+// status of that run.
+func WaitForJob(ctx context.Context, q Querier, job string, requested time.Time, interval time.Duration) (*JobStatus, error) {
+	if interval <= 0 {
+		interval = defaultJobPollInterval
+	}
+	for {
+		status, err := GetJobStatus(ctx, q, job)
+		if err != nil {
+			return nil, err
+		}
+		if !status.RequestedAt.Before(requested) && !status.StoppedAt.IsZero() {
+			return status, nil
+		}
+		if err = sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// jobHistorySQL selects the history of a job after an instance_id.
+const jobHistorySQL = `select h.instance_id, h.step_id, h.step_name, h.run_status, h.run_date, h.run_time,
+	h.run_duration, h.retries_attempted, h.message
+from msdb.dbo.sysjobhistory h
+join msdb.dbo.sysjobs j on j.job_id = h.job_id
+where j.name = @p1 and h.instance_id > @p2
+order by h.instance_id`
+
+// JobHistory returns the rows of the history of the SQL Server Agent job
+// with an InstanceID greater than after, oldest first. Pass the InstanceID
+// of the last row returned to fetch only the rows added since. SQL Server
+// Agent writes the rows of the steps of a run as the steps complete and the
+// row with StepID 0 when the run completed.
+func JobHistory(ctx context.Context, q Querier, job string, after int64) ([]JobStepHistory, error) {
+	rows, err := q.QueryContext(ctx, jobHistorySQL, job, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var history []JobStepHistory
+	for rows.Next() {
+		var h JobStepHistory
+		var status, runDate, runTime, runDuration int64
+		if err = rows.Scan(&h.InstanceID, &h.StepID, &h.StepName, &status, &runDate, &runTime, &runDuration, &h.RetriesAttempted, &h.Message); err != nil {
+			return nil, err
+		}
+		h.Status = JobRunStatus(status)
+		h.RunAt = agentDateTime(runDate, runTime)
+		h.Duration = agentDuration(runDuration)
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// agentDateTime returns the time of a date as yyyymmdd and a time as hhmmss,
+// the integers SQL Server Agent stores in its tables.
+func agentDateTime(date, t int64) time.Time {
+	if date == 0 {
+		return time.Time{}
+	}
+	return time.Date(int(date/10000), time.Month(date/100%100), int(date%100),
+		int(t/10000), int(t/100%100), int(t%100), 0, time.UTC)
+}
+
+// agentDuration returns the duration of a run_duration as hhmmss, the hours
+// may have more than two digits.
+func agentDuration(d int64) time.Duration {
+	return time.Duration(d/10000)*time.Hour + time.Duration(d/100%100)*time.Minute + time.Duration(d%100)*time.Second
+}
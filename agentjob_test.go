@@ -0,0 +1,88 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestAgentJob(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	requested := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	started := requested.Add(time.Second)
+	statusColumns := []string{"run_requested_date", "start_execution_date", "stop_execution_date", "last_executed_step_id", "step_name", "run_status", "message"}
+	polls := 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		switch {
+		case strings.Contains(query, "sp_start_job @job_name = @p1, @step_name = @p2"):
+			return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{requested}}}}}
+		case query == jobStatusSQL:
+			polls++
+			row := []interface{}{requested, started, nil, 1, "extract", nil, nil}
+			if polls > 1 {
+				row = []interface{}{requested, started, started.Add(time.Minute), 2, "load", 0, "The job failed."}
+			}
+			return mssqltest.Response{Results: []mssqltest.Result{{Columns: statusColumns, Rows: [][]interface{}{row}}}}
+		case query == jobHistorySQL:
+			return mssqltest.Response{Results: []mssqltest.Result{{
+				Columns: []string{"instance_id", "step_id", "step_name", "run_status", "run_date", "run_time", "run_duration", "retries_attempted", "message"},
+				Rows: [][]interface{}{
+					{11, 1, "extract", 1, 20240501, 20001, 12, 0, "Executed."},
+					{12, 0, "(Job outcome)", 0, 20240501, 20001, 1012305, 0, "The job failed."},
+				},
+			}}}
+		}
+		return mssqltest.Response{Err: &mssqltest.Error{Number: 208, Message: "unexpected query " + query}}
+	})
+	ctx := context.Background()
+	at, err := StartJob(ctx, db, "nightly etl", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !at.Equal(requested) {
+		t.Errorf("expected the time %v, got %v", requested, at)
+	}
+	status, err := GetJobStatus(ctx, db, "nightly etl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running || status.Outcome != JobInProgress || status.LastStepName != "extract" {
+		t.Errorf("expected a running job, got %+v", status)
+	}
+	status, err = WaitForJob(ctx, db, "nightly etl", at, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || status.Outcome != JobFailed || status.LastStepID != 2 || status.Message != "The job failed." {
+		t.Errorf("expected a failed job, got %+v", status)
+	}
+	history, err := JobHistory(ctx, db, "nightly etl", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", history)
+	}
+	outcome := history[1]
+	if outcome.InstanceID != 12 || outcome.Status != JobFailed || !outcome.RunAt.Equal(started) ||
+		outcome.Duration != 101*time.Hour+23*time.Minute+5*time.Second {
+		t.Errorf("unexpected job outcome %+v", outcome)
+	}
+}
+
+func TestAgentJobNotFound(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"run_requested_date"}}}}
+	})
+	if _, err := GetJobStatus(context.Background(), db, "missing"); err != ErrJobNotFound {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AppLockMode is the lock mode of sp_getapplock.
+type AppLockMode string
+
+const (
+	AppLockShared          AppLockMode = "Shared"
+	AppLockUpdate          AppLockMode = "Update"
+	AppLockIntentShared    AppLockMode = "IntentShared"
+	AppLockIntentExclusive AppLockMode = "IntentExclusive"
+	AppLockExclusive       AppLockMode = "Exclusive"
+)
+
+// Errors of GetAppLock and GetSessionAppLock for the return codes of sp_getapplock.
+var (
+	ErrAppLockTimeout  = errors.New("mssql: the application lock was not granted before the timeout")
+	ErrAppLockCanceled = errors.New("mssql: the application lock request was canceled")
+	ErrAppLockDeadlock = errors.New("mssql: the application lock request was chosen as deadlock victim")
+)
+
+// appLockTimeout returns the @LockTimeout of sp_getapplock for ctx in
+// milliseconds, the time until the deadline of ctx or -1 to wait until
+// ctx is canceled.
+func appLockTimeout(ctx context.Context) int64 {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return -1
+	}
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 0 {
+		ms = 0
+	}
+	return ms
+}
+
+func getAppLock(ctx context.Context, e Execer, resource string, mode AppLockMode, owner string) error {
+	var rs ReturnStatus
+	_, err := e.ExecContext(ctx, "sp_getapplock",
+		sql.Named("Resource", resource),
+		sql.Named("LockMode", string(mode)),
+		sql.Named("LockOwner", owner),
+		sql.Named("LockTimeout", appLockTimeout(ctx)),
+		&rs,
+	)
+	if err != nil {
+		return err
+	}
+	switch rs {
+	case 0, 1:
+		return nil
+	case -1:
+		return ErrAppLockTimeout
+	case -2:
+		return ErrAppLockCanceled
+	case -3:
+		return ErrAppLockDeadlock
+	}
+	return fmt.Errorf("mssql: sp_getapplock of %q failed with return code %d", resource, rs)
+}
+
+// GetAppLock acquires the application lock on resource in mode, owned by
+// the transaction tx, so it is released when tx commits or rolls back.
+// It waits for the lock until ctx is done; ErrAppLockTimeout is returned
+// when the deadline of ctx passes first.
+//
+//	tx, err := db.BeginTx(ctx, nil)
+//	...
+//	err = mssql.GetAppLock(ctx, tx, "nightly-import", mssql.AppLockExclusive)
+func GetAppLock(ctx context.Context, tx Execer, resource string, mode AppLockMode) error {
+	return getAppLock(ctx, tx, resource, mode, "Transaction")
+}
+
+// GetSessionAppLock acquires the application lock on resource in mode,
+// owned by the session of conn, outside of a transaction. It waits for the
+// lock like GetAppLock. The returned function releases the lock; it is
+// also released when the session ends.
+//
+//	conn, err := db.Conn(ctx)
+//	...
+//	release, err := mssql.GetSessionAppLock(ctx, conn, "nightly-import", mssql.AppLockExclusive)
+//	...
+//	defer release(ctx)
+func GetSessionAppLock(ctx context.Context, conn Execer, resource string, mode AppLockMode) (release func(context.Context) error, err error) {
+	if err = getAppLock(ctx, conn, resource, mode, "Session"); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		return ReleaseAppLock(ctx, conn, resource)
+	}, nil
+}
+
+// ReleaseAppLock releases the application lock on resource owned by the
+// session of conn.
+func ReleaseAppLock(ctx context.Context, conn Execer, resource string) error {
+	var rs ReturnStatus
+	_, err := conn.ExecContext(ctx, "sp_releaseapplock",
+		sql.Named("Resource", resource),
+		sql.Named("LockOwner", "Session"),
+		&rs,
+	)
+	if err == nil && rs != 0 {
+		err = fmt.Errorf("mssql: sp_releaseapplock of %q failed with return code %d", resource, rs)
+	}
+	return err
+}
+
+// SetLockTimeout sets the time statements of the session wait for locks
+// with SET LOCK_TIMEOUT, a negative timeout waits forever. Like the lock
+// timeout of the connection string, but for a single *sql.Conn or *sql.Tx.
+func SetLockTimeout(ctx context.Context, e Execer, timeout time.Duration) error {
+	ms := int64(-1)
+	if timeout >= 0 {
+		ms = timeout.Milliseconds()
+	}
+	_, err := e.ExecContext(ctx, fmt.Sprintf("SET LOCK_TIMEOUT %d", ms))
+	return err
+}
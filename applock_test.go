@@ -0,0 +1,98 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// appLockExecer records the calls of the app lock procedures and returns status.
+type appLockExecer struct {
+	recordingExecer
+	status ReturnStatus
+}
+
+func (e *appLockExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	for _, arg := range args {
+		if rs, ok := arg.(*ReturnStatus); ok {
+			*rs = e.status
+		}
+	}
+	return e.recordingExecer.ExecContext(ctx, query, args...)
+}
+
+func namedArg(args []interface{}, name string) interface{} {
+	for _, arg := range args {
+		if n, ok := arg.(sql.NamedArg); ok && n.Name == name {
+			return n.Value
+		}
+	}
+	return nil
+}
+
+func TestGetAppLock(t *testing.T) {
+	e := &appLockExecer{}
+	if err := GetAppLock(context.Background(), e, "import", AppLockExclusive); err != nil {
+		t.Fatal(err)
+	}
+	args := e.args[0]
+	if e.queries[0] != "sp_getapplock" || namedArg(args, "LockOwner") != "Transaction" || namedArg(args, "LockMode") != "Exclusive" {
+		t.Errorf("unexpected call %s %v", e.queries[0], args)
+	}
+	if timeout := namedArg(args, "LockTimeout"); timeout != int64(-1) {
+		t.Errorf("expected to wait without a deadline, got timeout %v", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	e.status = -1
+	if err := GetAppLock(ctx, e, "import", AppLockShared); err != ErrAppLockTimeout {
+		t.Errorf("expected ErrAppLockTimeout, got %v", err)
+	}
+	if timeout := namedArg(e.args[1], "LockTimeout").(int64); timeout <= 0 || timeout > 60000 {
+		t.Errorf("expected the time until the deadline, got timeout %d", timeout)
+	}
+	for status, want := range map[ReturnStatus]error{-2: ErrAppLockCanceled, -3: ErrAppLockDeadlock} {
+		e.status = status
+		if err := GetAppLock(ctx, e, "import", AppLockShared); err != want {
+			t.Errorf("expected %v for %d, got %v", want, status, err)
+		}
+	}
+	e.status = -999
+	if err := GetAppLock(ctx, e, "import", AppLockShared); err == nil {
+		t.Error("expected an error for a parameter error")
+	}
+}
+
+func TestGetSessionAppLock(t *testing.T) {
+	e := &appLockExecer{}
+	ctx := context.Background()
+	release, err := GetSessionAppLock(ctx, e, "import", AppLockUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namedArg(e.args[0], "LockOwner") != "Session" {
+		t.Errorf("expected a session lock, got %v", e.args[0])
+	}
+	if err = release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if e.queries[1] != "sp_releaseapplock" || namedArg(e.args[1], "Resource") != "import" {
+		t.Errorf("unexpected release %s %v", e.queries[1], e.args[1])
+	}
+	e.status = -999
+	if err = ReleaseAppLock(ctx, e, "import"); err == nil {
+		t.Error("expected an error for a lock that is not held")
+	}
+}
+
+func TestSetLockTimeout(t *testing.T) {
+	e := &recordingExecer{}
+	ctx := context.Background()
+	SetLockTimeout(ctx, e, 2*time.Second)
+	SetLockTimeout(ctx, e, -1)
+	if e.queries[0] != "SET LOCK_TIMEOUT 2000" || e.queries[1] != "SET LOCK_TIMEOUT -1" {
+		t.Errorf("unexpected queries %v", e.queries)
+	}
+}
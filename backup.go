@@ -0,0 +1,222 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Numbers of the informational messages of BACKUP and RESTORE.
+const (
+	msgBackupPercent   = 3211
+	msgBackupCompleted = 3014
+	msgBackupFile      = 4035
+)
+
+// defaultBackupStats is the percentage between two progress messages.
+const defaultBackupStats = 10
+
+// BackupProgress is the progress of a backup or restore, sent by the server
+// every STATS percent.
+type BackupProgress struct {
+	Percent int
+	Message string
+}
+
+// BackupFile are the pages of a database file that were backed up or restored.
+type BackupFile struct {
+	Name  string
+	Pages int64
+}
+
+// BackupResult is the summary of a backup or restore reported by the server.
+type BackupResult struct {
+	// Files are the pages of the files of the database.
+	Files []BackupFile
+	// Pages is the number of pages that were processed.
+	Pages int64
+	// Duration is the time the server reported for the backup or restore.
+	Duration time.Duration
+	// MBPerSec is the throughput the server reported.
+	MBPerSec float64
+	// Message is the final message of the server.
+	Message string
+}
+
+// BackupOptions configure BackupDatabase.
+type BackupOptions struct {
+	// Differential backs up the changes since the last full backup.
+	Differential bool
+	// CopyOnly makes a backup that does not affect the sequence of backups.
+	CopyOnly bool
+	// Compression compresses the backup.
+	Compression bool
+	// Checksum verifies the page checksums and adds a checksum to the backup.
+	Checksum bool
+	// Init overwrites the backup sets of the file instead of appending to it.
+	Init bool
+	// Stats is the percentage between two progress messages, 10 by default.
+	Stats int
+	// Progress, when set, receives the progress. The sends do not block,
+	// progress is dropped when the channel is not ready, so it should be
+	// buffered. The channel is not closed.
+	Progress chan<- BackupProgress
+}
+
+// RestoreOptions configure RestoreDatabase.
+type RestoreOptions struct {
+	// Replace overwrites an existing database of the same name.
+	Replace bool
+	// NoRecovery leaves the database restoring, to restore further backups.
+	NoRecovery bool
+	// Checksum verifies the checksums of the backup.
+	Checksum bool
+	// Move maps the logical names of the files in the backup to the
+	// physical files they are restored to.
+	Move map[string]string
+	// File is the position of the backup set in the file, the first one when 0.
+	File int
+	// Stats is the percentage between two progress messages, 10 by default.
+	Stats int
+	// Progress, when set, receives the progress like BackupOptions.Progress.
+	Progress chan<- BackupProgress
+}
+
+// BackupDatabase backs up database to the file disk on the server with
+// BACKUP DATABASE and returns the summary the server reported:
+//
+//	progress := make(chan mssql.BackupProgress, 10)
+//	go func() {
+//		for p := range progress {
+//			log.Printf("backup %d%%", p.Percent)
+//		}
+//	}()
+//	res, err := mssql.BackupDatabase(ctx, db, "sales", `D:\backup\sales.bak`, mssql.BackupOptions{Compression: true, Progress: progress})
+//	close(progress)
+//
+// The backup runs until it is done unless ctx is canceled, so ctx should
+// not have a short deadline.
+func BackupDatabase(ctx context.Context, e Execer, database, disk string, opts BackupOptions) (*BackupResult, error) {
+	name, err := QuoteIdentifier(database)
+	if err != nil {
+		return nil, err
+	}
+	with := []string{backupStats(opts.Stats)}
+	if opts.Differential {
+		with = append(with, "differential")
+	}
+	if opts.CopyOnly {
+		with = append(with, "copy_only")
+	}
+	if opts.Compression {
+		with = append(with, "compression")
+	}
+	if opts.Checksum {
+		with = append(with, "checksum")
+	}
+	if opts.Init {
+		with = append(with, "init")
+	}
+	query := fmt.Sprintf("backup database %s to disk = %s with %s", name, QuoteString(disk), strings.Join(with, ", "))
+	return execBackup(ctx, e, query, opts.Progress)
+}
+
+// RestoreDatabase restores database from the file disk on the server with
+// RESTORE DATABASE and returns the summary the server reported. Progress is
+// reported like for BackupDatabase.
+func RestoreDatabase(ctx context.Context, e Execer, database, disk string, opts RestoreOptions) (*BackupResult, error) {
+	name, err := QuoteIdentifier(database)
+	if err != nil {
+		return nil, err
+	}
+	with := []string{backupStats(opts.Stats)}
+	if opts.File > 0 {
+		with = append(with, "file = "+strconv.Itoa(opts.File))
+	}
+	if opts.Replace {
+		with = append(with, "replace")
+	}
+	if opts.NoRecovery {
+		with = append(with, "norecovery")
+	}
+	if opts.Checksum {
+		with = append(with, "checksum")
+	}
+	logical := make([]string, 0, len(opts.Move))
+	for l := range opts.Move {
+		logical = append(logical, l)
+	}
+	sort.Strings(logical)
+	for _, l := range logical {
+		with = append(with, "move "+QuoteString(l)+" to "+QuoteString(opts.Move[l]))
+	}
+	query := fmt.Sprintf("restore database %s from disk = %s with %s", name, QuoteString(disk), strings.Join(with, ", "))
+	return execBackup(ctx, e, query, opts.Progress)
+}
+
+// backupStats returns the STATS option for the percentage stats.
+func backupStats(stats int) string {
+	if stats <= 0 || stats > 100 {
+		stats = defaultBackupStats
+	}
+	return "stats = " + strconv.Itoa(stats)
+}
+
+// execBackup executes a BACKUP or RESTORE statement and collects its messages.
+func execBackup(ctx context.Context, e Execer, query string, progress chan<- BackupProgress) (*BackupResult, error) {
+	mon := &backupMonitor{progress: progress}
+	if _, err := e.ExecContext(ctx, query, mon); err != nil {
+		return nil, err
+	}
+	if mon.result.Message == "" {
+		return nil, errors.New("mssql: the server did not report the completion of the backup or restore")
+	}
+	return &mon.result, nil
+}
+
+// backupMonitor is passed as an argument of a BACKUP or RESTORE statement
+// to receive its informational messages.
+type backupMonitor struct {
+	progress chan<- BackupProgress
+	result   BackupResult
+}
+
+var (
+	backupPercentRe   = regexp.MustCompile(`^(\d+) percent processed`)
+	backupFileRe      = regexp.MustCompile(`^Processed (\d+) pages for database '.*', file '(.*)' on file`)
+	backupCompletedRe = regexp.MustCompile(`processed (\d+) pages in ([\d.]+) seconds \(([\d.]+) MB/sec\)`)
+)
+
+// add adds the informational message msg.
+func (m *backupMonitor) add(msg Error) {
+	switch msg.Number {
+	case msgBackupPercent:
+		match := backupPercentRe.FindStringSubmatch(msg.Message)
+		if match == nil || m.progress == nil {
+			return
+		}
+		percent, _ := strconv.Atoi(match[1])
+		select {
+		case m.progress <- BackupProgress{Percent: percent, Message: msg.Message}:
+		default:
+		}
+	case msgBackupFile:
+		if match := backupFileRe.FindStringSubmatch(msg.Message); match != nil {
+			pages, _ := strconv.ParseInt(match[1], 10, 64)
+			m.result.Files = append(m.result.Files, BackupFile{Name: match[2], Pages: pages})
+		}
+	case msgBackupCompleted:
+		m.result.Message = msg.Message
+		if match := backupCompletedRe.FindStringSubmatch(msg.Message); match != nil {
+			m.result.Pages, _ = strconv.ParseInt(match[1], 10, 64)
+			seconds, _ := strconv.ParseFloat(match[2], 64)
+			m.result.Duration = time.Duration(seconds * float64(time.Second))
+			m.result.MBPerSec, _ = strconv.ParseFloat(match[3], 64)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package mssql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestBackupDatabase(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	var queries []string
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		queries = append(queries, query)
+		return mssqltest.Response{
+			Messages: []mssqltest.Error{
+				{Number: msgBackupPercent, Message: "50 percent processed."},
+				{Number: msgBackupPercent, Message: "100 percent processed."},
+				{Number: msgBackupFile, Message: "Processed 360 pages for database 'sales', file 'sales' on file 1."},
+				{Number: msgBackupFile, Message: "Processed 2 pages for database 'sales', file 'sales_log' on file 1."},
+				{Number: msgBackupCompleted, Message: "BACKUP DATABASE successfully processed 362 pages in 0.075 seconds (37.630 MB/sec)."},
+			},
+		}
+	})
+	ctx := context.Background()
+	progress := make(chan BackupProgress, 10)
+	res, err := BackupDatabase(ctx, db, "sales", `D:\backup\o'brien.bak`, BackupOptions{CopyOnly: true, Compression: true, Stats: 50, Progress: progress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `backup database [sales] to disk = N'D:\backup\o''brien.bak' with stats = 50, copy_only, compression`
+	if queries[0] != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, queries[0])
+	}
+	if len(progress) != 2 || (<-progress).Percent != 50 || (<-progress).Percent != 100 {
+		t.Error("expected the progress of 50 and 100 percent")
+	}
+	if res.Pages != 362 || res.Duration != 75*time.Millisecond || res.MBPerSec != 37.63 ||
+		len(res.Files) != 2 || res.Files[1] != (BackupFile{Name: "sales_log", Pages: 2}) {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	_, err = RestoreDatabase(ctx, db, "sales_copy", `D:\backup\sales.bak`, RestoreOptions{
+		Replace: true,
+		Move:    map[string]string{"sales_log": `D:\data\sales_copy.ldf`, "sales": `D:\data\sales_copy.mdf`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `restore database [sales_copy] from disk = N'D:\backup\sales.bak' with stats = 10, replace, ` +
+		`move N'sales' to N'D:\data\sales_copy.mdf', move N'sales_log' to N'D:\data\sales_copy.ldf'`
+	if queries[1] != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, queries[1])
+	}
+}
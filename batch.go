@@ -0,0 +1,119 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Batch queues parameterized statements to send them to the server in a
+// single round trip. Pass it as the only argument of Query or Exec with an
+// empty query text:
+//
+//	var b mssql.Batch
+//	b.Queue("update dbo.stock set qty = qty - @p1 where id = @p2", 1, 10)
+//	b.Queue("select qty from dbo.stock where id = @p1", 10)
+//	b.Queue("select count(*) from dbo.orders where stock_id = @p1", 10)
+//	rows, err := db.QueryContext(ctx, "", &b)
+//
+// The statements run in order, each one as a separate call of an RPC request.
+// Their result sets follow each other, move to the results of the next
+// statement with Rows.NextResultSet. Pass a *RowCounts together with the
+// Batch to Exec to get the row count of each statement.
+//
+// The arguments of a statement are its parameters, named with sql.Named
+// or numbered @p1, @p2, ... A statement can also be a stored procedure name.
+// A Batch can be sent again after it was executed.
+type Batch struct {
+	stmts []batchStmt
+}
+
+type batchStmt struct {
+	query string
+	args  []interface{}
+}
+
+// Queue adds a statement with its arguments to the end of the batch.
+func (b *Batch) Queue(query string, args ...interface{}) {
+	b.stmts = append(b.stmts, batchStmt{query: query, args: args})
+}
+
+// Len returns the number of queued statements.
+func (b *Batch) Len() int {
+	return len(b.stmts)
+}
+
+// sendBatch sends the statements of the Batch passed as an argument
+// in a single RPC request.
+func (s *Stmt) sendBatch(ctx context.Context, batch *Batch, headers []headerStruct) error {
+	conn := s.c
+	if strings.TrimSpace(s.query) != "" {
+		return errors.New("mssql: the query text must be empty when a Batch is passed")
+	}
+	if len(batch.stmts) == 0 {
+		return errors.New("mssql: the Batch has no statements")
+	}
+	calls := make([]rpcCall, len(batch.stmts))
+	for i, bs := range batch.stmts {
+		args, err := conn.batchArgs(bs.args)
+		if err != nil {
+			return fmt.Errorf("mssql: statement %d of the Batch: %v", i+1, err)
+		}
+		if conn.sess.logFlags&logSQL != 0 {
+			conn.sess.logger.Log(ctx, msdsn.LogSQL, bs.query)
+		}
+		// every statement is executed once, it is neither prepared nor uses a handle
+		st := &Stmt{c: conn, query: bs.query, paramCount: -1}
+		isProc := isProc(bs.query)
+		params, decls, err := st.makeRPCParams(args, isProc)
+		if err != nil {
+			return fmt.Errorf("mssql: statement %d of the Batch: %v", i+1, err)
+		}
+		proc := sp_ExecuteSql
+		if isProc {
+			proc.name = bs.query
+		} else {
+			params[0] = makeStrParam(conn.queryLabel(ctx) + bs.query)
+			params[1] = makeStrParam(strings.Join(decls, ","))
+		}
+		calls[i] = rpcCall{proc: proc, params: params}
+	}
+	reset := conn.resetSession
+	conn.resetSession = false
+	if err := sendRpcs(conn.sess.buf, headers, calls, reset); err != nil {
+		if conn.sess.logFlags&logErrors != 0 {
+			conn.sess.logger.Log(ctx, msdsn.LogErrors, fmt.Sprintf("Failed to send Rpc with %v", err))
+		}
+		conn.connectionGood = false
+		return fmt.Errorf("failed to send RPC: %v", err)
+	}
+	return nil
+}
+
+// batchArgs converts the arguments of a queued statement like database/sql
+// converts the arguments of Query and Exec.
+func (c *Conn) batchArgs(values []interface{}) ([]namedValue, error) {
+	args := make([]namedValue, 0, len(values))
+	for i, v := range values {
+		nv := driver.NamedValue{Ordinal: i + 1, Value: v}
+		if arg, ok := v.(sql.NamedArg); ok {
+			nv.Name, nv.Value = arg.Name, arg.Value
+		}
+		if _, ok := nv.Value.(*Batch); ok {
+			return nil, errors.New("a Batch cannot be queued in a Batch")
+		}
+		if err := c.CheckNamedValue(&nv); err != nil {
+			if err == driver.ErrRemoveArgument {
+				return nil, fmt.Errorf("%T cannot be passed to a statement of a Batch, pass it with the Batch", nv.Value)
+			}
+			return nil, err
+		}
+		args = append(args, namedValueFromDriverNamedValue(nv))
+	}
+	return args, nil
+}
@@ -0,0 +1,279 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/internal/sqlexec"
+)
+
+// Statement is a batch of a sqlcmd script.
+type Statement struct {
+	// SQL is the text of the batch, with the scripting variables substituted.
+	SQL string
+	// Count is the number of times the batch runs, the count of GO.
+	Count int
+	// IgnoreErrors is set when the batch ends after ":on error ignore".
+	IgnoreErrors bool
+	// Line is the line of the script the batch starts at, from 1.
+	Line int
+}
+
+// Parse splits a script written for sqlcmd into its batches. It supports
+// the sqlcmd commands that deployment scripts use:
+//
+//	:setvar name "value"   sets a scripting variable, without a value it removes it
+//	$(name)                is replaced by the value of the scripting variable name
+//	GO [count]             ends a batch, which runs count times
+//	:on error exit|ignore  stops the script at the first error of a batch, the default, or continues it
+//
+// vars are the initial scripting variables, :setvar overrides them. Variable
+// names are case insensitive and are substituted everywhere, also in strings
+// and comments, like sqlcmd does. A variable that is not set is an error, as
+// are the other sqlcmd commands, e.g. :r and :connect. Commands and GO are
+// only recognized on their own line, outside of strings and comments.
+func Parse(script string, vars map[string]string) ([]Statement, error) {
+	p := parser{vars: make(map[string]string, len(vars)), start: 1}
+	for name, value := range vars {
+		p.vars[strings.ToUpper(name)] = value
+	}
+	lines := strings.Split(strings.Replace(script, "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		lineNo := i + 1
+		line, err := p.substitute(line)
+		if err != nil {
+			return nil, fmt.Errorf("batch: line %d: %v", lineNo, err)
+		}
+		if p.state.normal() {
+			if m := goLine.FindStringSubmatch(line); m != nil {
+				count := 1
+				if m[1] != "" {
+					if count, err = strconv.Atoi(m[1]); err != nil || count < 1 {
+						return nil, fmt.Errorf("batch: line %d: invalid count %s of GO", lineNo, m[1])
+					}
+				}
+				p.add(count)
+				p.start = lineNo + 1
+				continue
+			}
+			if command := strings.TrimSpace(line); strings.HasPrefix(command, ":") {
+				if err = p.command(command[1:]); err != nil {
+					return nil, fmt.Errorf("batch: line %d: %v", lineNo, err)
+				}
+				if len(p.lines) == 0 {
+					p.start = lineNo + 1
+				}
+				continue
+			}
+		}
+		if len(p.lines) == 0 && strings.TrimSpace(line) == "" {
+			p.start = lineNo + 1
+			continue
+		}
+		p.lines = append(p.lines, line)
+		p.state.scan(line)
+	}
+	p.add(1)
+	return p.statements, nil
+}
+
+// Run runs the batches of a script written for sqlcmd, as described for
+// Parse, with e. Errors of batches that run with ":on error ignore" are
+// ignored, the first error of another batch stops the script and is returned.
+// A transaction that the script leaves open stays open on the connection.
+func Run(ctx context.Context, e sqlexec.Execer, script string, vars map[string]string) error {
+	statements, err := Parse(script, vars)
+	if err != nil {
+		return err
+	}
+	for _, s := range statements {
+		for i := 0; i < s.Count; i++ {
+			if _, err = e.ExecContext(ctx, s.SQL); err != nil && !s.IgnoreErrors {
+				return fmt.Errorf("batch: the batch at line %d: %w", s.Line, err)
+			}
+		}
+	}
+	return nil
+}
+
+// goLine matches a line with the batch separator and an optional count.
+var goLine = regexp.MustCompile(`(?i)^\s*go(?:\s+(\S+))?\s*(?:--.*)?$`)
+
+type parser struct {
+	vars         map[string]string
+	ignoreErrors bool
+	state        scanState
+	lines        []string
+	start        int
+	statements   []Statement
+}
+
+// add ends the current batch.
+func (p *parser) add(count int) {
+	lines := p.lines
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	text := strings.Join(lines, "\n")
+	p.lines = nil
+	p.state = scanState{}
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	p.statements = append(p.statements, Statement{
+		SQL:          text,
+		Count:        count,
+		IgnoreErrors: p.ignoreErrors,
+		Line:         p.start,
+	})
+}
+
+func (p *parser) command(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return fmt.Errorf("missing sqlcmd command")
+	}
+	switch strings.ToLower(fields[0]) {
+	case "setvar":
+		rest := strings.TrimSpace(command[strings.Index(command, fields[0])+len(fields[0]):])
+		if rest == "" {
+			return fmt.Errorf(":setvar needs a variable name")
+		}
+		name, value := rest, ""
+		hasValue := false
+		if sep := strings.IndexAny(rest, " \t"); sep >= 0 {
+			name, value = rest[:sep], strings.TrimSpace(rest[sep+1:])
+			hasValue = true
+		}
+		if !validName(name) {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+		if !hasValue {
+			delete(p.vars, strings.ToUpper(name))
+			return nil
+		}
+		if strings.HasPrefix(value, `"`) {
+			if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+				return fmt.Errorf("unterminated value of variable %s", name)
+			}
+			value = strings.Replace(value[1:len(value)-1], `""`, `"`, -1)
+		}
+		p.vars[strings.ToUpper(name)] = value
+		return nil
+	case "on":
+		if len(fields) != 3 || !strings.EqualFold(fields[1], "error") {
+			return fmt.Errorf("expected :on error exit or :on error ignore")
+		}
+		switch strings.ToLower(fields[2]) {
+		case "exit":
+			p.ignoreErrors = false
+		case "ignore":
+			p.ignoreErrors = true
+		default:
+			return fmt.Errorf("expected :on error exit or :on error ignore")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported sqlcmd command :%s", fields[0])
+}
+
+// substitute replaces the scripting variables of line by their values.
+func (p *parser) substitute(line string) (string, error) {
+	if !strings.Contains(line, "$(") {
+		return line, nil
+	}
+	var sb strings.Builder
+	for {
+		i := strings.Index(line, "$(")
+		if i < 0 {
+			break
+		}
+		end := strings.IndexByte(line[i:], ')')
+		if end < 0 {
+			break
+		}
+		name := line[i+2 : i+end]
+		if !validName(name) {
+			// not a variable, e.g. the text "$(" in a string
+			sb.WriteString(line[:i+2])
+			line = line[i+2:]
+			continue
+		}
+		value, ok := p.vars[strings.ToUpper(name)]
+		if !ok {
+			return "", fmt.Errorf("scripting variable %s is not set", name)
+		}
+		sb.WriteString(line[:i])
+		sb.WriteString(value)
+		line = line[i+end+1:]
+	}
+	sb.WriteString(line)
+	return sb.String(), nil
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// scanState is the state of the lexer at the end of a line.
+type scanState struct {
+	// quote is the closing character of the string or quoted identifier the line ends in.
+	quote byte
+	// comment is the nesting depth of the block comment the line ends in.
+	comment int
+}
+
+func (s scanState) normal() bool {
+	return s.quote == 0 && s.comment == 0
+}
+
+func (s *scanState) scan(line string) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		var next byte
+		if i+1 < len(line) {
+			next = line[i+1]
+		}
+		switch {
+		case s.comment > 0:
+			if c == '*' && next == '/' {
+				s.comment--
+				i++
+			} else if c == '/' && next == '*' {
+				s.comment++
+				i++
+			}
+		case s.quote != 0:
+			if c == s.quote {
+				if next == s.quote {
+					// an escaped quote
+					i++
+				} else {
+					s.quote = 0
+				}
+			}
+		case c == '-' && next == '-':
+			return
+		case c == '/' && next == '*':
+			s.comment++
+			i++
+		case c == '\'' || c == '"':
+			s.quote = c
+		case c == '[':
+			s.quote = ']'
+		}
+	}
+}
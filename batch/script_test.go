@@ -0,0 +1,86 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	script := `:setvar DatabaseName "sales"
+:setvar Owner app
+use [$(DatabaseName)]
+GO
+-- a comment
+create table $(owner).t (x varchar(10) default '$(Owner)')
+go 3
+:on error ignore
+select 'a
+go
+:setvar x 1
+$(missing)'
+GO
+/* :setvar y 2
+go */
+select 1
+`
+	got, err := Parse(script, map[string]string{"missing": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Statement{
+		{SQL: "use [sales]", Count: 1, Line: 3},
+		{SQL: "-- a comment\ncreate table app.t (x varchar(10) default 'app')", Count: 3, Line: 5},
+		{SQL: "select 'a\ngo\n:setvar x 1\nb'", Count: 1, IgnoreErrors: true, Line: 9},
+		{SQL: "/* :setvar y 2\ngo */\nselect 1", Count: 1, IgnoreErrors: true, Line: 14},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		script, err string
+	}{
+		{"select $(x)", "line 1: scripting variable x is not set"},
+		{":setvar x 1\n:setvar x\nselect $(x)", "line 3: scripting variable x is not set"},
+		{"select 1\n:r other.sql", "line 2: unsupported sqlcmd command :r"},
+		{":on error continue", "line 1: expected :on error exit or :on error ignore"},
+		{"select 1\ngo 0", "line 2: invalid count 0 of GO"},
+		{`:setvar x "1`, "line 1: unterminated value of variable x"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.script, nil)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Parse(%q) error = %v, want %q", test.script, err, test.err)
+		}
+	}
+}
+
+type fakeExecer struct {
+	queries []string
+	fail    map[string]error
+}
+
+func (e *fakeExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.queries = append(e.queries, query)
+	return nil, e.fail[query]
+}
+
+func TestRun(t *testing.T) {
+	errFailed := errors.New("failed")
+	e := &fakeExecer{fail: map[string]error{"select 2": errFailed, "select 4": errFailed}}
+	script := "select 1\ngo 2\n:on error ignore\nselect 2\ngo\n:on error exit\nselect 3\ngo\nselect 4\ngo\nselect 5"
+	err := Run(context.Background(), e, script, nil)
+	if !errors.Is(err, errFailed) || !strings.Contains(err.Error(), "line 9") {
+		t.Errorf("Run() error = %v", err)
+	}
+	want := []string{"select 1", "select 1", "select 2", "select 3", "select 4"}
+	if !reflect.DeepEqual(e.queries, want) {
+		t.Errorf("queries = %q, want %q", e.queries, want)
+	}
+}
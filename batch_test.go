@@ -0,0 +1,108 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestBatch(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=batchhost;user id=sa;password=pwd;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("update dbo.stock set qty = qty - @p1 where id = @p2", mssqltest.Response{Results: []mssqltest.Result{{RowsAffected: 1}}})
+	srv.Handle("select qty from dbo.stock where id = @id", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"qty"}, Rows: [][]interface{}{{9}}}}})
+	srv.Handle("dbo.audit", mssqltest.Response{Results: []mssqltest.Result{{RowsAffected: 3}}})
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	roundtrips := func() (n int64) {
+		conn.Raw(func(driverConn interface{}) error {
+			n = driverConn.(*Conn).Statistics().ServerRoundtrips
+			return nil
+		})
+		return
+	}
+
+	var b Batch
+	b.Queue("update dbo.stock set qty = qty - @p1 where id = @p2", 1, 10)
+	b.Queue("select qty from dbo.stock where id = @id", sql.Named("id", 10))
+	b.Queue("dbo.audit", sql.Named("text", strings.Repeat("x", 5000)), nil)
+	if b.Len() != 3 {
+		t.Errorf("expected 3 statements, got %d", b.Len())
+	}
+	before := roundtrips()
+	rows, err := conn.QueryContext(ctx, "", &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var qty int
+	if !rows.Next() {
+		t.Fatal("expected the results of the select")
+	}
+	if err = rows.Scan(&qty); err != nil || qty != 9 {
+		t.Errorf("expected 9, got %d, %v", qty, err)
+	}
+	if rows.NextResultSet() {
+		t.Error("expected no more result sets")
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if n := roundtrips() - before; n != 1 {
+		t.Errorf("expected the batch to be sent in 1 round trip, got %d", n)
+	}
+
+	var counts RowCounts
+	res, err := conn.ExecContext(ctx, "", &b, &counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 5 {
+		t.Errorf("expected 5 rows affected, got %d", n)
+	}
+	if len(counts) != 3 || counts[0] != 1 || counts[1] != 1 || counts[2] != 3 {
+		t.Errorf("expected a row count per statement, got %v", counts)
+	}
+	queries := srv.Queries()
+	expected := []string{
+		"update dbo.stock set qty = qty - @p1 where id = @p2",
+		"select qty from dbo.stock where id = @id",
+		"dbo.audit",
+	}
+	if !equalStrings(queries[len(queries)-3:], expected) {
+		t.Errorf("expected the statements of the batch, got %v", queries)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=batchhost;user id=sa;password=pwd;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+
+	var b Batch
+	if _, err := db.ExecContext(ctx, "", &b); err == nil || !strings.Contains(err.Error(), "no statements") {
+		t.Errorf("expected an error for an empty batch, got %v", err)
+	}
+	b.Queue("select 1")
+	if _, err := db.ExecContext(ctx, "select 2", &b); err == nil || !strings.Contains(err.Error(), "must be empty") {
+		t.Errorf("expected an error for a query text, got %v", err)
+	}
+	var b2 Batch
+	var rs ReturnStatus
+	b2.Queue("select 1", &rs)
+	if _, err := db.ExecContext(ctx, "", &b2); err == nil || !strings.Contains(err.Error(), "statement 1") {
+		t.Errorf("expected an error for a marker argument of a statement, got %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "", &b); err != nil {
+		t.Errorf("expected the connection to be usable after the errors, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"time"
 )
 
 type packetType uint8
@@ -52,6 +53,18 @@ type tdsBuffer struct {
 	rsize       int
 	final       bool
 	rPacketType packetType
+	rSpid       uint16 // session id the server put in the last packet header
+
+	// stats counts the packets, it is nil when the statistics are not collected
+	stats *statsCounters
+	// trace writes the packets, it is nil when tracing is off
+	trace *tracer
+	// lastRead is the time the last packet was received, to tell how long the connection was idle
+	lastRead time.Time
+	// plpScratch is reused to read PLP strings before they are decoded
+	plpScratch []byte
+	// nbcBitmap is reused to read the null bitmaps of NBCROW tokens
+	nbcBitmap []byte
 
 	// afterFirst is assigned to right after tdsBuffer is created and
 	// before the first use. It is executed after the first packet is
@@ -90,8 +103,21 @@ func (w *tdsBuffer) flush() (err error) {
 
 	// Write packet into underlying transport.
 	if _, err = w.transport.Write(w.wbuf[:w.wpos]); err != nil {
+		if w.trace != nil {
+			w.trace.error("write", err)
+		}
 		return err
 	}
+	if w.trace != nil {
+		w.trace.packet("sent", w.wbuf[:w.wpos])
+	}
+	if w.stats != nil {
+		final := w.wbuf[1]&1 != 0
+		w.stats.sent(w.wpos, final)
+		if final {
+			w.stats.requestSent(time.Now())
+		}
+	}
 	// It is possible to create a whole new buffer after a flush.
 	// Useful for debugging. Normally reuse the buffer.
 	// w.wbuf = make([]byte, 1<<16)
@@ -156,6 +182,21 @@ func (w *tdsBuffer) FinishPacket() error {
 var headerSize = binary.Size(header{})
 
 func (r *tdsBuffer) readNextPacket() error {
+	err := r.readPacket()
+	if err == nil {
+		r.lastRead = time.Now()
+	}
+	if r.trace != nil {
+		if err != nil {
+			r.trace.error("read", err)
+		} else {
+			r.trace.packet("received", r.rbuf[:r.rsize])
+		}
+	}
+	return err
+}
+
+func (r *tdsBuffer) readPacket() error {
 	buf := r.rbuf[:headerSize]
 	_, err := io.ReadFull(r.transport, buf)
 	if err != nil {
@@ -185,6 +226,10 @@ func (r *tdsBuffer) readNextPacket() error {
 	r.rsize = int(h.Size)
 	r.final = h.Status != 0
 	r.rPacketType = h.PacketType
+	r.rSpid = h.Spid
+	if r.stats != nil {
+		r.stats.received(r.rsize, r.final, time.Now())
+	}
 	return nil
 }
 
@@ -297,6 +342,49 @@ func (r *tdsBuffer) UsVarChar() string {
 	return readUsVarCharOrPanic(r)
 }
 
+// appendN reads n bytes and appends them to buf, without the
+// intermediate copies of io.CopyN.
+func (r *tdsBuffer) appendN(buf []byte, n int) ([]byte, error) {
+	for n > 0 {
+		if r.rpos == r.rsize {
+			if r.final {
+				return buf, io.ErrUnexpectedEOF
+			}
+			if err := r.readNextPacket(); err != nil {
+				return buf, err
+			}
+		}
+		k := r.rsize - r.rpos
+		if k > n {
+			k = n
+		}
+		buf = append(buf, r.rbuf[r.rpos:r.rpos+k]...)
+		r.rpos += k
+		n -= k
+	}
+	return buf, nil
+}
+
+// skip reads n bytes and discards them.
+func (r *tdsBuffer) skip(n int) {
+	for n > 0 {
+		if r.rpos == r.rsize {
+			if r.final {
+				badStreamPanic(io.ErrUnexpectedEOF)
+			}
+			if err := r.readNextPacket(); err != nil {
+				badStreamPanic(err)
+			}
+		}
+		k := r.rsize - r.rpos
+		if k > n {
+			k = n
+		}
+		r.rpos += k
+		n -= k
+	}
+}
+
 func (r *tdsBuffer) Read(buf []byte) (copied int, err error) {
 	copied = 0
 	err = nil
@@ -557,3 +557,14 @@ func TestReadBVarCharOrPanic(t *testing.T) {
 	_ = readBVarCharOrPanic(memBuf)
 	t.Fatal("readBVarCharOrPanic() should panic on empty buffer, but it didn't")
 }
+
+func TestReadSpid(t *testing.T) {
+	buffer := makeBuf(100, []byte{0x04, 0x01, 0x00, 0x09, 0x00, 0x35, 0x01, 0x00, 0xff})
+	if _, err := buffer.BeginRead(); err != nil {
+		t.Fatal(err)
+	}
+	c := &Conn{sess: &tdsSession{buf: buffer}}
+	if spid := c.SPID(); spid != 53 {
+		t.Errorf("expected spid 53, got %d", spid)
+	}
+}
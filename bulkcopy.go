@@ -7,6 +7,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -29,6 +30,10 @@ type Bulk struct {
 	columnsName []string
 	tablename   string
 	numRows     int
+	// batchRows are the rows of the current batch, rowCount the rows of
+	// the completed batches.
+	batchRows int
+	rowCount  int64
 
 	headerSent bool
 	Options    BulkOptions
@@ -42,6 +47,13 @@ type BulkOptions struct {
 	RowsPerBatch      int
 	Order             []string
 	Tablock           bool
+	// BatchSize, when greater than 0, sends the rows in batches of BatchSize
+	// rows, every batch with its own INSERT BULK, instead of a single batch.
+	BatchSize int
+	// Columnstore checks that the table has a clustered columnstore index
+	// and that the batches are large enough to be compressed into
+	// rowgroups directly, see ColumnstoreBulkOptions.
+	Columnstore bool
 }
 
 type DataValue interface{}
@@ -65,32 +77,10 @@ func (cn *Conn) CreateBulkContext(ctx context.Context, table string, columns []s
 }
 
 func (b *Bulk) sendBulkCommand(ctx context.Context) (err error) {
-	//get table columns info
-	err = b.getMetadata(ctx)
-	if err != nil {
-		return err
-	}
-
-	//match the columns
-	for _, colname := range b.columnsName {
-		var bulkCol *columnStruct
-
-		for _, m := range b.metadata {
-			if m.ColName == colname {
-				bulkCol = &m
-				break
-			}
-		}
-		if bulkCol != nil {
-
-			if bulkCol.ti.TypeId == typeUdt {
-				//send udt as binary
-				bulkCol.ti.TypeId = typeBigVarBin
-			}
-			b.bulkColumns = append(b.bulkColumns, *bulkCol)
-			b.dlogf(ctx, "Adding column %s %s %#x", colname, bulkCol.ColName, bulkCol.ti.TypeId)
-		} else {
-			return fmt.Errorf("column %s does not exist in destination table %s", colname, b.tablename)
+	// the columns are matched for the first batch
+	if len(b.bulkColumns) == 0 {
+		if err = b.matchColumns(ctx); err != nil {
+			return err
 		}
 	}
 
@@ -159,6 +149,46 @@ func (b *Bulk) sendBulkCommand(ctx context.Context) (err error) {
 	return
 }
 
+// matchColumns reads the columns of the table and matches the columns of
+// the bulk copy with them.
+func (b *Bulk) matchColumns(ctx context.Context) (err error) {
+	if b.Options.Columnstore {
+		if err = b.checkColumnstore(ctx); err != nil {
+			return err
+		}
+	}
+
+	//get table columns info
+	err = b.getMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	//match the columns
+	for _, colname := range b.columnsName {
+		var bulkCol *columnStruct
+
+		for _, m := range b.metadata {
+			if m.ColName == colname {
+				bulkCol = &m
+				break
+			}
+		}
+		if bulkCol != nil {
+
+			if bulkCol.ti.TypeId == typeUdt {
+				//send udt as binary
+				bulkCol.ti.TypeId = typeBigVarBin
+			}
+			b.bulkColumns = append(b.bulkColumns, *bulkCol)
+			b.dlogf(ctx, "Adding column %s %s %#x", colname, bulkCol.ColName, bulkCol.ti.TypeId)
+		} else {
+			return fmt.Errorf("column %s does not exist in destination table %s", colname, b.tablename)
+		}
+	}
+	return nil
+}
+
 // AddRow immediately writes the row to the destination table.
 // The arguments are the row values in the order they were specified.
 func (b *Bulk) AddRow(row []interface{}) (err error) {
@@ -178,13 +208,22 @@ func (b *Bulk) AddRow(row []interface{}) (err error) {
 	if err != nil {
 		return
 	}
+	return b.writeRow(bytes)
+}
 
-	_, err = b.cn.sess.buf.Write(bytes)
+// writeRow writes the encoded row data and ends the batch when it has
+// BatchSize rows.
+func (b *Bulk) writeRow(data []byte) (err error) {
+	_, err = b.cn.sess.buf.Write(data)
 	if err != nil {
 		return
 	}
 
 	b.numRows = b.numRows + 1
+	b.batchRows++
+	if b.Options.BatchSize > 0 && b.batchRows >= b.Options.BatchSize {
+		err = b.finishBatch()
+	}
 	return
 }
 
@@ -196,7 +235,7 @@ func (b *Bulk) makeRowData(row []interface{}) ([]byte, error) {
 	for i, col := range b.bulkColumns {
 
 		if b.Debug {
-			logcol.WriteString(fmt.Sprintf(" col[%d]='%v' ", i, row[i]))
+			logcol.WriteString(fmt.Sprintf(" col[%d]='%s' ", i, b.cn.redactParam(row[i])))
 		}
 		param, err := b.makeParam(row[i], col)
 		if err != nil {
@@ -218,11 +257,16 @@ func (b *Bulk) makeRowData(row []interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Done sends the last batch and returns the number of rows copied.
 func (b *Bulk) Done() (rowcount int64, err error) {
-	if !b.headerSent {
-		//no rows had been sent
-		return 0, nil
+	if b.headerSent {
+		err = b.finishBatch()
 	}
+	return b.rowCount, err
+}
+
+// finishBatch ends the current batch and waits for the server to commit it.
+func (b *Bulk) finishBatch() error {
 	var buf = b.cn.sess.buf
 	buf.WriteByte(byte(tokenDone))
 
@@ -238,12 +282,20 @@ func (b *Bulk) Done() (rowcount int64, err error) {
 	buf.FinishPacket()
 
 	reader := startReading(b.cn.sess, b.ctx, outputs{})
-	err = reader.iterateResponse()
+	err := reader.iterateResponse()
 	if err != nil {
-		return 0, b.cn.checkBadConn(b.ctx, err, false)
+		return b.cn.checkBadConn(b.ctx, err, false)
 	}
 
-	return reader.rowCount, nil
+	if b.Options.Columnstore && b.batchRows < columnstoreMinRowGroupRows {
+		b.cn.logEvent(b.ctx, Event{Type: EventWarning, Message: fmt.Sprintf(
+			"bulk copy: the batch of %d rows is below %d rows and was loaded into the delta store of %s",
+			b.batchRows, columnstoreMinRowGroupRows, b.tablename)})
+	}
+	b.rowCount += reader.rowCount
+	b.batchRows = 0
+	b.headerSent = false
+	return nil
 }
 
 func (b *Bulk) createColMetadata() []byte {
@@ -381,7 +433,7 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 		case int64:
 			floatvalue = float64(val)
 		default:
-			err = fmt.Errorf("mssql: invalid type for float column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for float column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 
@@ -410,7 +462,7 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 		case []byte:
 			res.buffer = val
 		default:
-			err = fmt.Errorf("mssql: invalid type for nvarchar column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for nvarchar column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 		res.ti.Size = len(res.buffer)
@@ -432,14 +484,14 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 		case int64:
 			res.buffer = []byte(strconv.FormatInt(val, 10))
 		default:
-			err = fmt.Errorf("mssql: invalid type for varchar column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for varchar column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 		res.ti.Size = len(res.buffer)
 
 	case typeBit, typeBitN:
 		if reflect.TypeOf(val).Kind() != reflect.Bool {
-			err = fmt.Errorf("mssql: invalid type for bit column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for bit column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 		res.ti.TypeId = typeBitN
@@ -456,12 +508,12 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 		case string:
 			var t time.Time
 			if t, err = time.Parse(sqlDateTimeFormat, val); err != nil {
-				return res, fmt.Errorf("bulk: unable to convert string to date: %v", err)
+				return res, fmt.Errorf("bulk: unable to convert string to date: %s is not in the format %s", b.cn.redactParam(val), sqlDateTimeFormat)
 			}
 			res.buffer = encodeDateTime2(t, int(col.ti.Scale))
 			res.ti.Size = len(res.buffer)
 		default:
-			err = fmt.Errorf("mssql: invalid type for datetime2 column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for datetime2 column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 	case typeDateTimeOffsetN:
@@ -472,12 +524,12 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 		case string:
 			var t time.Time
 			if t, err = time.Parse(sqlDateTimeFormat, val); err != nil {
-				return res, fmt.Errorf("bulk: unable to convert string to date: %v", err)
+				return res, fmt.Errorf("bulk: unable to convert string to date: %s is not in the format %s", b.cn.redactParam(val), sqlDateTimeFormat)
 			}
 			res.buffer = encodeDateTimeOffset(t, int(col.ti.Scale))
 			res.ti.Size = len(res.buffer)
 		default:
-			err = fmt.Errorf("mssql: invalid type for datetimeoffset column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for datetimeoffset column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 	case typeDateN:
@@ -488,12 +540,12 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 		case string:
 			var t time.Time
 			if t, err = time.ParseInLocation(sqlDateFormat, val, time.UTC); err != nil {
-				return res, fmt.Errorf("bulk: unable to convert string to date: %v", err)
+				return res, fmt.Errorf("bulk: unable to convert string to date: %s is not in the format %s", b.cn.redactParam(val), sqlDateFormat)
 			}
 			res.buffer = encodeDate(t)
 			res.ti.Size = len(res.buffer)
 		default:
-			err = fmt.Errorf("mssql: invalid type for date column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for date column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 	case typeDateTime, typeDateTimeN, typeDateTim4:
@@ -503,10 +555,10 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 			t = val
 		case string:
 			if t, err = time.Parse(sqlDateTimeFormat, val); err != nil {
-				return res, fmt.Errorf("bulk: unable to convert string to date: %v", err)
+				return res, fmt.Errorf("bulk: unable to convert string to date: %s is not in the format %s", b.cn.redactParam(val), sqlDateTimeFormat)
 			}
 		default:
-			err = fmt.Errorf("mssql: invalid type for datetime column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for datetime column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 
@@ -532,80 +584,21 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 			res.buffer = encodeTime(t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), int(col.ti.Scale))
 			res.ti.Size = len(res.buffer)
 		default:
-			err = fmt.Errorf("mssql: invalid type for time column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for time column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 	// case typeMoney, typeMoney4, typeMoneyN:
 	case typeDecimal, typeDecimalN, typeNumeric, typeNumericN:
-		prec := col.ti.Prec
-		scale := col.ti.Scale
-		var dec decimal.Decimal
-		switch v := val.(type) {
-		case int:
-			dec = decimal.Int64ToDecimalScale(int64(v), 0)
-		case int8:
-			dec = decimal.Int64ToDecimalScale(int64(v), 0)
-		case int16:
-			dec = decimal.Int64ToDecimalScale(int64(v), 0)
-		case int32:
-			dec = decimal.Int64ToDecimalScale(int64(v), 0)
-		case int64:
-			dec = decimal.Int64ToDecimalScale(int64(v), 0)
-		case float32:
-			dec, err = decimal.Float64ToDecimalScale(float64(v), scale)
-		case float64:
-			dec, err = decimal.Float64ToDecimalScale(float64(v), scale)
-		case string:
-			dec, err = decimal.StringToDecimalScale(v, scale)
-		default:
-			return res, fmt.Errorf("unknown value for decimal: %T %#v", v, v)
-		}
-
-		if err != nil {
-			return res, err
-		}
-		dec.SetPrec(prec)
-
-		var length byte
-		switch {
-		case prec <= 9:
-			length = 4
-		case prec <= 19:
-			length = 8
-		case prec <= 28:
-			length = 12
-		default:
-			length = 16
-		}
-
-		buf := make([]byte, length+1)
 		// first byte length written by typeInfo.writer
-		res.ti.Size = int(length) + 1
-		// second byte sign
-		if !dec.IsPositive() {
-			buf[0] = 0
-		} else {
-			buf[0] = 1
-		}
-
-		ub := dec.UnscaledBytes()
-		l := len(ub)
-		if l > int(length) {
-			err = fmt.Errorf("decimal out of range: %s", dec)
-			return res, err
-		}
-		// reverse the bytes
-		for i, j := 1, l-1; j >= 0; i, j = i+1, j-1 {
-			buf[i] = ub[j]
-		}
-		res.buffer = buf
-	case typeBigVarBin, typeBigBinary:
+		res.ti.Size = decimalLength(col.ti.Prec) + 1
+		res.buffer, err = encodeDecimal(b.cn, val, col.ti.Prec, col.ti.Scale)
+	case typeBigVarBin, typeBigBinary, typeImage:
 		switch val := val.(type) {
 		case []byte:
 			res.ti.Size = len(val)
 			res.buffer = val
 		default:
-			err = fmt.Errorf("mssql: invalid type for Binary column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for Binary column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 	case typeGuid:
@@ -614,7 +607,7 @@ func (b *Bulk) makeParam(val DataValue, col columnStruct) (res param, err error)
 			res.ti.Size = len(val)
 			res.buffer = val
 		default:
-			err = fmt.Errorf("mssql: invalid type for Guid column: %T %s", val, val)
+			err = fmt.Errorf("mssql: invalid type for Guid column: %T %s", val, b.cn.redactParam(val))
 			return
 		}
 
@@ -630,3 +623,70 @@ func (b *Bulk) dlogf(ctx context.Context, format string, v ...interface{}) {
 		b.cn.sess.logger.Log(ctx, msdsn.LogDebug, fmt.Sprintf(format, v...))
 	}
 }
+
+// decimalLength returns the number of bytes of the integer part of a decimal
+func decimalLength(prec uint8) int {
+	switch {
+	case prec <= 9:
+		return 4
+	case prec <= 19:
+		return 8
+	case prec <= 28:
+		return 12
+	default:
+		return 16
+	}
+}
+
+// encodeDecimal encodes val as decimal(prec, scale), the sign byte followed
+// by the unscaled little endian integer. Values with more digits than prec
+// are out of range, values with more fractional digits than scale are
+// rejected, except floats, which are rounded. c redacts the value in errors.
+func encodeDecimal(c *Conn, val interface{}, prec, scale uint8) ([]byte, error) {
+	var dec decimal.Decimal
+	var err error
+	switch v := val.(type) {
+	case int:
+		dec, err = decimal.StringToDecimalScale(strconv.FormatInt(int64(v), 10), scale)
+	case int8:
+		dec, err = decimal.StringToDecimalScale(strconv.FormatInt(int64(v), 10), scale)
+	case int16:
+		dec, err = decimal.StringToDecimalScale(strconv.FormatInt(int64(v), 10), scale)
+	case int32:
+		dec, err = decimal.StringToDecimalScale(strconv.FormatInt(int64(v), 10), scale)
+	case int64:
+		dec, err = decimal.StringToDecimalScale(strconv.FormatInt(v, 10), scale)
+	case float32:
+		dec, err = decimal.Float64ToDecimalScale(float64(v), scale)
+	case float64:
+		dec, err = decimal.Float64ToDecimalScale(v, scale)
+	case string:
+		dec, err = decimal.StringToDecimalScale(v, scale)
+	default:
+		return nil, fmt.Errorf("mssql: invalid type for decimal: %T %s", val, c.redactParam(val))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mssql: invalid value for decimal(%d, %d): %T %s", prec, scale, val, c.redactParam(val))
+	}
+	// the unscaled integer has at most prec digits, prec-scale before the point
+	unscaled := dec.BigInt()
+	if unscaled.CmpAbs(decimalLimit(prec)) >= 0 {
+		return nil, fmt.Errorf("mssql: decimal out of range for decimal(%d, %d): %s", prec, scale, c.redactParam(val))
+	}
+	buf := make([]byte, decimalLength(prec)+1)
+	if dec.IsPositive() {
+		buf[0] = 1
+	}
+	// the integer is little endian
+	ub := unscaled.Bytes()
+	for i, j := 1, len(ub)-1; j >= 0; i, j = i+1, j-1 {
+		buf[i] = ub[j]
+	}
+	return buf, nil
+}
+
+// decimalLimit returns 10^prec, the smallest unscaled integer that does
+// not fit in a decimal of precision prec.
+func decimalLimit(prec uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(prec)), nil)
+}
@@ -0,0 +1,68 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+// Sizes of the rowgroups of columnstore indexes. A bulk load batch of at
+// least columnstoreMinRowGroupRows rows is compressed into rowgroups
+// directly, smaller batches are inserted into the delta store, which is
+// compressed later by the tuple mover. A rowgroup holds at most
+// columnstoreMaxRowGroupRows rows.
+// https://learn.microsoft.com/sql/relational-databases/indexes/columnstore-indexes-data-loading-guidance
+const (
+	columnstoreMinRowGroupRows = 102400
+	columnstoreMaxRowGroupRows = 1048576
+)
+
+// clusteredColumnstoreSQL counts the clustered columnstore indexes of a table.
+const clusteredColumnstoreSQL = "select count(*) from sys.indexes where object_id = object_id(@p1) and type = 5"
+
+// ColumnstoreBulkOptions returns BulkOptions to load a table with a
+// clustered columnstore index, so the rows are compressed into columnstore
+// rowgroups directly instead of being inserted into the delta store:
+//
+//	bulk := conn.CreateBulkContext(ctx, "dbo.sales", columns)
+//	bulk.Options = mssql.ColumnstoreBulkOptions("sale_date")
+//
+// The rows are sent in batches of 1048576 rows, the size of a full rowgroup.
+// order lists the columns the rows are sorted by, sort them by the columns
+// queries filter on so the server can skip the segments of rowgroups. The
+// load fails when the table has no clustered columnstore index, and an
+// EventWarning is logged for a batch of less than 102400 rows, usually the
+// last one, which is loaded into the delta store.
+func ColumnstoreBulkOptions(order ...string) BulkOptions {
+	return BulkOptions{
+		BatchSize:   columnstoreMaxRowGroupRows,
+		Order:       order,
+		Columnstore: true,
+	}
+}
+
+// checkColumnstore checks the options and that the table of a bulk copy
+// with the Columnstore option has a clustered columnstore index.
+func (b *Bulk) checkColumnstore(ctx context.Context) error {
+	if b.Options.BatchSize > 0 && b.Options.BatchSize < columnstoreMinRowGroupRows {
+		return fmt.Errorf("mssql: bulk copy: batches of %d rows are loaded into the delta store of a columnstore index, use at least %d rows",
+			b.Options.BatchSize, columnstoreMinRowGroupRows)
+	}
+	stmt, err := b.cn.prepareContext(ctx, clusteredColumnstoreSQL)
+	if err != nil {
+		return err
+	}
+	rows, err := stmt.QueryContext(ctx, []driver.NamedValue{{Ordinal: 1, Value: b.tablename}})
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err = rows.Next(dest); err != nil {
+		return err
+	}
+	if n, _ := dest[0].(int64); n == 0 {
+		return fmt.Errorf("mssql: bulk copy: %s has no clustered columnstore index", b.tablename)
+	}
+	return rows.Close()
+}
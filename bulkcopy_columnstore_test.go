@@ -0,0 +1,52 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestColumnstoreBulkOptions(t *testing.T) {
+	opts := ColumnstoreBulkOptions("sale_date")
+	if !opts.Columnstore || opts.BatchSize != columnstoreMaxRowGroupRows || len(opts.Order) != 1 || opts.Order[0] != "sale_date" {
+		t.Errorf("unexpected options %+v", opts)
+	}
+}
+
+func TestColumnstoreBulkCheck(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	var checks []string
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if query != clusteredColumnstoreSQL {
+			return mssqltest.Response{Err: &mssqltest.Error{Number: 208, Message: "unexpected query " + query}}
+		}
+		checks = append(checks, query)
+		return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{0}}}}}
+	})
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(driverConn interface{}) error {
+		bulk := driverConn.(*Conn).CreateBulkContext(ctx, "dbo.sales", []string{"id"})
+		bulk.Options = ColumnstoreBulkOptions()
+		bulk.Options.BatchSize = 1000
+		if err := bulk.AddRow([]interface{}{1}); err == nil || !strings.Contains(err.Error(), "delta store") {
+			t.Errorf("expected an error for small batches, got %v", err)
+		}
+		bulk.Options = ColumnstoreBulkOptions()
+		return bulk.AddRow([]interface{}{1})
+	})
+	if err == nil || !strings.Contains(err.Error(), "has no clustered columnstore index") {
+		t.Errorf("expected an error for a table without a clustered columnstore index, got %v", err)
+	}
+	if len(checks) != 1 {
+		t.Errorf("expected the index to be checked once, got %d", len(checks))
+	}
+}
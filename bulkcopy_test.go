@@ -438,3 +438,32 @@ func setupTable(ctx context.Context, t *testing.T, conn *sql.Conn, tableName str
 	}
 	return
 }
+
+func TestBulkDecimalParam(t *testing.T) {
+	b := &Bulk{}
+	values := []struct {
+		val      interface{}
+		expected string
+	}{
+		{5, "5.00"},
+		{int64(math.MinInt64), "-9223372036854775808.00"},
+		{-1234.567, "-1234.57"},
+		{"99999999999999999999.99", "99999999999999999999.99"},
+	}
+	col := columnStruct{ti: typeInfo{TypeId: typeDecimalN, Prec: 22, Scale: 2}}
+	for _, v := range values {
+		p, err := b.makeParam(v.val, col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ti.Size != 13 || len(p.buffer) != 13 {
+			t.Fatalf("expected 13 bytes for decimal(22, 2), got size %d with %d bytes", p.ti.Size, len(p.buffer))
+		}
+		if got := decodeDecimal(col.ti.Prec, col.ti.Scale, p.buffer); string(got) != v.expected {
+			t.Errorf("expected %s for %v, got %s", v.expected, v.val, got)
+		}
+	}
+	if _, err := b.makeParam("100000000000000000000", col); err == nil {
+		t.Error("expected an error for 21 digits before the point of decimal(22, 2)")
+	}
+}
@@ -0,0 +1,379 @@
+package mssql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/internal/decimal"
+)
+
+// BulkRowWriter writes the rows of a bulk copy value by value, in the order
+// of the columns, without boxing the values in an interface{} like AddRow:
+//
+//	w, err := bulk.RowWriter()
+//	...
+//	for i := range ids {
+//		w.Int64(ids[i])
+//		w.String(names[i])
+//		if err := w.EndRow(); err != nil {
+//			...
+//		}
+//	}
+//
+// The values are encoded straight into the type of their column. The
+// combinations of a value and a column that AddRow converts, like a string
+// into a date column, are converted the same way.
+type BulkRowWriter struct {
+	b      *Bulk
+	buf    bytes.Buffer
+	col    int
+	err    error
+	logcol bytes.Buffer
+	// scratch holds the fixed size values before they are written
+	scratch [17]byte
+}
+
+// RowWriter returns the writer of the rows of b. The first call sends the
+// bulk command, so that the columns of the table are known.
+func (b *Bulk) RowWriter() (*BulkRowWriter, error) {
+	if !b.headerSent {
+		if err := b.sendBulkCommand(b.ctx); err != nil {
+			return nil, err
+		}
+	}
+	w := &BulkRowWriter{b: b}
+	w.reset()
+	return w, nil
+}
+
+func (w *BulkRowWriter) reset() {
+	w.buf.Reset()
+	w.buf.WriteByte(byte(tokenRow))
+	w.col = 0
+	w.err = nil
+	w.logcol.Reset()
+}
+
+// next returns the column of the next value, ok is false after an error.
+func (w *BulkRowWriter) next() (col *columnStruct, ok bool) {
+	if w.err != nil {
+		return nil, false
+	}
+	if w.col >= len(w.b.bulkColumns) {
+		w.err = fmt.Errorf("row does not have the same number of columns than the destination table %d %d",
+			w.col+1, len(w.b.bulkColumns))
+		return nil, false
+	}
+	col = &w.b.bulkColumns[w.col]
+	if col.ti.Writer == nil {
+		w.err = fmt.Errorf("no writer for column: %s, TypeId: %#x", col.ColName, col.ti.TypeId)
+		return nil, false
+	}
+	return col, true
+}
+
+// write writes the value data of size bytes into the column col, a nil
+// data is NULL.
+func (w *BulkRowWriter) write(col *columnStruct, size int, data []byte) {
+	ti := col.ti
+	ti.Size = size
+	if err := col.ti.Writer(&w.buf, ti, data); err != nil {
+		w.err = fmt.Errorf("bulkcopy: %s", err.Error())
+		return
+	}
+	w.col++
+}
+
+// value converts v into the column col like AddRow does.
+func (w *BulkRowWriter) value(col *columnStruct, v interface{}) {
+	param, err := w.b.makeParam(v, *col)
+	if err != nil {
+		w.err = fmt.Errorf("bulkcopy: %s", err.Error())
+		return
+	}
+	w.write(col, param.ti.Size, param.buffer)
+}
+
+func (w *BulkRowWriter) log(v interface{}) {
+	w.logcol.WriteString(fmt.Sprintf(" col[%d]='%s' ", w.col, w.b.cn.redactParam(v)))
+}
+
+// Null writes NULL into the next column.
+func (w *BulkRowWriter) Null() {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	if w.b.Debug {
+		w.log(nil)
+	}
+	w.write(col, 0, nil)
+}
+
+// Bool writes v into the next column.
+func (w *BulkRowWriter) Bool(v bool) {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	if w.b.Debug {
+		w.log(v)
+	}
+	switch col.ti.TypeId {
+	case typeBit, typeBitN:
+		w.scratch[0] = 0
+		if v {
+			w.scratch[0] = 1
+		}
+		w.write(col, 1, w.scratch[:1])
+	default:
+		w.value(col, v)
+	}
+}
+
+// Int64 writes v into the next column.
+func (w *BulkRowWriter) Int64(v int64) {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	if w.b.Debug {
+		w.log(v)
+	}
+	switch col.ti.TypeId {
+	case typeInt1, typeInt2, typeInt4, typeInt8, typeIntN:
+		w.writeInt(col, v)
+	case typeFlt4, typeFlt8, typeFltN:
+		w.writeFloat(col, float64(v))
+	default:
+		w.value(col, v)
+	}
+}
+
+// Float64 writes v into the next column.
+func (w *BulkRowWriter) Float64(v float64) {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	if w.b.Debug {
+		w.log(v)
+	}
+	switch col.ti.TypeId {
+	case typeFlt4, typeFlt8, typeFltN:
+		w.writeFloat(col, v)
+	case typeInt1, typeInt2, typeInt4, typeInt8, typeIntN:
+		w.writeInt(col, int64(v))
+	default:
+		w.value(col, v)
+	}
+}
+
+func (w *BulkRowWriter) writeInt(col *columnStruct, v int64) {
+	buf := w.scratch[:col.ti.Size]
+	switch col.ti.Size {
+	case 1:
+		buf[0] = byte(v)
+	case 2:
+		binary.LittleEndian.PutUint16(buf, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(buf, uint32(v))
+	case 8:
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+	}
+	w.write(col, col.ti.Size, buf)
+}
+
+func (w *BulkRowWriter) writeFloat(col *columnStruct, v float64) {
+	switch col.ti.Size {
+	case 4:
+		binary.LittleEndian.PutUint32(w.scratch[:4], math.Float32bits(float32(v)))
+		w.write(col, 4, w.scratch[:4])
+	case 8:
+		binary.LittleEndian.PutUint64(w.scratch[:8], math.Float64bits(v))
+		w.write(col, 8, w.scratch[:8])
+	default:
+		w.write(col, col.ti.Size, nil)
+	}
+}
+
+// String writes v into the next column.
+func (w *BulkRowWriter) String(v string) {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	if w.b.Debug {
+		w.log(v)
+	}
+	switch col.ti.TypeId {
+	case typeNVarChar, typeNText, typeNChar:
+		data := str2ucs2(v)
+		w.write(col, len(data), data)
+	case typeVarChar, typeBigVarChar, typeText, typeChar, typeBigChar:
+		w.write(col, len(v), []byte(v))
+	default:
+		w.value(col, v)
+	}
+}
+
+// Bytes writes v into the next column, a nil v is NULL.
+func (w *BulkRowWriter) Bytes(v []byte) {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	if w.b.Debug {
+		w.log(v)
+	}
+	if v == nil {
+		w.write(col, 0, nil)
+		return
+	}
+	switch col.ti.TypeId {
+	case typeBigVarBin, typeBigBinary, typeImage, typeGuid,
+		typeNVarChar, typeNText, typeNChar,
+		typeVarChar, typeBigVarChar, typeText, typeChar, typeBigChar:
+		w.write(col, len(v), v)
+	default:
+		w.value(col, v)
+	}
+}
+
+// Time writes v into the next column.
+func (w *BulkRowWriter) Time(v time.Time) {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	if w.b.Debug {
+		w.log(v)
+	}
+	var data []byte
+	switch col.ti.TypeId {
+	case typeDateTime2N:
+		data = encodeDateTime2(v, int(col.ti.Scale))
+	case typeDateTimeOffsetN:
+		data = encodeDateTimeOffset(v, int(col.ti.Scale))
+	case typeDateN:
+		data = encodeDate(v)
+	case typeDateTime, typeDateTimeN, typeDateTim4:
+		switch col.ti.Size {
+		case 4:
+			data = encodeDateTim4(v)
+		case 8:
+			data = encodeDateTime(v)
+		default:
+			w.value(col, v)
+			return
+		}
+	case typeTimeN:
+		data = encodeTime(v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), int(col.ti.Scale))
+	default:
+		w.value(col, v)
+		return
+	}
+	w.write(col, len(data), data)
+}
+
+// Decimal writes the decimal with the unscaled value hi:lo, a 128-bit two's
+// complement integer, and scale digits after the decimal point into the
+// next column. The value is encoded as it is into a decimal column of the
+// same scale, other decimals are rescaled without rounding, as their text
+// is by AddRow.
+func (w *BulkRowWriter) Decimal(hi int64, lo uint64, scale int32) {
+	col, ok := w.next()
+	if !ok {
+		return
+	}
+	neg := hi < 0
+	uhi, ulo := uint64(hi), lo
+	if neg {
+		// the magnitude of the two's complement integer
+		var borrow uint64
+		ulo, borrow = bits.Sub64(0, lo, 0)
+		uhi, _ = bits.Sub64(0, uint64(hi), borrow)
+	}
+	fits := w.isDecimalOf(col, uhi, ulo, scale)
+	if !fits || w.b.Debug {
+		if scale < 0 || scale > 38 {
+			w.err = fmt.Errorf("bulkcopy: invalid scale of decimal: %d", scale)
+			return
+		}
+		var d decimal.Decimal
+		d.SetPositive(!neg)
+		d.SetScale(uint8(scale))
+		d.SetInteger(uint32(ulo), 0)
+		d.SetInteger(uint32(ulo>>32), 1)
+		d.SetInteger(uint32(uhi), 2)
+		d.SetInteger(uint32(uhi>>32), 3)
+		text := d.String()
+		if w.b.Debug {
+			w.log(text)
+		}
+		if !fits {
+			w.value(col, text)
+			return
+		}
+	}
+	// the sign byte followed by the little endian integer
+	n := decimalLength(col.ti.Prec)
+	w.scratch[0] = 0
+	if !neg {
+		w.scratch[0] = 1
+	}
+	binary.LittleEndian.PutUint64(w.scratch[1:9], ulo)
+	binary.LittleEndian.PutUint64(w.scratch[9:17], uhi)
+	w.write(col, n+1, w.scratch[:n+1])
+}
+
+// isDecimalOf returns if the magnitude hi:lo of a decimal with scale
+// digits after the point is in the range of the decimal column col.
+func (w *BulkRowWriter) isDecimalOf(col *columnStruct, hi, lo uint64, scale int32) bool {
+	switch col.ti.TypeId {
+	case typeDecimal, typeDecimalN, typeNumeric, typeNumericN:
+	default:
+		return false
+	}
+	if scale != int32(col.ti.Scale) || int(col.ti.Prec) >= len(decimalLimits) {
+		return false
+	}
+	limit := decimalLimits[col.ti.Prec]
+	return hi < limit[0] || hi == limit[0] && lo < limit[1]
+}
+
+// decimalLimits holds decimalLimit(prec) as its high and low 64 bits.
+var decimalLimits = func() (l [39][2]uint64) {
+	l[0] = [2]uint64{0, 1}
+	for prec := 1; prec < len(l); prec++ {
+		carry, lo := bits.Mul64(l[prec-1][1], 10)
+		l[prec] = [2]uint64{l[prec-1][0]*10 + carry, lo}
+	}
+	return
+}()
+
+// EndRow ends the row, which must have a value for every column, and writes
+// it to the destination table like AddRow. It returns the first error of
+// the values of the row; the next row starts over after an error.
+func (w *BulkRowWriter) EndRow() error {
+	b := w.b
+	defer w.reset()
+	if w.err != nil {
+		return w.err
+	}
+	if w.col != len(b.bulkColumns) {
+		return fmt.Errorf("row does not have the same number of columns than the destination table %d %d",
+			w.col, len(b.bulkColumns))
+	}
+	if !b.headerSent {
+		if err := b.sendBulkCommand(b.ctx); err != nil {
+			return err
+		}
+	}
+	b.dlogf(b.ctx, "row[%d] %s", b.numRows, w.logcol.String())
+	return b.writeRow(w.buf.Bytes())
+}
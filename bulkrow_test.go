@@ -0,0 +1,89 @@
+package mssql
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBulkRowWriter(t *testing.T) {
+	cols := []columnStruct{
+		{ColName: "id", ti: typeInfo{TypeId: typeIntN, Size: 4}},
+		{ColName: "big", ti: typeInfo{TypeId: typeInt8, Size: 8}},
+		{ColName: "ratio", ti: typeInfo{TypeId: typeFltN, Size: 8}},
+		{ColName: "active", ti: typeInfo{TypeId: typeBitN, Size: 1}},
+		{ColName: "total", ti: typeInfo{TypeId: typeDecimalN, Size: 9, Prec: 10, Scale: 2}},
+		{ColName: "wide", ti: typeInfo{TypeId: typeDecimalN, Size: 17, Prec: 38, Scale: 4}},
+		{ColName: "name", ti: typeInfo{TypeId: typeNVarChar, Size: 100}},
+		{ColName: "code", ti: typeInfo{TypeId: typeBigVarChar, Size: 10}},
+		{ColName: "payload", ti: typeInfo{TypeId: typeBigVarBin, Size: 100}},
+		{ColName: "created", ti: typeInfo{TypeId: typeDateTime2N, Scale: 7}},
+		{ColName: "day", ti: typeInfo{TypeId: typeDateN}},
+		{ColName: "legacy", ti: typeInfo{TypeId: typeDateTimeN, Size: 8}},
+		{ColName: "note", ti: typeInfo{TypeId: typeNVarChar, Size: 100}},
+	}
+	for i := range cols {
+		if err := writeTypeInfo(io.Discard, &cols[i].ti, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := &Bulk{cn: &Conn{}, bulkColumns: cols, headerSent: true}
+	created := time.Date(2024, 2, 29, 13, 14, 15, 123456700, time.UTC)
+
+	w := &BulkRowWriter{b: b}
+	w.reset()
+	w.Int64(-7)
+	w.Int64(math.MaxInt64)
+	w.Float64(0.25)
+	w.Bool(true)
+	w.Decimal(-1, math.MaxUint64-149, 2) // -1.50
+	w.Decimal(0, 123456, 1)              // 12345.6, rescaled
+	w.String("Zoë")
+	w.String("A1")
+	w.Bytes([]byte{1, 2, 3})
+	w.Time(created)
+	w.Time(created)
+	w.Time(created)
+	w.Null()
+	if w.err != nil {
+		t.Fatal(w.err)
+	}
+
+	expected, err := b.makeRowData([]interface{}{-7, int64(math.MaxInt64), 0.25, true, "-1.50", "12345.6",
+		"Zoë", "A1", []byte{1, 2, 3}, created, created, created, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.buf.Bytes(), expected) {
+		t.Errorf("expected the row of AddRow\n%x\ngot\n%x", expected, w.buf.Bytes())
+	}
+
+	w.reset()
+	w.Int64(1)
+	w.Int64(2)
+	w.String("not a float")
+	w.Bool(true)
+	if w.err == nil || !strings.Contains(w.err.Error(), "invalid type for float column") {
+		t.Errorf("expected the error of the string for the float column, got %v", w.err)
+	}
+	if err := w.EndRow(); err == nil || w.err != nil || w.col != 0 {
+		t.Errorf("expected EndRow to return the error and start the next row, got %v", err)
+	}
+
+	w.Int64(1)
+	if err := w.EndRow(); err == nil || !strings.Contains(err.Error(), "same number of columns") {
+		t.Errorf("expected an error for a row with a missing column, got %v", err)
+	}
+
+	w.Int64(1)
+	w.Int64(2)
+	w.Float64(0.5)
+	w.Bool(false)
+	w.Decimal(0, 10000000000, 2) // 100000000.00 is out of range for decimal(10, 2)
+	if w.err == nil || !strings.Contains(w.err.Error(), "out of range") {
+		t.Errorf("expected an out of range error, got %v", w.err)
+	}
+}
@@ -0,0 +1,53 @@
+package mssql
+
+import "sync"
+
+// Canceler cancels a running query without a context, e.g. when the user of
+// an interactive tool presses Ctrl+C in the middle of a long scan. Pass a
+// pointer to it as an argument of the query and call Cancel from any goroutine:
+//
+//	var canceler mssql.Canceler
+//	rows, err := db.QueryContext(ctx, "select * from dbo.events", &canceler)
+//	...
+//	go func() {
+//		<-interrupt
+//		canceler.Cancel()
+//	}()
+//	for rows.Next() {
+//		...
+//	}
+//	if errors.Is(rows.Err(), context.Canceled) {
+//		// canceled by the user
+//	}
+//
+// Cancel sends an attention to the server, which stops the statement, and
+// waits for its confirmation while the rows are read. Rows that are already
+// buffered by the driver may still be returned before the error. Query or
+// Next then fail with context.Canceled and the connection stays usable.
+// A Canceler cancels a single query, Cancel before the query starts cancels
+// it as soon as it is sent, Cancel after the rows are closed has no effect.
+type Canceler struct {
+	mu       sync.Mutex
+	cancel   func()
+	canceled bool
+}
+
+// Cancel cancels the query of c.
+func (c *Canceler) Cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.canceled = true
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// start sets the function that cancels the context of the running query.
+func (c *Canceler) start(cancel func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancel = cancel
+	if c.canceled {
+		cancel()
+	}
+}
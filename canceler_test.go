@@ -0,0 +1,52 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestCanceler(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	srv.Handle("waitfor delay '01:00'", mssqltest.Response{Delay: time.Minute})
+
+	ctx := context.Background()
+	var canceler Canceler
+	time.AfterFunc(50*time.Millisecond, canceler.Cancel)
+	start := time.Now()
+	_, err := db.QueryContext(ctx, "waitfor delay '01:00'", &canceler)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("the query was canceled after %v", elapsed)
+	}
+	// the connection is still usable
+	var n int
+	if err = db.QueryRowContext(ctx, "select 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1, got %d", n)
+	}
+}
+
+func TestCancelerBeforeQuery(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("waitfor delay '01:00'", mssqltest.Response{Delay: time.Minute})
+
+	var canceler Canceler
+	canceler.Cancel()
+	_, err := db.QueryContext(context.Background(), "waitfor delay '01:00'", &canceler)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package mssql
+
+import (
+	"github.com/microsoft/go-mssqldb/internal/cp"
+	"golang.org/x/text/encoding"
+)
+
+// RegisterCodePage sets the encoding used to decode char, varchar and text
+// values stored with a collation that maps to the given Windows code page,
+// replacing the built-in table for that code page if there is one.
+// For example, to use the tables from golang.org/x/text:
+//
+//	mssql.RegisterCodePage(1252, charmap.Windows1252)
+//
+// Code pages should be registered during init, registration is not synchronized.
+func RegisterCodePage(codePage int, enc encoding.Encoding) {
+	cp.RegisterCodePage(codePage, enc)
+}
@@ -0,0 +1,138 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// errLockTimeout is the number of the error of a statement that waited for
+// a lock longer than the LOCK_TIMEOUT of the session.
+const errLockTimeout = 1222
+
+// Defaults of ChunkOptions.
+const (
+	// defaultChunkSize stays below the 5000 locks at which the server
+	// escalates row locks to a table lock.
+	defaultChunkSize  = 4000
+	defaultRetries    = 3
+	defaultRetryDelay = 100 * time.Millisecond
+)
+
+// ChunkOptions configure ExecChunked.
+type ChunkOptions struct {
+	// ChunkSize is the number of rows changed by a chunk, passed as @chunk,
+	// 4000 by default.
+	ChunkSize int
+	// Retries is the number of times a chunk is retried after a deadlock, a
+	// lock timeout or a conflict on memory-optimized tables, 3 by default.
+	// A negative value does not retry. Chunks in a *sql.Tx are never
+	// retried, the transaction was rolled back by the error.
+	Retries int
+	// RetryDelay is the time before the first retry of a chunk, 100
+	// milliseconds by default. It doubles with every retry.
+	RetryDelay time.Duration
+	// Pause is the time to wait between chunks, to let other sessions and
+	// log backups run.
+	Pause time.Duration
+	// Progress, when set, is called after every chunk with the number of
+	// chunks and rows done so far.
+	Progress func(chunks int, rows int64)
+}
+
+// ExecChunked executes a large UPDATE or DELETE in chunks, so it neither
+// holds locks on the whole table nor fills the transaction log with a
+// single transaction. query changes at most @chunk rows with TOP and is
+// executed until it changes fewer rows:
+//
+//	n, err := mssql.ExecChunked(ctx, db, "delete top (@chunk) from dbo.logs where created < @p1",
+//		mssql.ChunkOptions{Progress: report}, cutoff)
+//
+// The query must change rows so they no longer match, otherwise it never
+// ends. Every chunk is a transaction of its own when e is a *sql.DB or a
+// *sql.Conn. A chunk that was chosen as deadlock victim, timed out waiting
+// for a lock or failed with a conflict on memory-optimized tables, see
+// IsMemoryOptimizedConflict, is retried, unless e is a *sql.Tx. ExecChunked
+// returns the number of rows changed. It needs the row counts of the
+// chunks and fails when the session has SET NOCOUNT ON.
+func ExecChunked(ctx context.Context, e Execer, query string, opts ChunkOptions, args ...interface{}) (int64, error) {
+	if !strings.Contains(strings.ToLower(query), "@chunk") {
+		return 0, errors.New("mssql: the query of ExecChunked must limit the rows it changes with TOP (@chunk)")
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	if opts.Retries == 0 {
+		opts.Retries = defaultRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultRetryDelay
+	}
+	if _, ok := e.(*sql.Tx); ok {
+		// the deadlock or conflict rolled back the changes of the earlier chunks
+		opts.Retries = -1
+	}
+	args = append(args[:len(args):len(args)], sql.Named("chunk", opts.ChunkSize))
+	var total int64
+	for chunks := 1; ; chunks++ {
+		n, err := execChunk(ctx, e, query, opts, args)
+		if err != nil {
+			return total, fmt.Errorf("mssql: chunk %d: %w", chunks, err)
+		}
+		total += n
+		if opts.Progress != nil {
+			opts.Progress(chunks, total)
+		}
+		if n < int64(opts.ChunkSize) {
+			return total, nil
+		}
+		if err = sleepContext(ctx, opts.Pause); err != nil {
+			return total, err
+		}
+	}
+}
+
+// execChunk executes a chunk and retries it after a deadlock, a lock timeout
+// or a conflict on memory-optimized tables.
+func execChunk(ctx context.Context, e Execer, query string, opts ChunkOptions, args []interface{}) (int64, error) {
+	var counts RowCounts
+	args = append(args[:len(args):len(args)], &counts)
+	delay := opts.RetryDelay
+	for retry := 0; ; retry++ {
+		res, err := e.ExecContext(ctx, query, args...)
+		if err == nil {
+			if len(counts) == 0 {
+				// otherwise the first chunk would look like the last one
+				return 0, errors.New("mssql: the chunk returned no row count, ExecChunked does not work with SET NOCOUNT ON")
+			}
+			return res.RowsAffected()
+		}
+		var sqlErr Error
+		isLockConflict := errors.As(err, &sqlErr) && (sqlErr.Number == errDeadlockVictim || sqlErr.Number == errLockTimeout)
+		if retry >= opts.Retries || !(isLockConflict || IsMemoryOptimizedConflict(err)) {
+			return 0, err
+		}
+		if err = sleepContext(ctx, delay); err != nil {
+			return 0, err
+		}
+		delay *= 2
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
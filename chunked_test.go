@@ -0,0 +1,95 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestExecChunked(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	responses := []mssqltest.Response{
+		{Results: []mssqltest.Result{{RowsAffected: 2}}},
+		{Err: &mssqltest.Error{Number: errDeadlockVictim, Class: 13, Message: "Transaction was deadlocked"}},
+		{Results: []mssqltest.Result{{RowsAffected: 2}}},
+		{Results: []mssqltest.Result{{RowsAffected: 1}}},
+	}
+	calls := 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if calls >= len(responses) {
+			t.Errorf("unexpected query %q", query)
+			return mssqltest.Response{}
+		}
+		calls++
+		return responses[calls-1]
+	})
+	var progress []int64
+	opts := ChunkOptions{
+		ChunkSize:  2,
+		RetryDelay: time.Millisecond,
+		Progress:   func(chunks int, rows int64) { progress = append(progress, rows) },
+	}
+	n, err := ExecChunked(context.Background(), db, "delete top (@chunk) from dbo.logs where created < @p1", opts, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || calls != 4 {
+		t.Errorf("expected 5 rows in 4 executions, got %d rows in %d", n, calls)
+	}
+	if len(progress) != 3 || progress[2] != 5 {
+		t.Errorf("expected progress after 3 chunks, got %v", progress)
+	}
+}
+
+func TestExecChunkedErrors(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := ExecChunked(ctx, db, "delete from dbo.logs", ChunkOptions{}); err == nil || !strings.Contains(err.Error(), "@chunk") {
+		t.Errorf("expected an error without @chunk, got %v", err)
+	}
+
+	calls := 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		calls++
+		return mssqltest.Response{Err: &mssqltest.Error{Number: 547, Class: 16, Message: "The DELETE statement conflicted with the REFERENCE constraint"}}
+	})
+	_, err := ExecChunked(ctx, db, "delete top (@chunk) from dbo.logs", ChunkOptions{RetryDelay: time.Millisecond})
+	var sqlErr Error
+	if !errors.As(err, &sqlErr) || sqlErr.Number != 547 || calls != 1 {
+		t.Errorf("expected error 547 without retries, got %v after %d executions", err, calls)
+	}
+
+	// a deadlock in a transaction rolled it back, so the chunk is not retried
+	calls = 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		calls++
+		return mssqltest.Response{Err: &mssqltest.Error{Number: errDeadlockVictim, Class: 13, Message: "Transaction was deadlocked"}}
+	})
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ExecChunked(ctx, tx, "delete top (@chunk) from dbo.logs", ChunkOptions{RetryDelay: time.Millisecond})
+	if !errors.As(err, &sqlErr) || sqlErr.Number != errDeadlockVictim || calls != 1 {
+		t.Errorf("expected the deadlock without retries in a transaction, got %v after %d executions", err, calls)
+	}
+	tx.Rollback()
+
+	// without row counts the first chunk would be taken for the last one
+	calls = 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		calls++
+		return mssqltest.Response{Results: []mssqltest.Result{{RowsAffected: 4000, NoCount: true}}}
+	})
+	if _, err = ExecChunked(ctx, db, "delete top (@chunk) from dbo.logs", ChunkOptions{}); err == nil || !strings.Contains(err.Error(), "NOCOUNT") || calls != 1 {
+		t.Errorf("expected an error under NOCOUNT, got %v after %d executions", err, calls)
+	}
+}
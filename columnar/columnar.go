@@ -0,0 +1,174 @@
+// Package columnar converts result sets of SQL Server into column vectors
+// in record batches, laid out like Apache Arrow arrays, for analytics
+// pipelines that consume columnar data.
+//
+// The package has no dependency on the Arrow module. The types of the
+// fields are named after the Arrow data types they map to, and the vectors
+// hold the values in the representation of the Arrow arrays, so they can
+// be copied into Arrow builders without conversions:
+//
+//	r, err := columnar.NewReader(rows, 64*1024)
+//	...
+//	for r.Next() {
+//		b := r.Batch()
+//		for i, f := range b.Schema {
+//			switch f.Type {
+//			case columnar.Int64:
+//				builder.Field(i).(*array.Int64Builder).AppendValues(b.Columns[i].Int64s, b.Columns[i].Valid)
+//			...
+//		}
+//	}
+package columnar
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Type is the data type of a column, named after the Arrow data type it maps to.
+type Type int
+
+const (
+	// Bool is a bit column, in Bools.
+	Bool Type = iota
+	// Uint8 is a tinyint column, in Int64s.
+	Uint8
+	// Int16 is a smallint column, in Int64s.
+	Int16
+	// Int32 is an int column, in Int64s.
+	Int32
+	// Int64 is a bigint column, in Int64s.
+	Int64
+	// Float32 is a real column, in Float64s.
+	Float32
+	// Float64 is a float column, in Float64s.
+	Float64
+	// Decimal128 is a decimal, numeric, money or smallmoney column with the
+	// Precision and Scale of the field, in Decimals.
+	Decimal128
+	// String is a character, xml or uniqueidentifier column, in Strings.
+	String
+	// Binary is a binary column, in Bytes.
+	Binary
+	// Date32 is a date column, as the days since 1970-01-01, in Int64s.
+	Date32
+	// Time64 is a time column, as the nanoseconds since midnight, in Int64s.
+	Time64
+	// Timestamp is a datetime, smalldatetime, datetime2 or datetimeoffset
+	// column, as the microseconds since 1970-01-01 00:00:00 in the TimeZone
+	// of the field, in Int64s. datetime2 and datetimeoffset values with a
+	// scale of 7 lose their last digit.
+	Timestamp
+)
+
+var typeNames = [...]string{"bool", "uint8", "int16", "int32", "int64", "float32", "float64", "decimal128", "utf8", "binary", "date32", "time64[ns]", "timestamp[us]"}
+
+// String returns the name of the Arrow data type.
+func (t Type) String() string {
+	if t < 0 || int(t) >= len(typeNames) {
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+	return typeNames[t]
+}
+
+// Field describes a column of a batch.
+type Field struct {
+	Name string
+	Type Type
+	// Precision and Scale of a Decimal128 field.
+	Precision int32
+	Scale     int32
+	// TimeZone of a Timestamp field, "UTC" for datetimeoffset columns
+	// and empty for the columns without a time zone.
+	TimeZone string
+	Nullable bool
+}
+
+// Decimal is a 128-bit two's complement integer, the unscaled value of a decimal.
+type Decimal struct {
+	Hi int64
+	Lo uint64
+}
+
+var (
+	two64  = new(big.Int).Lsh(big.NewInt(1), 64)
+	max128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+	min128 = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 127))
+)
+
+// DecimalFromBig returns the Decimal of an integer, ok is false if it
+// does not fit into 128 bits.
+func DecimalFromBig(i *big.Int) (d Decimal, ok bool) {
+	if i.Cmp(max128) > 0 || i.Cmp(min128) < 0 {
+		return d, false
+	}
+	v := new(big.Int).Set(i)
+	if v.Sign() < 0 {
+		v.Add(v, new(big.Int).Lsh(two64, 64))
+	}
+	lo := new(big.Int).And(v, new(big.Int).Sub(two64, big.NewInt(1)))
+	hi := new(big.Int).Rsh(v, 64)
+	return Decimal{Hi: int64(hi.Uint64()), Lo: lo.Uint64()}, true
+}
+
+// BigInt returns the integer of d.
+func (d Decimal) BigInt() *big.Int {
+	i := new(big.Int).SetInt64(d.Hi)
+	i.Lsh(i, 64)
+	return i.Add(i, new(big.Int).SetUint64(d.Lo))
+}
+
+// Text returns the decimal text of d with scale digits after the decimal point.
+func (d Decimal) Text(scale int32) string {
+	i := d.BigInt()
+	neg := i.Sign() < 0
+	digits := new(big.Int).Abs(i).String()
+	if scale > 0 {
+		if len(digits) <= int(scale) {
+			digits = strings.Repeat("0", int(scale)-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-int(scale)] + "." + digits[len(digits)-int(scale):]
+	}
+	if neg {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Column is a column vector of a batch. Only the slice of the type of its
+// field is set, see Type, and has the length of the batch like Valid.
+type Column struct {
+	// Valid is false for the NULL values.
+	Valid    []bool
+	Bools    []bool
+	Int64s   []int64
+	Float64s []float64
+	Decimals []Decimal
+	Strings  []string
+	Bytes    [][]byte
+}
+
+// Len returns the number of values of c.
+func (c *Column) Len() int {
+	return len(c.Valid)
+}
+
+// IsNull returns if the value i is NULL.
+func (c *Column) IsNull(i int) bool {
+	return !c.Valid[i]
+}
+
+// Batch is a record batch, the values of a number of rows by column.
+type Batch struct {
+	Schema  []Field
+	Columns []*Column
+}
+
+// Len returns the number of rows of b.
+func (b *Batch) Len() int {
+	if len(b.Columns) == 0 {
+		return 0
+	}
+	return b.Columns[0].Len()
+}
@@ -0,0 +1,222 @@
+package columnar
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func newTestDB(t *testing.T) (*sql.DB, *mssqltest.Server) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := msdsn.Parse(srv.URL())
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	connector := mssql.NewConnectorConfig(config)
+	connector.Dialer = srv
+	return sql.OpenDB(connector), srv
+}
+
+func TestReader(t *testing.T) {
+	db, srv := newTestDB(t)
+	defer srv.Close()
+	defer db.Close()
+	created := time.Date(2024, 2, 29, 13, 14, 15, 123456700, time.UTC)
+	srv.Handle("select * from dbo.events", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "name", "ok", "score", "payload", "created"},
+		Rows: [][]interface{}{
+			{int64(1), "a", true, 1.5, []byte{1}, created},
+			{int64(2), nil, false, nil, nil, nil},
+			{int64(3), "c", nil, 2.5, []byte{3}, created},
+		},
+	}}})
+	rows, err := db.QueryContext(context.Background(), "select * from dbo.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	r, err := NewReader(rows, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []Type
+	for _, f := range r.Schema() {
+		types = append(types, f.Type)
+	}
+	if !reflect.DeepEqual(types, []Type{Int64, String, Bool, Float64, Binary, Timestamp}) {
+		t.Errorf("unexpected types %v", types)
+	}
+	var batches []*Batch
+	for r.Next() {
+		batches = append(batches, r.Batch())
+	}
+	if err = r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || batches[0].Len() != 2 || batches[1].Len() != 1 {
+		t.Fatalf("expected batches of 2 and 1 rows, got %d batches", len(batches))
+	}
+	b := batches[0]
+	if !reflect.DeepEqual(b.Columns[0].Int64s, []int64{1, 2}) {
+		t.Errorf("unexpected ids %v", b.Columns[0].Int64s)
+	}
+	if !reflect.DeepEqual(b.Columns[1].Valid, []bool{true, false}) || b.Columns[1].Strings[0] != "a" {
+		t.Errorf("unexpected names %+v", b.Columns[1])
+	}
+	if ts := b.Columns[5].Int64s[0]; ts != created.UnixMicro() {
+		t.Errorf("expected the timestamp %d, got %d", created.UnixMicro(), ts)
+	}
+	if b := batches[1]; !b.Columns[2].IsNull(0) || b.Columns[3].Float64s[0] != 2.5 {
+		t.Errorf("unexpected last batch %+v", b.Columns)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	f := Field{Name: "total", Type: Decimal128, Precision: 38, Scale: 4}
+	for _, test := range []struct {
+		text     string
+		expected string
+	}{
+		{"1234.5600", "12345600"},
+		{"-0.0001", "-1"},
+		{"-99999999999999999999999999999999.9999", "-999999999999999999999999999999999999"},
+		{"0", "0"},
+	} {
+		d, err := decimalOf(f, []byte(test.text))
+		if err != nil {
+			t.Errorf("%s: %v", test.text, err)
+			continue
+		}
+		if got := d.BigInt().String(); got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.text, test.expected, got)
+		}
+	}
+	if _, err := decimalOf(f, "1.00001"); err == nil {
+		t.Error("expected an error for a decimal with a larger scale")
+	}
+	if _, ok := DecimalFromBig(new(big.Int).Lsh(big.NewInt(1), 127)); ok {
+		t.Error("expected 2^127 to be out of range")
+	}
+}
+
+func TestDateAndTime(t *testing.T) {
+	v := time.Date(1969, 12, 31, 1, 2, 3, 4, time.FixedZone("", 3600))
+	if d, _ := int64Of(Field{Type: Date32}, v); d != -1 {
+		t.Errorf("expected the day -1, got %d", d)
+	}
+	if ns, _ := int64Of(Field{Type: Time64}, v); ns != int64(time.Hour+2*time.Minute+3*time.Second+4) {
+		t.Errorf("unexpected time %d", ns)
+	}
+	// datetimeoffset is the instant in UTC
+	if us, _ := int64Of(Field{Type: Timestamp, TimeZone: "UTC"}, v); us != v.UnixMicro() {
+		t.Errorf("unexpected timestamp %d", us)
+	}
+	if _, err := int64Of(Field{Type: Date32}, "1969-12-31"); err == nil {
+		t.Error("expected an error for a date scanned as a string")
+	}
+}
+
+func TestDecimalText(t *testing.T) {
+	for _, test := range []struct {
+		i        int64
+		scale    int32
+		expected string
+	}{
+		{12345600, 4, "1234.5600"},
+		{-1, 4, "-0.0001"},
+		{42, 0, "42"},
+		{0, 2, "0.00"},
+	} {
+		d, _ := DecimalFromBig(big.NewInt(test.i))
+		if got := d.Text(test.scale); got != test.expected {
+			t.Errorf("%d scale %d: expected %s, got %s", test.i, test.scale, test.expected, got)
+		}
+	}
+}
+
+func TestBulkValues(t *testing.T) {
+	created := time.Date(2024, 2, 29, 13, 14, 15, 123456000, time.UTC)
+	day, _ := int64Of(Field{Type: Date32}, created)
+	d, _ := DecimalFromBig(big.NewInt(-150))
+	b := &Batch{
+		Schema: []Field{
+			{Name: "id", Type: Int32},
+			{Name: "total", Type: Decimal128, Precision: 10, Scale: 2},
+			{Name: "day", Type: Date32},
+			{Name: "created", Type: Timestamp},
+			{Name: "note", Type: String},
+		},
+		Columns: []*Column{
+			{Valid: []bool{true}, Int64s: []int64{7}},
+			{Valid: []bool{true}, Decimals: []Decimal{d}},
+			{Valid: []bool{true}, Int64s: []int64{day}},
+			{Valid: []bool{true}, Int64s: []int64{created.UnixMicro()}},
+			{Valid: []bool{false}, Strings: []string{""}},
+		},
+	}
+	w := &recordingWriter{}
+	for j, c := range b.Columns {
+		c.write(w, b.Schema[j], 0)
+	}
+	expected := []interface{}{int64(7), Decimal{Hi: -1, Lo: d.Lo}, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), created, nil}
+	if !reflect.DeepEqual(w.row, expected) {
+		t.Errorf("expected %v, got %v", expected, w.row)
+	}
+
+	src := Batches(b, b)
+	n := 0
+	for src.Next() {
+		n += src.Batch().Len()
+	}
+	if n != 2 || src.Err() != nil {
+		t.Errorf("expected 2 rows, got %d", n)
+	}
+}
+
+// recordingWriter records the values written into a row.
+type recordingWriter struct {
+	row []interface{}
+}
+
+func (w *recordingWriter) Null()             { w.row = append(w.row, nil) }
+func (w *recordingWriter) Bool(v bool)       { w.row = append(w.row, v) }
+func (w *recordingWriter) Int64(v int64)     { w.row = append(w.row, v) }
+func (w *recordingWriter) Float64(v float64) { w.row = append(w.row, v) }
+func (w *recordingWriter) String(v string)   { w.row = append(w.row, v) }
+func (w *recordingWriter) Bytes(v []byte)    { w.row = append(w.row, v) }
+func (w *recordingWriter) Time(v time.Time)  { w.row = append(w.row, v) }
+func (w *recordingWriter) Decimal(hi int64, lo uint64, scale int32) {
+	w.row = append(w.row, Decimal{Hi: hi, Lo: lo})
+}
+
+func TestLoadRejectsAnotherSchema(t *testing.T) {
+	db, srv := newTestDB(t)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	first := &Batch{Schema: []Field{{Name: "id", Type: Int64}}}
+	second := &Batch{
+		Schema:  []Field{{Name: "id", Type: String}},
+		Columns: []*Column{{Valid: []bool{true}, Strings: []string{"1"}}},
+	}
+	_, err = Load(ctx, conn, "dbo.events", Batches(first, second), mssql.BulkOptions{})
+	if err == nil || err.Error() != "columnar: the schema of batch 1 differs from the schema of the first batch" {
+		t.Errorf("expected an error for the schema of the second batch, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package columnar
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Source is a sequence of batches, like a Reader.
+type Source interface {
+	Next() bool
+	Batch() *Batch
+	Err() error
+}
+
+// Batches returns the Source of the batches b.
+func Batches(b ...*Batch) Source {
+	return &batches{list: b, i: -1}
+}
+
+type batches struct {
+	list []*Batch
+	i    int
+}
+
+func (s *batches) Next() bool {
+	s.i++
+	return s.i < len(s.list)
+}
+
+func (s *batches) Batch() *Batch {
+	return s.list[s.i]
+}
+
+func (s *batches) Err() error {
+	return nil
+}
+
+// Load copies the batches of src into table with bulk copy, the columns of
+// the table are the names of the fields of the first batch, and returns
+// the number of rows copied:
+//
+//	n, err := columnar.Load(ctx, conn, "dbo.events", columnar.Batches(b1, b2), mssql.BulkOptions{Tablock: true})
+//
+// Every batch must have the schema of the first one. The batches are added
+// with AddBatch, so a batch is never converted into rows as a whole.
+func Load(ctx context.Context, conn *sql.Conn, table string, src Source, opts mssql.BulkOptions) (rowcount int64, err error) {
+	err = conn.Raw(func(driverConn interface{}) error {
+		cn, ok := driverConn.(*mssql.Conn)
+		if !ok {
+			return fmt.Errorf("columnar: Load needs a connection of the mssql driver, got %T", driverConn)
+		}
+		var bulk *mssql.Bulk
+		var schema []Field
+		for n := 0; src.Next(); n++ {
+			b := src.Batch()
+			if bulk == nil {
+				schema = b.Schema
+				names := make([]string, len(b.Schema))
+				for i, f := range b.Schema {
+					names[i] = f.Name
+				}
+				bulk = cn.CreateBulkContext(ctx, table, names)
+				bulk.Options = opts
+			} else if !sameSchema(schema, b.Schema) {
+				return fmt.Errorf("columnar: the schema of batch %d differs from the schema of the first batch", n)
+			}
+			if err := AddBatch(bulk, b); err != nil {
+				return err
+			}
+		}
+		if err := src.Err(); err != nil {
+			return err
+		}
+		if bulk == nil {
+			return nil
+		}
+		var err error
+		rowcount, err = bulk.Done()
+		return err
+	})
+	return rowcount, err
+}
+
+func sameSchema(a, b []Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AddBatch adds the rows of b to bulk, in the order of the columns of bulk.
+// The values of the column vectors are written into the rows with a
+// mssql.BulkRowWriter, without boxing them, and decimals are written as
+// their 128-bit integer.
+func AddBatch(bulk *mssql.Bulk, b *Batch) error {
+	n := b.Len()
+	if n == 0 {
+		return nil
+	}
+	w, err := bulk.RowWriter()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		for j, c := range b.Columns {
+			c.write(w, b.Schema[j], i)
+		}
+		if err := w.EndRow(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowWriter is the mssql.BulkRowWriter the values are written to.
+type rowWriter interface {
+	Null()
+	Bool(v bool)
+	Int64(v int64)
+	Float64(v float64)
+	Decimal(hi int64, lo uint64, scale int32)
+	String(v string)
+	Bytes(v []byte)
+	Time(v time.Time)
+}
+
+// write writes the value i of c, of the field f, into the next column of w.
+func (c *Column) write(w rowWriter, f Field, i int) {
+	if !c.Valid[i] {
+		w.Null()
+		return
+	}
+	switch f.Type {
+	case Bool:
+		w.Bool(c.Bools[i])
+	case Uint8, Int16, Int32, Int64:
+		w.Int64(c.Int64s[i])
+	case Float32, Float64:
+		w.Float64(c.Float64s[i])
+	case Decimal128:
+		w.Decimal(c.Decimals[i].Hi, c.Decimals[i].Lo, f.Scale)
+	case Binary:
+		w.Bytes(c.Bytes[i])
+	case Date32:
+		w.Time(time.Unix(c.Int64s[i]*24*60*60, 0).UTC())
+	case Time64:
+		w.Time(time.Unix(0, c.Int64s[i]).UTC())
+	case Timestamp:
+		// the instant in UTC, or the wall clock of a timestamp without a time zone
+		w.Time(time.UnixMicro(c.Int64s[i]).UTC())
+	default:
+		w.String(c.Strings[i])
+	}
+}
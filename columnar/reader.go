@@ -0,0 +1,243 @@
+package columnar
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Reader reads the rows of a result set into batches.
+type Reader struct {
+	rows   *sql.Rows
+	size   int
+	schema []Field
+	values []interface{}
+	scan   []interface{}
+	batch  *Batch
+	err    error
+}
+
+// NewReader returns a reader of the current result set of rows in batches
+// of up to size rows. The rows are read from the server as the batches are
+// read, so memory is bounded by the size of a batch.
+func NewReader(rows *sql.Rows, size int) (*Reader, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("columnar: invalid batch size %d", size)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	schema := make([]Field, len(types))
+	for i, typ := range types {
+		schema[i] = fieldOf(typ)
+	}
+	r := &Reader{
+		rows:   rows,
+		size:   size,
+		schema: schema,
+		values: make([]interface{}, len(types)),
+		scan:   make([]interface{}, len(types)),
+	}
+	for i := range r.values {
+		r.scan[i] = &r.values[i]
+	}
+	return r, nil
+}
+
+// fieldOf maps a column type of SQL Server to a field.
+func fieldOf(typ *sql.ColumnType) Field {
+	f := Field{Name: typ.Name()}
+	f.Nullable, _ = typ.Nullable()
+	switch typ.DatabaseTypeName() {
+	case "BIT":
+		f.Type = Bool
+	case "TINYINT":
+		f.Type = Uint8
+	case "SMALLINT":
+		f.Type = Int16
+	case "INT":
+		f.Type = Int32
+	case "BIGINT":
+		f.Type = Int64
+	case "REAL":
+		f.Type = Float32
+	case "FLOAT":
+		f.Type = Float64
+	case "DECIMAL":
+		f.Type = Decimal128
+		precision, scale, _ := typ.DecimalSize()
+		f.Precision, f.Scale = int32(precision), int32(scale)
+	case "MONEY":
+		f.Type, f.Precision, f.Scale = Decimal128, 19, 4
+	case "SMALLMONEY":
+		f.Type, f.Precision, f.Scale = Decimal128, 10, 4
+	case "BINARY", "VARBINARY", "IMAGE":
+		f.Type = Binary
+	case "DATE":
+		f.Type = Date32
+	case "TIME":
+		f.Type = Time64
+	case "DATETIME", "SMALLDATETIME", "DATETIME2":
+		f.Type = Timestamp
+	case "DATETIMEOFFSET":
+		f.Type, f.TimeZone = Timestamp, "UTC"
+	default:
+		f.Type = String
+	}
+	return f
+}
+
+// Schema returns the fields of the batches.
+func (r *Reader) Schema() []Field {
+	return r.schema
+}
+
+// Next reads the next batch, it returns false when there are no more rows
+// or reading failed, see Err.
+func (r *Reader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	b := &Batch{Schema: r.schema, Columns: make([]*Column, len(r.schema))}
+	for i := range b.Columns {
+		b.Columns[i] = &Column{}
+	}
+	for n := 0; n < r.size && r.rows.Next(); n++ {
+		if r.err = r.rows.Scan(r.scan...); r.err != nil {
+			return false
+		}
+		for i, v := range r.values {
+			if r.err = appendValue(b.Columns[i], r.schema[i], v); r.err != nil {
+				return false
+			}
+		}
+	}
+	if r.err = r.rows.Err(); r.err != nil || b.Len() == 0 {
+		return false
+	}
+	r.batch = b
+	return true
+}
+
+// Batch returns the batch read by Next.
+func (r *Reader) Batch() *Batch {
+	return r.batch
+}
+
+// Err returns the error that stopped Next.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// appendValue appends the value v of a column of field f to c.
+func appendValue(c *Column, f Field, v interface{}) error {
+	valid := v != nil
+	c.Valid = append(c.Valid, valid)
+	switch f.Type {
+	case Bool:
+		b, ok := v.(bool)
+		c.Bools = append(c.Bools, b)
+		return checkType(f, v, valid && !ok)
+	case Uint8, Int16, Int32, Int64, Date32, Time64, Timestamp:
+		i, err := int64Of(f, v)
+		c.Int64s = append(c.Int64s, i)
+		return err
+	case Float32, Float64:
+		x, ok := v.(float64)
+		c.Float64s = append(c.Float64s, x)
+		return checkType(f, v, valid && !ok)
+	case Decimal128:
+		d, err := decimalOf(f, v)
+		c.Decimals = append(c.Decimals, d)
+		return err
+	case Binary:
+		b, ok := v.([]byte)
+		c.Bytes = append(c.Bytes, b)
+		return checkType(f, v, valid && !ok)
+	}
+	var s string
+	switch v := v.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		var u mssql.UniqueIdentifier
+		if len(v) == 16 && u.Scan(v) == nil {
+			s = u.String()
+		} else {
+			s = string(v)
+		}
+	default:
+		s = fmt.Sprint(v)
+	}
+	c.Strings = append(c.Strings, s)
+	return nil
+}
+
+func checkType(f Field, v interface{}, mismatch bool) error {
+	if mismatch {
+		return fmt.Errorf("columnar: unexpected value of type %T for the %s column %s", v, f.Type, f.Name)
+	}
+	return nil
+}
+
+// int64Of returns the value of an integer, date or time column.
+func int64Of(f Field, v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		if f.Type != Date32 && f.Type != Time64 && f.Type != Timestamp {
+			return v, nil
+		}
+	case time.Time:
+		switch f.Type {
+		case Date32:
+			return time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60), nil
+		case Time64:
+			return int64(v.Hour())*int64(time.Hour) + int64(v.Minute())*int64(time.Minute) + int64(v.Second())*int64(time.Second) + int64(v.Nanosecond()), nil
+		case Timestamp:
+			if f.TimeZone == "" {
+				// the wall clock, without a time zone
+				v = time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+			}
+			return v.UnixMicro(), nil
+		}
+	}
+	return 0, checkType(f, v, true)
+}
+
+var ten = big.NewInt(10)
+
+// decimalOf returns the unscaled value of a decimal or money column,
+// which the driver returns as its text.
+func decimalOf(f Field, v interface{}) (Decimal, error) {
+	var text string
+	switch v := v.(type) {
+	case nil:
+		return Decimal{}, nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return Decimal{}, checkType(f, v, true)
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return Decimal{}, fmt.Errorf("columnar: invalid decimal %q in column %s", text, f.Name)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(ten, big.NewInt(int64(f.Scale)), nil)))
+	if !r.IsInt() {
+		return Decimal{}, fmt.Errorf("columnar: decimal %q has more than %d digits after the decimal point in column %s", text, f.Scale, f.Name)
+	}
+	d, ok := DecimalFromBig(r.Num())
+	if !ok {
+		return Decimal{}, fmt.Errorf("columnar: decimal %q is out of range in column %s", text, f.Name)
+	}
+	return d, nil
+}
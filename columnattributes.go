@@ -0,0 +1,46 @@
+package mssql
+
+// ColumnAttributes receives the attributes of the columns of a result set
+// that sql.ColumnType does not report. Pass a pointer to it as an argument
+// of the query:
+//
+//	var attrs mssql.ColumnAttributes
+//	rows, err := db.QueryContext(ctx, "select * from dbo.orders", &attrs)
+//	...
+//	for i, col := range attrs.Columns {
+//		if col.Identity || col.Computed {
+//			// the column cannot be inserted, skip it
+//		}
+//	}
+//
+// Columns has the columns of the current result set of rows, in the order of
+// rows.Columns, once the query returned or rows.NextResultSet moved to the
+// next result set.
+type ColumnAttributes struct {
+	Columns []ColumnAttribute
+}
+
+// ColumnAttribute are the attributes of a column of a result set.
+type ColumnAttribute struct {
+	Name string
+	// Identity reports an identity column.
+	Identity bool
+	// Computed reports a computed column.
+	Computed bool
+	// Hidden reports a column that is only returned for browse mode
+	// queries, e.g. a key column added by FOR BROWSE.
+	Hidden bool
+}
+
+// set sets the attributes to the ones of columns.
+func (a *ColumnAttributes) set(columns []columnStruct) {
+	a.Columns = make([]ColumnAttribute, len(columns))
+	for i, col := range columns {
+		a.Columns[i] = ColumnAttribute{
+			Name:     col.ColName,
+			Identity: col.Flags&colFlagIdentity != 0,
+			Computed: col.Flags&colFlagComputed != 0,
+			Hidden:   col.Flags&colFlagHidden != 0,
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Column describes a column of a result set.
+type Column struct {
+	Name string
+	// Type is the type name of the column without the length, e.g. "NVARCHAR",
+	// like sql.ColumnType.DatabaseTypeName returns it.
+	Type string
+	// Length is the length of a variable length column in characters for
+	// character types and in bytes for binary types, math.MaxInt64 for the
+	// max types, and 0 for the other types.
+	Length int64
+	// Precision and Scale are set for the decimal and numeric types, and
+	// the scale of the fraction of the seconds for the time, datetime2 and
+	// datetimeoffset types.
+	Precision int64
+	Scale     int64
+	Nullable  bool
+	// Schema, Table and SourceColumn are the origin of the column, if it
+	// is a column of a table. They are only set by DescribeColumns.
+	Schema       string
+	Table        string
+	SourceColumn string
+}
+
+// ColumnsTyped returns the columns of the current result set of rows:
+//
+//	rows, err := db.QueryContext(ctx, query)
+//	...
+//	cols, err := mssql.ColumnsTyped(rows)
+//
+// The result metadata of SQL Server has no origin of the columns, use
+// DescribeColumns to get the tables they come from.
+func ColumnsTyped(rows *sql.Rows) ([]Column, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]Column, len(types))
+	for i, typ := range types {
+		col := Column{Name: typ.Name(), Type: typ.DatabaseTypeName()}
+		if length, ok := typ.Length(); ok {
+			col.Length = length
+		}
+		if precision, scale, ok := typ.DecimalSize(); ok {
+			col.Precision, col.Scale = precision, scale
+		}
+		col.Nullable, _ = typ.Nullable()
+		cols[i] = col
+	}
+	return cols, nil
+}
+
+// DescribeColumns returns the columns of the first result set of query,
+// with the tables they come from, without running it. It uses
+// sp_describe_first_result_set, params declares the parameters of query
+// like for sp_executesql, e.g. "@p1 int, @p2 nvarchar(50)", and may be empty:
+//
+//	cols, err := mssql.DescribeColumns(ctx, db, "select id, name from dbo.users where id = @p1", "@p1 int")
+//
+// Columns that are computed have no origin.
+func DescribeColumns(ctx context.Context, q Querier, query, params string) ([]Column, error) {
+	var p interface{}
+	if params != "" {
+		p = params
+	}
+	rows, err := q.QueryContext(ctx, "exec sp_describe_first_result_set @tsql = @p1, @params = @p2, @browse_information_mode = 1", query, p)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(names))
+	scan := make([]interface{}, len(names))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	value := func(name string) interface{} {
+		for i, n := range names {
+			if n == name {
+				return values[i]
+			}
+		}
+		return nil
+	}
+	var cols []Column
+	for rows.Next() {
+		if err = rows.Scan(scan...); err != nil {
+			return nil, err
+		}
+		if hidden, _ := value("is_hidden").(bool); hidden {
+			continue
+		}
+		col, err := describedColumn(value)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+// describedColumn makes the Column of a row of sp_describe_first_result_set.
+func describedColumn(value func(name string) interface{}) (Column, error) {
+	col := Column{}
+	col.Name, _ = value("name").(string)
+	col.Nullable, _ = value("is_nullable").(bool)
+	col.Schema, _ = value("source_schema").(string)
+	col.Table, _ = value("source_table").(string)
+	col.SourceColumn, _ = value("source_column").(string)
+	typeName, _ := value("system_type_name").(string)
+	// e.g. "int", "nvarchar(50)", "varbinary(max)" or "decimal(18,2)"
+	col.Type = strings.ToUpper(typeName)
+	var args string
+	if open := strings.IndexByte(typeName, '('); open >= 0 && strings.HasSuffix(typeName, ")") {
+		col.Type = strings.ToUpper(typeName[:open])
+		args = typeName[open+1 : len(typeName)-1]
+	}
+	switch col.Type {
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR", "BINARY", "VARBINARY":
+		if args == "max" {
+			col.Length = math.MaxInt64
+		} else if n, err := strconv.ParseInt(args, 10, 64); err == nil {
+			col.Length = n
+		} else {
+			return col, fmt.Errorf("mssql: unexpected type %s of column %s", typeName, col.Name)
+		}
+	case "TEXT", "NTEXT", "IMAGE", "XML":
+		col.Length = math.MaxInt64
+	case "DECIMAL", "NUMERIC", "TIME", "DATETIME2", "DATETIMEOFFSET":
+		col.Precision = toInt64(value("precision"))
+		col.Scale = toInt64(value("scale"))
+	}
+	return col, nil
+}
+
+func toInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package mssql
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestColumnsTyped(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select id, name from dbo.users", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "name"},
+		Rows:    [][]interface{}{{int64(1), "a"}},
+	}}})
+	rows, err := db.QueryContext(context.Background(), "select id, name from dbo.users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, err := ColumnsTyped(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 2 || cols[0].Name != "id" || cols[0].Type != "BIGINT" || !cols[0].Nullable || cols[1].Type != "NVARCHAR" || cols[1].Length == 0 {
+		t.Errorf("unexpected columns %+v", cols)
+	}
+}
+
+func TestDescribeColumns(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("exec sp_describe_first_result_set @tsql = @p1, @params = @p2, @browse_information_mode = 1", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"is_hidden", "column_ordinal", "name", "is_nullable", "system_type_name", "precision", "scale", "source_schema", "source_table", "source_column"},
+		Rows: [][]interface{}{
+			{false, int64(1), "id", false, "int", int64(10), int64(0), "dbo", "users", "id"},
+			{false, int64(2), "name", true, "nvarchar(50)", int64(0), int64(0), "dbo", "users", "name"},
+			{false, int64(3), "photo", true, "varbinary(max)", int64(0), int64(0), "dbo", "users", "photo"},
+			{false, int64(4), "total", true, "decimal(18,2)", int64(18), int64(2), nil, nil, nil},
+			{true, int64(5), "key", false, "int", int64(10), int64(0), "dbo", "users", "key"},
+		},
+	}}})
+	cols, err := DescribeColumns(context.Background(), db, "select id, name, photo, price * 2 as total from dbo.users where id = @p1", "@p1 int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Column{
+		{Name: "id", Type: "INT", Schema: "dbo", Table: "users", SourceColumn: "id"},
+		{Name: "name", Type: "NVARCHAR", Length: 50, Nullable: true, Schema: "dbo", Table: "users", SourceColumn: "name"},
+		{Name: "photo", Type: "VARBINARY", Length: math.MaxInt64, Nullable: true, Schema: "dbo", Table: "users", SourceColumn: "photo"},
+		{Name: "total", Type: "DECIMAL", Precision: 18, Scale: 2, Nullable: true},
+	}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cols)
+	}
+}
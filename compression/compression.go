@@ -0,0 +1,197 @@
+// Package compression compresses the TDS stream of connections over slow
+// network links.
+//
+// SQL Server does not compress TDS, so the compressed stream is
+// decompressed by a Proxy running close to the server. The client side is
+// a Dialer for mssql.Connector.Dialer:
+//
+//	connector.Dialer = compression.NewDialer(&net.Dialer{}, flate.BestSpeed)
+//
+// and the connection string addresses the proxy instead of the server:
+//
+//	go (&compression.Proxy{Target: "sqlserver.internal:1433"}).Serve(listener)
+//
+// When a connection is opened the Dialer and the Proxy negotiate the
+// compression before the pre-login of the driver. A Dialer connecting to
+// a server without a Proxy fails with ErrNotNegotiated.
+package compression
+
+import (
+	"bufio"
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// version 1 of the handshake is flate compression in both directions.
+const version = 1
+
+var (
+	hello = []byte{'T', 'D', 'S', 'Z', version}
+	ack   = []byte{'T', 'D', 'S', 'Z', version}
+)
+
+// ErrNotNegotiated is returned by Dialer when the other side of the
+// connection did not acknowledge the compression.
+var ErrNotNegotiated = errors.New("compression: the server did not negotiate compression, is the proxy running?")
+
+// handshakeTimeout limits the handshake when the context has no deadline.
+const handshakeTimeout = 15 * time.Second
+
+// ContextDialer dials network connections, mssql.Dialer and *net.Dialer implement it.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network string, addr string) (net.Conn, error)
+}
+
+// Dialer dials connections with a compressed stream to a Proxy.
+type Dialer struct {
+	dialer ContextDialer
+	level  int
+}
+
+// NewDialer returns a Dialer that compresses the connections of d
+// with a compress/flate level.
+func NewDialer(d ContextDialer, level int) *Dialer {
+	return &Dialer{dialer: d, level: level}
+}
+
+// DialContext dials addr and negotiates the compression with the Proxy.
+// Only tcp connections are compressed, the SQL Server Browser is dialed with udp.
+func (d *Dialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil || network == "udp" {
+		return conn, err
+	}
+	setHandshakeDeadline(ctx, conn)
+	if _, err = conn.Write(hello); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply := make([]byte, len(ack))
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrNotNegotiated, err)
+	}
+	if string(reply) != string(ack) {
+		conn.Close()
+		return nil, ErrNotNegotiated
+	}
+	conn.SetDeadline(time.Time{})
+	return newConn(conn, d.level)
+}
+
+func setHandshakeDeadline(ctx context.Context, conn net.Conn) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(handshakeTimeout)
+	}
+	conn.SetDeadline(deadline)
+}
+
+// Conn is a connection with a compressed stream.
+type Conn struct {
+	net.Conn
+	r io.ReadCloser
+
+	mu sync.Mutex
+	w  *flate.Writer
+}
+
+func newConn(conn net.Conn, level int) (*Conn, error) {
+	w, err := flate.NewWriter(conn, level)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{Conn: conn, r: flate.NewReader(bufio.NewReader(conn)), w: w}, nil
+}
+
+// Read reads decompressed data.
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// Write compresses b and flushes it to the network, so the other side
+// can read b without waiting for more data.
+func (c *Conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, err := c.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	return n, c.w.Flush()
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.r.Close()
+	return c.Conn.Close()
+}
+
+// Proxy accepts compressed connections of Dialer and forwards them
+// decompressed to Target.
+type Proxy struct {
+	// Target is the address of the SQL Server, host:port.
+	Target string
+	// Level is the compress/flate level of the responses, 0 is flate.DefaultCompression.
+	Level int
+	// Dialer dials the Target, the zero value uses a net.Dialer.
+	Dialer ContextDialer
+}
+
+// Serve accepts connections of l until l is closed.
+func (p *Proxy) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *Proxy) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	req := make([]byte, len(hello))
+	if _, err := io.ReadFull(conn, req); err != nil || string(req) != string(hello) {
+		return
+	}
+	d := p.Dialer
+	if d == nil {
+		d = &net.Dialer{}
+	}
+	target, err := d.DialContext(context.Background(), "tcp", p.Target)
+	if err != nil {
+		return
+	}
+	defer target.Close()
+	if _, err = conn.Write(ack); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	level := p.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	compressed, err := newConn(conn, level)
+	if err != nil {
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(target, compressed)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(compressed, target)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,79 @@
+package compression_test
+
+import (
+	"compress/flate"
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/compression"
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestCompressedConnection(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	text := strings.Repeat("compressible ", 1000)
+	srv.Handle("select text", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"text"}, Rows: [][]interface{}{{text}}}}})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go (&compression.Proxy{Target: srv.Addr().String()}).Serve(l)
+
+	config, err := msdsn.Parse("sqlserver://sa:pwd@" + l.Addr().String() + "?encrypt=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := mssql.NewConnectorConfig(config)
+	connector.Dialer = compression.NewDialer(&net.Dialer{}, flate.BestSpeed)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var got string
+	if err = conn.QueryRowContext(context.Background(), "select text").Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != text {
+		t.Errorf("unexpected result of %d characters", len(got))
+	}
+	var stats mssql.Statistics
+	conn.Raw(func(driverConn interface{}) error {
+		stats = driverConn.(*mssql.Conn).Statistics()
+		return nil
+	})
+	if stats.BytesReceived < int64(2*len(text)) {
+		t.Errorf("expected the decompressed response to be counted, got %d bytes", stats.BytesReceived)
+	}
+}
+
+func TestDialerWithoutProxy(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	d := compression.NewDialer(&net.Dialer{}, flate.BestSpeed)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = d.DialContext(ctx, "tcp", srv.Addr().String())
+	if !errors.Is(err, compression.ErrNotNegotiated) {
+		t.Errorf("expected ErrNotNegotiated, got %v", err)
+	}
+}
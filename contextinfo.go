@@ -0,0 +1,115 @@
+package mssql
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// MaxContextInfoSize is the largest CONTEXT_INFO of a session in bytes.
+const MaxContextInfoSize = 128
+
+// ContextInfo is the binary CONTEXT_INFO of a session, an older and
+// lighter alternative to SESSION_CONTEXT that triggers read with the
+// CONTEXT_INFO() function, e.g. to record the application user in an
+// audit table.
+//
+// The Int64, String and UniqueIdentifier constructors encode a value
+// the way SQL Server converts binary to the matching type, so a trigger
+// can read it with
+//
+//	convert(bigint, substring(context_info(), 1, 8))
+//	convert(nvarchar(64), context_info())
+//	convert(uniqueidentifier, substring(context_info(), 1, 16))
+type ContextInfo []byte
+
+// ContextInfoInt64 encodes v as a big endian bigint.
+func ContextInfoInt64(v int64) ContextInfo {
+	info := make(ContextInfo, 8)
+	binary.BigEndian.PutUint64(info, uint64(v))
+	return info
+}
+
+// ContextInfoString encodes s as nvarchar, s must fit in 64 UTF-16 code units.
+func ContextInfoString(s string) (ContextInfo, error) {
+	info := ContextInfo(str2ucs2(s))
+	if len(info) > MaxContextInfoSize {
+		return nil, fmt.Errorf("mssql: context info string of %d bytes is longer than %d bytes", len(info), MaxContextInfoSize)
+	}
+	return info, nil
+}
+
+// ContextInfoUniqueIdentifier encodes u as a uniqueidentifier.
+func ContextInfoUniqueIdentifier(u UniqueIdentifier) ContextInfo {
+	v, _ := u.Value()
+	return ContextInfo(v.([]byte))
+}
+
+// Int64 decodes the bigint in the first 8 bytes of info.
+func (info ContextInfo) Int64() (int64, error) {
+	if len(info) < 8 {
+		return 0, fmt.Errorf("mssql: context info of %d bytes is too short for a bigint", len(info))
+	}
+	return int64(binary.BigEndian.Uint64(info)), nil
+}
+
+// String decodes info as nvarchar, without the zero characters that pad it.
+func (info ContextInfo) String() string {
+	b := []byte(info)
+	for len(b) >= 2 && b[len(b)-1] == 0 && b[len(b)-2] == 0 {
+		b = b[:len(b)-2]
+	}
+	return utf16le2str(b[:len(b)&^1])
+}
+
+// UniqueIdentifier decodes the uniqueidentifier in the first 16 bytes of info.
+func (info ContextInfo) UniqueIdentifier() (UniqueIdentifier, error) {
+	var u UniqueIdentifier
+	if len(info) < 16 {
+		return u, fmt.Errorf("mssql: context info of %d bytes is too short for a uniqueidentifier", len(info))
+	}
+	err := u.Scan([]byte(info[:16]))
+	return u, err
+}
+
+// SetContextInfo sets the CONTEXT_INFO of the session to info, at most
+// MaxContextInfoSize bytes. It cannot be set back to NULL, an empty info
+// sets it to a single zero byte.
+//
+// CONTEXT_INFO belongs to a single connection, so use a *sql.Conn or a
+// *sql.Tx, not a *sql.DB, or set it in Connector.OnConnect and
+// Connector.OnReset. The connection pool clears it when the connection is reused.
+//
+//	conn, err := db.Conn(ctx)
+//	...
+//	err = mssql.SetContextInfo(ctx, conn, mssql.ContextInfoInt64(userID))
+func SetContextInfo(ctx context.Context, e Execer, info ContextInfo) error {
+	if len(info) > MaxContextInfoSize {
+		return fmt.Errorf("mssql: context info of %d bytes is longer than %d bytes", len(info), MaxContextInfoSize)
+	}
+	if len(info) == 0 {
+		info = ContextInfo{0}
+	}
+	_, err := e.ExecContext(ctx, "set context_info @p1", []byte(info))
+	return err
+}
+
+// ReadContextInfo returns the CONTEXT_INFO of the session, nil if it was
+// never set.
+func ReadContextInfo(ctx context.Context, q Querier) (ContextInfo, error) {
+	rows, err := q.QueryContext(ctx, "select context_info()")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var info []byte
+	if rows.Next() {
+		if err = rows.Scan(&info); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,84 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestContextInfoEncoding(t *testing.T) {
+	info := ContextInfoInt64(-2)
+	if !bytes.Equal(info, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}) {
+		t.Errorf("unexpected bigint encoding %x", []byte(info))
+	}
+	// the server pads the value with zeros
+	padded := append(info[:8:8], make([]byte, MaxContextInfoSize-8)...)
+	if v, err := ContextInfo(padded).Int64(); err != nil || v != -2 {
+		t.Errorf("expected -2, got %d, %v", v, err)
+	}
+	if _, err := (ContextInfo{1}).Int64(); err == nil {
+		t.Error("expected an error for a short context info")
+	}
+
+	info, err := ContextInfoString("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(info, []byte("a\x00l\x00i\x00c\x00e\x00")) {
+		t.Errorf("unexpected nvarchar encoding %x", []byte(info))
+	}
+	padded = append(info[:len(info):len(info)], make([]byte, MaxContextInfoSize-len(info))...)
+	if s := ContextInfo(padded).String(); s != "alice" {
+		t.Errorf("expected alice, got %q", s)
+	}
+	if _, err = ContextInfoString(strings.Repeat("x", 65)); err == nil {
+		t.Error("expected an error for a string longer than 64 characters")
+	}
+
+	u := UniqueIdentifier{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	info = ContextInfoUniqueIdentifier(u)
+	if !bytes.Equal(info[:8], []byte{0x04, 0x03, 0x02, 0x01, 0x06, 0x05, 0x08, 0x07}) {
+		t.Errorf("unexpected uniqueidentifier encoding %x", []byte(info))
+	}
+	if got, err := info.UniqueIdentifier(); err != nil || got != u {
+		t.Errorf("expected %s, got %s, %v", u, got, err)
+	}
+}
+
+func TestSetContextInfo(t *testing.T) {
+	e := &recordingExecer{}
+	ctx := context.Background()
+	if err := SetContextInfo(ctx, e, ContextInfoInt64(42)); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetContextInfo(ctx, e, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.queries) != 2 || e.queries[0] != "set context_info @p1" {
+		t.Fatalf("unexpected queries %v", e.queries)
+	}
+	if arg := e.args[1][0].([]byte); !bytes.Equal(arg, []byte{0}) {
+		t.Errorf("expected an empty context info to be sent as 0x00, got %x", arg)
+	}
+	if err := SetContextInfo(ctx, e, make(ContextInfo, MaxContextInfoSize+1)); err == nil {
+		t.Error("expected an error for a context info longer than 128 bytes")
+	}
+}
+
+func TestReadContextInfo(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer db.Close()
+	defer srv.Close()
+	stored := []byte(ContextInfoInt64(7))
+	srv.Handle("select context_info()", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{stored}}}}})
+	info, err := ReadContextInfo(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := info.Int64(); err != nil || v != 7 {
+		t.Errorf("expected 7, got %d, %v", v, err)
+	}
+}
@@ -0,0 +1,155 @@
+package mssql
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVOptions are the options of WriteCSV. The zero value writes
+// comma separated values with a header and empty fields for NULL.
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' if it is 0. Use '\t' for TSV.
+	Comma rune
+	// Null is the text of NULL values.
+	Null string
+	// NoHeader omits the header of column names.
+	NoHeader bool
+	// UseCRLF ends the lines with \r\n instead of \n.
+	UseCRLF bool
+	// TimeFormat is the layout of the values of the datetime, datetime2,
+	// smalldatetime and datetimeoffset columns, instead of the format of
+	// CONVERT with style 121 at the scale of the column.
+	TimeFormat string
+}
+
+// WriteCSV writes the rows of the current result set of rows to w as CSV
+// and returns the number of rows written:
+//
+//	rows, err := db.QueryContext(ctx, "select * from dbo.orders")
+//	...
+//	defer rows.Close()
+//	n, err := mssql.WriteCSV(w, rows, mssql.CSVOptions{Null: "NULL"})
+//
+// Fields are quoted as needed. Dates and times are formatted like CONVERT
+// with style 121 formats them, decimal and money values keep their exact
+// digits, bit values are written as 1 and 0, binary values as hexadecimal
+// with a 0x prefix and uniqueidentifier values in their canonical form.
+// The rows are streamed, w is flushed after every row.
+func WriteCSV(w io.Writer, rows *sql.Rows, opts CSVOptions) (n int64, err error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	cw.UseCRLF = opts.UseCRLF
+	record := make([]string, len(types))
+	if !opts.NoHeader {
+		for i, typ := range types {
+			record[i] = typ.Name()
+		}
+		if err = cw.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	values := make([]interface{}, len(types))
+	scan := make([]interface{}, len(types))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(scan...); err != nil {
+			return n, err
+		}
+		for i, typ := range types {
+			record[i] = formatCSVValue(typ, values[i], opts)
+		}
+		if err = cw.Write(record); err != nil {
+			return n, err
+		}
+		cw.Flush()
+		if err = cw.Error(); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err = rows.Err(); err != nil {
+		return n, err
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// formatCSVValue returns the text of the value v of a column of type typ.
+func formatCSVValue(typ *sql.ColumnType, v interface{}, opts CSVOptions) string {
+	switch v := v.(type) {
+	case nil:
+		return opts.Null
+	case string:
+		return v
+	case []byte:
+		switch typ.DatabaseTypeName() {
+		case "DECIMAL", "MONEY", "SMALLMONEY":
+			return string(v)
+		case "UNIQUEIDENTIFIER":
+			var u UniqueIdentifier
+			if u.Scan(v) == nil {
+				return u.String()
+			}
+		case "BINARY", "VARBINARY", "IMAGE", "SQL_VARIANT":
+			return "0x" + hex.EncodeToString(v)
+		}
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		bits := 64
+		if typ.DatabaseTypeName() == "REAL" {
+			bits = 32
+		}
+		return strconv.FormatFloat(v, 'g', -1, bits)
+	case time.Time:
+		return formatCSVTime(typ, v, opts)
+	}
+	return fmt.Sprint(v)
+}
+
+// formatCSVTime formats the value of a date or time column.
+func formatCSVTime(typ *sql.ColumnType, t time.Time, opts CSVOptions) string {
+	var ti typeInfo
+	switch typ.DatabaseTypeName() {
+	case "DATE":
+		return t.Format("2006-01-02")
+	case "TIME":
+		ti.TypeId = typeTimeN
+	case "DATETIME2":
+		ti.TypeId = typeDateTime2N
+	case "DATETIMEOFFSET":
+		ti.TypeId = typeDateTimeOffsetN
+	case "SMALLDATETIME":
+		ti.TypeId = typeDateTim4
+	case "DATETIME":
+		ti.TypeId = typeDateTime
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+	if opts.TimeFormat != "" && ti.TypeId != typeTimeN {
+		return t.Format(opts.TimeFormat)
+	}
+	if _, scale, ok := typ.DecimalSize(); ok {
+		ti.Scale = uint8(scale)
+	}
+	return formatDateTime(ti, t)
+}
@@ -0,0 +1,57 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestWriteCSV(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select * from dbo.orders", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "note", "paid", "created", "hash"},
+		Rows: [][]interface{}{
+			{int64(1), `say "hi", then leave`, true, time.Date(2024, 2, 29, 13, 14, 15, 100, time.UTC), []byte{0xca, 0xfe}},
+			{int64(2), nil, false, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		},
+	}}})
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "select * from dbo.orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := WriteCSV(&buf, rows, CSVOptions{Null: "NULL"})
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "id,note,paid,created,hash\n" +
+		`1,"say ""hi"", then leave",1,2024-02-29 13:14:15.0000001,0xcafe` + "\n" +
+		"2,NULL,0,2024-03-01 00:00:00.0000000,NULL\n"
+	if n != 2 || buf.String() != expected {
+		t.Errorf("expected 2 rows of\n%s\ngot %d rows of\n%s", expected, n, buf.String())
+	}
+
+	rows, err = db.QueryContext(ctx, "select * from dbo.orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	_, err = WriteCSV(&buf, rows, CSVOptions{Comma: '\t', NoHeader: true, TimeFormat: "2006-01-02"})
+	rows.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "1\t\"say \"\"hi\"\", then leave\"\t1\t2024-02-29\t0xcafe\n" +
+		"2\t\t0\t2024-03-01\t\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, buf.String())
+	}
+}
@@ -0,0 +1,44 @@
+package mssql
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// ErrDACInUse is returned when a dedicated administrator connection to a server
+// is opened while the process has one open already. SQL Server allows only
+// one dedicated administrator connection at a time.
+var ErrDACInUse = errors.New("mssql: a dedicated administrator connection to the server is already open")
+
+// dacServers holds the servers with an open dedicated administrator connection.
+var dacServers = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: map[string]bool{}}
+
+// isDAC reports if p connects with the admin protocol to the dedicated administrator connection.
+func isDAC(p msdsn.Config) bool {
+	return len(p.Protocols) == 1 && p.Protocols[0] == "admin"
+}
+
+// acquireDAC reserves the dedicated administrator connection of the server of p.
+// It returns the key to release it with.
+func acquireDAC(p msdsn.Config) (string, error) {
+	key := strings.ToLower(p.Host + `\` + p.Instance)
+	dacServers.Lock()
+	defer dacServers.Unlock()
+	if dacServers.m[key] {
+		return "", ErrDACInUse
+	}
+	dacServers.m[key] = true
+	return key, nil
+}
+
+func releaseDAC(key string) {
+	dacServers.Lock()
+	delete(dacServers.m, key)
+	dacServers.Unlock()
+}
@@ -0,0 +1,62 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+func TestDACBrowserMessages(t *testing.T) {
+	if req := dacRequest("inst"); !bytes.Equal(req, []byte{0x0f, 1, 'i', 'n', 's', 't', 0}) {
+		t.Errorf("unexpected CLNT_UCAST_DAC message %v", req)
+	}
+	data := parseDAC([]byte{5, 6, 0, 1, 0xb3, 0x05}, "inst")
+	p := msdsn.Config{Host: "host", Instance: "inst"}
+	if err := (tcpDialer{}).ParseBrowserData(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Port != 1459 {
+		t.Errorf("expected the DAC port 1459, got %d", p.Port)
+	}
+	if len(parseDAC([]byte{5, 6, 0}, "inst")) != 0 {
+		t.Error("expected no data for a short response")
+	}
+}
+
+func TestDACSingleNonPooledConnection(t *testing.T) {
+	var dials int32
+	faults := &FaultInjector{Dial: func(ctx context.Context, server string) error {
+		atomic.AddInt32(&dials, 1)
+		return nil
+	}}
+	db, srv := newFaultTestDB(t, "server=admin:dachost;user id=sa;password=pwd;encrypt=disable", faults)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Conn(ctx); !errors.Is(err, ErrDACInUse) {
+		t.Errorf("expected ErrDACInUse for a second connection, got %v", err)
+	}
+	if _, err = conn.ExecContext(ctx, "select 1"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if _, err = db.ExecContext(ctx, "select 1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&dials); n != 2 {
+		t.Errorf("expected the connection to be closed instead of pooled, got %d dials", n)
+	}
+	if idle := db.Stats().Idle; idle != 0 {
+		t.Errorf("expected no idle connections, got %d", idle)
+	}
+}
@@ -0,0 +1,117 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// databaseKey is the context key of the database of a Database.
+type databaseKey struct{}
+
+// Database is a handle to a database of the server of a *sql.DB, for
+// designs with a database per tenant or shard. Every statement executed
+// through the handle runs in the database: when the connection it is
+// executed on is in another database, the driver switches it with USE
+// first. Connections returned to the pool are reset to the database of
+// the connection string by their next use.
+//
+//	tenant, err := mssql.WithDatabase(db, "tenant_"+id)
+//	...
+//	rows, err := tenant.QueryContext(ctx, "select id, total from orders")
+type Database struct {
+	db   *sql.DB
+	name string
+}
+
+// WithDatabase returns a handle to the database name of the server of db.
+// The connections of db must be of this driver.
+func WithDatabase(db *sql.DB, name string) (*Database, error) {
+	if _, err := QuoteIdentifier(name); err != nil {
+		return nil, err
+	}
+	return &Database{db: db, name: name}, nil
+}
+
+// Name returns the name of the database.
+func (d *Database) Name() string {
+	return d.name
+}
+
+// Context returns a copy of ctx that runs the statements executed with it
+// in the database, e.g. to pass it to a *sql.Stmt of the *sql.DB.
+func (d *Database) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, databaseKey{}, d.name)
+}
+
+// ExecContext executes query in the database like sql.DB.ExecContext.
+func (d *Database) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(d.Context(ctx), query, args...)
+}
+
+// QueryContext executes query in the database like sql.DB.QueryContext.
+func (d *Database) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(d.Context(ctx), query, args...)
+}
+
+// QueryRowContext executes query in the database like sql.DB.QueryRowContext.
+func (d *Database) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(d.Context(ctx), query, args...)
+}
+
+// BeginTx starts a transaction in the database. The statements of the
+// transaction run in the database, unless they change it with USE.
+func (d *Database) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return d.db.BeginTx(d.Context(ctx), opts)
+}
+
+// Conn returns a connection of the pool that was switched to the database.
+// It stays in the database until it is closed, unless its statements
+// change it with USE.
+func (d *Database) Conn(ctx context.Context) (*sql.Conn, error) {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*Conn)
+		if !ok {
+			return fmt.Errorf("mssql: WithDatabase requires a connection of this driver, got %T", driverConn)
+		}
+		return c.useContextDatabase(d.Context(ctx))
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// useContextDatabase switches the session to the database of the Database
+// that ctx was returned by, unless it is in the database already. A session
+// that will be reset is switched in any case, the reset changes the database
+// back to the database of the login.
+func (c *Conn) useContextDatabase(ctx context.Context) error {
+	name, ok := ctx.Value(databaseKey{}).(string)
+	if !ok || (!c.resetSession && strings.EqualFold(c.sess.database, name)) {
+		return nil
+	}
+	headers := []headerStruct{
+		{hdrtype: dataStmHdrTransDescr,
+			data: transDescrHdr{c.sess.tranid, 1}.pack()},
+	}
+	reset := c.resetSession
+	c.resetSession = false
+	query := "use " + TSQLQuoter{}.ID(name)
+	if err := sendSqlBatch72(c.sess.buf, query, headers, reset); err != nil {
+		c.connectionGood = false
+		return c.checkBadConn(ctx, fmt.Errorf("failed to send SQL Batch: %v", err), true)
+	}
+	// the outputs belong to the statement that is executed next
+	outs := c.outs
+	c.outs = outputs{}
+	err := c.simpleProcessResp(ctx)
+	c.outs = outs
+	return err
+}
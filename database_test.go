@@ -0,0 +1,140 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestWithDatabaseValidation(t *testing.T) {
+	for _, name := range []string{"", strings.Repeat("x", 129), "a\x00b"} {
+		if _, err := WithDatabase(nil, name); err == nil {
+			t.Errorf("expected an error for database name %q", name)
+		}
+	}
+	if _, err := WithDatabase(nil, strings.Repeat("ü", 128)); err != nil {
+		t.Errorf("expected a name of 128 characters to be valid, got %v", err)
+	}
+}
+
+func TestWithDatabase(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if strings.HasPrefix(query, "use ") {
+			return mssqltest.Response{Database: "tenant]1"}
+		}
+		return mssqltest.Response{}
+	})
+	tenant, err := WithDatabase(db, "tenant]1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	conn, err := tenant.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.ExecContext(ctx, "update orders set total = 0"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// the connection was returned to the pool, it is switched again
+	if _, err = tenant.ExecContext(ctx, "update orders set total = @p1", 2); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := tenant.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.ExecContext(ctx, "update orders set total = 3"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	want := []string{
+		"use [tenant]]1]",
+		"update orders set total = 0",
+		"use [tenant]]1]",
+		"update orders set total = @p1",
+		"use [tenant]]1]",
+		"update orders set total = 3",
+	}
+	if got := srv.Queries(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected queries\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestWithDatabaseDescribesParametersPerDatabase(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable;describeparameters=true", nil)
+	defer srv.Close()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	database := "master"
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		switch {
+		case strings.HasPrefix(query, "use "):
+			database = strings.Trim(query[len("use "):], "[]")
+			return mssqltest.Response{Database: database}
+		case query == "sp_describe_undeclared_parameters":
+			// the columns are in the order of sp_describe_undeclared_parameters
+			typeName := map[string]string{"tenant1": "varchar(10)", "tenant2": "int"}[database]
+			return mssqltest.Response{Results: []mssqltest.Result{{
+				Columns: []string{"parameter_ordinal", "name", "suggested_system_type_id", "suggested_system_type_name"},
+				Rows:    [][]interface{}{{1, "@p1", 167, typeName}},
+			}}}
+		}
+		return mssqltest.Response{}
+	})
+	tenant1, err := WithDatabase(db, "tenant1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant2, err := WithDatabase(db, "tenant2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, tenant := range []*Database{tenant1, tenant2, tenant1, tenant2} {
+		if _, err = tenant.ExecContext(ctx, "update orders set total = 0 where code = @p1", "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	conn.Raw(func(driverConn interface{}) error {
+		c := driverConn.(*Conn)
+		for _, name := range []string{"tenant1", "tenant2"} {
+			key := describedQuery{database: name, query: "update orders set total = 0 where code = @p1"}
+			types = append(types, c.paramTypes[key]["@p1"])
+		}
+		return nil
+	})
+	conn.Close()
+	if strings.Join(types, ",") != "varchar(10),int" {
+		t.Errorf("expected the types of the parameter in tenant1 and tenant2 to be varchar(10),int, got %v", types)
+	}
+	// the types are described once per database, in the database
+	want := []string{
+		"use [tenant1]",
+		"sp_describe_undeclared_parameters",
+		"update orders set total = 0 where code = @p1",
+		"use [tenant2]",
+		"sp_describe_undeclared_parameters",
+		"update orders set total = 0 where code = @p1",
+		"use [tenant1]",
+		"update orders set total = 0 where code = @p1",
+		"use [tenant2]",
+		"update orders set total = 0 where code = @p1",
+	}
+	if got := srv.Queries(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected queries\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
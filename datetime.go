@@ -0,0 +1,111 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// isDateTimeType returns if ti is a date or time type.
+func isDateTimeType(ti typeInfo) bool {
+	switch ti.TypeId {
+	case typeDateTim4, typeDateTime, typeDateTimeN, typeDateN, typeTimeN, typeDateTime2N, typeDateTimeOffsetN:
+		return true
+	}
+	return false
+}
+
+// convertDateTimes applies the datetime scan, datetime location and
+// server timezone settings of the connection to the date and time values of a row.
+func (c *Conn) convertDateTimes(cols []columnStruct, dest []driver.Value) {
+	if c.dateTimeScan != msdsn.DateTimeScanString && c.dateTimeLocation == nil && c.serverTimezone == nil {
+		return
+	}
+	for i, v := range dest {
+		t, ok := v.(time.Time)
+		if !ok || i >= len(cols) {
+			continue
+		}
+		ti := cols[i].originalTypeInfo()
+		if !isDateTimeType(ti) {
+			continue
+		}
+		if c.dateTimeScan == msdsn.DateTimeScanString {
+			dest[i] = formatDateTime(ti, t)
+			continue
+		}
+		switch ti.TypeId {
+		case typeDateTimeOffsetN:
+			if c.dateTimeLocation != nil {
+				t = t.In(c.dateTimeLocation)
+			}
+		case typeDateN, typeTimeN:
+			if c.dateTimeLocation != nil {
+				t = withLocation(t, c.dateTimeLocation)
+			}
+		default:
+			if c.serverTimezone != nil {
+				// the wall clock of the server, converted to the location
+				t = withLocation(t, c.serverTimezone)
+				if c.dateTimeLocation != nil {
+					t = t.In(c.dateTimeLocation)
+				}
+			} else if c.dateTimeLocation != nil {
+				t = withLocation(t, c.dateTimeLocation)
+			}
+		}
+		dest[i] = t
+	}
+}
+
+// withLocation returns the time with the wall clock of t in loc.
+func withLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// toServerTimezone converts a time.Time parameter to the server timezone of the connection.
+func (c *Conn) toServerTimezone(t time.Time) time.Time {
+	if c == nil || c.serverTimezone == nil {
+		return t
+	}
+	return t.In(c.serverTimezone)
+}
+
+// formatDateTime returns the canonical string of a value of a date or time
+// column, like CONVERT with style 121 formats it: "2006-01-02", "15:04:05.1234567",
+// "2006-01-02 15:04:05.123" or "2006-01-02 15:04:05.1234567 -07:00". The number of
+// digits of the fraction of the seconds is the scale of the column.
+func formatDateTime(ti typeInfo, t time.Time) string {
+	var layout string
+	switch ti.TypeId {
+	case typeDateN:
+		return t.Format("2006-01-02")
+	case typeTimeN:
+		layout = "15:04:05" + fraction(ti.Scale)
+	case typeDateTime2N:
+		layout = "2006-01-02 15:04:05" + fraction(ti.Scale)
+	case typeDateTimeOffsetN:
+		layout = "2006-01-02 15:04:05" + fraction(ti.Scale) + " -07:00"
+	case typeDateTim4:
+		layout = "2006-01-02 15:04:05"
+	case typeDateTime:
+		layout = "2006-01-02 15:04:05.000"
+	default:
+		// datetimen is a smalldatetime of 4 or a datetime of 8 bytes
+		if ti.Size == 4 {
+			layout = "2006-01-02 15:04:05"
+		} else {
+			layout = "2006-01-02 15:04:05.000"
+		}
+	}
+	return t.Format(layout)
+}
+
+func fraction(scale uint8) string {
+	if scale == 0 {
+		return ""
+	}
+	return "." + strings.Repeat("0", int(scale))
+}
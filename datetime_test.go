@@ -0,0 +1,120 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func dateTimeColumns() []columnStruct {
+	return []columnStruct{
+		{ti: typeInfo{TypeId: typeDateN, Size: 3}},
+		{ti: typeInfo{TypeId: typeTimeN, Scale: 3}},
+		{ti: typeInfo{TypeId: typeDateTime2N, Scale: 7}},
+		{ti: typeInfo{TypeId: typeDateTimeOffsetN, Scale: 0}},
+		{ti: typeInfo{TypeId: typeDateTimeN, Size: 4}},
+		{ti: typeInfo{TypeId: typeDateTime}},
+		{ti: typeInfo{TypeId: typeNVarChar}},
+	}
+}
+
+func dateTimeRow() []driver.Value {
+	offset := time.FixedZone("", 2*60*60)
+	return []driver.Value{
+		time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(1, 1, 1, 13, 14, 15, 123000000, time.UTC),
+		time.Date(2024, 2, 29, 13, 14, 15, 123456700, time.UTC),
+		time.Date(2024, 2, 29, 13, 14, 15, 0, offset),
+		time.Date(2024, 2, 29, 13, 14, 0, 0, time.UTC),
+		time.Date(2024, 2, 29, 13, 14, 15, 123000000, time.UTC),
+		"text",
+	}
+}
+
+func TestConvertDateTimesString(t *testing.T) {
+	c := &Conn{dateTimeScan: msdsn.DateTimeScanString}
+	row := dateTimeRow()
+	c.convertDateTimes(dateTimeColumns(), row)
+	expected := []driver.Value{
+		"2024-02-29",
+		"13:14:15.123",
+		"2024-02-29 13:14:15.1234567",
+		"2024-02-29 13:14:15 +02:00",
+		"2024-02-29 13:14:00",
+		"2024-02-29 13:14:15.123",
+		"text",
+	}
+	if !reflect.DeepEqual(row, expected) {
+		t.Errorf("expected %v, got %v", expected, row)
+	}
+	if typ := c.scanType(typeInfo{TypeId: typeDateTime2N}); typ != reflect.TypeOf("") {
+		t.Errorf("expected the scan type string, got %v", typ)
+	}
+}
+
+func TestConvertDateTimesLocation(t *testing.T) {
+	loc := time.FixedZone("test", -5*60*60)
+	c := &Conn{dateTimeScan: msdsn.DateTimeScanTime, dateTimeLocation: loc}
+	row := dateTimeRow()
+	c.convertDateTimes(dateTimeColumns(), row)
+	// the same wall clock, in the location
+	if v := row[2].(time.Time); v.Location() != loc || v.Hour() != 13 || v.Nanosecond() != 123456700 {
+		t.Errorf("unexpected datetime2 %v", v)
+	}
+	// the same instant, in the location
+	if v := row[3].(time.Time); v.Location() != loc || !v.Equal(time.Date(2024, 2, 29, 11, 14, 15, 0, time.UTC)) {
+		t.Errorf("unexpected datetimeoffset %v", v)
+	}
+	if row[6] != "text" {
+		t.Errorf("unexpected nvarchar %v", row[6])
+	}
+}
+
+func TestDateTimeScanString(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable;datetime scan=string", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select created from dbo.orders", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"created"},
+		Rows:    [][]interface{}{{time.Date(2024, 2, 29, 13, 14, 15, 100, time.UTC)}},
+	}}})
+	var created string
+	if err := db.QueryRowContext(context.Background(), "select created from dbo.orders").Scan(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created != "2024-02-29 13:14:15.0000001" {
+		t.Errorf("unexpected value %q", created)
+	}
+}
+
+func TestServerTimezone(t *testing.T) {
+	server := time.FixedZone("server", 1*60*60)
+	loc := time.FixedZone("app", -5*60*60)
+	c := &Conn{serverTimezone: server, dateTimeLocation: loc}
+	row := dateTimeRow()
+	c.convertDateTimes(dateTimeColumns(), row)
+	// datetime2 13:14:15 on the server is 12:14:15 UTC
+	if v := row[2].(time.Time); v.Location() != loc || !v.Equal(time.Date(2024, 2, 29, 12, 14, 15, 123456700, time.UTC)) {
+		t.Errorf("unexpected datetime2 %v", v)
+	}
+	if v := row[4].(time.Time); !v.Equal(time.Date(2024, 2, 29, 12, 14, 0, 0, time.UTC)) {
+		t.Errorf("unexpected smalldatetime %v", v)
+	}
+	// the date keeps its wall clock
+	if v := row[0].(time.Time); v.Location() != loc || v.Day() != 29 || v.Hour() != 0 {
+		t.Errorf("unexpected date %v", v)
+	}
+
+	p := c.toServerTimezone(time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC))
+	if p.Location() != server || p.Hour() != 13 {
+		t.Errorf("unexpected parameter %v", p)
+	}
+	if p = (&Conn{}).toServerTimezone(time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)); p.Location() != time.UTC {
+		t.Errorf("unexpected parameter without a server timezone %v", p)
+	}
+}
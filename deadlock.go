@@ -0,0 +1,142 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"time"
+)
+
+// errDeadlockVictim is the number of the error sent to the session that
+// was chosen as deadlock victim.
+const errDeadlockVictim = 1205
+
+// deadlockGraphSQL returns the latest deadlock graph of the system_health
+// session of the last minutes in which the session @p1 was the victim.
+// SQL Server does not send the graph with the error.
+const deadlockGraphSQL = `select top (1) convert(nvarchar(max), e.x.query('(data[@name="xml_report"]/value/deadlock)[1]'))
+from (
+	select convert(xml, t.target_data) as target_data
+	from sys.dm_xe_session_targets t
+	join sys.dm_xe_sessions s on s.address = t.event_session_address
+	where s.name = 'system_health' and t.target_name = 'ring_buffer'
+) as ring
+cross apply ring.target_data.nodes('RingBufferTarget/event[@name="xml_deadlock_report"]') as e(x)
+where e.x.exist('data[@name="xml_report"]/value/deadlock[victim-list/victimProcess/@id = process-list/process[@spid = sql:variable("@p1")]/@id]') = 1
+and e.x.value('@timestamp', 'datetime2') >= dateadd(minute, -5, sysutcdatetime())
+order by e.x.value('@timestamp', 'datetime2') desc`
+
+// deadlockGraphPoll is the interval in which attachDeadlockGraph looks for
+// the graph, the system_health session dispatches its events with a delay.
+const deadlockGraphPoll = 500 * time.Millisecond
+
+// attachDeadlockGraph returns the deadlock error e of the session of c with
+// its deadlock graph, when the Connector asks for it and the graph is found
+// before the DeadlockGraphTimeout or the end of ctx.
+func (c *Conn) attachDeadlockGraph(ctx context.Context, e Error) Error {
+	if c.connector == nil || c.connector.DeadlockGraphTimeout <= 0 {
+		return e
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.connector.DeadlockGraphTimeout)
+	defer cancel()
+	graph, err := c.connector.deadlockGraph(ctx, c.SPID())
+	if err != nil {
+		c.logEvent(ctx, Event{Type: EventWarning, Err: err, Message: "reading the deadlock graph failed: " + err.Error()})
+		return e
+	}
+	e.DeadlockGraph = graph
+	return e
+}
+
+// deadlockGraph polls the system_health session for the graph of the
+// deadlock of the session spid until ctx is done, over the connection the
+// Connector keeps for the graphs.
+func (c *Connector) deadlockGraph(ctx context.Context, spid int) (string, error) {
+	conn, err := c.takeDeadlockConn(ctx)
+	if err != nil {
+		return "", err
+	}
+	for {
+		graph, err := conn.queryDeadlockGraph(ctx, spid)
+		if err != nil {
+			conn.Close()
+			return "", err
+		}
+		if graph != "" {
+			c.putDeadlockConn(conn)
+			return graph, nil
+		}
+		select {
+		case <-ctx.Done():
+			c.putDeadlockConn(conn)
+			return "", nil
+		case <-time.After(deadlockGraphPoll):
+		}
+	}
+}
+
+// takeDeadlockConn returns the idle connection for the deadlock graphs, or
+// a new connection when there is none or another deadlock is using it.
+func (c *Connector) takeDeadlockConn(ctx context.Context) (*Conn, error) {
+	c.deadlockMu.Lock()
+	conn := c.deadlockConn
+	c.deadlockConn = nil
+	c.deadlockMu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	dc, err := c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dc.(*Conn), nil
+}
+
+// putDeadlockConn keeps conn for the next deadlock graph, or closes it when
+// the Connector already keeps a connection or was closed.
+func (c *Connector) putDeadlockConn(conn *Conn) {
+	c.deadlockMu.Lock()
+	keep := c.deadlockConn == nil && !c.deadlockClosed && conn.connectionGood
+	if keep {
+		c.deadlockConn = conn
+	}
+	c.deadlockMu.Unlock()
+	if !keep {
+		conn.Close()
+	}
+}
+
+// Close closes the connection the Connector keeps to read deadlock graphs
+// for DeadlockGraphTimeout. sql.DB.Close calls it for the Connector of
+// sql.OpenDB. The Connector can still open connections after Close.
+func (c *Connector) Close() error {
+	c.deadlockMu.Lock()
+	conn := c.deadlockConn
+	c.deadlockConn = nil
+	c.deadlockClosed = true
+	c.deadlockMu.Unlock()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+func (c *Conn) queryDeadlockGraph(ctx context.Context, spid int) (string, error) {
+	stmt := &Stmt{c: c, query: deadlockGraphSQL, skipEncryption: true}
+	rows, err := stmt.QueryContext(ctx, []driver.NamedValue{{Ordinal: 1, Value: int64(spid)}})
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	switch err = rows.Next(dest); err {
+	case nil:
+	case io.EOF:
+		// the graph was not dispatched yet
+		return "", nil
+	default:
+		return "", err
+	}
+	graph, _ := dest[0].(string)
+	return graph, nil
+}
@@ -0,0 +1,79 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestDeadlockGraph(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	graph := `<deadlock><victim-list><victimProcess id="process1" /></victim-list></deadlock>`
+	graphs := 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		switch {
+		case strings.Contains(query, "update orders"):
+			return mssqltest.Response{Err: &mssqltest.Error{Number: errDeadlockVictim, Class: 13, Message: "Transaction was deadlocked"}}
+		case strings.Contains(query, "xml_deadlock_report"):
+			graphs++
+			if graphs == 1 {
+				// not dispatched yet
+				return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}}}}
+			}
+			return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{graph}}}}}
+		}
+		return mssqltest.Response{}
+	})
+	ctx := context.Background()
+
+	var e Error
+	_, err := db.ExecContext(ctx, "update orders set status = 1")
+	if !errors.As(err, &e) || e.Number != errDeadlockVictim || e.DeadlockGraph != "" {
+		t.Fatalf("expected a deadlock error without a graph by default, got %#v", err)
+	}
+	if graphs != 0 {
+		t.Fatalf("expected no graph query by default, got %d", graphs)
+	}
+
+	c, err := NewConnector("server=test;encrypt=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Dialer = srv
+	c.DeadlockGraphTimeout = 5 * time.Second
+	conn, err := c.Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	stmt := &Stmt{c: conn.(*Conn), query: "update orders set status = 1"}
+	_, err = stmt.ExecContext(ctx, nil)
+	if !errors.As(err, &e) || e.DeadlockGraph != graph {
+		t.Fatalf("expected the deadlock graph, got %#v", err)
+	}
+	if graphs != 2 {
+		t.Errorf("expected the graph to be polled until it was dispatched, got %d queries", graphs)
+	}
+
+	// the next deadlock reuses the connection of the graphs
+	logins := srv.Logins()
+	_, err = stmt.ExecContext(ctx, nil)
+	if !errors.As(err, &e) || e.DeadlockGraph != graph {
+		t.Fatalf("expected the deadlock graph, got %#v", err)
+	}
+	if srv.Logins() != logins {
+		t.Errorf("expected no new login for the second graph, got %d logins after %d", srv.Logins(), logins)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.deadlockConn != nil {
+		t.Error("expected Close to close the connection of the graphs")
+	}
+}
@@ -0,0 +1,70 @@
+package mssql
+
+import "strings"
+
+// DecodeColumns may be passed as an argument to Query to decode only the
+// named columns of the result sets. The values of the other columns are read
+// from the connection without being converted, they are returned as NULL.
+// It avoids the UCS-2 conversion and the allocations of wide columns that the
+// caller does not use, e.g. when it maps a subset of the columns of SELECT *:
+//
+//	rows, err := db.QueryContext(ctx, "select * from dbo.documents", mssql.DecodeColumns{"id", "title"})
+//
+// Column names are matched case insensitively. Scan the skipped columns into
+// a destination that accepts NULL, e.g. *interface{} or *sql.RawBytes.
+type DecodeColumns []string
+
+// skipColumns marks the columns that are not in decode.
+func skipColumns(columns []columnStruct, decode DecodeColumns) {
+	for i := range columns {
+		columns[i].skip = true
+		for _, name := range decode {
+			if strings.EqualFold(columns[i].ColName, name) {
+				columns[i].skip = false
+				break
+			}
+		}
+	}
+}
+
+// skipValue reads a value of ti from r without decoding it. Only the types
+// with variable length values are skipped, the other values are decoded.
+func skipValue(ti *typeInfo, r *tdsBuffer) {
+	switch ti.TypeId {
+	case typeXml, typeUdt:
+		skipPLP(r)
+	case typeBigVarBin, typeBigVarChar, typeBigBinary, typeBigChar, typeNVarChar, typeNChar:
+		if ti.Size == 0xffff {
+			skipPLP(r)
+			return
+		}
+		if size := r.uint16(); size != 0xffff {
+			r.skip(int(size))
+		}
+	case typeText, typeImage, typeNText:
+		textptrsize := int(r.byte())
+		if textptrsize == 0 {
+			return
+		}
+		// the text pointer and the timestamp
+		r.skip(textptrsize + 8)
+		if size := r.int32(); size != -1 {
+			r.skip(int(size))
+		}
+	default:
+		ti.Reader(ti, r, nil)
+	}
+}
+
+func skipPLP(r *tdsBuffer) {
+	if r.uint64() == _PLP_NULL {
+		return
+	}
+	for {
+		chunksize := r.uint32()
+		if chunksize == 0 {
+			return
+		}
+		r.skip(int(chunksize))
+	}
+}
@@ -0,0 +1,62 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestParseRowSkipColumns(t *testing.T) {
+	columns, data := makeRowColumns()
+	// a short nvarchar and a NULL varchar follow the PLP columns
+	columns = append(columns,
+		columnStruct{ti: typeInfo{TypeId: typeNVarChar, Size: 100, Buffer: make([]byte, 100), Reader: readShortLenType}},
+		columnStruct{ti: typeInfo{TypeId: typeBigVarChar, Size: 100, Buffer: make([]byte, 100), Reader: readShortLenType}},
+	)
+	data = append(data, 4, 0, 'h', 0, 'i', 0, 0xff, 0xff)
+	for i := range columns {
+		columns[i].ColName = []string{"id", "a", "b", "c", "d", "e", "f"}[i]
+	}
+	skipColumns(columns, DecodeColumns{"ID", "d"})
+	r := makeReplyBuf(append(data, data...))
+	if _, err := r.BeginRead(); err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 2; n++ {
+		row := make([]interface{}, len(columns))
+		if err := parseRow(context.Background(), r, nil, columns, row); err != nil {
+			t.Fatal(err)
+		}
+		expected := []interface{}{int64(42), nil, nil, nil, bytes.Repeat([]byte{1, 2, 3, 4}, 50), nil, nil}
+		if !reflect.DeepEqual(row, expected) {
+			t.Errorf("expected %v, got %v", expected, row)
+		}
+	}
+	if r.rpos != r.rsize {
+		t.Errorf("%d bytes of the rows were not read", r.rsize-r.rpos)
+	}
+}
+
+func TestDecodeColumns(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select * from dbo.documents", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "body", "title"},
+		Rows:    [][]interface{}{{1, strings.Repeat("body ", 1000), "first"}},
+	}}})
+	var id int
+	var body interface{}
+	var title string
+	err := db.QueryRowContext(context.Background(), "select * from dbo.documents", DecodeColumns{"id", "title"}).Scan(&id, &body, &title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || body != nil || title != "first" {
+		t.Errorf("unexpected row %v, %v, %v", id, body, title)
+	}
+}
@@ -0,0 +1,85 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// maxDescribedQueries limits the number of queries with cached parameter types per connection
+const maxDescribedQueries = 1000
+
+// describedQuery is the key of the parameter types cached by describeParams.
+type describedQuery struct {
+	database string
+	query    string
+}
+
+// describeParams looks up the types the server deduces for the parameters of
+// the query with sp_describe_undeclared_parameters. The input parameters are
+// declared with these types instead of the types of the Go values, so they are
+// compared with columns of the same type instead of being implicitly converted.
+// The types are cached per database and query text on the connection, the
+// same query can refer to tables with other columns in another database.
+// If the server cannot describe the query, the types of the Go values are used.
+func (s *Stmt) describeParams(ctx context.Context) error {
+	key := describedQuery{database: s.c.sess.database, query: s.query}
+	types, ok := s.c.paramTypes[key]
+	if !ok {
+		q := Stmt{c: s.c,
+			query:          "sp_describe_undeclared_parameters",
+			skipEncryption: true,
+		}
+		oldouts := s.c.outs
+		s.c.clearOuts()
+		rows, err := q.queryContext(ctx, []namedValue{{Name: "tsql", Ordinal: 1, Value: s.query}})
+		if err == nil {
+			types, err = processDescribeUndeclaredParameters(rows)
+			rows.Close()
+		}
+		s.c.outs = oldouts
+		if err != nil {
+			if !s.c.connectionGood {
+				return err
+			}
+			if s.c.sess.logFlags&logErrors != 0 {
+				s.c.sess.logger.Log(ctx, msdsn.LogErrors, fmt.Sprintf("Failed to describe the parameters of the query: %v", err))
+			}
+			types = map[string]string{}
+		}
+		if s.c.paramTypes == nil || len(s.c.paramTypes) >= maxDescribedQueries {
+			s.c.paramTypes = make(map[describedQuery]map[string]string)
+		}
+		s.c.paramTypes[key] = types
+	}
+	s.paramTypes = types
+	return nil
+}
+
+// processDescribeUndeclaredParameters reads the parameter names and
+// suggested types from the result of sp_describe_undeclared_parameters
+// https://learn.microsoft.com/sql/relational-databases/system-stored-procedures/sp-describe-undeclared-parameters-transact-sql
+func processDescribeUndeclaredParameters(rows driver.Rows) (map[string]string, error) {
+	types := make(map[string]string)
+	values := make([]driver.Value, len(rows.Columns()))
+	if len(values) < 4 {
+		return nil, fmt.Errorf("mssql: unexpected result of sp_describe_undeclared_parameters with %d columns", len(values))
+	}
+	err := rows.Next(values)
+	for err == nil {
+		// name and suggested_system_type_name
+		name, _ := values[1].(string)
+		typeName, _ := values[3].(string)
+		if len(name) > 0 && len(typeName) > 0 {
+			types[name] = typeName
+		}
+		err = rows.Next(values)
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	return types, nil
+}
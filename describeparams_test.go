@@ -0,0 +1,93 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type describeRowsStub struct {
+	rows [][]driver.Value
+}
+
+func (r *describeRowsStub) Columns() []string {
+	return []string{"parameter_ordinal", "name", "suggested_system_type_id", "suggested_system_type_name"}
+}
+
+func (r *describeRowsStub) Close() error { return nil }
+
+func (r *describeRowsStub) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestProcessDescribeUndeclaredParameters(t *testing.T) {
+	rows := &describeRowsStub{rows: [][]driver.Value{
+		{int64(1), "@p1", int64(167), "varchar(50)"},
+		{int64(2), "@name", int64(231), "nvarchar(100)"},
+		{int64(3), "@p3", nil, nil},
+	}}
+	types, err := processDescribeUndeclaredParameters(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"@p1": "varchar(50)", "@name": "nvarchar(100)"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected %v, got %v", expected, types)
+	}
+}
+
+func TestDescribeParamsDeclaresSuggestedTypes(t *testing.T) {
+	const query = "select * from t where code = @p1 and name = @name and id = @p3"
+	c := &Conn{sess: &tdsSession{}, describeParameters: true}
+	c.paramTypes = map[describedQuery]map[string]string{
+		{query: query}: {"@p1": "varchar(50)", "@name": "nvarchar(100)", "@p3": "int"},
+	}
+	s := &Stmt{c: c, query: query}
+	if err := s.describeParams(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	args := []namedValue{
+		{Ordinal: 1, Value: "ab-12"},
+		{Name: "name", Ordinal: 2, Value: nil},
+		{Ordinal: 3, Value: sql.Out{Dest: int64(0)}},
+		{Ordinal: 4, Value: int64(1)},
+	}
+	_, decls, err := s.makeRPCParams(args, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(decls, ",")
+	expected := "@p1 varchar(50),@name nvarchar(100),@p3 bigint output,@p4 bigint"
+	if got != expected {
+		t.Errorf("expected declarations %q, got %q", expected, got)
+	}
+}
+
+func TestDescribeParamsCachedPerDatabase(t *testing.T) {
+	const query = "select * from t where code = @p1"
+	c := &Conn{sess: &tdsSession{database: "sales"}, describeParameters: true}
+	c.paramTypes = map[describedQuery]map[string]string{
+		{database: "sales", query: query}: {"@p1": "varchar(10)"},
+		{database: "hr", query: query}:    {"@p1": "int"},
+	}
+	s := &Stmt{c: c, query: query}
+	for _, database := range []string{"sales", "hr", "sales"} {
+		c.sess.database = database
+		if err := s.describeParams(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		expected := c.paramTypes[describedQuery{database: database, query: query}]
+		if !reflect.DeepEqual(s.paramTypes, expected) {
+			t.Errorf("expected the types %v of %s, got %v", expected, database, s.paramTypes)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package diagnostics queries the dynamic management views of SQL Server
+// for the requests, sessions, blocking and waits of the server.
+//
+// The queries need the VIEW SERVER STATE permission, or VIEW SERVER
+// PERFORMANCE STATE on SQL Server 2022 and later.
+package diagnostics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/internal/sqlexec"
+)
+
+// Request is a request that is running on the server, from sys.dm_exec_requests.
+type Request struct {
+	SessionID         int
+	RequestID         int
+	StartTime         time.Time
+	Status            string
+	Command           string
+	Database          string
+	BlockingSessionID int
+	WaitType          string
+	WaitTime          time.Duration
+	WaitResource      string
+	CPUTime           time.Duration
+	ElapsedTime       time.Duration
+	Reads             int64
+	Writes            int64
+	LogicalReads      int64
+	// Text is the statement of the batch that is running.
+	Text string
+}
+
+const requestsQuery = `select r.session_id, r.request_id, r.start_time, r.status, r.command,
+	isnull(db_name(r.database_id), ''), isnull(r.blocking_session_id, 0),
+	isnull(r.wait_type, ''), r.wait_time, r.wait_resource, r.cpu_time, r.total_elapsed_time,
+	r.reads, r.writes, r.logical_reads,
+	isnull(substring(t.text, r.statement_start_offset / 2 + 1,
+		(case r.statement_end_offset when -1 then datalength(t.text) else r.statement_end_offset end - r.statement_start_offset) / 2 + 1), '')
+from sys.dm_exec_requests r
+outer apply sys.dm_exec_sql_text(r.sql_handle) t
+where r.session_id <> @@spid and r.session_id in (select session_id from sys.dm_exec_sessions where is_user_process = 1)
+order by r.total_elapsed_time desc`
+
+// Requests returns the running requests of user sessions, except the
+// request of q itself, with the longest running first.
+func Requests(ctx context.Context, q sqlexec.Querier) ([]Request, error) {
+	rows, err := q.QueryContext(ctx, requestsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []Request
+	for rows.Next() {
+		var r Request
+		var waitTime, cpuTime, elapsedTime int64
+		err = rows.Scan(&r.SessionID, &r.RequestID, &r.StartTime, &r.Status, &r.Command,
+			&r.Database, &r.BlockingSessionID,
+			&r.WaitType, &waitTime, &r.WaitResource, &cpuTime, &elapsedTime,
+			&r.Reads, &r.Writes, &r.LogicalReads, &r.Text)
+		if err != nil {
+			return nil, err
+		}
+		r.WaitTime = milliseconds(waitTime)
+		r.CPUTime = milliseconds(cpuTime)
+		r.ElapsedTime = milliseconds(elapsedTime)
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// Session is a user session, from sys.dm_exec_sessions.
+type Session struct {
+	SessionID      int
+	LoginTime      time.Time
+	HostName       string
+	ProgramName    string
+	LoginName      string
+	Status         string
+	Database       string
+	CPUTime        time.Duration
+	LogicalReads   int64
+	LastRequestEnd time.Time
+	// OpenTransactions is the number of open transactions of the session.
+	OpenTransactions int
+}
+
+const sessionsQuery = `select s.session_id, s.login_time, isnull(s.host_name, ''), isnull(s.program_name, ''),
+	s.login_name, s.status, isnull(db_name(s.database_id), ''), s.cpu_time, s.logical_reads,
+	isnull(s.last_request_end_time, s.login_time), s.open_transaction_count
+from sys.dm_exec_sessions s
+where s.is_user_process = 1
+order by s.session_id`
+
+// Sessions returns the user sessions of the server.
+func Sessions(ctx context.Context, q sqlexec.Querier) ([]Session, error) {
+	rows, err := q.QueryContext(ctx, sessionsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []Session
+	for rows.Next() {
+		var s Session
+		var cpuTime int64
+		err = rows.Scan(&s.SessionID, &s.LoginTime, &s.HostName, &s.ProgramName,
+			&s.LoginName, &s.Status, &s.Database, &cpuTime, &s.LogicalReads,
+			&s.LastRequestEnd, &s.OpenTransactions)
+		if err != nil {
+			return nil, err
+		}
+		s.CPUTime = milliseconds(cpuTime)
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+// BlockingChain is a session that blocks other sessions without being blocked itself,
+// and the sessions that wait for it directly or through other blocked sessions.
+type BlockingChain struct {
+	// Head is the session at the head of the chain.
+	Head int
+	// Blocked are the blocked requests of the chain, each waits
+	// for the session in its BlockingSessionID.
+	Blocked []Request
+}
+
+// BlockingChains returns the blocking chains of the running requests,
+// with the chain that blocks the most requests first.
+func BlockingChains(ctx context.Context, q sqlexec.Querier) ([]BlockingChain, error) {
+	requests, err := Requests(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return blockingChains(requests), nil
+}
+
+// blockingChains groups the blocked requests by the session at the head of their chain.
+func blockingChains(requests []Request) []BlockingChain {
+	blockedBy := make(map[int]int)
+	for _, r := range requests {
+		if r.BlockingSessionID != 0 && r.BlockingSessionID != r.SessionID {
+			blockedBy[r.SessionID] = r.BlockingSessionID
+		}
+	}
+	head := func(session int) int {
+		// follow the chain, a cycle is a deadlock that the server resolves soon
+		seen := map[int]bool{session: true}
+		for {
+			next, ok := blockedBy[session]
+			if !ok || seen[next] {
+				return session
+			}
+			seen[next] = true
+			session = next
+		}
+	}
+	var chains []BlockingChain
+	index := make(map[int]int)
+	for _, r := range requests {
+		if _, ok := blockedBy[r.SessionID]; !ok {
+			continue
+		}
+		h := head(r.SessionID)
+		i, ok := index[h]
+		if !ok {
+			i = len(chains)
+			index[h] = i
+			chains = append(chains, BlockingChain{Head: h})
+		}
+		chains[i].Blocked = append(chains[i].Blocked, r)
+	}
+	sort.SliceStable(chains, func(i, j int) bool {
+		return len(chains[i].Blocked) > len(chains[j].Blocked)
+	})
+	return chains
+}
+
+// WaitStat is the accumulated time that tasks waited for a wait type
+// since the server started or the statistics were cleared, from sys.dm_os_wait_stats.
+type WaitStat struct {
+	WaitType     string
+	WaitingTasks int64
+	WaitTime     time.Duration
+	MaxWaitTime  time.Duration
+	// SignalWaitTime is the part of WaitTime that tasks waited for a CPU after they were signaled.
+	SignalWaitTime time.Duration
+}
+
+// wait types of idle background tasks, which are not interesting for troubleshooting
+const idleWaitTypes = `'BROKER_EVENTHANDLER', 'BROKER_RECEIVE_WAITFOR', 'BROKER_TASK_STOP', 'BROKER_TO_FLUSH',
+	'BROKER_TRANSMITTER', 'CHECKPOINT_QUEUE', 'CLR_AUTO_EVENT', 'CLR_MANUAL_EVENT', 'DIRTY_PAGE_POLL',
+	'DISPATCHER_QUEUE_SEMAPHORE', 'FT_IFTS_SCHEDULER_IDLE_WAIT', 'HADR_FILESTREAM_IOMGR_IOCOMPLETION',
+	'HADR_WORK_QUEUE', 'LAZYWRITER_SLEEP', 'LOGMGR_QUEUE', 'ONDEMAND_TASK_QUEUE',
+	'QDS_ASYNC_QUEUE', 'QDS_CLEANUP_STALE_QUERIES_TASK_MAIN_LOOP_SLEEP', 'QDS_PERSIST_TASK_MAIN_LOOP_SLEEP',
+	'REQUEST_FOR_DEADLOCK_SEARCH', 'SLEEP_SYSTEMTASK', 'SLEEP_TASK', 'SP_SERVER_DIAGNOSTICS_SLEEP',
+	'SQLTRACE_BUFFER_FLUSH', 'SQLTRACE_INCREMENTAL_FLUSH_SLEEP', 'WAITFOR', 'XE_DISPATCHER_WAIT', 'XE_TIMER_EVENT'`
+
+const waitStatsQuery = `select top (@p1) wait_type, waiting_tasks_count, wait_time_ms, max_wait_time_ms, signal_wait_time_ms
+from sys.dm_os_wait_stats
+where waiting_tasks_count > 0 and wait_type not in (` + idleWaitTypes + `)
+order by wait_time_ms desc`
+
+// WaitStats returns the top wait types by wait time, without the
+// wait types of idle background tasks.
+func WaitStats(ctx context.Context, q sqlexec.Querier, top int) ([]WaitStat, error) {
+	rows, err := q.QueryContext(ctx, waitStatsQuery, top)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []WaitStat
+	for rows.Next() {
+		var w WaitStat
+		var waitTime, maxWaitTime, signalWaitTime int64
+		if err = rows.Scan(&w.WaitType, &w.WaitingTasks, &waitTime, &maxWaitTime, &signalWaitTime); err != nil {
+			return nil, err
+		}
+		w.WaitTime = milliseconds(waitTime)
+		w.MaxWaitTime = milliseconds(maxWaitTime)
+		w.SignalWaitTime = milliseconds(signalWaitTime)
+		res = append(res, w)
+	}
+	return res, rows.Err()
+}
+
+func milliseconds(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,43 @@
+package diagnostics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlockingChains(t *testing.T) {
+	requests := []Request{
+		{SessionID: 51},
+		{SessionID: 52, BlockingSessionID: 60},
+		{SessionID: 53, BlockingSessionID: 52},
+		{SessionID: 54, BlockingSessionID: 61},
+		{SessionID: 55, BlockingSessionID: 53},
+		// a deadlock cycle
+		{SessionID: 56, BlockingSessionID: 57},
+		{SessionID: 57, BlockingSessionID: 56},
+		// blocked by itself through parallel tasks
+		{SessionID: 58, BlockingSessionID: 58},
+	}
+	chains := blockingChains(requests)
+	sessions := make(map[int][]int)
+	var heads []int
+	for _, c := range chains {
+		heads = append(heads, c.Head)
+		for _, r := range c.Blocked {
+			sessions[c.Head] = append(sessions[c.Head], r.SessionID)
+		}
+	}
+	// chains with the same number of blocked requests keep the order of the requests
+	if !reflect.DeepEqual(heads, []int{60, 61, 57, 56}) {
+		t.Errorf("unexpected heads %v", heads)
+	}
+	if !reflect.DeepEqual(sessions[60], []int{52, 53, 55}) {
+		t.Errorf("expected 52, 53 and 55 blocked by 60, got %v", sessions[60])
+	}
+	if !reflect.DeepEqual(sessions[61], []int{54}) {
+		t.Errorf("expected 54 blocked by 61, got %v", sessions[61])
+	}
+	if len(blockingChains(requests[:1])) != 0 {
+		t.Error("expected no chains without blocked requests")
+	}
+}
@@ -0,0 +1,99 @@
+package mssql
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// fallbackDelay is the head start of the addresses of the preferred family
+// when addresses are dialed in parallel, as recommended by RFC 8305.
+const fallbackDelay = 300 * time.Millisecond
+
+// lookupIPAddr resolves host names, it is replaced in tests.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+type dnsEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// dnsCache caches the addresses of the server host names for the
+// dns cache ttl of the connection string. It is global because
+// connections opened with sql.Open do not share a Connector.
+var dnsCache = struct {
+	sync.Mutex
+	m map[string]dnsEntry
+}{m: map[string]dnsEntry{}}
+
+// lookupIP returns the addresses of host, from the cache when they were
+// resolved less than ttl ago.
+func lookupIP(ctx context.Context, host string, ttl time.Duration) ([]net.IP, error) {
+	key := strings.ToLower(host)
+	if ttl > 0 {
+		dnsCache.Lock()
+		e, ok := dnsCache.m[key]
+		dnsCache.Unlock()
+		if ok && time.Now().Before(e.expires) {
+			return e.ips, nil
+		}
+	}
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	if ttl > 0 {
+		dnsCache.Lock()
+		dnsCache.m[key] = dnsEntry{ips: ips, expires: time.Now().Add(ttl)}
+		dnsCache.Unlock()
+	}
+	return ips, nil
+}
+
+// forgetIP removes the cached addresses of host, so the next connection
+// resolves them again. It is called when no address of host could be dialed,
+// after a failover the host name points to a new address.
+func forgetIP(host string) {
+	dnsCache.Lock()
+	delete(dnsCache.m, strings.ToLower(host))
+	dnsCache.Unlock()
+}
+
+// preferred reports if ip is of the family preferred by the ippreference of the connection string.
+func preferred(ip net.IP, preference string) bool {
+	switch preference {
+	case msdsn.IPPreferenceIPv4First:
+		return ip.To4() != nil
+	case msdsn.IPPreferenceIPv6First:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// sortIPs moves the addresses of the preferred family to the front,
+// keeping the order of the resolver within each family.
+func sortIPs(ips []net.IP, preference string) []net.IP {
+	if preference == "" || preference == msdsn.IPPreferencePlatformDefault {
+		return ips
+	}
+	res := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if preferred(ip, preference) {
+			res = append(res, ip)
+		}
+	}
+	for _, ip := range ips {
+		if !preferred(ip, preference) {
+			res = append(res, ip)
+		}
+	}
+	return res
+}
@@ -0,0 +1,222 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// fakeLookup replaces the DNS resolution with addresses that change on every lookup.
+func fakeLookup(t *testing.T) *int {
+	lookups := 0
+	old := lookupIPAddr
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		return []net.IPAddr{{IP: net.IPv4(10, 0, 0, byte(lookups))}}, nil
+	}
+	t.Cleanup(func() { lookupIPAddr = old })
+	return &lookups
+}
+
+func TestLookupIPCache(t *testing.T) {
+	lookups := fakeLookup(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := lookupIP(ctx, "nocache.example.com", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *lookups != 2 {
+		t.Errorf("expected a lookup per connection without a ttl, got %d", *lookups)
+	}
+
+	*lookups = 0
+	defer forgetIP("cached.example.com")
+	first, _ := lookupIP(ctx, "cached.example.com", time.Minute)
+	second, _ := lookupIP(ctx, "CACHED.example.com", time.Minute)
+	if *lookups != 1 || !first[0].Equal(second[0]) {
+		t.Errorf("expected the cached addresses, got %d lookups and %v, %v", *lookups, first, second)
+	}
+	forgetIP("cached.example.com")
+	third, _ := lookupIP(ctx, "cached.example.com", time.Minute)
+	if *lookups != 2 || third[0].Equal(first[0]) {
+		t.Errorf("expected a new lookup after forgetting the host, got %d lookups and %v", *lookups, third)
+	}
+
+	*lookups = 0
+	defer forgetIP("expired.example.com")
+	lookupIP(ctx, "expired.example.com", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	lookupIP(ctx, "expired.example.com", time.Nanosecond)
+	if *lookups != 2 {
+		t.Errorf("expected a new lookup after the ttl, got %d lookups", *lookups)
+	}
+}
+
+type failingAddrDialer struct {
+	addrs []string
+}
+
+func (d *failingAddrDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	d.addrs = append(d.addrs, addr)
+	return nil, errors.New("unreachable")
+}
+
+func TestDialForgetsCachedAddresses(t *testing.T) {
+	fakeLookup(t)
+	defer forgetIP("failover.example.com")
+	p, err := msdsn.Parse("sqlserver://failover.example.com?dns+cache+ttl=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialer := &failingAddrDialer{}
+	c := NewConnectorConfig(p)
+	c.Dialer = dialer
+	for i := 0; i < 2; i++ {
+		if _, err = (tcpDialer{}).DialSqlConnection(context.Background(), c, &p); err == nil {
+			t.Fatal("expected a dial error")
+		}
+	}
+	expected := []string{"10.0.0.1:1433", "10.0.0.2:1433"}
+	if !equalStrings(dialer.addrs, expected) {
+		t.Errorf("expected the host to be resolved again after a failure, dialed %v", dialer.addrs)
+	}
+}
+
+func TestSortIPs(t *testing.T) {
+	v4a, v4b, v6 := net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), net.ParseIP("fd00::1")
+	ips := []net.IP{v6, v4a, v4b}
+	tests := []struct {
+		preference string
+		expected   []net.IP
+	}{
+		{msdsn.IPPreferencePlatformDefault, []net.IP{v6, v4a, v4b}},
+		{msdsn.IPPreferenceIPv4First, []net.IP{v4a, v4b, v6}},
+		{msdsn.IPPreferenceIPv6First, []net.IP{v6, v4a, v4b}},
+	}
+	for _, test := range tests {
+		got := sortIPs(ips, test.preference)
+		for i := range got {
+			if !got[i].Equal(test.expected[i]) {
+				t.Errorf("%s: expected %v, got %v", test.preference, test.expected, got)
+				break
+			}
+		}
+	}
+}
+
+// familyDialer hangs when dialing the addresses of one family until the context is done,
+// or fails them at once when refuse is set.
+type familyDialer struct {
+	mu     sync.Mutex
+	dials  map[string]time.Time
+	hang   func(ip net.IP) bool
+	refuse bool
+}
+
+func (d *familyDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	d.dials[addr] = time.Now()
+	d.mu.Unlock()
+	host, _, _ := net.SplitHostPort(addr)
+	if d.hang(net.ParseIP(host)) {
+		if d.refuse {
+			return nil, errors.New("connection refused")
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func TestDialIPPreference(t *testing.T) {
+	old := lookupIPAddr
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("fd00::1")}, {IP: net.IPv4(10, 0, 0, 1)}}, nil
+	}
+	defer func() { lookupIPAddr = old }()
+
+	dial := func(preference string, hang func(ip net.IP) bool, refuse bool) map[string]time.Time {
+		p, err := msdsn.Parse("sqlserver://dualstack.example.com?ippreference=" + preference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dialer := &familyDialer{dials: map[string]time.Time{}, hang: hang, refuse: refuse}
+		c := NewConnectorConfig(p)
+		c.Dialer = dialer
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := (tcpDialer{}).DialSqlConnection(ctx, c, &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		time.Sleep(2 * fallbackDelay)
+		dialer.mu.Lock()
+		defer dialer.mu.Unlock()
+		dials := map[string]time.Time{}
+		for k, v := range dialer.dials {
+			dials[k] = v
+		}
+		return dials
+	}
+
+	// the preferred family connects, the other family is not dialed
+	dials := dial(msdsn.IPPreferenceIPv4First, func(ip net.IP) bool { return false }, false)
+	if _, ok := dials["[fd00::1]:1433"]; ok || len(dials) != 1 {
+		t.Errorf("expected only the IPv4 address to be dialed, got %v", dials)
+	}
+
+	// the preferred family is unroutable, the other family is dialed after the fallback delay
+	dials = dial(msdsn.IPPreferenceIPv4First, func(ip net.IP) bool { return ip.To4() != nil }, false)
+	v4, v6 := dials["10.0.0.1:1433"], dials["[fd00::1]:1433"]
+	if v4.IsZero() || v6.IsZero() || v6.Sub(v4) < fallbackDelay-10*time.Millisecond {
+		t.Errorf("expected the IPv6 address to be dialed after the fallback delay, got %v", dials)
+	}
+
+	// the preferred family refuses the connection, the other family is dialed at once
+	dials = dial(msdsn.IPPreferenceIPv4First, func(ip net.IP) bool { return ip.To4() != nil }, true)
+	v4, v6 = dials["10.0.0.1:1433"], dials["[fd00::1]:1433"]
+	if v4.IsZero() || v6.IsZero() || v6.Sub(v4) >= fallbackDelay/2 {
+		t.Errorf("expected the IPv6 address to be dialed once the IPv4 address failed, got %v", dials)
+	}
+}
+
+func TestAddressDialContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	addrCtx, addrCancel := addressDialContext(ctx, 2)
+	addrDeadline, ok := addrCtx.Deadline()
+	addrCancel()
+	if left := time.Until(addrDeadline); !ok || left > 5*time.Second || left < 4*time.Second {
+		t.Errorf("expected half of the time for the first of two addresses, got %v", left)
+	}
+	addrCtx, addrCancel = addressDialContext(ctx, 1)
+	addrDeadline, _ = addrCtx.Deadline()
+	addrCancel()
+	if !addrDeadline.Equal(deadline) {
+		t.Errorf("expected the last address to get the time left, got %v instead of %v", addrDeadline, deadline)
+	}
+	addrCtx, addrCancel = addressDialContext(ctx, 100)
+	addrDeadline, _ = addrCtx.Deadline()
+	addrCancel()
+	if left := time.Until(addrDeadline); left < minDialTimeout-time.Second {
+		t.Errorf("expected at least the minimum dial timeout, got %v", left)
+	}
+	addrCtx, addrCancel = addressDialContext(context.Background(), 3)
+	_, ok = addrCtx.Deadline()
+	addrCancel()
+	if ok {
+		t.Error("expected no deadline without a deadline of the dial")
+	}
+}
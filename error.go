@@ -2,6 +2,7 @@ package mssql
 
 import (
 	"database/sql/driver"
+	"errors"
 	"fmt"
 )
 
@@ -21,6 +22,9 @@ type Error struct {
 	// All lists all errors that were received from first to last.
 	// This includes the last one, which is described in the other members.
 	All []Error
+	// DeadlockGraph is the deadlock XML of a deadlock error when
+	// Connector.DeadlockGraphTimeout is set and the graph was found.
+	DeadlockGraph string
 }
 
 func (e Error) Error() string {
@@ -60,6 +64,52 @@ func (e Error) SQLErrorLineNo() int32 {
 	return e.LineNo
 }
 
+// Severity levels of SQL Server errors, stored in Error.Class.
+// https://learn.microsoft.com/sql/relational-databases/errors-events/database-engine-error-severities
+const (
+	// Messages with a severity up to SeverityInformational are
+	// informational, the server sends them as INFO tokens.
+	SeverityInformational = 10
+	// Errors with a severity of SeverityFatal or higher terminate
+	// the connection, it cannot be used anymore.
+	SeverityFatal = 20
+)
+
+// IsInformational reports whether the message is informational,
+// like PRINT output, instead of an error.
+func (e Error) IsInformational() bool {
+	return e.Class <= SeverityInformational
+}
+
+// IsFatal reports whether the error terminated the connection.
+func (e Error) IsFatal() bool {
+	return e.Class >= SeverityFatal
+}
+
+// Error numbers of errors that report that the transaction was rolled back
+// or cannot be committed anymore.
+const (
+	errTranCountMismatch     = 266
+	errUncommittableTran     = 3930
+	errNoBeginCommit         = 3902
+	errNoBeginRollback       = 3903
+	errUncommittableBatchEnd = 3998
+)
+
+// IsTransactionAborted reports whether the error reports that the transaction
+// was rolled back by the server or is doomed and can only be rolled back.
+func (e Error) IsTransactionAborted() bool {
+	switch e.Number {
+	case errTranCountMismatch, errUncommittableTran, errNoBeginCommit, errNoBeginRollback, errUncommittableBatchEnd:
+		return true
+	}
+	return false
+}
+
+// ErrTransactionRolledBack is returned by Commit when the server already rolled
+// back the transaction, e.g. after an error in a session with XACT_ABORT ON.
+var ErrTransactionRolledBack = errors.New("mssql: the transaction was rolled back by the server")
+
 type StreamError struct {
 	InnerError error
 }
@@ -85,3 +85,37 @@ func TestBadStreamPanicf(t *testing.T) {
 
 	t.Fatalf("badStreamPanicf did not panic as expected when passed %s", expectedMsg)
 }
+
+func TestErrorSeverity(t *testing.T) {
+	tests := []struct {
+		class         uint8
+		informational bool
+		fatal         bool
+	}{
+		{0, true, false},
+		{10, true, false},
+		{11, false, false},
+		{16, false, false},
+		{19, false, false},
+		{20, false, true},
+		{25, false, true},
+	}
+	for _, tt := range tests {
+		err := Error{Class: tt.class}
+		if err.IsInformational() != tt.informational || err.IsFatal() != tt.fatal {
+			t.Errorf("severity %d: IsInformational() = %v, IsFatal() = %v, wanted %v, %v",
+				tt.class, err.IsInformational(), err.IsFatal(), tt.informational, tt.fatal)
+		}
+	}
+}
+
+func TestIsTransactionAborted(t *testing.T) {
+	for _, number := range []int32{266, 3902, 3903, 3930, 3998} {
+		if !(Error{Number: number}).IsTransactionAborted() {
+			t.Errorf("expected error %d to abort the transaction", number)
+		}
+	}
+	if (Error{Number: 2627}).IsTransactionAborted() {
+		t.Error("expected a constraint violation not to abort the transaction")
+	}
+}
@@ -0,0 +1,106 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// EventType is the type of an Event.
+type EventType int
+
+const (
+	// EventConnected is sent after a successful login,
+	// Duration is the time it took to dial and log in.
+	EventConnected EventType = iota + 1
+	// EventConnectFailed is sent when dialing or the login failed.
+	EventConnectFailed
+	// EventFailover is sent before connecting to the failover partner
+	// after the connection to the server failed.
+	EventFailover
+	// EventRedirect is sent when the server routed the login to another
+	// server, like a read-only replica of an availability group.
+	EventRedirect
+	// EventDisconnected is sent when a connection is closed.
+	EventDisconnected
+	// EventRetry is sent when a request failed on a bad connection and
+	// database/sql may retry it on another connection.
+	EventRetry
+	// EventWarning is sent for unexpected but recoverable behavior of the
+	// server or the connection string.
+	EventWarning
+)
+
+var eventTypeNames = map[EventType]string{
+	EventConnected:     "connected",
+	EventConnectFailed: "connect failed",
+	EventFailover:      "failover",
+	EventRedirect:      "redirect",
+	EventDisconnected:  "disconnected",
+	EventRetry:         "retry",
+	EventWarning:       "warning",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("event %d", int(t))
+}
+
+// Event is a structured event of the life of a connection.
+type Event struct {
+	Type EventType
+	// Server is the server of the connection, as host, host\instance or host:port.
+	Server string
+	// SPID is the server process id of the session, 0 before the login.
+	SPID int
+	// Duration is the time the operation took, it is set for EventConnected
+	// and EventConnectFailed.
+	Duration time.Duration
+	// Err is the error of EventConnectFailed and EventRetry.
+	Err error
+	// Message describes the event.
+	Message string
+}
+
+// EventLogger receives the events of the connections of a Connector.
+// LogEvent is called on the goroutine that uses the connection,
+// it should return quickly and must not use the connection.
+type EventLogger interface {
+	LogEvent(ctx context.Context, e Event)
+}
+
+// logEvent sends e to the EventLogger of the Connector, if any.
+func (c *Connector) logEvent(ctx context.Context, e Event) {
+	if c != nil && c.EventLogger != nil {
+		c.EventLogger.LogEvent(ctx, e)
+	}
+}
+
+// logEvent sends e with the server and SPID of the session to the
+// EventLogger of the Connector, if any.
+func (c *Conn) logEvent(ctx context.Context, e Event) {
+	if c.connector == nil || c.connector.EventLogger == nil {
+		return
+	}
+	e.Server = c.sess.server
+	if c.sess.buf != nil {
+		e.SPID = int(c.sess.buf.rSpid)
+	}
+	c.connector.EventLogger.LogEvent(ctx, e)
+}
+
+// serverName returns the server of p for events.
+func serverName(p msdsn.Config) string {
+	name := p.Host
+	if len(p.Instance) > 0 {
+		name += "\\" + p.Instance
+	}
+	if p.Port != 0 {
+		name += fmt.Sprintf(":%d", p.Port)
+	}
+	return name
+}
@@ -0,0 +1,85 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) LogEvent(ctx context.Context, e Event) {
+	r.events = append(r.events, e)
+}
+
+type failDialer struct{}
+
+func (failDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return nil, errors.New("dial failed")
+}
+
+func TestConnectEvents(t *testing.T) {
+	params, err := msdsn.Parse("sqlserver://primary:1433?failoverpartner=secondary&failoverport=1434&protocol=tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &eventRecorder{}
+	c := newConnector(params, driverInstanceNoProcess)
+	c.Dialer = failDialer{}
+	c.EventLogger = rec
+	if _, err = driverInstanceNoProcess.connect(context.Background(), c, params); err == nil {
+		t.Fatal("expected the connection to fail")
+	}
+
+	expected := []struct {
+		typ    EventType
+		server string
+	}{
+		{EventConnectFailed, "primary:1433"},
+		{EventFailover, "secondary:1434"},
+		{EventConnectFailed, "secondary:1434"},
+	}
+	if len(rec.events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), rec.events)
+	}
+	for i, e := range expected {
+		got := rec.events[i]
+		if got.Type != e.typ || got.Server != e.server {
+			t.Errorf("expected %s event for %s, got %s for %s", e.typ, e.server, got.Type, got.Server)
+		}
+		if got.Type == EventConnectFailed && got.Err == nil {
+			t.Errorf("expected the error in the %s event", got.Type)
+		}
+	}
+}
+
+func TestSessionEvents(t *testing.T) {
+	rec := &eventRecorder{}
+	c := &Connector{EventLogger: rec}
+	// an ENVCHANGE of an unknown type
+	b := makeEnvChange(100, "x", "")
+	sess := &tdsSession{
+		buf:       &tdsBuffer{packetSize: len(b), rbuf: b, rsize: len(b)},
+		connector: c,
+		server:    "localhost",
+	}
+	processEnvChg(context.Background(), sess)
+
+	sess.buf = makeBuf(defaultPacketSize, nil)
+	conn := &Conn{connector: c, sess: sess}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rec.events) != 2 || rec.events[0].Type != EventWarning || rec.events[1].Type != EventDisconnected {
+		t.Fatalf("expected a warning and a disconnected event, got %+v", rec.events)
+	}
+	if rec.events[1].Server != "localhost" {
+		t.Errorf("expected the server of the session, got %s", rec.events[1].Server)
+	}
+}
@@ -0,0 +1,105 @@
+package mssql
+
+import (
+	"context"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// showplanColumn is the name of the column of the result sets in which
+// the server sends the plans of SET STATISTICS XML.
+const showplanColumn = "Microsoft SQL Server 2005 XML Showplan"
+
+// QueryPlan is the result of ExplainAnalyze.
+type QueryPlan struct {
+	// Plans are the actual execution plans of the statements of the query
+	// in showplan XML, in the order they were executed.
+	Plans []string
+	// Rows is the number of rows the query returned.
+	Rows int64
+	// Duration is the time from sending the query until all results were read.
+	Duration time.Duration
+	// CPUTime and ElapsedTime are the CPU and elapsed time of the statements
+	// measured by the server, the sum of the QueryTimeStats of the plans.
+	// They are 0 for servers older than SQL Server 2016 SP1.
+	CPUTime     time.Duration
+	ElapsedTime time.Duration
+}
+
+// ExplainAnalyze executes query with args with SET STATISTICS XML ON and
+// returns the actual execution plans of its statements with the time it
+// took. The rows of the query are read and discarded, so use it for queries
+// whose effects are acceptable, e.g. in a transaction that is rolled back.
+//
+//	plan, err := mssql.ExplainAnalyze(ctx, db, "select * from orders where customer_id = @p1", 42)
+//
+// The query is sent in a single batch with the SET statements, q can be a
+// *sql.DB.
+func ExplainAnalyze(ctx context.Context, q Querier, query string, args ...interface{}) (*QueryPlan, error) {
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, "set statistics xml on; "+query+"\nset statistics xml off", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	plan := &QueryPlan{}
+	for {
+		cols, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		isPlan := len(cols) == 1 && cols[0] == showplanColumn
+		for rows.Next() {
+			if !isPlan {
+				plan.Rows++
+				continue
+			}
+			var showplan string
+			if err = rows.Scan(&showplan); err != nil {
+				return nil, err
+			}
+			plan.Plans = append(plan.Plans, showplan)
+			cpu, elapsed := queryTimeStats(showplan)
+			plan.CPUTime += cpu
+			plan.ElapsedTime += elapsed
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	plan.Duration = time.Since(start)
+	return plan, nil
+}
+
+// queryTimeStats returns the sum of the CpuTime and ElapsedTime attributes
+// of the QueryTimeStats elements of showplan, which are in milliseconds.
+func queryTimeStats(showplan string) (cpu, elapsed time.Duration) {
+	d := xml.NewDecoder(strings.NewReader(showplan))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return cpu, elapsed
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "QueryTimeStats" {
+			continue
+		}
+		for _, attr := range start.Attr {
+			ms, err := strconv.ParseInt(attr.Value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch attr.Name.Local {
+			case "CpuTime":
+				cpu += time.Duration(ms) * time.Millisecond
+			case "ElapsedTime":
+				elapsed += time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
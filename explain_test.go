@@ -0,0 +1,41 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestExplainAnalyze(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	showplan := `<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan"><BatchSequence><Batch><Statements>` +
+		`<StmtSimple><QueryPlan><QueryTimeStats CpuTime="3" ElapsedTime="12" /></QueryPlan></StmtSimple>` +
+		`</Statements></Batch></BatchSequence></ShowPlanXML>`
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if !strings.HasPrefix(query, "set statistics xml on; select id from orders") {
+			return mssqltest.Response{Err: &mssqltest.Error{Number: 208, Message: "unexpected query " + query}}
+		}
+		return mssqltest.Response{Results: []mssqltest.Result{
+			{Columns: []string{"id"}, Rows: [][]interface{}{{1}, {2}}},
+			{Columns: []string{showplanColumn}, Rows: [][]interface{}{{showplan}}},
+		}}
+	})
+	plan, err := ExplainAnalyze(context.Background(), db, "select id from orders where customer_id = @p1", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Rows != 2 || len(plan.Plans) != 1 || plan.Plans[0] != showplan {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+	if plan.CPUTime != 3*time.Millisecond || plan.ElapsedTime != 12*time.Millisecond {
+		t.Errorf("unexpected server times %v, %v", plan.CPUTime, plan.ElapsedTime)
+	}
+	if plan.Duration <= 0 {
+		t.Error("expected the duration of the query")
+	}
+}
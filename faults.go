@@ -0,0 +1,73 @@
+package mssql
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+)
+
+// FaultInjector injects faults into the connections of a Connector, to test
+// the retry and failover handling of an application deterministically.
+// Set it as Connector.FaultInjector in tests only. All hooks are optional.
+type FaultInjector struct {
+	// Dial is called before the driver dials server, which is host, host\instance
+	// or host:port. A returned error fails the connection attempt, the driver
+	// then tries the failover partner, if one is configured.
+	Dial func(ctx context.Context, server string) error
+
+	// Read is called before every read from the network. It may sleep to
+	// simulate a slow network. A returned error fails the read, like a
+	// connection reset by the peer, and the connection is marked bad.
+	Read func() error
+
+	// Write is called before every write to the network, like Read.
+	Write func() error
+
+	// Response is called when the first packet of a response arrived,
+	// including the responses during the login. When it returns an error,
+	// the response is discarded and the error is reported as if the server
+	// had sent it. Errors with a Class of SeverityFatal or higher mark the
+	// connection bad.
+	Response func() *Error
+}
+
+// faultConn calls the Read and Write hooks of a FaultInjector.
+type faultConn struct {
+	net.Conn
+	faults *FaultInjector
+}
+
+func (c faultConn) Read(b []byte) (int, error) {
+	if c.faults.Read != nil {
+		if err := c.faults.Read(); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Read(b)
+}
+
+func (c faultConn) Write(b []byte) (int, error) {
+	if c.faults.Write != nil {
+		if err := c.faults.Write(); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Write(b)
+}
+
+// injectResponseFault returns the error of the Response hook, after it discarded
+// the rest of the response.
+func (sess *tdsSession) injectResponseFault() *Error {
+	if sess.connector == nil || sess.connector.FaultInjector == nil || sess.connector.FaultInjector.Response == nil {
+		return nil
+	}
+	injected := sess.connector.FaultInjector.Response()
+	if injected == nil {
+		return nil
+	}
+	if _, err := io.Copy(ioutil.Discard, sess.buf); err != nil {
+		badStreamPanic(err)
+	}
+	return injected
+}
@@ -0,0 +1,97 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func newFaultTestDB(t *testing.T, dsn string, faults *FaultInjector) (*sql.DB, *mssqltest.Server) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle("select 1", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{1}}}}})
+	config, err := msdsn.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := NewConnectorConfig(config)
+	connector.Dialer = srv
+	connector.FaultInjector = faults
+	return sql.OpenDB(connector), srv
+}
+
+func TestFaultInjectorDial(t *testing.T) {
+	var servers []string
+	faults := &FaultInjector{
+		Dial: func(ctx context.Context, server string) error {
+			servers = append(servers, server)
+			if server == "primary" {
+				return errors.New("injected dial failure")
+			}
+			return nil
+		},
+	}
+	db, srv := newFaultTestDB(t, "server=primary;failoverpartner=secondary;encrypt=disable", faults)
+	defer srv.Close()
+	defer db.Close()
+
+	var v int
+	if err := db.QueryRow("select 1").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 2 || servers[0] != "primary" || servers[1] != "secondary" {
+		t.Errorf("expected to dial the primary and then the failover partner, got %v", servers)
+	}
+}
+
+func TestFaultInjectorResponseAndRead(t *testing.T) {
+	var injectError, resetReads int32
+	faults := &FaultInjector{
+		Response: func() *Error {
+			if atomic.CompareAndSwapInt32(&injectError, 1, 0) {
+				return &Error{Number: 4060, Class: SeverityFatal, Message: "injected fatal error"}
+			}
+			return nil
+		},
+		Read: func() error {
+			if atomic.CompareAndSwapInt32(&resetReads, 1, 0) {
+				return syscall.ECONNRESET
+			}
+			return nil
+		},
+	}
+	db, srv := newFaultTestDB(t, "server=localhost;encrypt=disable", faults)
+	defer srv.Close()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var v int
+	if err := db.QueryRow("select 1").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&injectError, 1)
+	var sqlErr Error
+	if err := db.QueryRow("select 1").Scan(&v); !errors.As(err, &sqlErr) || sqlErr.Number != 4060 {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	atomic.StoreInt32(&resetReads, 1)
+	if err := db.QueryRow("select 1").Scan(&v); err == nil {
+		t.Fatal("expected the injected read failure")
+	}
+	// the broken connections were replaced
+	if err := db.QueryRow("select 1").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if stats := db.Stats(); stats.OpenConnections != 1 {
+		t.Errorf("expected 1 open connection, got %d", stats.OpenConnections)
+	}
+}
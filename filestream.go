@@ -0,0 +1,206 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// defaultFileStreamChunkSize is the size of the chunks ReadTo and WriteFrom
+// transfer with a statement.
+const defaultFileStreamChunkSize = 1 << 20
+
+// ErrFileStreamNotSupported is returned by FileStream.OpenFile on platforms
+// without the streaming API of FILESTREAM, use the T-SQL fallback of
+// FileStreamColumn.ReadTo and WriteFrom there.
+var ErrFileStreamNotSupported = errors.New("mssql: the FILESTREAM streaming API is only available on Windows")
+
+// FileStreamAccess is the access to a FILESTREAM value opened with FileStream.OpenFile.
+type FileStreamAccess int
+
+// Access modes of FileStream.OpenFile, SQL_FILESTREAM_DESIRED_ACCESS of OpenSqlFilestream.
+const (
+	FileStreamRead      FileStreamAccess = 0
+	FileStreamWrite     FileStreamAccess = 1
+	FileStreamReadWrite FileStreamAccess = 2
+)
+
+// FileStream is the logical path of a FILESTREAM value, PathName(), and the
+// transaction context of the transaction it was read in,
+// GET_FILESTREAM_TRANSACTION_CONTEXT(), which OpenFile needs to open the
+// value with the streaming API of the server.
+type FileStream struct {
+	Path               string
+	TransactionContext []byte
+}
+
+// FileStreamColumn identifies the value of a varbinary(max) FILESTREAM
+// column, or the file_stream column of a FileTable, in the row whose
+// KeyColumn equals Key:
+//
+//	col := mssql.FileStreamColumn{Schema: "dbo", Table: "documents", Column: "content", KeyColumn: "id", Key: id}
+//	tx, err := db.BeginTx(ctx, nil)
+//	...
+//	fs, err := col.Open(ctx, tx)
+//	...
+//	f, err := fs.OpenFile(mssql.FileStreamRead)
+//	if errors.Is(err, mssql.ErrFileStreamNotSupported) {
+//		_, err = col.ReadTo(ctx, tx, w, 0)
+//	} else if err == nil {
+//		_, err = io.Copy(w, f)
+//		f.Close()
+//	}
+//	...
+//	err = tx.Commit()
+type FileStreamColumn struct {
+	// Schema, Table and Column are the names of the column, an empty Schema
+	// uses the default schema of the user.
+	Schema string
+	Table  string
+	Column string
+	// KeyColumn and Key select the row.
+	KeyColumn string
+	Key       interface{}
+}
+
+// names returns the quoted names of the table, the column and the key column.
+func (c FileStreamColumn) names() (table, column, key string, err error) {
+	if table, err = QuoteSchemaObject(c.Schema, c.Table); err != nil {
+		return
+	}
+	if column, err = QuoteIdentifier(c.Column); err != nil {
+		return
+	}
+	key, err = QuoteIdentifier(c.KeyColumn)
+	return
+}
+
+// Open returns the path and transaction context of the value in tx, which
+// must be a transaction. The value must not be NULL, set it to 0x to create
+// an empty file first. A file opened with FileStream.OpenFile must be closed
+// before tx is committed or rolled back.
+func (c FileStreamColumn) Open(ctx context.Context, tx Querier) (*FileStream, error) {
+	table, column, key, err := c.names()
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("select %s.PathName(), get_filestream_transaction_context() from %s where %s = @p1", column, table, key)
+	rows, err := tx.QueryContext(ctx, query, c.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		return nil, err
+	}
+	var path sql.NullString
+	var txContext []byte
+	if err = rows.Scan(&path, &txContext); err != nil {
+		return nil, err
+	}
+	if !path.Valid {
+		return nil, fmt.Errorf("mssql: the FILESTREAM value of %s is NULL", c.Column)
+	}
+	if len(txContext) == 0 {
+		return nil, errors.New("mssql: a FILESTREAM value can only be opened in a transaction")
+	}
+	return &FileStream{Path: path.String, TransactionContext: txContext}, rows.Close()
+}
+
+// ReadTo copies the value to w in chunks of chunkSize bytes, 1 MiB when
+// chunkSize is 0, with a statement for every chunk, a fallback for
+// platforms without the streaming API. It returns the number of bytes
+// copied. A NULL value copies nothing. Run it in a snapshot or repeatable
+// read transaction to read the chunks of a single version of the value.
+func (c FileStreamColumn) ReadTo(ctx context.Context, q Querier, w io.Writer, chunkSize int) (int64, error) {
+	table, column, key, err := c.names()
+	if err != nil {
+		return 0, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultFileStreamChunkSize
+	}
+	query := fmt.Sprintf("select substring(%s, @p2, @p3) from %s where %s = @p1", column, table, key)
+	var n int64
+	for {
+		chunk, err := c.readChunk(ctx, q, query, n+1, chunkSize)
+		if err != nil {
+			return n, err
+		}
+		written, err := w.Write(chunk)
+		n += int64(written)
+		if err != nil || len(chunk) < chunkSize {
+			return n, err
+		}
+	}
+}
+
+// readChunk reads size bytes of the value at the offset counting from 1.
+func (c FileStreamColumn) readChunk(ctx context.Context, q Querier, query string, offset int64, size int) ([]byte, error) {
+	rows, err := q.QueryContext(ctx, query, c.Key, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		return nil, err
+	}
+	var chunk []byte
+	if err = rows.Scan(&chunk); err != nil {
+		return nil, err
+	}
+	return chunk, rows.Close()
+}
+
+// WriteFrom replaces the value with the content of r in chunks of chunkSize
+// bytes, 1 MiB when chunkSize is 0, with a statement for every chunk, a
+// fallback for platforms without the streaming API. The first chunk replaces
+// the value, the others are appended with UPDATE .WRITE, so e should be a
+// transaction for the value not to be left partly written. It returns the
+// number of bytes written.
+func (c FileStreamColumn) WriteFrom(ctx context.Context, e Execer, r io.Reader, chunkSize int) (int64, error) {
+	table, column, key, err := c.names()
+	if err != nil {
+		return 0, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultFileStreamChunkSize
+	}
+	set := fmt.Sprintf("update %s set %s = @p2 where %s = @p1", table, column, key)
+	appendChunk := fmt.Sprintf("update %s set %s.write(@p2, null, null) where %s = @p1", table, column, key)
+	buf := make([]byte, chunkSize)
+	var n int64
+	for {
+		read, err := io.ReadFull(r, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return n, err
+		}
+		if read == 0 && n > 0 {
+			return n, nil
+		}
+		query := appendChunk
+		if n == 0 {
+			query = set
+		}
+		res, err := e.ExecContext(ctx, query, c.Key, buf[:read])
+		if err != nil {
+			return n, err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return n, sql.ErrNoRows
+		}
+		n += int64(read)
+		if last {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package mssql
+
+import "os"
+
+// OpenFile returns ErrFileStreamNotSupported, the streaming API of
+// FILESTREAM is only available on Windows. Use FileStreamColumn.ReadTo and
+// WriteFrom instead.
+func (f *FileStream) OpenFile(access FileStreamAccess) (*os.File, error) {
+	return nil, ErrFileStreamNotSupported
+}
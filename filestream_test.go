@@ -0,0 +1,92 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestFileStreamOpen(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	var txContext interface{}
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		want := "select [content].PathName(), get_filestream_transaction_context() from [dbo].[documents] where [id] = @p1"
+		if query != want {
+			return mssqltest.Response{Err: &mssqltest.Error{Number: 208, Message: "unexpected query " + query}}
+		}
+		return mssqltest.Response{Results: []mssqltest.Result{{
+			Columns: []string{"path", "context"},
+			Rows:    [][]interface{}{{`\\srv\fs\documents\content\42`, txContext}},
+		}}}
+	})
+	col := FileStreamColumn{Schema: "dbo", Table: "documents", Column: "content", KeyColumn: "id", Key: 42}
+	ctx := context.Background()
+	if _, err := col.Open(ctx, db); err == nil || !strings.Contains(err.Error(), "transaction") {
+		t.Errorf("expected an error outside of a transaction, got %v", err)
+	}
+	txContext = []byte{1, 2, 3}
+	fs, err := col.Open(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.Path != `\\srv\fs\documents\content\42` || !bytes.Equal(fs.TransactionContext, []byte{1, 2, 3}) {
+		t.Errorf("unexpected %+v", fs)
+	}
+	if runtime.GOOS != "windows" {
+		if _, err = fs.OpenFile(FileStreamRead); !errors.Is(err, ErrFileStreamNotSupported) {
+			t.Errorf("expected ErrFileStreamNotSupported, got %v", err)
+		}
+	}
+}
+
+func TestFileStreamReadWrite(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	chunks := [][]byte{[]byte("abcd"), []byte("efgh"), []byte("ij")}
+	var queries []string
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		queries = append(queries, query)
+		if strings.HasPrefix(query, "select substring") {
+			chunk := chunks[len(queries)-1]
+			return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{chunk}}}}}
+		}
+		return mssqltest.Response{Results: []mssqltest.Result{{RowsAffected: 1}}}
+	})
+	col := FileStreamColumn{Table: "documents", Column: "content", KeyColumn: "id", Key: 42}
+	ctx := context.Background()
+	var buf bytes.Buffer
+	n, err := col.ReadTo(ctx, db, &buf, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 || buf.String() != "abcdefghij" || len(queries) != 3 {
+		t.Errorf("expected 10 bytes in 3 chunks, got %d %q in %d", n, buf.String(), len(queries))
+	}
+
+	queries = nil
+	n, err = col.WriteFrom(ctx, db, strings.NewReader("abcdefgh"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"update [documents] set [content] = @p2 where [id] = @p1",
+		"update [documents] set [content].write(@p2, null, null) where [id] = @p1",
+	}
+	if n != 8 || strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected 8 bytes written by\n%s\ngot %d by\n%s", strings.Join(want, "\n"), n, strings.Join(queries, "\n"))
+	}
+
+	// an empty reader sets an empty value
+	queries = nil
+	if n, err = col.WriteFrom(ctx, db, strings.NewReader(""), 4); err != nil || n != 0 || len(queries) != 1 {
+		t.Errorf("expected a single update, got %d, %v, %q", n, err, queries)
+	}
+}
@@ -0,0 +1,69 @@
+package mssql
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// fileStreamDLLs are the client libraries that export OpenSqlFilestream,
+// the OLE DB Driver for SQL Server and the older SQL Server Native Client.
+var fileStreamDLLs = []string{"msoledbsql.dll", "sqlncli11.dll"}
+
+var (
+	openSqlFilestreamOnce sync.Once
+	openSqlFilestream     *syscall.Proc
+	openSqlFilestreamErr  error
+)
+
+// loadOpenSqlFilestream finds OpenSqlFilestream in the first client library
+// that is installed.
+func loadOpenSqlFilestream() (*syscall.Proc, error) {
+	openSqlFilestreamOnce.Do(func() {
+		for _, name := range fileStreamDLLs {
+			dll, err := syscall.LoadDLL(name)
+			if err != nil {
+				continue
+			}
+			if openSqlFilestream, err = dll.FindProc("OpenSqlFilestream"); err == nil {
+				return
+			}
+		}
+		openSqlFilestreamErr = fmt.Errorf("%w: OpenSqlFilestream needs the Microsoft OLE DB Driver for SQL Server", ErrFileStreamNotSupported)
+	})
+	return openSqlFilestream, openSqlFilestreamErr
+}
+
+// OpenFile opens the value with OpenSqlFilestream of the Microsoft OLE DB
+// Driver for SQL Server, which streams it over SMB instead of TDS. The file
+// must be closed before the transaction of the FileStream ends. It returns
+// an error wrapping ErrFileStreamNotSupported when the driver is not
+// installed.
+func (f *FileStream) OpenFile(access FileStreamAccess) (*os.File, error) {
+	proc, err := loadOpenSqlFilestream()
+	if err != nil {
+		return nil, err
+	}
+	if len(f.TransactionContext) == 0 {
+		return nil, errors.New("mssql: the FILESTREAM transaction context is empty")
+	}
+	path, err := syscall.UTF16PtrFromString(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	h, _, callErr := proc.Call(
+		uintptr(unsafe.Pointer(path)),
+		uintptr(access),
+		0,
+		uintptr(unsafe.Pointer(&f.TransactionContext[0])),
+		uintptr(len(f.TransactionContext)),
+		0,
+	)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return nil, &os.PathError{Op: "OpenSqlFilestream", Path: f.Path, Err: callErr}
+	}
+	return os.NewFile(h, f.Path), nil
+}
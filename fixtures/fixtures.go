@@ -0,0 +1,372 @@
+// Package fixtures loads rows into the tables of a SQL Server database,
+// so that integration tests start from a repeatable state.
+//
+//	var f fixtures.Fixtures
+//	f.Add("dbo.customers", []Customer{{ID: 1, Name: "alice"}})
+//	f.Add("dbo.orders", []map[string]interface{}{{"id": 10, "customer_id": 1}})
+//	if err := f.Load(ctx, db); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Load empties the tables of the fixtures and inserts their rows in a
+// single transaction. The tables are emptied in the reverse and filled in
+// the order of their foreign keys, so the rows of a referenced table are
+// inserted before the rows that reference them. Tables that are referenced
+// by a foreign key are emptied with DELETE and their identity is reseeded,
+// the other tables are truncated. Rows can set the identity column of a
+// table, IDENTITY_INSERT is set on for the table while its rows are inserted.
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/internal/jsonfields"
+	"github.com/microsoft/go-mssqldb/schema"
+)
+
+// Fixtures are the rows of tables.
+type Fixtures struct {
+	tables []table
+}
+
+type table struct {
+	name string
+	rows []row
+}
+
+// row is a row of a table, its columns are in insert order.
+type row struct {
+	columns []string
+	values  []interface{}
+}
+
+// Add adds rows to the fixtures of the table name, e.g. "dbo.customers"
+// or "customers" in the dbo schema. rows is a slice of structs, of pointers
+// to structs or of map[string]interface{}. The columns of a struct are its
+// fields, named like the keys of the field in encoding/json or by their db
+// tag. Fields with the json or db tag "-" and unexported fields are not
+// inserted, fields with the json option of the db tag, as in
+// db:"payload,json", are inserted as their JSON.
+func (f *Fixtures) Add(name string, rows interface{}) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("fixtures: expected a slice of rows for %s, got %T", name, rows)
+	}
+	t := f.table(name)
+	for i := 0; i < v.Len(); i++ {
+		r, err := makeRow(v.Index(i))
+		if err != nil {
+			return fmt.Errorf("fixtures: row %d of %s: %v", i+1, name, err)
+		}
+		t.rows = append(t.rows, r)
+	}
+	return nil
+}
+
+// AddJSON adds the rows of a JSON object with a property per table,
+// whose value is an array of row objects:
+//
+//	{"dbo.customers": [{"id": 1, "name": "alice"}], "dbo.orders": [{"id": 10, "customer_id": 1}]}
+//
+// Numbers are inserted as integers when they have no fraction, objects and
+// arrays as their JSON text.
+func (f *Fixtures) AddJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var doc map[string][]map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("fixtures: %v", err)
+	}
+	names := make([]string, 0, len(doc))
+	for name := range doc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rows := doc[name]
+		for _, r := range rows {
+			for column, value := range r {
+				converted, err := jsonValue(value)
+				if err != nil {
+					return fmt.Errorf("fixtures: column %s of %s: %v", column, name, err)
+				}
+				r[column] = converted
+			}
+		}
+		if err := f.Add(name, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	return v, nil
+}
+
+func (f *Fixtures) table(name string) *table {
+	for i := range f.tables {
+		if f.tables[i].name == name {
+			return &f.tables[i]
+		}
+	}
+	f.tables = append(f.tables, table{name: name})
+	return &f.tables[len(f.tables)-1]
+}
+
+func makeRow(v reflect.Value) (row, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return row{}, fmt.Errorf("nil row")
+		}
+		v = v.Elem()
+	}
+	var r row
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return row{}, fmt.Errorf("expected string keys, got %s", v.Type())
+		}
+		for _, key := range v.MapKeys() {
+			r.columns = append(r.columns, key.String())
+		}
+		// maps have no order, the columns are inserted in the order of their names
+		sort.Strings(r.columns)
+		for _, column := range r.columns {
+			r.values = append(r.values, v.MapIndex(reflect.ValueOf(column).Convert(v.Type().Key())).Interface())
+		}
+	case reflect.Struct:
+		structRow(v, &r)
+	default:
+		return row{}, fmt.Errorf("expected a struct or a map, got %s", v.Type())
+	}
+	if len(r.columns) == 0 {
+		return row{}, fmt.Errorf("no columns")
+	}
+	return r, nil
+}
+
+func structRow(v reflect.Value, r *row) {
+	for _, field := range jsonfields.Fields(v.Type(), nil) {
+		r.columns = append(r.columns, field.Name)
+		value := v.FieldByIndex(field.Index).Interface()
+		if field.JSON {
+			value = mssql.JSON{V: value}
+		}
+		r.values = append(r.values, value)
+	}
+}
+
+// Load empties the tables of the fixtures and inserts their rows.
+func (f *Fixtures) Load(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	tables, err := schema.Tables(ctx, conn)
+	if err != nil {
+		return err
+	}
+	ordered, err := f.order(tables)
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	referenced := make(map[string]bool)
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			referenced[key(fk.ReferencedSchema, fk.ReferencedTable)] = true
+		}
+	}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if err = empty(ctx, tx, ordered[i].table, referenced); err != nil {
+			return err
+		}
+	}
+	for _, o := range ordered {
+		if err = insert(ctx, tx, o.table, o.rows); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type loadTable struct {
+	table *schema.Table
+	rows  []row
+}
+
+// order returns the tables of the fixtures with referenced tables first.
+func (f *Fixtures) order(tables []schema.Table) ([]loadTable, error) {
+	byKey := make(map[string]*schema.Table, len(tables))
+	for i := range tables {
+		byKey[key(tables[i].Schema, tables[i].Name)] = &tables[i]
+	}
+	pending := make(map[string]loadTable, len(f.tables))
+	var keys []string
+	for _, t := range f.tables {
+		k := tableKey(t.name)
+		st := byKey[k]
+		if st == nil {
+			return nil, fmt.Errorf("fixtures: table %s not found", t.name)
+		}
+		if lt, ok := pending[k]; ok {
+			// the same table with a different name
+			lt.rows = append(lt.rows, t.rows...)
+			pending[k] = lt
+			continue
+		}
+		pending[k] = loadTable{table: st, rows: t.rows}
+		keys = append(keys, k)
+	}
+	var res []loadTable
+	for len(pending) > 0 {
+		progress := false
+		for _, k := range keys {
+			t, ok := pending[k]
+			if !ok {
+				continue
+			}
+			ready := true
+			for _, fk := range t.table.ForeignKeys {
+				ref := key(fk.ReferencedSchema, fk.ReferencedTable)
+				if _, waiting := pending[ref]; waiting && ref != k {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				res = append(res, t)
+				delete(pending, k)
+				progress = true
+			}
+		}
+		if !progress {
+			var cycle []string
+			for _, k := range keys {
+				if _, ok := pending[k]; ok {
+					cycle = append(cycle, k)
+				}
+			}
+			return nil, fmt.Errorf("fixtures: the foreign keys of %s form a cycle", strings.Join(cycle, ", "))
+		}
+	}
+	return res, nil
+}
+
+func key(schemaName, name string) string {
+	return strings.ToLower(schemaName + "." + name)
+}
+
+// tableKey returns the key of a table name of the fixtures.
+func tableKey(name string) string {
+	name = strings.NewReplacer("[", "", "]", "").Replace(name)
+	if !strings.Contains(name, ".") {
+		name = "dbo." + name
+	}
+	return strings.ToLower(name)
+}
+
+func quotedName(t *schema.Table) string {
+	return quote(t.Schema) + "." + quote(t.Name)
+}
+
+func quote(name string) string {
+	return mssql.TSQLQuoter{}.ID(name)
+}
+
+func empty(ctx context.Context, tx *sql.Tx, t *schema.Table, referenced map[string]bool) error {
+	name := quotedName(t)
+	if !referenced[key(t.Schema, t.Name)] {
+		_, err := tx.ExecContext(ctx, "truncate table "+name)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "delete from "+name); err != nil {
+		return err
+	}
+	if !hasIdentity(t) {
+		return nil
+	}
+	// the identity of a table without rows since it was created already
+	// starts at the seed, reseeding it would skip the seed
+	literal := "N" + mssql.TSQLQuoter{}.Value(name)
+	_, err := tx.ExecContext(ctx, "if exists (select * from sys.identity_columns where object_id = object_id("+literal+") and last_value is not null)\n"+
+		"begin\n"+
+		"declare @reseed bigint = ident_seed("+literal+") - ident_incr("+literal+")\n"+
+		"dbcc checkident ("+literal+", reseed, @reseed) with no_infomsgs\n"+
+		"end")
+	return err
+}
+
+func hasIdentity(t *schema.Table) bool {
+	for _, c := range t.Columns {
+		if c.Identity {
+			return true
+		}
+	}
+	return false
+}
+
+func insert(ctx context.Context, tx *sql.Tx, t *schema.Table, rows []row) (err error) {
+	name := quotedName(t)
+	identity := ""
+	for _, c := range t.Columns {
+		if c.Identity {
+			identity = strings.ToLower(c.Name)
+		}
+	}
+	identityInsert := false
+	for _, r := range rows {
+		for _, column := range r.columns {
+			if identity != "" && strings.ToLower(column) == identity {
+				identityInsert = true
+			}
+		}
+	}
+	if identityInsert {
+		if _, err = tx.ExecContext(ctx, "set identity_insert "+name+" on"); err != nil {
+			return err
+		}
+		defer func() {
+			if _, offErr := tx.ExecContext(ctx, "set identity_insert "+name+" off"); err == nil {
+				err = offErr
+			}
+		}()
+	}
+	for _, r := range rows {
+		columns := make([]string, len(r.columns))
+		params := make([]string, len(r.columns))
+		for i, column := range r.columns {
+			columns[i] = quote(column)
+			params[i] = fmt.Sprintf("@p%d", i+1)
+		}
+		query := "insert into " + name + " (" + strings.Join(columns, ", ") + ") values (" + strings.Join(params, ", ") + ")"
+		if _, err = tx.ExecContext(ctx, query, r.values...); err != nil {
+			return fmt.Errorf("fixtures: %s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+	"github.com/microsoft/go-mssqldb/schema"
+)
+
+type customer struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Note string `json:"-"`
+}
+
+// catalog answers the queries of schema.Tables for the tables
+// dbo.customers, dbo.orders referencing customers and dbo.log.
+func catalog(query string) mssqltest.Response {
+	result := func(columns string, rows ...[]interface{}) mssqltest.Response {
+		return mssqltest.Response{Results: []mssqltest.Result{{Columns: strings.Split(columns, ","), Rows: rows}}}
+	}
+	switch {
+	case strings.Contains(query, "from sys.tables t\n"):
+		return result("object_id,schema,name",
+			[]interface{}{1, "dbo", "customers"},
+			[]interface{}{2, "dbo", "orders"},
+			[]interface{}{3, "dbo", "log"})
+	case strings.Contains(query, "from sys.columns c"):
+		return result("object_id,name,type,max_length,precision,scale,is_nullable,is_identity,is_computed,default,collation",
+			[]interface{}{1, "id", "bigint", 8, 19, 0, false, true, false, "", ""},
+			[]interface{}{2, "id", "int", 4, 10, 0, false, false, false, "", ""},
+			[]interface{}{3, "id", "int", 4, 10, 0, false, true, false, "", ""})
+	case strings.Contains(query, "from sys.indexes i"):
+		return result("object_id,index_id,name,type,is_unique,is_primary_key,is_unique_constraint,column,is_descending_key,is_included_column")
+	case strings.Contains(query, "from sys.foreign_keys fk"):
+		return result("parent_object_id,name,column,referenced_schema,referenced_table,referenced_column,on_delete,on_update",
+			[]interface{}{2, "fk_orders_customers", "customer_id", "dbo", "customers", "id", "NO_ACTION", "NO_ACTION"})
+	}
+	return mssqltest.Response{Results: []mssqltest.Result{{RowsAffected: 1}}}
+}
+
+func TestLoad(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.HandleFunc(catalog)
+	config, err := msdsn.Parse(srv.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := mssql.NewConnectorConfig(config)
+	connector.Dialer = srv
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	var f Fixtures
+	if err = f.AddJSON(strings.NewReader(`{"orders": [{"id": 10, "customer_id": 1, "meta": {"a": [1]}}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Add("dbo.customers", []*customer{{ID: 1, Name: "alice", Note: "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Add("[dbo].[log]", []map[string]interface{}{{"message": "started"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Load(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	var queries []string
+	for _, q := range srv.Queries() {
+		if !strings.HasPrefix(q, "select ") {
+			queries = append(queries, strings.SplitN(q, "\n", 2)[0])
+		}
+	}
+	expected := []string{
+		"truncate table [dbo].[orders]",
+		"truncate table [dbo].[log]",
+		"delete from [dbo].[customers]",
+		"if exists (select * from sys.identity_columns where object_id = object_id(N'[dbo].[customers]') and last_value is not null)",
+		"set identity_insert [dbo].[customers] on",
+		"insert into [dbo].[customers] ([id], [name]) values (@p1, @p2)",
+		"set identity_insert [dbo].[customers] off",
+		"insert into [dbo].[log] ([message]) values (@p1)",
+		"insert into [dbo].[orders] ([customer_id], [id], [meta]) values (@p1, @p2, @p3)",
+	}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("unexpected queries\n%s", strings.Join(queries, "\n"))
+	}
+}
+
+func TestOrderCycle(t *testing.T) {
+	tables := []schema.Table{
+		{Schema: "dbo", Name: "a", ForeignKeys: []schema.ForeignKey{{ReferencedSchema: "dbo", ReferencedTable: "b"}}},
+		{Schema: "dbo", Name: "b", ForeignKeys: []schema.ForeignKey{{ReferencedSchema: "dbo", ReferencedTable: "a"}}},
+		{Schema: "dbo", Name: "tree", ForeignKeys: []schema.ForeignKey{{ReferencedSchema: "dbo", ReferencedTable: "tree"}}},
+	}
+	var f Fixtures
+	f.Add("tree", []map[string]interface{}{{"id": 1}})
+	if ordered, err := f.order(tables); err != nil || len(ordered) != 1 {
+		t.Errorf("expected a self reference to be ignored, got %v", err)
+	}
+	f.Add("a", []map[string]interface{}{{"id": 1}})
+	f.Add("b", []map[string]interface{}{{"id": 1}})
+	if _, err := f.order(tables); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected an error for a cycle, got %v", err)
+	}
+	f.Add("missing", []map[string]interface{}{{"id": 1}})
+	if _, err := f.order(tables); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected an error for a missing table, got %v", err)
+	}
+	if err := f.Add("a", []int{1}); err == nil {
+		t.Error("expected an error for a row that is not a struct or a map")
+	}
+}
+
+func TestStructRowJSON(t *testing.T) {
+	type event struct {
+		ID      int64          `db:"id"`
+		Payload map[string]int `db:"payload,json"`
+	}
+	r, err := makeRow(reflect.ValueOf(event{ID: 1, Payload: map[string]int{"a": 1}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := row{columns: []string{"id", "payload"}, values: []interface{}{int64(1), mssql.JSON{V: map[string]int{"a": 1}}}}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %+v, got %+v", expected, r)
+	}
+}
@@ -0,0 +1,89 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// SelectJSON runs a FOR JSON query and returns the JSON document.
+//
+// The server splits the document of a FOR JSON or FOR XML query into rows
+// of about 2033 characters, scanning only the first row returns a truncated
+// document. SelectJSON joins the rows back into a single document:
+//
+//	doc, err := mssql.SelectJSON(ctx, db, "select id, name from dbo.users where active = @p1 for json path", true)
+//
+// A query without rows returns an empty document, the server returns no
+// rows instead of an empty array. Use WITHOUT_ARRAY_WRAPPER or ISNULL
+// around a subquery to get a different document.
+func SelectJSON(ctx context.Context, q Querier, query string, args ...interface{}) ([]byte, error) {
+	return selectDocument(ctx, q, query, args)
+}
+
+// SelectXML runs a FOR XML query and returns the XML document,
+// see SelectJSON.
+func SelectXML(ctx context.Context, q Querier, query string, args ...interface{}) ([]byte, error) {
+	return selectDocument(ctx, q, query, args)
+}
+
+func selectDocument(ctx context.Context, q Querier, query string, args []interface{}) ([]byte, error) {
+	r, err := OpenDocument(ctx, q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// OpenDocument runs a FOR JSON or FOR XML query and returns a reader of
+// the document, which is read from the server while it is read.
+// Close the reader to release the connection.
+func OpenDocument(ctx context.Context, q Querier, query string, args ...interface{}) (io.ReadCloser, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if len(cols) != 1 {
+		rows.Close()
+		return nil, fmt.Errorf("mssql: expected the single column of a FOR JSON or FOR XML query, got %d columns", len(cols))
+	}
+	return &documentReader{rows: rows}, nil
+}
+
+// documentReader reads the rows of a document.
+type documentReader struct {
+	rows  *sql.Rows
+	chunk sql.RawBytes
+	// rest is the unread part of chunk
+	rest []byte
+}
+
+func (r *documentReader) Read(p []byte) (int, error) {
+	for len(r.rest) == 0 {
+		if !r.rows.Next() {
+			if err := r.rows.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		if err := r.rows.Scan(&r.chunk); err != nil {
+			return 0, err
+		}
+		r.rest = r.chunk
+	}
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	return n, nil
+}
+
+func (r *documentReader) Close() error {
+	return r.rows.Close()
+}
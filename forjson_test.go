@@ -0,0 +1,58 @@
+package mssql
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestSelectJSON(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=jsonhost;user id=sa;password=pwd;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	doc := `[` + strings.Repeat(`{"id":1,"name":"ünïcode"},`, 400) + `{"id":2}]`
+	var rows [][]interface{}
+	for rest := []rune(doc); len(rest) > 0; {
+		n := 2033
+		if n > len(rest) {
+			n = len(rest)
+		}
+		rows = append(rows, []interface{}{string(rest[:n])})
+		rest = rest[n:]
+	}
+	if len(rows) < 3 {
+		t.Fatalf("expected the document to be split into several rows, got %d", len(rows))
+	}
+	srv.Handle("select * from dbo.users for json path", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"JSON_F52E2B61-18A1-11d1-B105-00805F49916B"}, Rows: rows}}})
+	srv.Handle("select * from dbo.empty for json path", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"JSON_F52E2B61-18A1-11d1-B105-00805F49916B"}}}})
+	srv.Handle("select id, name from dbo.users", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"id", "name"}}}})
+	ctx := context.Background()
+
+	got, err := SelectJSON(ctx, db, "select * from dbo.users for json path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != doc {
+		t.Errorf("expected the document of %d bytes, got %d bytes", len(doc), len(got))
+	}
+
+	r, err := OpenDocument(ctx, db, "select * from dbo.users for json path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(got) != doc {
+		t.Errorf("expected the document from the reader, got %d bytes and %v", len(got), err)
+	}
+
+	if got, err = SelectXML(ctx, db, "select * from dbo.empty for json path"); err != nil || len(got) != 0 {
+		t.Errorf("expected an empty document, got %q and %v", got, err)
+	}
+	if _, err = SelectJSON(ctx, db, "select id, name from dbo.users"); err == nil || !strings.Contains(err.Error(), "2 columns") {
+		t.Errorf("expected an error for a query with 2 columns, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// FullTextTerm is a search condition of the CONTAINS and CONTAINSTABLE
+// full-text predicates, built from user input that is sanitized, so it
+// cannot change the structure of the condition or make it invalid. Pass
+// it as the parameter of the predicate:
+//
+//	term := mssql.FullTextAnd(mssql.FullTextPhrase(brand), mssql.FullTextPrefix(model))
+//	rows, err := db.QueryContext(ctx, "select id from products where contains(description, @p1)", term)
+//
+// A term built from input without words is invalid, its error is returned
+// when the term is sent. FREETEXT takes any text as parameter, it needs no
+// FullTextTerm.
+type FullTextTerm struct {
+	cond string
+	err  error
+}
+
+// errFullTextEmpty is the error of a term built from input without words.
+var errFullTextEmpty = errors.New("mssql: the full-text search term has no words")
+
+// fullTextWords returns the words of s, without the double quotes and
+// asterisks that would end a phrase or make it a prefix term.
+func fullTextWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == '"' || r == '*' || unicode.IsSpace(r) || unicode.IsControl(r)
+	})
+}
+
+// FullTextPhrase returns a term matching the words of s in order, a
+// simple term for a single word.
+func FullTextPhrase(s string) FullTextTerm {
+	words := fullTextWords(s)
+	if len(words) == 0 {
+		return FullTextTerm{err: errFullTextEmpty}
+	}
+	return FullTextTerm{cond: `"` + strings.Join(words, " ") + `"`}
+}
+
+// FullTextPrefix returns a term matching words that start with the words
+// of s, such as "data*" for data and database.
+func FullTextPrefix(s string) FullTextTerm {
+	words := fullTextWords(s)
+	if len(words) == 0 {
+		return FullTextTerm{err: errFullTextEmpty}
+	}
+	return FullTextTerm{cond: `"` + strings.Join(words, " ") + `*"`}
+}
+
+// fullTextJoin returns the terms joined by op, in parentheses.
+func fullTextJoin(op string, terms []FullTextTerm) FullTextTerm {
+	if len(terms) == 0 {
+		return FullTextTerm{err: errFullTextEmpty}
+	}
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	conds := make([]string, len(terms))
+	for i, t := range terms {
+		if t.err != nil {
+			return t
+		}
+		conds[i] = t.cond
+	}
+	return FullTextTerm{cond: "(" + strings.Join(conds, " "+op+" ") + ")"}
+}
+
+// FullTextAnd returns a term matching text that matches all terms.
+func FullTextAnd(terms ...FullTextTerm) FullTextTerm {
+	return fullTextJoin("AND", terms)
+}
+
+// FullTextOr returns a term matching text that matches any of the terms.
+func FullTextOr(terms ...FullTextTerm) FullTextTerm {
+	return fullTextJoin("OR", terms)
+}
+
+// FullTextAndNot returns a term matching text that matches t, but not not.
+func FullTextAndNot(t, not FullTextTerm) FullTextTerm {
+	if t.err != nil {
+		return t
+	}
+	if not.err != nil {
+		return not
+	}
+	return FullTextTerm{cond: "(" + t.cond + " AND NOT " + not.cond + ")"}
+}
+
+// FullTextNear returns a term matching text in which the terms are at
+// most maxDistance terms apart, in their order when inOrder is set. A
+// maxDistance below 0 allows any distance. The terms must be phrases or
+// prefix terms.
+func FullTextNear(maxDistance int, inOrder bool, terms ...FullTextTerm) FullTextTerm {
+	if len(terms) < 2 {
+		return FullTextTerm{err: errors.New("mssql: a full-text NEAR term needs at least 2 terms")}
+	}
+	conds := make([]string, len(terms))
+	for i, t := range terms {
+		if t.err != nil {
+			return t
+		}
+		if !strings.HasPrefix(t.cond, `"`) {
+			return FullTextTerm{err: errors.New("mssql: a full-text NEAR term can only combine phrases and prefix terms")}
+		}
+		conds[i] = t.cond
+	}
+	distance := "MAX"
+	if maxDistance >= 0 {
+		distance = fmt.Sprint(maxDistance)
+	}
+	order := "FALSE"
+	if inOrder {
+		order = "TRUE"
+	}
+	return FullTextTerm{cond: fmt.Sprintf("NEAR((%s), %s, %s)", strings.Join(conds, ", "), distance, order)}
+}
+
+// FullTextAll returns a term matching text with all words of the user
+// input s, a word ending in an asterisk is a prefix term.
+func FullTextAll(s string) FullTextTerm {
+	return fullTextJoin("AND", fullTextInputTerms(s))
+}
+
+// FullTextAny returns a term matching text with any of the words of the
+// user input s, a word ending in an asterisk is a prefix term.
+func FullTextAny(s string) FullTextTerm {
+	return fullTextJoin("OR", fullTextInputTerms(s))
+}
+
+func fullTextInputTerms(s string) []FullTextTerm {
+	var terms []FullTextTerm
+	for _, field := range strings.Fields(s) {
+		term := FullTextPhrase(field)
+		if strings.HasSuffix(field, "*") {
+			term = FullTextPrefix(field)
+		}
+		if term.err == nil {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// String returns the search condition of t, or "" if t is invalid.
+func (t FullTextTerm) String() string {
+	return t.cond
+}
+
+// Err returns the error of an invalid term.
+func (t FullTextTerm) Err() error {
+	return t.err
+}
+
+// Value returns the search condition of t as nvarchar parameter.
+func (t FullTextTerm) Value() (driver.Value, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return t.cond, nil
+}
@@ -0,0 +1,50 @@
+package mssql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFullTextTerm(t *testing.T) {
+	for _, test := range []struct {
+		term FullTextTerm
+		want string
+	}{
+		{FullTextPhrase(`  red "wine" `), `"red wine"`},
+		{FullTextPrefix("data*"), `"data*"`},
+		{FullTextAnd(FullTextPhrase("red"), FullTextPrefix("win")), `("red" AND "win*")`},
+		{FullTextOr(FullTextPhrase("a"), FullTextAnd(FullTextPhrase("b"), FullTextPhrase("c"))), `("a" OR ("b" AND "c"))`},
+		{FullTextAndNot(FullTextPhrase("wine"), FullTextPhrase("white")), `("wine" AND NOT "white")`},
+		{FullTextNear(5, true, FullTextPhrase("red"), FullTextPrefix("win")), `NEAR(("red", "win*"), 5, TRUE)`},
+		{FullTextNear(-1, false, FullTextPhrase("a"), FullTextPhrase("b")), `NEAR(("a", "b"), MAX, FALSE)`},
+		{FullTextAll(`red win* ') or 1=1 --`), `("red" AND "win*" AND "')" AND "or" AND "1=1" AND "--")`},
+		{FullTextAny(`"a" ** b`), `("a" OR "b")`},
+		{FullTextAll("single"), `"single"`},
+	} {
+		if err := test.term.Err(); err != nil || test.term.String() != test.want {
+			t.Errorf("expected %s, got %s, %v", test.want, test.term, err)
+		}
+	}
+	for _, term := range []FullTextTerm{
+		FullTextPhrase(`" * "`),
+		FullTextAll("  "),
+		FullTextAnd(FullTextPhrase("a"), FullTextPhrase("")),
+		FullTextAndNot(FullTextPhrase("a"), FullTextPrefix("*")),
+		FullTextNear(1, false, FullTextPhrase("a")),
+		FullTextNear(1, false, FullTextPhrase("a"), FullTextOr(FullTextPhrase("b"), FullTextPhrase("c"))),
+	} {
+		if _, err := term.Value(); err == nil {
+			t.Errorf("expected an error for %q", term)
+		}
+	}
+}
+
+func TestFullTextTermParameter(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "select 1 where 1 = @p1", FullTextAll("")); err != errFullTextEmpty {
+		t.Errorf("expected the error of the term, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package mssql
+
+import "fmt"
+
+// MaxRows may be passed as an argument to Query to limit the number of
+// rows of its response, across all its result sets. Rows.Next fails with
+// a *LimitError when the response has more rows, and closing the rows
+// cancels the rest of the query:
+//
+//	rows, err := db.QueryContext(ctx, query, mssql.MaxRows(10000), mssql.MaxBytes(64<<20))
+//
+// It protects services that run queries of their callers from runaway
+// SELECT * queries.
+type MaxRows int64
+
+// MaxBytes may be passed as an argument to Query to limit the size of the
+// rows of its response, like MaxRows. The size of a row is the length of
+// its string and []byte values, and 8 bytes for each of its other values.
+type MaxBytes int64
+
+// LimitError is returned by Rows.Next when the response of a query exceeds
+// its MaxRows or MaxBytes.
+type LimitError struct {
+	// Limit is "MaxRows" or "MaxBytes".
+	Limit string
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("mssql: the response of the query exceeds %s of %d", e.Limit, e.Max)
+}
+
+// checkLimits counts row against the MaxRows and MaxBytes of the query.
+func (t *tokenProcessor) checkLimits(row []interface{}) error {
+	if t.outs.maxRows == 0 && t.outs.maxBytes == 0 {
+		return nil
+	}
+	t.rowsRead++
+	if t.outs.maxRows > 0 && t.rowsRead > t.outs.maxRows {
+		return &LimitError{Limit: "MaxRows", Max: t.outs.maxRows}
+	}
+	for _, v := range row {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			t.bytesRead += int64(len(v))
+		case []byte:
+			t.bytesRead += int64(len(v))
+		default:
+			t.bytesRead += 8
+		}
+	}
+	if t.outs.maxBytes > 0 && t.bytesRead > t.outs.maxBytes {
+		return &LimitError{Limit: "MaxBytes", Max: t.outs.maxBytes}
+	}
+	return nil
+}
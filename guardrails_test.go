@@ -0,0 +1,57 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestMaxRowsAndBytes(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	var rows [][]interface{}
+	for i := 0; i < 100; i++ {
+		rows = append(rows, []interface{}{int64(i), "0123456789"})
+	}
+	srv.Handle("select * from dbo.events", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"id", "payload"}, Rows: rows}}})
+	ctx := context.Background()
+
+	count := func(args ...interface{}) (int, error) {
+		r, err := db.QueryContext(ctx, "select * from dbo.events", args...)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		n := 0
+		for r.Next() {
+			n++
+		}
+		return n, r.Err()
+	}
+
+	n, err := count(MaxRows(10))
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxRows" || n != 10 {
+		t.Errorf("expected a MaxRows error after 10 rows, got %d rows and %v", n, err)
+	}
+	// a row is 8 bytes for the id and 10 for the payload
+	n, err = count(MaxBytes(18 * 5))
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxBytes" || n != 5 {
+		t.Errorf("expected a MaxBytes error after 5 rows, got %d rows and %v", n, err)
+	}
+	if n, err = count(MaxRows(100)); err != nil || n != 100 {
+		t.Errorf("expected 100 rows, got %d and %v", n, err)
+	}
+	if _, err = count(MaxRows(0)); err == nil {
+		t.Error("expected an error for MaxRows(0)")
+	}
+	// the connection is still usable
+	var one int
+	if err = db.QueryRowContext(ctx, "select 1").Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+}
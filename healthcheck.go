@@ -0,0 +1,108 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// HealthState is the state of a database reported by HealthCheck.
+type HealthState int
+
+const (
+	// HealthOK is a database that answered within the slow threshold.
+	HealthOK HealthState = iota
+	// HealthDegraded is a database that answered, but slower than the slow threshold.
+	HealthDegraded
+	// HealthDown is a database that failed or did not answer within the timeout.
+	HealthDown
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthOK:
+		return "ok"
+	case HealthDegraded:
+		return "degraded"
+	case HealthDown:
+		return "down"
+	}
+	return fmt.Sprintf("HealthState(%d)", int(s))
+}
+
+// Defaults of HealthCheckOptions.
+const (
+	defaultHealthCheckTimeout = 2 * time.Second
+	defaultSlowThreshold      = 500 * time.Millisecond
+)
+
+// HealthCheckOptions configure HealthCheck.
+type HealthCheckOptions struct {
+	// Timeout is the time the check waits for a connection and the answer
+	// of the server before it reports HealthDown, 2 seconds by default.
+	Timeout time.Duration
+	// SlowThreshold is the time after which an answer is reported as
+	// HealthDegraded, 500 milliseconds by default.
+	SlowThreshold time.Duration
+}
+
+// HealthStatus is the result of HealthCheck.
+type HealthStatus struct {
+	State HealthState
+	// Latency is the time the check took, including the wait for a
+	// connection of the pool.
+	Latency time.Duration
+	// Err is the error of a check with HealthDown.
+	Err error
+}
+
+// HealthCheck checks that db answers a query, e.g. for the readiness probe
+// of a Kubernetes pod. It runs select 1 on a connection of the pool
+// without preparing it, so it takes a single round trip and does not fill
+// the statement cache:
+//
+//	status := mssql.HealthCheck(ctx, db, mssql.HealthCheckOptions{})
+//	if status.State == mssql.HealthDown {
+//		http.Error(w, status.Err.Error(), http.StatusServiceUnavailable)
+//		return
+//	}
+//
+// The connections of db must be of this driver.
+func HealthCheck(ctx context.Context, db *sql.DB, opts HealthCheckOptions) HealthStatus {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultHealthCheckTimeout
+	}
+	if opts.SlowThreshold <= 0 {
+		opts.SlowThreshold = defaultSlowThreshold
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := healthCheck(ctx, db)
+	status := HealthStatus{Latency: time.Since(start), Err: err}
+	switch {
+	case err != nil:
+		status.State = HealthDown
+	case status.Latency > opts.SlowThreshold:
+		status.State = HealthDegraded
+	}
+	return status
+}
+
+func healthCheck(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*Conn)
+		if !ok {
+			return fmt.Errorf("mssql: HealthCheck requires a connection of this driver, got %T", driverConn)
+		}
+		stmt := &Stmt{c: c, query: defaultHealthCheckSQL, skipEncryption: true}
+		_, err := stmt.ExecContext(ctx, nil)
+		return err
+	})
+}
@@ -0,0 +1,56 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestHealthCheckStates(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+
+	status := HealthCheck(ctx, db, HealthCheckOptions{})
+	if status.State != HealthOK || status.Err != nil {
+		t.Fatalf("expected ok, got %s, %v", status.State, status.Err)
+	}
+
+	srv.Handle("select 1", mssqltest.Response{Delay: 50 * time.Millisecond, Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{1}}}}})
+	status = HealthCheck(ctx, db, HealthCheckOptions{SlowThreshold: 10 * time.Millisecond})
+	if status.State != HealthDegraded || status.Latency < 50*time.Millisecond {
+		t.Errorf("expected degraded after 50ms, got %s after %v, %v", status.State, status.Latency, status.Err)
+	}
+
+	status = HealthCheck(ctx, db, HealthCheckOptions{Timeout: 10 * time.Millisecond})
+	if status.State != HealthDown || status.Err == nil {
+		t.Errorf("expected down after the timeout, got %s, %v", status.State, status.Err)
+	}
+	for _, q := range srv.Queries() {
+		if q != "select 1" {
+			t.Errorf("expected only unprepared health checks, got %q", q)
+		}
+	}
+}
+
+func TestHealthCheckDown(t *testing.T) {
+	faults := &FaultInjector{
+		Dial: func(ctx context.Context, server string) error {
+			return errors.New("injected dial failure")
+		},
+	}
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", faults)
+	defer srv.Close()
+	defer db.Close()
+	status := HealthCheck(context.Background(), db, HealthCheckOptions{})
+	if status.State != HealthDown || status.Err == nil {
+		t.Errorf("expected down, got %s, %v", status.State, status.Err)
+	}
+	if s := HealthState(7).String(); s != "HealthState(7)" {
+		t.Errorf("unexpected name %q", s)
+	}
+}
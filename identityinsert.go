@@ -0,0 +1,46 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// WithIdentityInsert runs f on a connection of db with IDENTITY_INSERT set
+// on for table, so that f can insert explicit values into its identity column:
+//
+//	err := mssql.WithIdentityInsert(ctx, db, "dbo.customers", func(conn *sql.Conn) error {
+//		_, err := conn.ExecContext(ctx, "insert into dbo.customers (id, name) values (@p1, @p2)", 42, "migrated")
+//		return err
+//	})
+//
+// IDENTITY_INSERT is a setting of the session, f must use conn and not db.
+// It is set off again after f returned, even when f failed or ctx was
+// cancelled. When that fails the connection is closed instead of being
+// returned to the pool with IDENTITY_INSERT on.
+//
+// table is inserted into the SET statement as is, quote it if needed.
+func WithIdentityInsert(ctx context.Context, db *sql.DB, table string, f func(conn *sql.Conn) error) (err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, "set identity_insert "+table+" on"); err != nil {
+		return err
+	}
+	defer func() {
+		// the cleanup runs when ctx is done too
+		_, offErr := conn.ExecContext(context.Background(), "set identity_insert "+table+" off")
+		if offErr == nil {
+			return
+		}
+		conn.Raw(func(interface{}) error {
+			return driver.ErrBadConn
+		})
+		if err == nil {
+			err = offErr
+		}
+	}()
+	return f(conn)
+}
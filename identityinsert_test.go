@@ -0,0 +1,71 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestWithIdentityInsert(t *testing.T) {
+	var dials int32
+	faults := &FaultInjector{Dial: func(ctx context.Context, server string) error {
+		atomic.AddInt32(&dials, 1)
+		return nil
+	}}
+	db, srv := newFaultTestDB(t, "server=identityhost;user id=sa;password=pwd;encrypt=disable", faults)
+	defer srv.Close()
+	defer db.Close()
+	ok := mssqltest.Response{Results: []mssqltest.Result{{}}}
+	srv.Handle("set identity_insert dbo.customers on", ok)
+	srv.Handle("set identity_insert dbo.customers off", ok)
+	srv.Handle("insert into dbo.customers (id) values (@p1)", mssqltest.Response{Results: []mssqltest.Result{{RowsAffected: 1}}})
+	ctx := context.Background()
+
+	err := WithIdentityInsert(ctx, db, "dbo.customers", func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "insert into dbo.customers (id) values (@p1)", 42)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("failed")
+	err = WithIdentityInsert(ctx, db, "dbo.customers", func(conn *sql.Conn) error {
+		return failure
+	})
+	if err != failure {
+		t.Errorf("expected the error of f, got %v", err)
+	}
+	queries := srv.Queries()
+	expected := []string{
+		"set identity_insert dbo.customers on",
+		"insert into dbo.customers (id) values (@p1)",
+		"set identity_insert dbo.customers off",
+		"set identity_insert dbo.customers on",
+		"set identity_insert dbo.customers off",
+	}
+	if !equalStrings(queries, expected) {
+		t.Errorf("unexpected queries %v", queries)
+	}
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("expected the connection to be reused, got %d dials", n)
+	}
+
+	// without a response for the cleanup the connection is closed
+	err = WithIdentityInsert(ctx, db, "dbo.orders", func(conn *sql.Conn) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error when IDENTITY_INSERT cannot be set")
+	}
+	srv.Handle("set identity_insert dbo.orders on", ok)
+	if err = WithIdentityInsert(ctx, db, "dbo.orders", func(conn *sql.Conn) error { return nil }); err == nil {
+		t.Error("expected the error of the cleanup")
+	}
+	if idle := db.Stats().Idle; idle != 0 {
+		t.Errorf("expected the connection to be closed after the failed cleanup, got %d idle connections", idle)
+	}
+}
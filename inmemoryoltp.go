@@ -0,0 +1,117 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Numbers of the errors of transactions on memory-optimized tables that
+// failed because of a concurrent transaction, they succeed when retried.
+const (
+	errXTPCommitDependency    = 41301
+	errXTPWriteConflict       = 41302
+	errXTPRepeatableRead      = 41305
+	errXTPSerializable        = 41325
+	errXTPTooManyDependencies = 41839
+)
+
+// maxConflictRetries is the number of times a statement that failed with a
+// conflict on memory-optimized tables is retried with the inmemoryoltp
+// connection string parameter, after conflictRetryDelay, doubled for every
+// retry.
+const (
+	maxConflictRetries = 3
+	conflictRetryDelay = 5 * time.Millisecond
+)
+
+// elevateToSnapshotSQL reads the MEMORY_OPTIMIZED_ELEVATE_TO_SNAPSHOT
+// option of the current database.
+const elevateToSnapshotSQL = "select is_memory_optimized_elevate_to_snapshot_on, db_name() from sys.databases where database_id = db_id()"
+
+// IsMemoryOptimizedConflict reports whether err is an error of a transaction
+// on memory-optimized tables that failed because of a concurrent transaction,
+// a write conflict (41302), a failed repeatable read (41305) or serializable
+// (41325) validation, or a failed commit dependency (41301, 41839). The
+// transaction was rolled back and succeeds when it is retried as a whole:
+//
+//	for retry := 0; ; retry++ {
+//		err = transfer(ctx, db, from, to, amount)
+//		if retry == 3 || !mssql.IsMemoryOptimizedConflict(err) {
+//			break
+//		}
+//	}
+//
+// Statements passed RetryConflicts outside of transactions are retried by
+// the driver with the inmemoryoltp connection string parameter.
+func IsMemoryOptimizedConflict(err error) bool {
+	var sqlErr Error
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	switch sqlErr.Number {
+	case errXTPCommitDependency, errXTPWriteConflict, errXTPRepeatableRead, errXTPSerializable, errXTPTooManyDependencies:
+		return true
+	}
+	return false
+}
+
+// RetryConflicts may be passed as an argument to Exec or Query to retry a
+// statement that failed with a conflict on memory-optimized tables on a
+// connection with the inmemoryoltp parameter. Only pass it to a single
+// statement or a natively compiled procedure, which runs as an atomic
+// block. A batch of statements or an interpreted procedure is retried as a
+// whole, including the statements that succeeded before the conflict:
+//
+//	res, err := db.ExecContext(ctx, "dbo.usp_transfer", from, to, amount, mssql.RetryConflicts{})
+type RetryConflicts struct{}
+
+// mayRetryConflict reports whether a statement with outs that failed with
+// err is retried on its connection after the retries it was already retried.
+// Only statements passed RetryConflicts on a connection with the inmemoryoltp
+// parameter, that failed with a conflict on memory-optimized tables outside
+// of a transaction, are retried. It waits before the retry.
+func (s *Stmt) mayRetryConflict(ctx context.Context, outs outputs, err error, retries int) bool {
+	c := s.c
+	if c.connector == nil || !c.connector.params.InMemoryOLTP || !outs.retryConflicts || retries >= maxConflictRetries || !IsMemoryOptimizedConflict(err) {
+		return false
+	}
+	if !c.connectionGood || c.sess.tranid != 0 || ctx.Err() != nil {
+		return false
+	}
+	if c.sess.logFlags&logRetries != 0 {
+		c.sess.logger.Log(ctx, msdsn.LogRetries, err.Error())
+	}
+	c.logEvent(ctx, Event{Type: EventRetry, Err: err, Message: err.Error()})
+	return sleepContext(ctx, conflictRetryDelay<<retries) == nil
+}
+
+// checkElevateToSnapshot warns once per Connector with the inmemoryoltp
+// parameter when the database does not elevate READ COMMITTED to SNAPSHOT
+// for memory-optimized tables, so explicit transactions need the SNAPSHOT
+// table hint to access them.
+func (c *Conn) checkElevateToSnapshot(ctx context.Context) {
+	if c.connector == nil || !c.connector.params.InMemoryOLTP || !atomic.CompareAndSwapUint32(&c.connector.elevateChecked, 0, 1) {
+		return
+	}
+	stmt := &Stmt{c: c, query: elevateToSnapshotSQL, skipEncryption: true}
+	rows, err := stmt.QueryContext(ctx, nil)
+	if err != nil {
+		c.logEvent(ctx, Event{Type: EventWarning, Err: err, Message: "reading MEMORY_OPTIMIZED_ELEVATE_TO_SNAPSHOT failed"})
+		return
+	}
+	defer rows.Close()
+	values := make([]driver.Value, 2)
+	if rows.Next(values) != nil {
+		return
+	}
+	if on, _ := values[0].(bool); !on {
+		database, _ := values[1].(string)
+		c.logEvent(ctx, Event{Type: EventWarning, Message: "MEMORY_OPTIMIZED_ELEVATE_TO_SNAPSHOT is off for database " + database +
+			", explicit READ COMMITTED transactions need the SNAPSHOT table hint to access memory-optimized tables"})
+	}
+}
@@ -0,0 +1,97 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestIsMemoryOptimizedConflict(t *testing.T) {
+	for number, want := range map[int32]bool{41301: true, 41302: true, 41305: true, 41325: true, 41839: true, 1205: false, 41368: false} {
+		err := fmt.Errorf("exec: %w", Error{Number: number})
+		if got := IsMemoryOptimizedConflict(err); got != want {
+			t.Errorf("expected %t for %d, got %t", want, number, got)
+		}
+	}
+}
+
+func TestInMemoryOLTPRetry(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	calls := 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if query == elevateToSnapshotSQL {
+			return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"on", "db"}, Rows: [][]interface{}{{false, "sales"}}}}}
+		}
+		calls++
+		if calls <= 2 {
+			return mssqltest.Response{Err: &mssqltest.Error{Number: errXTPWriteConflict, Class: 16, Message: "The current transaction attempted to update a record that has been updated since this transaction started."}}
+		}
+		return mssqltest.Response{Results: []mssqltest.Result{{RowsAffected: 1}}}
+	})
+	open := func(dsn string) (*sql.DB, *eventRecorder) {
+		config, err := msdsn.Parse(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := &eventRecorder{}
+		connector := NewConnectorConfig(config)
+		connector.Dialer = srv
+		connector.EventLogger = rec
+		return sql.OpenDB(connector), rec
+	}
+	ctx := context.Background()
+
+	db, rec := open("server=test;encrypt=disable;inmemoryoltp=true")
+	defer db.Close()
+	if _, err = db.ExecContext(ctx, "dbo.usp_transfer", 42, RetryConflicts{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 2 retries, got %d executions", calls)
+	}
+	warnings, retries := 0, 0
+	for _, e := range rec.events {
+		switch e.Type {
+		case EventWarning:
+			warnings++
+			if !strings.Contains(e.Message, "MEMORY_OPTIMIZED_ELEVATE_TO_SNAPSHOT is off for database sales") {
+				t.Errorf("unexpected warning %q", e.Message)
+			}
+		case EventRetry:
+			retries++
+		}
+	}
+	if warnings != 1 || retries != 2 {
+		t.Errorf("expected a warning and 2 retry events, got %+v", rec.events)
+	}
+
+	// statements and procedures are only retried when they are marked
+	batch := "update dbo.accounts set balance -= @p1 where id = 1; update dbo.accounts set balance += @p1 where id = 2"
+	for _, query := range []string{batch, "dbo.usp_interpreted"} {
+		calls = 0
+		if _, err = db.ExecContext(ctx, query, 42); !IsMemoryOptimizedConflict(err) || calls != 1 {
+			t.Errorf("expected the conflict of %s without retries, got %v after %d executions", query, err, calls)
+		}
+	}
+	calls = 0
+	if _, err = db.ExecContext(ctx, "update dbo.accounts set balance -= @p1 where id = 1", 42, RetryConflicts{}); err != nil || calls != 3 {
+		t.Errorf("expected the marked statement to be retried, got %v after %d executions", err, calls)
+	}
+
+	// without the parameter the conflict is returned
+	calls = 0
+	plain, _ := open("server=test;encrypt=disable")
+	defer plain.Close()
+	if _, err = plain.ExecContext(ctx, "dbo.usp_transfer", 42, RetryConflicts{}); !IsMemoryOptimizedConflict(err) || calls != 1 {
+		t.Errorf("expected the conflict without retries, got %v after %d executions", err, calls)
+	}
+}
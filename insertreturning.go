@@ -0,0 +1,106 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/internal/jsonfields"
+)
+
+// InsertReturning runs query, an insert with an OUTPUT INSERTED clause, and
+// scans the rows it outputs into dest, a pointer to a struct or to a slice
+// of structs or of pointers to structs:
+//
+//	var p product
+//	err := mssql.InsertReturning(ctx, db, &p, "insert into dbo.products (sku, name) output inserted.* values (@p1, @p2)", sku, name)
+//
+// The columns are scanned into the fields of the struct as described for
+// OpenJSON, matched by name and then ignoring case. Columns without a field
+// are skipped. The JSON of a column is unmarshaled into a field with the
+// json option of the db tag, as in db:"payload,json". A struct receives the
+// first row, sql.ErrNoRows is returned when no row was inserted. The rows
+// are appended to a slice.
+func InsertReturning(ctx context.Context, q Querier, dest interface{}, query string, args ...interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("mssql: InsertReturning expects a pointer to a struct or to a slice of structs, got %T", dest)
+	}
+	v = v.Elem()
+	elem := v.Type()
+	if v.Kind() == reflect.Slice {
+		elem = elem.Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("mssql: InsertReturning expects a pointer to a struct or to a slice of structs, got %T", dest)
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fields := scanFields(elem, columns)
+	scan := make([]interface{}, len(columns))
+	var skipped interface{}
+	for rows.Next() {
+		row := v
+		if v.Kind() == reflect.Slice {
+			row = reflect.New(elem).Elem()
+		}
+		for i, field := range fields {
+			if field == nil {
+				scan[i] = &skipped
+				continue
+			}
+			scan[i] = row.FieldByIndex(field.Index).Addr().Interface()
+			if field.JSON {
+				scan[i] = &JSON{V: scan[i]}
+			}
+		}
+		if err = rows.Scan(scan...); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Struct {
+			return rows.Close()
+		}
+		if v.Type().Elem().Kind() == reflect.Ptr {
+			row = row.Addr()
+		}
+		v.Set(reflect.Append(v, row))
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Struct {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// scanFields returns the fields of the struct type t the columns are
+// scanned into, nil for the columns without a field.
+func scanFields(t reflect.Type, columns []string) []*jsonfields.Field {
+	fields := jsonfields.Fields(t, nil)
+	res := make([]*jsonfields.Field, len(columns))
+	for i, column := range columns {
+		for j := range fields {
+			if fields[j].Name == column {
+				res[i] = &fields[j]
+				break
+			}
+			if res[i] == nil && strings.EqualFold(fields[j].Name, column) {
+				res[i] = &fields[j]
+			}
+		}
+	}
+	return res
+}
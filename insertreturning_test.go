@@ -0,0 +1,80 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestInsertReturning(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+	const insert = "insert into dbo.products (sku, name) output inserted.* select sku, name from dbo.staged"
+	srv.Handle(insert, mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "SKU", "name", "rowversion"},
+		Rows: [][]interface{}{
+			{int64(1), "a-1", "apple", []byte{1}},
+			{int64(2), "b-2", nil, []byte{2}},
+		},
+	}}})
+	const none = "insert into dbo.products (sku) output inserted.* select sku from dbo.staged where 1 = 0"
+	srv.Handle(none, mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"id", "sku"}}}})
+
+	type product struct {
+		ID   int64 `json:"id"`
+		SKU  string
+		Name *string `json:"name"`
+	}
+	apple := "apple"
+
+	var p product
+	if err := InsertReturning(ctx, db, &p, insert); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, product{ID: 1, SKU: "a-1", Name: &apple}) {
+		t.Errorf("unexpected first row %+v", p)
+	}
+
+	var products []*product
+	if err := InsertReturning(ctx, db, &products, insert); err != nil {
+		t.Fatal(err)
+	}
+	expected := []*product{{ID: 1, SKU: "a-1", Name: &apple}, {ID: 2, SKU: "b-2"}}
+	if !reflect.DeepEqual(products, expected) {
+		t.Errorf("expected %+v, got %+v", expected, products)
+	}
+
+	if err := InsertReturning(ctx, db, &p, none); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+	var values []product
+	if err := InsertReturning(ctx, db, &values, none); err != nil || len(values) != 0 {
+		t.Errorf("expected no rows, got %v %v", values, err)
+	}
+	const document = "insert into dbo.documents (payload) output inserted.id, inserted.payload values (@p1)"
+	srv.Handle(document, mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "payload"},
+		Rows:    [][]interface{}{{int64(3), `{"a":1}`}},
+	}}})
+	var d struct {
+		ID      int64          `db:"id"`
+		Payload map[string]int `db:"payload,json"`
+	}
+	if err := InsertReturning(ctx, db, &d, document, JSON{V: map[string]int{"a": 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != 3 || !reflect.DeepEqual(d.Payload, map[string]int{"a": 1}) {
+		t.Errorf("expected the payload to be unmarshaled, got %+v", d)
+	}
+
+	for _, dest := range []interface{}{p, &apple, (*product)(nil)} {
+		if err := InsertReturning(ctx, db, dest, insert); err == nil {
+			t.Errorf("expected an error for the destination %T", dest)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package cp
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestCharsetToUTF8(t *testing.T) {
+	latin := Collation{LcidAndFlags: 0x0409, SortId: 52}
+	cyrillic := Collation{LcidAndFlags: 0x0419}
+	tests := []struct {
+		name string
+		col  Collation
+		in   []byte
+		want string
+	}{
+		{"cp1252", latin, []byte{'c', 'a', 'f', 0xe9}, "café"},
+		{"cp1251", cyrillic, []byte{0xcf, 0xf0, 0xe8}, "При"},
+		{"utf8", Collation{LcidAndFlags: 0x04d00409, SortId: 0}, []byte("caf\xc3\xa9"), "café"},
+		{"unicode only", Collation{LcidAndFlags: 0x0439}, []byte("abc"), "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CharsetToUTF8(tt.col, tt.in); got != tt.want {
+				t.Errorf("CharsetToUTF8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodePagesMapASCII(t *testing.T) {
+	for _, codePage := range []int{437, 850, 874, 932, 936, 949, 950, 1250, 1251, 1252, 1253, 1254, 1255, 1256, 1257, 1258} {
+		cm := codepage2charset(codePage)
+		for b := 0; b < 0x80; b++ {
+			if cm.sb[b] != rune(b) {
+				t.Errorf("code page %d maps byte %#x to %q", codePage, b, cm.sb[b])
+				break
+			}
+		}
+	}
+}
+
+func TestRegisterCodePage(t *testing.T) {
+	col := Collation{LcidAndFlags: 0x0409, SortId: 52}
+	if cp := col.CodePage(); cp != 1252 {
+		t.Fatalf("CodePage() = %d, want 1252", cp)
+	}
+	RegisterCodePage(1252, charmap.ISO8859_5)
+	defer delete(registeredCodePages, 1252)
+
+	if got := CharsetToUTF8(col, []byte{0xbf}); got != "П" {
+		t.Errorf("CharsetToUTF8() with registered code page = %q, want %q", got, "П")
+	}
+}
+
+func TestCollationIsUTF8(t *testing.T) {
+	// Latin1_General_100_CI_AS_SC_UTF8
+	utf8 := Collation{LcidAndFlags: 0x24d00409}
+	if !utf8.IsUTF8() {
+		t.Error("IsUTF8() = false for a UTF-8 collation")
+	}
+	if cp := utf8.CodePage(); cp != 65001 {
+		t.Errorf("CodePage() = %d, want 65001", cp)
+	}
+	// Latin1_General_100_CI_AS_SC
+	latin := Collation{LcidAndFlags: 0x20d00409}
+	if latin.IsUTF8() {
+		t.Error("IsUTF8() = true for a code page collation")
+	}
+}
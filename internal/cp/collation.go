@@ -7,6 +7,11 @@ type Collation struct {
 	SortId       uint8
 }
 
+// collation flags, shifted down from LcidAndFlags
+const (
+	fUTF8 = 0x40
+)
+
 func (c Collation) getLcid() uint32 {
 	return c.LcidAndFlags & 0x000fffff
 }
@@ -18,3 +23,9 @@ func (c Collation) getFlags() uint32 {
 func (c Collation) getVersion() uint32 {
 	return (c.LcidAndFlags & 0xf0000000) >> 28
 }
+
+// IsUTF8 reports whether the collation stores non-Unicode
+// character data as UTF-8, available since SQL Server 2019.
+func (c Collation) IsUTF8() bool {
+	return c.getFlags()&fUTF8 != 0
+}
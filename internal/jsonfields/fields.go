@@ -0,0 +1,96 @@
+// Package jsonfields lists the fields of a struct that become the columns
+// of a row, named like encoding/json names the keys of an object, or by
+// their db tag. It is shared by OpenJSON, Upsert, InsertReturning, the DDL
+// of package schema and package fixtures.
+//
+// This package is not subject to any API compatibility guarantee.
+package jsonfields
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field is an exported field of a struct or of a struct embedded in it.
+type Field struct {
+	reflect.StructField
+	// Name is the name of the column, the name of the db tag of the field,
+	// or else its key in encoding/json, its json tag or its name.
+	Name string
+	// Index is the index sequence of the field for reflect.Value.FieldByIndex.
+	Index []int
+	// OmitEmpty is set by the omitempty option of the json tag.
+	OmitEmpty bool
+	// JSON is set by the json option of the db tag, as in db:"payload,json",
+	// the column holds the JSON of the field.
+	JSON bool
+}
+
+// Fields returns the exported fields of the struct type t in order. Fields
+// with the json or db tag "-" and the fields skip reports are left out. The
+// fields of embedded structs without a name are promoted like encoding/json
+// promotes them.
+func Fields(t reflect.Type, skip func(reflect.StructField) bool) []Field {
+	return appendFields(nil, t, nil, skip)
+}
+
+func appendFields(fields []Field, t reflect.Type, index []int, skip func(reflect.StructField) bool) []Field {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, hasTag := field.Tag.Lookup("json")
+		dbTag := field.Tag.Get("db")
+		if tag == "-" || dbTag == "-" || (skip != nil && skip(field)) {
+			continue
+		}
+		name, options := splitTag(tag)
+		dbName, dbOptions := splitTag(dbTag)
+		fieldIndex := append(index[:len(index):len(index)], i)
+		if field.Anonymous && name == "" && dbName == "" && field.Type.Kind() == reflect.Struct {
+			fields = appendFields(fields, field.Type, fieldIndex, skip)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if dbName != "" {
+			name = dbName
+		} else if !hasTag || name == "" {
+			name = field.Name
+		}
+		fields = append(fields, Field{
+			StructField: field,
+			Name:        name,
+			Index:       fieldIndex,
+			OmitEmpty:   strings.Contains(options+",", ",omitempty,"),
+			JSON:        strings.Contains(dbOptions+",", ",json,"),
+		})
+	}
+	return fields
+}
+
+// splitTag splits a tag into the name and the options, which start with a comma.
+func splitTag(tag string) (name, options string) {
+	if comma := strings.IndexByte(tag, ','); comma >= 0 {
+		return tag[:comma], tag[comma:]
+	}
+	return tag, ""
+}
+
+// IsEmpty reports whether v is empty like encoding/json decides for omitempty.
+func IsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package jsonfields
+
+import (
+	"reflect"
+	"testing"
+)
+
+type base struct {
+	ID int64 `json:"id"`
+}
+
+type named struct {
+	Value int
+}
+
+type row struct {
+	base
+	Named    named  `json:"named"`
+	Name     string `json:"name,omitempty"`
+	Skipped  string `json:"-"`
+	Dash     string `json:"-,"`
+	Internal string `db:"-"`
+	Plain    bool
+	Payload  map[string]int `db:"payload,json"`
+	Renamed  string         `json:"json_name" db:"db_name"`
+	hidden   int
+}
+
+func TestFields(t *testing.T) {
+	fields := Fields(reflect.TypeOf(row{}), func(f reflect.StructField) bool {
+		return f.Tag.Get("db") == "-"
+	})
+	var got []string
+	for _, f := range fields {
+		got = append(got, f.Name)
+	}
+	expected := []string{"id", "named", "name", "-", "Plain", "payload", "db_name"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the fields %v, got %v", expected, got)
+	}
+	if !reflect.DeepEqual(fields[0].Index, []int{0, 0}) || fields[2].OmitEmpty != true || fields[1].OmitEmpty {
+		t.Errorf("unexpected index or options %+v", fields)
+	}
+	if !fields[5].JSON || fields[6].JSON || fields[1].JSON {
+		t.Errorf("expected only the payload to have the json option %+v", fields)
+	}
+	v := reflect.ValueOf(row{base: base{ID: 7}})
+	if id := v.FieldByIndex(fields[0].Index).Int(); id != 7 {
+		t.Errorf("expected the promoted id 7, got %d", id)
+	}
+	if !IsEmpty(v.FieldByIndex(fields[2].Index)) || IsEmpty(v.FieldByIndex(fields[0].Index)) {
+		t.Error("expected an empty name and a non-empty id")
+	}
+}
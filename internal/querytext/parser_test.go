@@ -34,6 +34,9 @@ func TestParseParams(t *testing.T) {
 		{"select $12", "select @p12", 12},
 		{"select ? /* ? /* ? */ ? */ ?", "select @p1 /* ? /* ? */ ? */ @p2", 2},
 		{"select ? /* ? / ? */ ?", "select @p1 /* ? / ? */ @p2", 2},
+		{"select * from [srv].[db].[dbo].[t?] where id = ?", "select * from [srv].[db].[dbo].[t?] where id = @p1", 1},
+		{"select * from openquery([srv], N'select * from t where a = ''?'' and b = ''$1''') where c = ?",
+			"select * from openquery([srv], N'select * from t where a = ''?'' and b = ''$1''') where c = @p1", 1},
 		{"select $", "select $", 0},
 		{"select x::y", "select x:@y", 1},
 		{"select '", "select '", 0},
@@ -0,0 +1,21 @@
+// Package sqlexec defines the interfaces of the database/sql types that the
+// helpers of the driver and of its packages run their statements on. The
+// driver package exports them as mssql.Querier and mssql.Execer.
+//
+// This package is not subject to any API compatibility guarantee.
+package sqlexec
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
@@ -0,0 +1,43 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON stores the value V as JSON in a nvarchar or varbinary column.
+// As a parameter V is marshaled and sent as nvarchar, as a Scan destination
+// the column is unmarshaled into V, which must be a pointer:
+//
+//	_, err := db.Exec("insert into dbo.events (payload) values (@p1)", mssql.JSON{V: payload})
+//	err = db.QueryRow("select payload from dbo.events where id = @p1", id).Scan(&mssql.JSON{V: &payload})
+//
+// A nil V is sent as NULL, a NULL column is unmarshaled like the JSON null.
+type JSON struct {
+	V interface{}
+}
+
+func (j *JSON) Scan(v interface{}) error {
+	switch vt := v.(type) {
+	case nil:
+		return json.Unmarshal([]byte("null"), j.V)
+	case string:
+		return json.Unmarshal([]byte(vt), j.V)
+	case []byte:
+		return json.Unmarshal(vt, j.V)
+	default:
+		return fmt.Errorf("mssql: cannot unmarshal JSON from %T", v)
+	}
+}
+
+func (j JSON) Value() (driver.Value, error) {
+	if j.V == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,52 @@
+package mssql
+
+import (
+	"testing"
+)
+
+type jsonPayload struct {
+	Name string            `json:"name"`
+	Tags []string          `json:"tags"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+func TestJSONScan(t *testing.T) {
+	t.Parallel()
+	var p jsonPayload
+	if err := (&JSON{V: &p}).Scan(`{"name":"a","tags":["x","y"]}`); err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "a" || len(p.Tags) != 2 {
+		t.Errorf("unexpected value %+v", p)
+	}
+	var m map[string]int
+	if err := (&JSON{V: &m}).Scan([]byte(`{"a":1}`)); err != nil || m["a"] != 1 {
+		t.Errorf("expected the map from a varbinary value, got %v and %v", m, err)
+	}
+	if err := (&JSON{V: &m}).Scan(nil); err != nil || m != nil {
+		t.Errorf("expected NULL to reset the map, got %v and %v", m, err)
+	}
+	if err := (&JSON{V: &p}).Scan(int64(1)); err == nil {
+		t.Error("expected an error for an integer value")
+	}
+	if err := (&JSON{V: p}).Scan(`{}`); err == nil {
+		t.Error("expected an error for a destination that is not a pointer")
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	t.Parallel()
+	v, err := JSON{V: jsonPayload{Name: "a", Tags: []string{"x"}}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `{"name":"a","tags":["x"]}` {
+		t.Errorf("unexpected value %v", v)
+	}
+	if v, err = (JSON{}).Value(); v != nil || err != nil {
+		t.Errorf("expected NULL for a nil value, got %v and %v", v, err)
+	}
+	if _, err = (JSON{V: func() {}}).Value(); err == nil {
+		t.Error("expected an error for a value that cannot be marshaled")
+	}
+}
@@ -6,6 +6,8 @@ package mssql_test
 import (
 	"database/sql"
 	"log"
+
+	mssql "github.com/microsoft/go-mssqldb"
 )
 
 // This example shows the usage of Connector type
@@ -34,10 +36,21 @@ func ExampleLastInsertId() {
 	n, err := res.LastInsertId()
 	if err != nil {
 		log.Print(err)
-		// Gets error: LastInsertId is not supported. Please use the OUTPUT clause or add `select ID = convert(bigint, SCOPE_IDENTITY())` to the end of your query.
+		// Gets error: LastInsertId is not supported. Please pass mssql.LastInsertId{} to Exec, use the OUTPUT clause or add `select ID = convert(bigint, SCOPE_IDENTITY())` to the end of your query.
 	}
 	log.Printf("LastInsertId: %d\n", n)
 
+	// Retrieve scope identity by passing mssql.LastInsertId{} to Exec
+	res, err = db.Exec("insert into foo (baz) values (@p1)", 5, mssql.LastInsertId{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	n, err = res.LastInsertId()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("LastInsertId from Exec: %d\n", n)
+
 	// Retrieve scope identity by adding 'select ID = convert(bigint, SCOPE_IDENTITY())' to the end of the query
 	rows, err := db.Query("insert into foo (baz) values (10); select ID = convert(bigint, SCOPE_IDENTITY())")
 	if err != nil {
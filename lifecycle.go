@@ -0,0 +1,77 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// Session executes statements on a connection of a Connector in the
+// OnConnect and OnClose callbacks, outside of the connection pool. It
+// implements Execer, so it can be passed to SetSessionContext.
+type Session struct {
+	c *Conn
+}
+
+// ExecContext executes query with args on the connection of the session.
+// The args are converted like database/sql converts the args of Exec,
+// sql.Named arguments included.
+func (s *Session) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	c := s.c
+	defer c.clearOuts()
+	list := make([]namedValue, 0, len(args))
+	for _, arg := range args {
+		nv := driver.NamedValue{Ordinal: len(list) + 1, Value: arg}
+		if named, ok := arg.(sql.NamedArg); ok {
+			nv.Name, nv.Value = named.Name, named.Value
+		}
+		switch err := c.CheckNamedValue(&nv); err {
+		case nil:
+		case driver.ErrRemoveArgument:
+			continue
+		default:
+			return nil, err
+		}
+		list = append(list, namedValue{Name: nv.Name, Ordinal: nv.Ordinal, Value: nv.Value})
+	}
+	stmt, err := c.prepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	return stmt.exec(ctx, list)
+}
+
+// onConnect calls the OnConnect callback of the connector with the new
+// connection c, c is closed when the callback fails.
+func (c *Conn) onConnect(ctx context.Context) error {
+	if c.connector == nil || c.connector.OnConnect == nil {
+		return nil
+	}
+	if err := c.connector.OnConnect(ctx, &Session{c: c}); err != nil {
+		c.Close()
+		return err
+	}
+	return nil
+}
+
+// onReset calls the OnReset callback of the connector with c, whose session
+// is reset for its reuse.
+func (c *Conn) onReset(ctx context.Context) error {
+	if c.connector == nil || c.connector.OnReset == nil {
+		return nil
+	}
+	return c.connector.OnReset(ctx, &Session{c: c})
+}
+
+// onClose calls the OnClose callback of the connector before c is closed.
+// It is not called for broken connections, its error is reported as a warning event.
+func (c *Conn) onClose() {
+	if c.connector == nil || c.connector.OnClose == nil || !c.connectionGood {
+		return
+	}
+	ctx := context.Background()
+	if err := c.connector.OnClose(ctx, &Session{c: c}); err != nil {
+		c.logEvent(ctx, Event{Type: EventWarning, Err: err, Message: "OnClose failed: " + err.Error()})
+	}
+}
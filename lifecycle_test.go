@@ -0,0 +1,90 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestLifecycleCallbacks(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.Handle("select 1", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{1}}}}})
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		return mssqltest.Response{}
+	})
+	config, err := msdsn.Parse("server=test;encrypt=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := NewConnectorConfig(config)
+	connector.Dialer = srv
+	connects, resets, closes := 0, 0, 0
+	connector.OnConnect = func(ctx context.Context, s *Session) error {
+		connects++
+		_, err := s.ExecContext(ctx, "set context_info 0x01")
+		if err != nil {
+			return err
+		}
+		return SetSessionContext(ctx, s, "tenant_id", 42, true)
+	}
+	connector.OnReset = func(ctx context.Context, s *Session) error {
+		resets++
+		_, err := s.ExecContext(ctx, "set context_info 0x01")
+		return err
+	}
+	connector.OnClose = func(ctx context.Context, s *Session) error {
+		closes++
+		_, err := s.ExecContext(ctx, "drop table if exists dbo.scratch")
+		return err
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+	for i := 0; i < 3; i++ {
+		if err = db.QueryRow("select 1").Scan(new(int)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// OnConnect is called once, OnReset after each reset of the reused connection
+	if connects != 1 || resets != 2 || closes != 1 {
+		t.Errorf("expected a connect, 2 resets and a close, got %d connects, %d resets and %d closes", connects, resets, closes)
+	}
+	reset := "set context_info 0x01,select 1,"
+	expected := "set context_info 0x01,sp_set_session_context,select 1," + reset + reset + "drop table if exists dbo.scratch"
+	if queries := strings.Join(srv.Queries(), ","); queries != expected {
+		t.Errorf("expected the queries %s, got %s", expected, queries)
+	}
+}
+
+func TestOnConnectFailure(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	c, err := NewConnector("server=test;encrypt=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Dialer = srv
+	injected := errors.New("setup failed")
+	c.OnConnect = func(ctx context.Context, s *Session) error {
+		return injected
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	if err = db.Ping(); !errors.Is(err, injected) {
+		t.Errorf("expected the OnConnect error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package mssql
+
+import "strings"
+
+// EscapeLike escapes the wildcards %, _ and [ in s, so s matches itself in
+// a LIKE pattern. With an escape character of 0 the wildcards are escaped
+// with brackets, which needs no ESCAPE clause:
+//
+//	pattern := "%" + mssql.EscapeLike(term, 0) + "%"
+//	rows, err := db.QueryContext(ctx, "select id from products where name like @p1", pattern)
+//
+// Otherwise they are escaped with escape, which has to be passed in the
+// ESCAPE clause of the predicate, e.g. like @p1 escape '\'.
+func EscapeLike(s string, escape rune) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case escape == 0 && (r == '%' || r == '_' || r == '['):
+			b.WriteByte('[')
+			b.WriteRune(r)
+			b.WriteByte(']')
+			continue
+		case escape != 0 && (r == '%' || r == '_' || r == '[' || r == escape):
+			b.WriteRune(escape)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// LikeContains, LikeStartsWith and LikeEndsWith are string parameters that
+// are sent as LIKE patterns matching strings that contain, start or end with
+// the value, with the wildcards in the value escaped by EscapeLike:
+//
+//	db.QueryContext(ctx, "select id from products where name like @term", sql.Named("term", mssql.LikeContains(term)))
+type (
+	LikeContains   string
+	LikeStartsWith string
+	LikeEndsWith   string
+)
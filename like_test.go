@@ -0,0 +1,42 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		escape rune
+		want   string
+	}{
+		{"100%", 0, "100[%]"},
+		{"a_b[c]", 0, "a[_]b[[]c]"},
+		{"plain", 0, "plain"},
+		{`50%_\`, '\\', `50\%\_\\`},
+		{"[x]", '!', "![x]"},
+		{"ü%", 'ü', "üüü%"},
+	} {
+		if got := EscapeLike(test.in, test.escape); got != test.want {
+			t.Errorf("EscapeLike(%q, %q) = %q, expected %q", test.in, test.escape, got, test.want)
+		}
+	}
+}
+
+func TestLikeParameters(t *testing.T) {
+	c := &Conn{}
+	for _, test := range []struct {
+		in   interface{}
+		want string
+	}{
+		{LikeContains("10%"), "%10[%]%"},
+		{LikeStartsWith("a_"), "a[_]%"},
+		{LikeEndsWith("[b"), "%[[]b"},
+	} {
+		nv := driver.NamedValue{Name: "term", Value: test.in}
+		if err := c.CheckNamedValue(&nv); err != nil || nv.Value != test.want {
+			t.Errorf("expected %T to be sent as %q, got %v, %v", test.in, test.want, nv.Value, err)
+		}
+	}
+}
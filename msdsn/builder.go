@@ -0,0 +1,219 @@
+package msdsn
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ConnStringBuilder builds a connection string from parameters, so secrets
+// with characters like ; { } = or @ do not have to be escaped by hand.
+// The connection string is a sqlserver:// URL that Parse returns the same
+// parameters for:
+//
+//	dsn, err := msdsn.NewConnStringBuilder(`db.example.com\prod`).
+//		Login("app", password).
+//		Database("orders").
+//		Set(msdsn.Encrypt, "true").
+//		Build()
+type ConnStringBuilder struct {
+	server   string
+	port     uint64
+	user     string
+	password string
+	hasLogin bool
+	params   url.Values
+	err      error
+}
+
+// NewConnStringBuilder returns a builder for a connection to server, a host,
+// host\instance or a comma separated list of servers like a,b:1500,c\inst.
+// The port of the builder is the port of the servers of a list without one.
+func NewConnStringBuilder(server string) *ConnStringBuilder {
+	return &ConnStringBuilder{server: server, params: url.Values{}}
+}
+
+// Port sets the TCP port of the server.
+func (b *ConnStringBuilder) Port(port uint16) *ConnStringBuilder {
+	b.port = uint64(port)
+	return b
+}
+
+// Database sets the database of the connection.
+func (b *ConnStringBuilder) Database(database string) *ConnStringBuilder {
+	return b.Set(Database, database)
+}
+
+// Login sets the user id and the password of SQL Server authentication,
+// or of authenticators and federated authentication that use them.
+func (b *ConnStringBuilder) Login(user, password string) *ConnStringBuilder {
+	b.user, b.password, b.hasLogin = user, password, true
+	return b
+}
+
+// Set sets the connection string parameter key, one of the key constants of
+// the package or their ADO synonyms, to value. Keys are case insensitive.
+func (b *ConnStringBuilder) Set(key, value string) *ConnStringBuilder {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if synonym, ok := adoSynonyms[key]; ok {
+		key = synonym
+	}
+	switch key {
+	case Server:
+		b.server = value
+	case Port:
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil && b.err == nil {
+			b.err = fmt.Errorf("invalid tcp port '%v': %v", value, err.Error())
+		}
+		b.port = port
+	case UserID:
+		b.user, b.hasLogin = value, true
+	case Password:
+		b.password, b.hasLogin = value, true
+	default:
+		b.params.Set(key, value)
+	}
+	return b
+}
+
+// Build validates the parameters and returns the connection string.
+func (b *ConnStringBuilder) Build() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	if err := b.validate(); err != nil {
+		return "", err
+	}
+	params := b.params
+	host, instance, port := b.server, "", b.port
+	if strings.Contains(host, ",") {
+		// a list of servers is a parameter, the host of a URL cannot
+		// have instances or a server without a port after one with a port
+		servers, err := parseServers(host, b.port)
+		if err != nil {
+			return "", err
+		}
+		params = url.Values{}
+		for k, v := range b.params {
+			params[k] = v
+		}
+		params.Set(Server, formatServers(servers))
+		host, port = servers[0].Host, 0
+	} else if i := strings.IndexByte(host, '\\'); i >= 0 {
+		host, instance = host[:i], host[i+1:]
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	if port > 0 {
+		host = net.JoinHostPort(host, strconv.FormatUint(port, 10))
+	} else if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		host = "[" + host + "]"
+	}
+	u := url.URL{
+		Scheme:   "sqlserver",
+		Host:     host,
+		RawQuery: params.Encode(),
+	}
+	if instance != "" {
+		u.Path = "/" + instance
+	}
+	if b.hasLogin {
+		u.User = url.UserPassword(b.user, b.password)
+	}
+	dsn := u.String()
+	if _, err := Parse(dsn); err != nil {
+		return "", err
+	}
+	return dsn, nil
+}
+
+// Config returns the parsed configuration of the connection string.
+func (b *ConnStringBuilder) Config() (Config, error) {
+	dsn, err := b.Build()
+	if err != nil {
+		return Config{}, err
+	}
+	return Parse(dsn)
+}
+
+// tlsParameters are the parameters that have no effect without encryption.
+var tlsParameters = []string{Certificate, HostNameInCertificate, TLSServerName, TLSMin, ClientCertificate, ClientKey}
+
+// passwordlessFedAuth are the federated authentication workflows of the
+// azuread package that do not take a password.
+var passwordlessFedAuth = map[string]bool{
+	"activedirectoryintegrated":      true,
+	"activedirectoryinteractive":     true,
+	"activedirectorydefault":         true,
+	"activedirectorymsi":             true,
+	"activedirectorymanagedidentity": true,
+	"activedirectorydevicecode":      true,
+	"activedirectoryazcli":           true,
+}
+
+// validate reports parameters that exclude each other, which Parse ignores.
+func (b *ConnStringBuilder) validate() error {
+	fedAuth := b.params.Get("fedauth")
+	if fedAuth != "" && b.params.Get("authenticator") != "" {
+		return fmt.Errorf("fedauth and authenticator cannot be used together")
+	}
+	if passwordlessFedAuth[strings.ToLower(fedAuth)] && b.password != "" {
+		return fmt.Errorf("fedauth %s does not use a password", fedAuth)
+	}
+	if b.params.Get(ChangePassword) != "" && b.password == "" {
+		return fmt.Errorf("%s requires the current password", ChangePassword)
+	}
+	if err := b.validateAuthenticator(); err != nil {
+		return err
+	}
+	if strings.EqualFold(b.params.Get(Encrypt), "disable") {
+		for _, key := range tlsParameters {
+			if _, ok := b.params[key]; ok {
+				return fmt.Errorf("%s cannot be used with encrypt=disable", key)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAuthenticator reports a user id and password that the integrated
+// authenticator of the authenticator parameter cannot log in with.
+func (b *ConnStringBuilder) validateAuthenticator() error {
+	authenticator := b.params.Get("authenticator")
+	if authenticator == "" {
+		return nil
+	}
+	if b.params.Get(ChangePassword) != "" {
+		return fmt.Errorf("%s cannot be used with authenticator %s, it changes the password of a SQL Server login", ChangePassword, authenticator)
+	}
+	switch authenticator {
+	case "ntlm":
+		if !strings.ContainsRune(b.user, '\\') || b.password == "" {
+			return fmt.Errorf("authenticator ntlm requires a user id of the form domain\\user and its password")
+		}
+	case "winsspi":
+		if b.user == "" && b.password != "" {
+			return fmt.Errorf("authenticator winsspi without a user id logs in as the current Windows user and does not use a password")
+		}
+		if b.user != "" && !strings.ContainsRune(b.user, '\\') {
+			return fmt.Errorf("authenticator winsspi requires a user id of the form domain\\user")
+		}
+	case "krb5":
+		keytab := b.params.Get("krb5-keytabfile")
+		switch {
+		case b.password != "" && b.user == "":
+			return fmt.Errorf("authenticator krb5 with a password requires the user id")
+		case b.password != "" && keytab != "":
+			return fmt.Errorf("krb5-keytabfile cannot be used with a password, the password is used instead")
+		case b.password != "" && b.params.Get("krb5-configfile") == "":
+			return fmt.Errorf("authenticator krb5 with a password requires krb5-configfile")
+		case keytab != "" && b.user == "":
+			return fmt.Errorf("krb5-keytabfile requires the user id")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package msdsn
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConnStringBuilderRoundTrip(t *testing.T) {
+	password := `p;a{s}s=w"o'r@d:/?#% `
+	b := NewConnStringBuilder(`db.example.com\prod`).
+		Port(1434).
+		Login(`DOMAIN\app;user`, password).
+		Database("orders;archive").
+		Set("App", "billing & invoicing").
+		Set(Encrypt, "true").
+		Set(LogParam, "3")
+	dsn, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Host != "db.example.com" || p.Instance != "prod" || p.Port != 1434 {
+		t.Errorf("unexpected server %s\\%s:%d", p.Host, p.Instance, p.Port)
+	}
+	if p.User != `DOMAIN\app;user` || p.Password != password {
+		t.Errorf("unexpected login %q %q", p.User, p.Password)
+	}
+	if p.Database != "orders;archive" || p.AppName != "billing & invoicing" || p.Encryption != EncryptionRequired || p.LogFlags != 3 {
+		t.Errorf("unexpected parameters of %s", dsn)
+	}
+}
+
+func TestConnStringBuilderServers(t *testing.T) {
+	tests := []struct {
+		builder *ConnStringBuilder
+		host    string
+		port    uint64
+	}{
+		{NewConnStringBuilder(""), "localhost", 0},
+		{NewConnStringBuilder("::1").Port(1433), "::1", 1433},
+		{NewConnStringBuilder("fe80::1"), "fe80::1", 0},
+		{NewConnStringBuilder("a").Set("Data Source", "b").Set("port", "1500"), "b", 1500},
+	}
+	for _, test := range tests {
+		p, err := test.builder.Config()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Host != test.host || p.Port != test.port {
+			t.Errorf("expected %s:%d, got %s:%d", test.host, test.port, p.Host, p.Port)
+		}
+	}
+}
+
+func TestConnStringBuilderServerList(t *testing.T) {
+	tests := []struct {
+		builder *ConnStringBuilder
+		servers []ServerAddress
+	}{
+		{NewConnStringBuilder(`a:1433,c\inst`), []ServerAddress{{Host: "a", Port: 1433}, {Host: "c", Instance: "inst"}}},
+		{NewConnStringBuilder(`a\i1,b\i2`), []ServerAddress{{Host: "a", Instance: "i1"}, {Host: "b", Instance: "i2"}}},
+		{NewConnStringBuilder(`a,b:1500,c\inst`).Port(1400), []ServerAddress{{Host: "a", Port: 1400}, {Host: "b", Port: 1500}, {Host: "c", Instance: "inst", Port: 1400}}},
+		{NewConnStringBuilder(`fe80::1,[fe80::2]:1500`), []ServerAddress{{Host: "fe80::1"}, {Host: "fe80::2", Port: 1500}}},
+	}
+	for _, test := range tests {
+		dsn, err := test.builder.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := Parse(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.Servers, test.servers) {
+			t.Errorf("expected the servers %v of %s, got %v", test.servers, dsn, p.Servers)
+		}
+		first := test.servers[0]
+		if p.Host != first.Host || p.Instance != first.Instance || p.Port != first.Port {
+			t.Errorf("expected the first server %v of %s, got %s\\%s:%d", first, dsn, p.Host, p.Instance, p.Port)
+		}
+	}
+	if _, err := NewConnStringBuilder("a,1433,b").Build(); err == nil {
+		t.Error("expected an error for a port in the list of servers")
+	}
+}
+
+func TestConnStringBuilderValidation(t *testing.T) {
+	tests := []struct {
+		builder *ConnStringBuilder
+		err     string
+	}{
+		{NewConnStringBuilder("a").Set("fedauth", "ActiveDirectoryPassword").Set("authenticator", "krb5"), "cannot be used together"},
+		{NewConnStringBuilder("a").Set("fedauth", "ActiveDirectoryMSI").Login("client-id", "secret"), "does not use a password"},
+		{NewConnStringBuilder("a").Set(ChangePassword, "new"), "requires the current password"},
+		{NewConnStringBuilder("a").Set(Encrypt, "DISABLE").Set(HostNameInCertificate, "b"), "encrypt=disable"},
+		{NewConnStringBuilder("a").Set(Port, "http"), "invalid tcp port"},
+		{NewConnStringBuilder("a").Set(LogParam, "all"), "invalid log parameter"},
+		{NewConnStringBuilder("a").Set("authenticator", "winsspi").Set(Password, "secret"), "does not use a password"},
+		{NewConnStringBuilder("a").Set("authenticator", "winsspi").Login("app", "secret"), `domain\user`},
+		{NewConnStringBuilder("a").Set("authenticator", "ntlm").Login(`corp\app`, ""), "and its password"},
+		{NewConnStringBuilder("a").Set("authenticator", "ntlm").Login(`corp\app`, "old").Set(ChangePassword, "new"), "cannot be used with authenticator"},
+		{NewConnStringBuilder("a").Set("authenticator", "krb5").Set(Password, "secret"), "requires the user id"},
+		{NewConnStringBuilder("a").Set("authenticator", "krb5").Login("app", "secret").Set("krb5-keytabfile", "k").Set("krb5-configfile", "c"), "cannot be used with a password"},
+		{NewConnStringBuilder("a").Set("authenticator", "krb5").Login("app", "secret"), "requires krb5-configfile"},
+		{NewConnStringBuilder("a").Set("authenticator", "krb5").Set("krb5-keytabfile", "k"), "requires the user id"},
+	}
+	for _, test := range tests {
+		_, err := test.builder.Build()
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected an error with %q, got %v", test.err, err)
+		}
+	}
+	if _, err := NewConnStringBuilder("a").Set("fedauth", "ActiveDirectoryServicePrincipal").Login("client-id", "secret").Build(); err != nil {
+		t.Errorf("expected a service principal with a secret to be valid, got %v", err)
+	}
+	for _, valid := range []*ConnStringBuilder{
+		NewConnStringBuilder("a").Set("authenticator", "winsspi"),
+		NewConnStringBuilder("a").Set("authenticator", "ntlm").Login(`corp\app`, "secret"),
+		NewConnStringBuilder("a").Set("authenticator", "krb5").Login("app", "secret").Set("krb5-configfile", "c"),
+		NewConnStringBuilder("a").Set("authenticator", "krb5").Set(UserID, "app").Set("krb5-keytabfile", "k"),
+		NewConnStringBuilder("a").Set("authenticator", "krb5").Set("krb5-credcachefile", "cc"),
+	} {
+		if _, err := valid.Build(); err != nil {
+			t.Errorf("expected a valid authenticator, got %v", err)
+		}
+	}
+}
@@ -33,3 +33,37 @@ func setupTLSCommonName(config *tls.Config, pem []byte) error {
 	}
 	return nil
 }
+
+// setupTLSServerName sends serverName in the TLS handshake instead of the
+// name the certificate is verified against, which is the current server name
+// of config. Go verifies the certificate against the name it sends, so the
+// verification is done in the VerifyConnection callback instead.
+func setupTLSServerName(config *tls.Config, serverName string) {
+	verifyName := config.ServerName
+	config.ServerName = serverName
+	if config.InsecureSkipVerify && config.VerifyConnection == nil {
+		return
+	}
+	if verify := config.VerifyConnection; verify != nil {
+		// the common name verification compares with the server name of the state
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			cs.ServerName = verifyName
+			return verify(cs)
+		}
+		return
+	}
+	config.InsecureSkipVerify = true
+	roots := config.RootCAs
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		opts := x509.VerifyOptions{
+			DNSName:       verifyName,
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
@@ -10,3 +10,9 @@ func setupTLSCommonName(config *tls.Config, pem []byte) error {
 	// See https://golang.org/issue/40748 for details.
 	return skipSetup
 }
+
+func setupTLSServerName(config *tls.Config, serverName string) {
+	// Prior to Go 1.15, there is no VerifyConnection callback to verify
+	// the certificate against another name than the one sent.
+	config.ServerName = serverName
+}
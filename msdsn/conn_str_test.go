@@ -19,6 +19,25 @@ func TestInvalidConnectionString(t *testing.T) {
 		"packet size=invalid",
 		"connection timeout=invalid",
 		"dial timeout=invalid",
+		"connection lifetime=invalid",
+		"server=a,b;server order=invalid",
+		"server=a,b;server cooldown=invalid",
+		"server=a,,b",
+		"server=a:99999,b",
+		"server=a,1433,b",
+		"connection lifetime jitter=-1",
+		"health check interval=invalid",
+		"dns cache ttl=invalid",
+		"ippreference=IPv5First",
+		"datetime scan=int",
+		"datetime location=Nowhere/Invalid",
+		"server timezone=Nowhere/Invalid",
+		"bit scan=string",
+		"param redaction=partial",
+		"retry reads=sometimes",
+		"inmemoryoltp=maybe",
+		"routing cache ttl=invalid",
+		"user instance=invalid",
 		"keepalive=invalid",
 		"encrypt=invalid",
 		"trustservercertificate=invalid",
@@ -26,6 +45,15 @@ func TestInvalidConnectionString(t *testing.T) {
 		"applicationintent=ReadOnly",
 		"disableretry=invalid",
 		"multisubnetfailover=invalid",
+		"columnencryption=invalid",
+		"ansi nulls=invalid",
+		"xact abort=sometimes",
+		"lock timeout=-2",
+		"deadlock priority=11",
+		"deadlock priority=invalid",
+		"dateformat=invalid",
+		"preparestatements=invalid",
+		"describeparameters=invalid",
 
 		// ODBC mode
 		"odbc:password={",
@@ -93,6 +121,47 @@ func TestValidConnectionString(t *testing.T) {
 		{"connection timeout=3;dial timeout=4;keepalive=5", func(p Config) bool {
 			return p.ConnTimeout == 3*time.Second && p.DialTimeout == 4*time.Second && p.KeepAlive == 5*time.Second
 		}},
+		{"server=a,b:1500,c\\inst;port=1433;server order=random;server cooldown=5", func(p Config) bool {
+			return p.Host == "a" && p.Port == 1433 && p.RandomServerOrder && p.ServerCooldown == 5*time.Second &&
+				reflect.DeepEqual(p.Servers, []ServerAddress{{Host: "a", Port: 1433}, {Host: "b", Port: 1500}, {Host: "c", Instance: "inst", Port: 1433}})
+		}},
+		{"server=a\\inst,b", func(p Config) bool {
+			return p.Host == "a" && p.Instance == "inst" && p.Port == 0 && !p.RandomServerOrder && p.ServerCooldown == 30*time.Second &&
+				reflect.DeepEqual(p.Servers, []ServerAddress{{Host: "a", Instance: "inst"}, {Host: "b"}})
+		}},
+		{`server=(localdb)\MSSQLLocalDB;attachdbfilename=C:\data\app.mdf;user instance=true`, func(p Config) bool {
+			return p.AttachDBFilename == `C:\data\app.mdf` && p.UserInstance
+		}},
+		{`server=.\SQLExpress;initial file name=C:\data\app.mdf`, func(p Config) bool {
+			return p.AttachDBFilename == `C:\data\app.mdf` && !p.UserInstance
+		}},
+		{"ippreference=ipv6first", func(p Config) bool { return p.IPPreference == IPPreferenceIPv6First }},
+		{"ippreference=IPv4First", func(p Config) bool { return p.IPPreference == IPPreferenceIPv4First }},
+		{"server=a", func(p Config) bool { return p.IPPreference == IPPreferencePlatformDefault }},
+		{"dns cache ttl=300", func(p Config) bool { return p.DNSCacheTTL == 5*time.Minute }},
+		{"server=a", func(p Config) bool { return p.DateTimeScan == DateTimeScanTime && p.DateTimeLocation == nil }},
+		{"datetime scan=String;datetime location=UTC", func(p Config) bool {
+			return p.DateTimeScan == DateTimeScanString && p.DateTimeLocation == time.UTC
+		}},
+		{"server timezone=UTC", func(p Config) bool { return p.ServerTimezone == time.UTC }},
+		{"server=a", func(p Config) bool { return p.BitScan == BitScanBool }},
+		{"bit scan=INT", func(p Config) bool { return p.BitScan == BitScanInt }},
+		{"param redaction=Hash", func(p Config) bool { return p.ParamRedaction == ParamRedactionHash }},
+		{"retry reads=true", func(p Config) bool { return p.RetryReads }},
+		{"inmemoryoltp=true", func(p Config) bool { return p.InMemoryOLTP }},
+		{"server=x", func(p Config) bool { return p.ParamRedaction == ParamRedactionNone }},
+		{"server=a,1500", func(p Config) bool { return p.Host == "a" && p.Port == 1500 && len(p.Servers) == 0 }},
+		{"sqlserver://a,b:1500?server+order=random", func(p Config) bool {
+			return p.Host == "a" && p.Port == 1500 && p.RandomServerOrder &&
+				reflect.DeepEqual(p.Servers, []ServerAddress{{Host: "a", Port: 1500}, {Host: "b", Port: 1500}})
+		}},
+		{"sqlserver://a:1400,b:1500", func(p Config) bool {
+			return p.Host == "a" && p.Port == 1400 &&
+				reflect.DeepEqual(p.Servers, []ServerAddress{{Host: "a", Port: 1400}, {Host: "b", Port: 1500}})
+		}},
+		{"connection lifetime=300;connection lifetime jitter=30;health check interval=60", func(p Config) bool {
+			return p.ConnectionLifetime == 300*time.Second && p.LifetimeJitter == 30*time.Second && p.HealthCheckInterval == time.Minute
+		}},
 		{"log=63", func(p Config) bool { return p.LogFlags == 63 && p.Port == 0 }},
 		{"log=63;port=1000", func(p Config) bool { return p.LogFlags == 63 && p.Port == 1000 }},
 		{"log=64", func(p Config) bool { return p.LogFlags == 64 }},
@@ -100,11 +169,26 @@ func TestValidConnectionString(t *testing.T) {
 		{"log=64;packet size=300", func(p Config) bool { return p.LogFlags == 64 && p.PacketSize == 512 }},
 		{"log=64;packet size=8192", func(p Config) bool { return p.LogFlags == 64 && p.PacketSize == 8192 }},
 		{"log=64;packet size=48000", func(p Config) bool { return p.LogFlags == 64 && p.PacketSize == 32767 }},
+		{"log=64;packet size=70000", func(p Config) bool { return p.LogFlags == 64 && p.PacketSize == 32767 }},
 		{"disableretry=true", func(p Config) bool { return p.DisableRetry }},
 		{"disableretry=false", func(p Config) bool { return !p.DisableRetry }},
 		{"disableretry=1", func(p Config) bool { return p.DisableRetry }},
 		{"disableretry=0", func(p Config) bool { return !p.DisableRetry }},
 		{"", func(p Config) bool { return p.DisableRetry == disableRetryDefault }},
+		{"ansi nulls=true;ansi warnings=1;arithabort=false;quoted identifier=true", func(p Config) bool {
+			return reflect.DeepEqual(p.SessionOptions, []string{"SET ANSI_NULLS ON", "SET ANSI_WARNINGS ON", "SET ARITHABORT OFF", "SET QUOTED_IDENTIFIER ON"})
+		}},
+		{"lock timeout=5000;deadlock priority=low;dateformat=YMD", func(p Config) bool {
+			return reflect.DeepEqual(p.SessionOptions, []string{"SET LOCK_TIMEOUT 5000", "SET DEADLOCK_PRIORITY LOW", "SET DATEFORMAT ymd"})
+		}},
+		{"xact abort=true", func(p Config) bool { return reflect.DeepEqual(p.SessionOptions, []string{"SET XACT_ABORT ON"}) }},
+		{"deadlock priority=-5", func(p Config) bool { return reflect.DeepEqual(p.SessionOptions, []string{"SET DEADLOCK_PRIORITY -5"}) }},
+		{"", func(p Config) bool { return p.SessionOptions == nil }},
+		{"columnencryption=enabled", func(p Config) bool { return p.ColumnEncryption }},
+		{"columnencryption=disabled", func(p Config) bool { return !p.ColumnEncryption }},
+		{"preparestatements=true", func(p Config) bool { return p.PrepareStatements }},
+		{"describeparameters=true", func(p Config) bool { return p.DescribeParameters }},
+		{"server=.", func(p Config) bool { return !p.PrepareStatements }},
 		{"MultiSubnetFailover=true", func(p Config) bool { return p.MultiSubnetFailover }},
 		{"MultiSubnetFailover=false", func(p Config) bool { return !p.MultiSubnetFailover }},
 
@@ -217,7 +301,7 @@ func TestSplitConnectionStringURL(t *testing.T) {
 }
 
 func TestConnParseRoundTripFixed(t *testing.T) {
-	connStr := "sqlserver://sa:sa@localhost/sqlexpress?database=master&log=127&disableretry=true&dial+timeout=30"
+	connStr := "sqlserver://sa:sa@localhost/sqlexpress?database=master&log=127&disableretry=true&dial+timeout=30&packet+size=16383&columnencryption=true"
 	params, err := Parse(connStr)
 	if err != nil {
 		t.Fatal("Test URL is not valid", err)
@@ -231,6 +315,48 @@ func TestConnParseRoundTripFixed(t *testing.T) {
 	}
 }
 
+func TestConnParseRoundTripServers(t *testing.T) {
+	connStr := "sqlserver://sa:sa@a:1400,b,c:1600?server+order=random&server+cooldown=10"
+	params, err := Parse(connStr)
+	if err != nil {
+		t.Fatal("Test URL is not valid", err)
+	}
+	rtParams, err := Parse(params.URL().String())
+	if err != nil {
+		t.Fatal("Params after roundtrip are not valid", err)
+	}
+	if !reflect.DeepEqual(params.Servers, rtParams.Servers) || !rtParams.RandomServerOrder || rtParams.ServerCooldown != 10*time.Second {
+		t.Fatal("Servers do not match after roundtrip", params.Servers, rtParams.Servers)
+	}
+}
+
+func TestConnParseRoundTripServerList(t *testing.T) {
+	for _, connStr := range []string{
+		`server=a:1433,c\inst`,
+		`server=a\i1,b\i2`,
+		`server=a,b:1500,c\inst:1600;port=1400`,
+		`server=c\inst,a:1433,[fe80::1]:1500,fe80::2`,
+		`sqlserver://sa:sa@?server=a%3A1433%2Cc%5Cinst`,
+	} {
+		params, err := Parse(connStr)
+		if err != nil {
+			t.Fatalf("%s is not valid: %v", connStr, err)
+		}
+		u := params.URL().String()
+		rtParams, err := Parse(u)
+		if err != nil {
+			t.Fatalf("%s after roundtrip of %s is not valid: %v", u, connStr, err)
+		}
+		if len(params.Servers) < 2 || !reflect.DeepEqual(params.Servers, rtParams.Servers) {
+			t.Errorf("servers of %s do not match after roundtrip, %v and %v", connStr, params.Servers, rtParams.Servers)
+		}
+		if params.Host != rtParams.Host || params.Instance != rtParams.Instance || params.Port != rtParams.Port {
+			t.Errorf("first server of %s does not match after roundtrip, %s\\%s:%d and %s\\%s:%d", connStr,
+				params.Host, params.Instance, params.Port, rtParams.Host, rtParams.Instance, rtParams.Port)
+		}
+	}
+}
+
 func TestServerNameInTLSConfig(t *testing.T) {
 	var tests = []struct {
 		dsn          string
@@ -323,3 +449,27 @@ func TestReadCertificate(t *testing.T) {
 	assert.NotNil(t, err, "Expected error while reading certificate, found nil")
 	assert.Nil(t, cert, "Expected certificate to be nil, found %v", cert)
 }
+
+func TestAppNameAndWorkstation(t *testing.T) {
+	p, err := Parse("server=somehost;app=inventory-api;wsid=node-7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AppName != "inventory-api" {
+		t.Errorf("expected app name inventory-api, got %s", p.AppName)
+	}
+	if p.Workstation != "node-7" {
+		t.Errorf("expected workstation node-7, got %s", p.Workstation)
+	}
+
+	p, err = Parse("sqlserver://somehost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AppName != "go-mssqldb" {
+		t.Errorf("expected default app name go-mssqldb, got %s", p.AppName)
+	}
+	if hostname, err := os.Hostname(); err == nil && p.Workstation != hostname {
+		t.Errorf("expected workstation to default to the host name %s, got %s", hostname, p.Workstation)
+	}
+}
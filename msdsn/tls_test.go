@@ -0,0 +1,165 @@
+//go:build go1.15
+// +build go1.15
+
+package msdsn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCertificate writes a self-signed certificate for host and its key
+// to PEM files and returns the files and the certificate for a TLS server.
+func newTestCertificate(t *testing.T, host string) (string, string, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	file, keyFile := filepath.Join(dir, host+".pem"), filepath.Join(dir, host+".key")
+	if err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file, keyFile, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// handshake runs a TLS handshake of a client with config against a server
+// with cert and returns the server name the client sent. The server
+// requires a client certificate signed by clientCA, if set.
+func handshake(config *tls.Config, cert tls.Certificate, clientCA *x509.Certificate) (string, error) {
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if clientCA != nil {
+		serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		serverConfig.ClientCAs = x509.NewCertPool()
+		serverConfig.ClientCAs.AddCert(clientCA)
+	}
+	sni := make(chan string, 1)
+	serverConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		sni <- hello.ServerName
+		return nil, nil
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- tls.Server(conn, serverConfig).Handshake()
+	}()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	client := tls.Client(conn, config)
+	err = client.Handshake()
+	if err == nil {
+		// with TLS 1.3 the server rejects the client certificate after the
+		// client finished its handshake
+		err = <-serverErr
+	} else {
+		conn.Close()
+		<-serverErr
+	}
+	return <-sni, err
+}
+
+func TestTLSServerName(t *testing.T) {
+	file, _, cert := newTestCertificate(t, "db.example.com")
+	tests := []struct {
+		dsn   string
+		sni   string
+		valid bool
+	}{
+		{"server=127.0.0.1;encrypt=true;certificate=" + file + ";hostnameincertificate=db.example.com", "db.example.com", true},
+		{"server=127.0.0.1;encrypt=true;certificate=" + file + ";tlsservername=db.example.com", "db.example.com", true},
+		{"server=127.0.0.1;encrypt=true;certificate=" + file + ";hostnameincertificate=db.example.com;tlsservername=pe.example.net", "pe.example.net", true},
+		{"server=127.0.0.1;encrypt=true;certificate=" + file + ";hostnameincertificate=other.example.com;tlsservername=db.example.com", "db.example.com", false},
+		{"server=127.0.0.1;encrypt=true;certificate=" + file + ";tlsservername=pe.example.net", "pe.example.net", false},
+	}
+	for _, test := range tests {
+		p, err := Parse(test.dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.HostInCertificateProvided {
+			t.Errorf("%s: expected the server name not to be replaced by routing", test.dsn)
+		}
+		sni, err := handshake(p.TLSConfig, cert, nil)
+		if sni != test.sni {
+			t.Errorf("%s: expected the server name %s, got %s", test.dsn, test.sni, sni)
+		}
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%s: expected valid %v, got %v", test.dsn, test.valid, err)
+		}
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	file, _, cert := newTestCertificate(t, "db.example.com")
+	clientFile, clientKey, clientCert := newTestCertificate(t, "app.example.com")
+	clientCA, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn := "server=db.example.com;encrypt=true;certificate=" + file
+	p, err := Parse(dsn + ";clientcertificate=" + clientFile + ";clientkey=" + clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = handshake(p.TLSConfig, cert, clientCA); err != nil {
+		t.Errorf("expected the client certificate to be accepted, got %v", err)
+	}
+	p, err = Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = handshake(p.TLSConfig, cert, clientCA); err == nil {
+		t.Error("expected the handshake without a client certificate to fail")
+	}
+	for _, params := range []string{";clientcertificate=" + clientFile, ";clientkey=" + clientKey, ";clientcertificate=" + file + ";clientkey=" + clientKey} {
+		if _, err = Parse(dsn + params); err == nil {
+			t.Errorf("expected %s to be invalid", params)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package mssql
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/binary"
@@ -13,6 +14,7 @@ import (
 	"net"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -29,6 +31,43 @@ import (
 //	log.Printf("return status = %d", rs)
 type ReturnStatus int32
 
+// RowCounts may be used to get the number of rows affected by each
+// statement of a batch executed with Exec, in the order the statements ran.
+// Statements that do not report a row count, like SET NOCOUNT ON, are not included.
+//
+//	var counts mssql.RowCounts
+//	_, err := db.Exec("update t1 set x = 1; delete from t2", &counts)
+//	log.Printf("updated %d, deleted %d", counts[0], counts[1])
+type RowCounts []int64
+
+// LastInsertId may be passed as an argument to Exec to make Result.LastInsertId
+// return the value of SCOPE_IDENTITY() after the statement ran.
+// A select of SCOPE_IDENTITY() is appended to the query text, so it cannot
+// be used when the query is a stored procedure name.
+//
+//	res, err := db.Exec("insert into foo (baz) values (@p1)", 1, mssql.LastInsertId{})
+//	id, err := res.LastInsertId()
+type LastInsertId struct{}
+
+// NoQueryTimeout disables the connection timeout for a single query.
+// Pass it into the parameters of a query that waits longer than the
+// connection timeout, like WAITFOR (RECEIVE ...) or WAITFOR DELAY.
+// Use the context of the query to limit how long it runs.
+type NoQueryTimeout struct{}
+
+// appended to the query text when LastInsertId is passed to Exec
+const lastInsertIdQuery = "\n;select convert(bigint, SCOPE_IDENTITY())"
+
+// errPreparedHandleNotFound is the number of the error
+// "Could not find prepared statement with handle %d."
+const errPreparedHandleNotFound = 8179
+
+// errPreparedHandleInvalid is the number of the error "The prepared statement
+// handle %d is not valid in this context.", raised when the database, the default
+// schema or the ANSI_NULLS or QUOTED_IDENTIFIER settings changed since the
+// statement was prepared.
+const errPreparedHandleInvalid = 586
+
 var driverInstance = &Driver{processQueryText: true}
 var driverInstanceNoProcess = &Driver{processQueryText: false}
 var tcpDialerInstance *tcpDialer = &tcpDialer{}
@@ -159,6 +198,10 @@ func newConnector(config msdsn.Config, driver *Driver) *Connector {
 // In the future, settings that cannot be passed through a string DSN
 // may be set directly on the connector.
 type Connector struct {
+	// stats aggregates the statistics of the connections of the Connector.
+	// It is the first field to keep its counters 64-bit aligned for atomic access.
+	stats statsCounters
+
 	params msdsn.Config
 	driver *Driver
 
@@ -201,7 +244,116 @@ type Connector struct {
 	// If Dialer is not set, normal net dialers are used.
 	Dialer Dialer
 
+	// MessageHandler, when set, is called with every informational message
+	// the server sends, such as PRINT output, RAISERROR with a severity of 10
+	// or lower and DBCC progress messages, as soon as the message is received.
+	// Together with RAISERROR ... WITH NOWAIT this reports progress of long
+	// running batches while they run.
+	//
+	// MessageHandler is called on the goroutine that reads the server
+	// response, it should return quickly and must not use the connection.
+	MessageHandler func(ctx context.Context, msg Error)
+
+	// DatabaseChanged, when set, is called when the server reports a change
+	// of the current database of a session, during login and after a USE
+	// statement. Like MessageHandler it is called on the goroutine that reads
+	// the server response.
+	DatabaseChanged func(ctx context.Context, oldDatabase, newDatabase string)
+
+	// HealthCheckSQL is the query that checks a pooled connection that was idle
+	// longer than the health check interval of the connection string before
+	// it is used again. It defaults to "select 1".
+	HealthCheckSQL string
+
+	// EventLogger, when set, receives structured events of the connections,
+	// such as logins with their duration, failovers, redirects, retries and
+	// warnings about unexpected server behavior.
+	EventLogger EventLogger
+
+	// FaultInjector, when set, injects faults into the connections. Use it in tests only.
+	FaultInjector *FaultInjector
+
+	// TraceWriter, when set, receives a trace of the network traffic of the
+	// connections at TraceLevel, to debug protocol issues without a packet sniffer.
+	// Login passwords, authentication tokens and parameter values are redacted.
+	TraceWriter io.Writer
+	TraceLevel  TraceLevel
+
+	// OnConnect, when set, is called after each physical connection was
+	// established and logged in, before it is added to the connection pool,
+	// to set up the connection with the Session, e.g. for SET statements or
+	// CONTEXT_INFO. When it fails the connection is closed and Connect fails.
+	// It is called once per physical connection, see OnReset.
+	OnConnect func(ctx context.Context, s *Session) error
+
+	// OnReset, when set, is called when the pool hands out a used connection,
+	// after its session was reset with sp_reset_connection, which drops its
+	// temporary tables and clears its CONTEXT_INFO and SESSION_CONTEXT, to set
+	// up that state again. It can be the same function as OnConnect. The first
+	// statement it executes applies the reset. When it fails the connection
+	// is discarded by the pool.
+	OnReset func(ctx context.Context, s *Session) error
+
+	// OnClose, when set, is called before a connection that is not broken
+	// is closed, e.g. to clean up objects it created on the server.
+	OnClose func(ctx context.Context, s *Session) error
+
+	// PasswordProvider, when set, is called for every new connection to get
+	// the password of the login, instead of the password of the connection
+	// string. Use it with secrets that are rotated by a vault, so new
+	// connections of the pool use the current password without a restart.
+	// Tokens of federated authentication are requested for every new
+	// connection by the connectors of NewSecurityTokenConnector and
+	// NewActiveDirectoryTokenConnector already.
+	PasswordProvider func(ctx context.Context) (string, error)
+
+	// NewPassword, when set, changes the password of the SQL Server login at
+	// the next login, for logins created with MUST_CHANGE or whose password
+	// expired. It takes precedence over the change password parameter of the
+	// connection string. Once the password was changed, new connections of
+	// the Connector log in with it.
+	NewPassword string
+
+	// ClientCertificate, when set, is sent to the server in the TLS handshake
+	// for mutual TLS, e.g. with a TLS terminating proxy that authenticates
+	// clients. It takes precedence over the clientcertificate and clientkey
+	// files of the connection string.
+	ClientCertificate *tls.Certificate
+
+	// DeadlockGraphTimeout, when set, makes a deadlock error (1205) wait up to
+	// this long for the deadlock graph of the system_health Extended Events
+	// session and attach it to Error.DeadlockGraph, within the deadline of the
+	// context of the statement. The graph is read over a separate connection
+	// that the Connector keeps for the next deadlocks until Close, the login
+	// needs the VIEW SERVER STATE permission.
+	DeadlockGraphTimeout time.Duration
+
+	// QueryLabels, when set, returns labels for the statements executed with
+	// ctx as pairs of keys and values, like WithQueryLabels, e.g. the name of
+	// the application and the trace id of the OpenTelemetry span of ctx.
+	// The labels of ctx are added after them.
+	QueryLabels func(ctx context.Context) []string
+
 	keyProviders aecmk.ColumnEncryptionKeyProviderMap
+
+	// routes are the cached routes of read-only logins.
+	routes routingCache
+
+	// elevateChecked is set to 1 once a connection of the Connector checked
+	// MEMORY_OPTIMIZED_ELEVATE_TO_SNAPSHOT for the inmemoryoltp parameter.
+	elevateChecked uint32
+
+	// passwordMu guards changedPassword, the password that a login of the
+	// Connector changed the password replacedPassword of the login to.
+	passwordMu       sync.Mutex
+	replacedPassword string
+	changedPassword  string
+
+	// deadlockMu guards deadlockConn, the idle connection that reads the
+	// deadlock graphs, and deadlockClosed, set by Close.
+	deadlockMu     sync.Mutex
+	deadlockConn   *Conn
+	deadlockClosed bool
 }
 
 type Dialer interface {
@@ -224,6 +376,9 @@ func (c *Connector) getDialer(p *msdsn.Config) Dialer {
 
 // RegisterCekProvider associates the given provider with the named key store. If an entry of the given name already exists, that entry is overwritten
 func (c *Connector) RegisterCekProvider(name string, provider aecmk.ColumnEncryptionKeyProvider) {
+	if c.keyProviders == nil {
+		c.keyProviders = make(aecmk.ColumnEncryptionKeyProviderMap)
+	}
 	c.keyProviders[name] = aecmk.NewCekProvider(provider)
 }
 
@@ -233,8 +388,28 @@ type Conn struct {
 	transactionCtx context.Context
 	resetSession   bool
 
-	processQueryText bool
-	connectionGood   bool
+	processQueryText  bool
+	connectionGood    bool
+	prepareStatements bool
+	// describeParameters declares parameters with the types from sp_describe_undeclared_parameters
+	describeParameters bool
+	// paramTypes caches the described parameter types by database and query text
+	paramTypes map[describedQuery]map[string]string
+	// resets counts the session resets, a reset unprepares the statements of the session
+	resets int
+	// expires is the end of the lifetime of the connection, zero if it does not expire
+	expires time.Time
+	// dac is the server of a dedicated administrator connection, empty for other connections
+	dac string
+	// dateTimeScan, dateTimeLocation and serverTimezone are the
+	// datetime scan, datetime location and server timezone settings
+	dateTimeScan     string
+	dateTimeLocation *time.Location
+	serverTimezone   *time.Location
+	// bitScan is the bit scan setting
+	bitScan string
+	// paramRedaction is the param redaction setting
+	paramRedaction string
 
 	outs outputs
 }
@@ -242,12 +417,64 @@ type Conn struct {
 type outputs struct {
 	params       map[string]interface{}
 	returnStatus *ReturnStatus
+	rowCounts    *RowCounts
+	lastInsertId bool
 	msgq         *sqlexp.ReturnMessage
+	// prepareHandle receives the handle returned by sp_prepexec
+	prepareHandle  *int32
+	notifSub       *queryNotifSub
+	noTimeout      bool
+	queryHints     *QueryHints
+	batch          *Batch
+	canceler       *Canceler
+	readAhead      int
+	decodeColumns  DecodeColumns
+	maxRows        int64
+	maxBytes       int64
+	nonIdempotent  bool
+	retryConflicts bool
+	statistics     *QueryStatistics
+	backup         *backupMonitor
+	columnAttrs    *ColumnAttributes
+	// queryLabel is the comment of the labels sent in front of the query
+	queryLabel string
+}
+
+// Database returns the current database of the session, as last reported by the server.
+// Use it through sql.Conn.Raw to check which database a pooled connection points at.
+func (c *Conn) Database() string {
+	return c.sess.database
+}
+
+// Language returns the current language of the session, as last reported by the server.
+func (c *Conn) Language() string {
+	return c.sess.language
+}
+
+// SPID returns the server process id of the session, the session_id in
+// sys.dm_exec_sessions, that can be passed to KILL.
+// It is zero before the first response is received from the server.
+func (c *Conn) SPID() int {
+	return int(c.sess.buf.rSpid)
+}
+
+// UTF8Support reports whether the server acknowledged the UTF-8 support of
+// the driver at login. Servers from SQL Server 2019 on send the varchar
+// values of UTF-8 collations as UTF-8 then.
+func (c *Conn) UTF8Support() bool {
+	return c.sess.utf8Support
+}
+
+// PacketSize returns the TDS packet size negotiated with the server.
+func (c *Conn) PacketSize() int {
+	return c.sess.buf.PackageSize()
 }
 
 // IsValid satisfies the driver.Validator interface.
+// A connection past its lifetime and a dedicated administrator connection
+// are not valid, database/sql closes them instead of returning them to the pool.
 func (c *Conn) IsValid() bool {
-	return c.connectionGood
+	return c.connectionGood && !c.expired(time.Now()) && c.dac == ""
 }
 
 // checkBadConn marks the connection as bad based on the characteristics
@@ -271,19 +498,30 @@ func (c *Conn) checkBadConn(ctx context.Context, err error, mayRetry bool) error
 		panic("driver.ErrBadConn in checkBadConn. This should not happen.")
 	}
 
-	switch err.(type) {
+	switch e := err.(type) {
 	case net.Error:
 		c.connectionGood = false
 	case StreamError:
 		c.connectionGood = false
 	case ServerError:
 		c.connectionGood = false
+	case Error:
+		// the server closes the session after a fatal error, even when
+		// it was not reported with the DONE_SRVERROR status
+		if e.IsFatal() {
+			c.connectionGood = false
+		}
+	}
+
+	if e, ok := err.(Error); ok && e.Number == errDeadlockVictim {
+		err = c.attachDeadlockGraph(ctx, e)
 	}
 
 	if !c.connectionGood && mayRetry && !c.connector.params.DisableRetry {
 		if c.sess.logFlags&logRetries != 0 {
 			c.sess.logger.Log(ctx, msdsn.LogRetries, err.Error())
 		}
+		c.logEvent(ctx, Event{Type: EventRetry, Err: err, Message: err.Error()})
 		return newRetryableError(err)
 	}
 
@@ -310,6 +548,10 @@ func (c *Conn) Commit() error {
 	if !c.connectionGood {
 		return driver.ErrBadConn
 	}
+	if c.sess.tranid == 0 {
+		// the server already rolled back the transaction
+		return ErrTransactionRolledBack
+	}
 	if err := c.sendCommitRequest(); err != nil {
 		return c.checkBadConn(c.transactionCtx, err, true)
 	}
@@ -337,6 +579,10 @@ func (c *Conn) Rollback() error {
 	if !c.connectionGood {
 		return driver.ErrBadConn
 	}
+	if c.sess.tranid == 0 {
+		// nothing to roll back, the server already rolled back the transaction
+		return nil
+	}
 	if err := c.sendRollbackRequest(); err != nil {
 		return c.checkBadConn(c.transactionCtx, err, true)
 	}
@@ -368,6 +614,9 @@ func (c *Conn) begin(ctx context.Context, tdsIsolation isoLevel) (tx driver.Tx,
 	if !c.connectionGood {
 		return nil, driver.ErrBadConn
 	}
+	if err = c.useContextDatabase(ctx); err != nil {
+		return nil, err
+	}
 	err = c.sendBeginRequest(ctx, tdsIsolation)
 	if err != nil {
 		return nil, c.checkBadConn(ctx, err, true)
@@ -412,13 +661,50 @@ func (d *Driver) open(ctx context.Context, dsn string) (*Conn, error) {
 		return nil, err
 	}
 	c := newConnector(params, nil)
-	return d.connect(ctx, c, params)
+	conn, err := d.connect(ctx, c, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.SessionOptions) > 0 {
+		// apply the session options of the connection string
+		if err = conn.ResetSession(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
 }
 
 // connect to the server, using the provided context for dialing only.
 func (d *Driver) connect(ctx context.Context, c *Connector, params msdsn.Config) (*Conn, error) {
+	if !isDAC(params) {
+		return d.connectServer(ctx, c, params)
+	}
+	key, err := acquireDAC(params)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.connectServer(ctx, c, params)
+	if err != nil {
+		releaseDAC(key)
+		return nil, err
+	}
+	conn.dac = key
+	return conn, nil
+}
+
+func (d *Driver) connectServer(ctx context.Context, c *Connector, params msdsn.Config) (*Conn, error) {
+	start := time.Now()
+	if len(params.Servers) > 1 {
+		sess, err := d.connectServers(ctx, c, params)
+		if err != nil {
+			return nil, err
+		}
+		return d.newConn(ctx, c, sess, params, start), nil
+	}
 	sess, err := connect(ctx, c, d.logger, params)
 	if err != nil {
+		c.logEvent(ctx, Event{Type: EventConnectFailed, Server: serverName(params), Duration: time.Since(start), Err: err, Message: err.Error()})
 		// main server failed, try fail-over partner
 		if params.FailOverPartner == "" {
 			return nil, err
@@ -428,26 +714,46 @@ func (d *Driver) connect(ctx context.Context, c *Connector, params msdsn.Config)
 		if params.FailOverPort != 0 {
 			params.Port = params.FailOverPort
 		}
+		c.logEvent(ctx, Event{Type: EventFailover, Server: serverName(params), Message: "connecting to the failover partner"})
 
+		start = time.Now()
 		sess, err = connect(ctx, c, d.logger, params)
 		if err != nil {
 			// fail-over partner also failed, now fail
+			c.logEvent(ctx, Event{Type: EventConnectFailed, Server: serverName(params), Duration: time.Since(start), Err: err, Message: err.Error()})
 			return nil, err
 		}
 	}
+	return d.newConn(ctx, c, sess, params, start), nil
+}
 
-	conn := &Conn{
-		connector:        c,
-		sess:             sess,
-		transactionCtx:   context.Background(),
-		processQueryText: d.processQueryText,
-		connectionGood:   true,
-	}
+func (d *Driver) newConn(ctx context.Context, c *Connector, sess *tdsSession, params msdsn.Config, start time.Time) *Conn {
+	c.logEvent(ctx, Event{Type: EventConnected, Server: sess.server, SPID: int(sess.buf.rSpid), Duration: time.Since(start), Message: "logged in"})
 
-	return conn, nil
+	return &Conn{
+		connector:          c,
+		sess:               sess,
+		transactionCtx:     context.Background(),
+		processQueryText:   d.processQueryText,
+		connectionGood:     true,
+		prepareStatements:  params.PrepareStatements,
+		describeParameters: params.DescribeParameters,
+		expires:            connectionExpiry(time.Now(), params),
+		dateTimeScan:       params.DateTimeScan,
+		dateTimeLocation:   params.DateTimeLocation,
+		serverTimezone:     params.ServerTimezone,
+		bitScan:            params.BitScan,
+		paramRedaction:     params.ParamRedaction,
+	}
 }
 
 func (c *Conn) Close() error {
+	c.onClose()
+	c.logEvent(context.Background(), Event{Type: EventDisconnected, Message: "connection closed"})
+	if c.dac != "" {
+		releaseDAC(c.dac)
+		c.dac = ""
+	}
 	c.sess.buf.bufClose()
 	return c.sess.buf.transport.Close()
 }
@@ -458,6 +764,15 @@ type Stmt struct {
 	paramCount     int
 	notifSub       *queryNotifSub
 	skipEncryption bool
+	// paramTypes are the declared types of the parameters by name, see describeParams
+	paramTypes map[string]string
+
+	// handle of the statement prepared on the server, 0 if it is not prepared
+	handle int32
+	// parameter declarations and session reset count the handle was prepared with
+	handleDecls  string
+	handleResets int
+	executions   int
 }
 
 type queryNotifSub struct {
@@ -481,22 +796,40 @@ func (c *Conn) prepareContext(ctx context.Context, query string) (*Stmt, error)
 	if c.processQueryText {
 		query, paramCount = querytext.ParseParams(query)
 	}
-	return &Stmt{c, query, paramCount, nil, false}, nil
+	return &Stmt{c: c, query: query, paramCount: paramCount}, nil
 }
 
 func (s *Stmt) Close() error {
+	if s.handle == 0 || s.handleResets != s.c.resets || !s.c.connectionGood {
+		return nil
+	}
+	handle := s.handle
+	s.handle = 0
+	if err := s.c.sendUnprepareRequest(handle); err != nil {
+		return s.c.checkBadConn(context.Background(), err, false)
+	}
+	return s.c.simpleProcessResp(context.Background())
+}
+
+func (c *Conn) sendUnprepareRequest(handle int32) error {
+	headers := []headerStruct{
+		{hdrtype: dataStmHdrTransDescr,
+			data: transDescrHdr{c.sess.tranid, 1}.pack()},
+	}
+	reset := c.resetSession
+	c.resetSession = false
+	if err := sendRpc(c.sess.buf, headers, sp_Unprepare, 0, []param{makeHandleParam(handle)}, reset); err != nil {
+		if c.sess.logFlags&logErrors != 0 {
+			c.sess.logger.Log(context.Background(), msdsn.LogErrors, fmt.Sprintf("Failed to send Rpc with %v", err))
+		}
+		c.connectionGood = false
+		return fmt.Errorf("failed to send RPC: %v", err)
+	}
 	return nil
 }
 
 func (s *Stmt) SetQueryNotification(id, options string, timeout time.Duration) {
-	// 2.2.5.3.1 Query Notifications Header
-	// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/e168d373-a7b7-41aa-b6ca-25985466a7e0
-	// Timeout in milliseconds in TDS protocol.
-	to := uint32(timeout / time.Millisecond)
-	if to < 1 {
-		to = 1
-	}
-	s.notifSub = &queryNotifSub{id, options, to}
+	s.notifSub = newQueryNotifSub(id, options, timeout)
 }
 
 func (s *Stmt) NumInput() int {
@@ -509,19 +842,26 @@ func (s *Stmt) sendQuery(ctx context.Context, args []namedValue) (err error) {
 			data: transDescrHdr{s.c.sess.tranid, 1}.pack()},
 	}
 
-	if s.notifSub != nil {
+	notifSub := s.notifSub
+	if notifSub == nil {
+		notifSub = s.c.outs.notifSub
+	}
+	if notifSub != nil {
 		headers = append(headers,
 			headerStruct{
 				hdrtype: dataStmHdrQueryNotif,
 				data: queryNotifHdr{
-					s.notifSub.msgText,
-					s.notifSub.options,
-					s.notifSub.timeout,
+					notifSub.msgText,
+					notifSub.options,
+					notifSub.timeout,
 				}.pack(),
 			})
 	}
 
 	conn := s.c
+	if conn.outs.batch != nil {
+		return s.sendBatch(ctx, conn.outs.batch, headers)
+	}
 
 	// no need to check number of parameters here, it is checked by database/sql
 	if conn.sess.logFlags&logSQL != 0 {
@@ -530,9 +870,9 @@ func (s *Stmt) sendQuery(ctx context.Context, args []namedValue) (err error) {
 	if conn.sess.logFlags&logParams != 0 && len(args) > 0 {
 		for i := 0; i < len(args); i++ {
 			if len(args[i].Name) > 0 {
-				s.c.sess.logger.Log(ctx, msdsn.LogParams, fmt.Sprintf("\t@%s\t%v", args[i].Name, args[i].Value))
+				s.c.sess.logger.Log(ctx, msdsn.LogParams, fmt.Sprintf("\t@%s\t%s", args[i].Name, s.c.redactParam(args[i].Value)))
 			} else {
-				s.c.sess.logger.Log(ctx, msdsn.LogParams, fmt.Sprintf("\t@p%d\t%v", i+1, args[i].Value))
+				s.c.sess.logger.Log(ctx, msdsn.LogParams, fmt.Sprintf("\t@p%d\t%s", i+1, s.c.redactParam(args[i].Value)))
 			}
 		}
 	}
@@ -540,8 +880,9 @@ func (s *Stmt) sendQuery(ctx context.Context, args []namedValue) (err error) {
 	reset := conn.resetSession
 	conn.resetSession = false
 	isProc := isProc(s.query)
+	conn.outs.queryLabel = conn.queryLabel(ctx)
 	if len(args) == 0 && !isProc {
-		if err = sendSqlBatch72(conn.sess.buf, s.query, headers, reset); err != nil {
+		if err = sendSqlBatch72(conn.sess.buf, conn.outs.queryLabel+s.query, headers, reset); err != nil {
 			if conn.sess.logFlags&logErrors != 0 {
 				conn.sess.logger.Log(ctx, msdsn.LogErrors, fmt.Sprintf("Failed to send SqlBatch with %v", err))
 			}
@@ -563,8 +904,7 @@ func (s *Stmt) sendQuery(ctx context.Context, args []namedValue) (err error) {
 			if err != nil {
 				return
 			}
-			params[0] = makeStrParam(s.query)
-			params[1] = makeStrParam(strings.Join(decls, ","))
+			proc, params = s.queryRPC(params, strings.Join(decls, ","))
 		}
 		if err = sendRpc(conn.sess.buf, headers, proc, 0, params, reset); err != nil {
 			if conn.sess.logFlags&logErrors != 0 {
@@ -577,6 +917,61 @@ func (s *Stmt) sendQuery(ctx context.Context, args []namedValue) (err error) {
 	return
 }
 
+// queryRPC fills in the leading parameters of a parameterized query with
+// its text, the query of s with its labels, and returns the procedure to
+// send it with.
+// With prepared statements enabled, a statement that is executed more than once
+// is prepared with sp_prepexec, later executions only send its handle to sp_execute.
+func (s *Stmt) queryRPC(params []param, decls string) (procId, []param) {
+	c := s.c
+	text := c.outs.queryLabel + s.query
+	if s.handle != 0 && s.handleResets != c.resets {
+		// the handle was dropped when the session was reset
+		s.handle = 0
+	}
+	s.executions++
+	switch {
+	case !c.prepareStatements || s.doEncryption():
+	case s.handle != 0 && s.handleDecls == decls:
+		params = params[1:]
+		params[0] = makeHandleParam(s.handle)
+		return sp_Execute, params
+	case s.handle == 0 && s.executions > 1:
+		handle := makeHandleParam(0)
+		handle.Flags = fByRevValue
+		handle.buffer = []byte{}
+		params[0] = makeStrParam(decls)
+		params[1] = makeStrParam(text)
+		s.handleDecls = decls
+		s.handleResets = c.resets
+		c.outs.prepareHandle = &s.handle
+		return sp_PrepExec, append([]param{handle}, params...)
+	}
+	params[0] = makeStrParam(text)
+	params[1] = makeStrParam(decls)
+	return sp_ExecuteSql, params
+}
+
+// resetInvalidHandle forgets the handle of the statement if the server
+// reports that it does not know it anymore, or that it is not valid since
+// the database or the settings of the session changed, so the statement can
+// be prepared again. Both errors are raised before the statement runs, other
+// errors are not retried, the statement may have had effects already. Like
+// ADO.NET, the statement is only prepared again once, the error of the
+// second execution is returned.
+func (s *Stmt) resetInvalidHandle(err error) bool {
+	var sqlErr Error
+	if s.handle == 0 || !errors.As(err, &sqlErr) {
+		return false
+	}
+	switch sqlErr.Number {
+	case errPreparedHandleNotFound, errPreparedHandleInvalid:
+		s.handle = 0
+		return true
+	}
+	return false
+}
+
 // isProc takes the query text in s and determines if it is a stored proc name
 // or SQL text.
 func isProc(s string) bool {
@@ -681,7 +1076,12 @@ func (s *Stmt) makeRPCParams(args []namedValue, isProc bool) ([]param, []string,
 			params[i+offset].ti.Size = 0
 		}
 
-		decls[i] = fmt.Sprintf("%s %s%s", name, makeDecl(tiDecl), output)
+		typeName, described := s.paramTypes[name]
+		if !described || isOutputValue(val.Value) {
+			// output parameters are declared with the type of their destination
+			typeName = makeDecl(tiDecl)
+		}
+		decls[i] = fmt.Sprintf("%s %s%s", name, typeName, output)
 
 	}
 	return params, decls, nil
@@ -722,20 +1122,63 @@ func (s *Stmt) queryContext(ctx context.Context, args []namedValue) (rows driver
 	if !s.c.connectionGood {
 		return nil, driver.ErrBadConn
 	}
+	// the parameters are described and encrypted in the database the
+	// statement runs in, their types are cached per database
+	if err = s.c.useContextDatabase(ctx); err != nil {
+		return nil, err
+	}
 	if s.doEncryption() && len(args) > 0 {
 		args, err = s.encryptArgs(ctx, args)
+	} else if s.c.describeParameters && len(args) > 0 && !isProc(s.query) {
+		err = s.describeParams(ctx)
 	}
 	if err != nil {
 		return nil, err
 	}
+	if s.c.outs.queryHints != nil {
+		if s, err = s.withQueryHints(); err != nil {
+			return nil, err
+		}
+	}
+	if s.c.outs.statistics != nil {
+		if s, err = s.withStatistics(); err != nil {
+			return nil, err
+		}
+	}
+	outs := s.c.outs
 	if err = s.sendQuery(ctx, args); err != nil {
 		return nil, s.c.checkBadConn(ctx, err, true)
 	}
-	return s.processQueryResponse(ctx)
+	rows, err = s.processQueryResponse(ctx)
+	if err != nil && s.resetInvalidHandle(err) {
+		s.c.outs = outs
+		if err = s.sendQuery(ctx, args); err != nil {
+			return nil, s.c.checkBadConn(ctx, err, true)
+		}
+		return s.processQueryResponse(ctx)
+	}
+	for retries := 0; err != nil && s.mayRetryConflict(ctx, outs, err, retries); retries++ {
+		s.c.outs = outs
+		if err = s.sendQuery(ctx, args); err != nil {
+			return nil, s.c.checkBadConn(ctx, err, true)
+		}
+		rows, err = s.processQueryResponse(ctx)
+	}
+	if err != nil && s.mayRetryRead(ctx, outs) {
+		if s.c.sess.logFlags&logRetries != 0 {
+			s.c.sess.logger.Log(ctx, msdsn.LogRetries, err.Error())
+		}
+		s.c.logEvent(ctx, Event{Type: EventRetry, Err: err, Message: err.Error()})
+		return nil, newRetryableError(err)
+	}
+	return rows, err
 }
 
 func (s *Stmt) processQueryResponse(ctx context.Context) (res driver.Rows, err error) {
 	ctx, cancel := context.WithCancel(ctx)
+	if s.c.outs.canceler != nil {
+		s.c.outs.canceler.start(cancel)
+	}
 	reader := startReading(s.c.sess, ctx, s.c.outs)
 	s.c.clearOuts()
 	// For apps using a message queue, return right away and let Rowsq do all the work
@@ -765,6 +1208,10 @@ loop:
 					break loop
 				case doneStruct:
 					if token.isError() {
+						// read the rest of the response, so the statement
+						// can be retried on the connection
+						for tok, err := reader.nextToken(); tok != nil && err == nil; tok, err = reader.nextToken() {
+						}
 						// need to cleanup cancellable context
 						cancel()
 						return nil, s.c.checkBadConn(ctx, token.getError(), false)
@@ -795,21 +1242,88 @@ func (s *Stmt) exec(ctx context.Context, args []namedValue) (res driver.Result,
 	if !s.c.connectionGood {
 		return nil, driver.ErrBadConn
 	}
+	// the parameters are described and encrypted in the database the
+	// statement runs in, their types are cached per database
+	if err = s.c.useContextDatabase(ctx); err != nil {
+		return nil, err
+	}
 	if s.doEncryption() && len(args) > 0 {
 		args, err = s.encryptArgs(ctx, args)
+	} else if s.c.describeParameters && len(args) > 0 && !isProc(s.query) {
+		err = s.describeParams(ctx)
 	}
 	if err != nil {
 		return nil, err
 	}
+	if s.c.outs.queryHints != nil {
+		if s, err = s.withQueryHints(); err != nil {
+			return nil, err
+		}
+	}
+	if s.c.outs.statistics != nil {
+		if s, err = s.withStatistics(); err != nil {
+			return nil, err
+		}
+	}
+	if s.c.outs.lastInsertId {
+		if isProc(s.query) {
+			s.c.clearOuts()
+			return nil, errors.New("mssql: LastInsertId cannot be used with a stored procedure name")
+		}
+		// SCOPE_IDENTITY is only visible in the batch that inserted the row,
+		// so the select has to be sent together with the query
+		withId := *s
+		withId.query = s.query + lastInsertIdQuery
+		// the copy is executed once, it must neither use nor prepare a handle
+		withId.handle = 0
+		withId.executions = 0
+		s = &withId
+	}
+	outs := s.c.outs
 	if err = s.sendQuery(ctx, args); err != nil {
 		return nil, s.c.checkBadConn(ctx, err, true)
 	}
-	if res, err = s.processExec(ctx); err != nil {
+	res, err = s.processExec(ctx)
+	if err != nil && s.resetInvalidHandle(err) {
+		s.c.outs = outs
+		if err = s.sendQuery(ctx, args); err != nil {
+			return nil, s.c.checkBadConn(ctx, err, true)
+		}
+		res, err = s.processExec(ctx)
+	}
+	for retries := 0; err != nil && s.mayRetryConflict(ctx, outs, err, retries); retries++ {
+		s.c.outs = outs
+		if err = s.sendQuery(ctx, args); err != nil {
+			return nil, s.c.checkBadConn(ctx, err, true)
+		}
+		res, err = s.processExec(ctx)
+	}
+	if err != nil {
 		return nil, err
 	}
 	return
 }
 
+// withQueryHints returns a copy of the statement with the QueryHints
+// that were passed as an argument applied to its query text.
+func (s *Stmt) withQueryHints() (*Stmt, error) {
+	if isProc(s.query) {
+		s.c.clearOuts()
+		return nil, errors.New("mssql: QueryHints cannot be used with a stored procedure name")
+	}
+	query, err := s.c.outs.queryHints.apply(s.query)
+	if err != nil {
+		s.c.clearOuts()
+		return nil, err
+	}
+	withHints := *s
+	withHints.query = query
+	// the copy is executed once, it must neither use nor prepare a handle
+	withHints.handle = 0
+	withHints.executions = 0
+	return &withHints, nil
+}
+
 func (s *Stmt) processExec(ctx context.Context) (res driver.Result, err error) {
 	reader := startReading(s.c.sess, ctx, s.c.outs)
 	s.c.clearOuts()
@@ -817,7 +1331,23 @@ func (s *Stmt) processExec(ctx context.Context) (res driver.Result, err error) {
 	if err != nil {
 		return nil, s.c.checkBadConn(ctx, err, false)
 	}
-	return &Result{s.c, reader.rowCount}, nil
+	result := &Result{c: s.c, rowsAffected: reader.rowCount}
+	if reader.outs.lastInsertId {
+		result.hasLastInsertId = true
+		if len(reader.lastRow) == 1 {
+			result.lastInsertId, _ = reader.lastRow[0].(int64)
+			result.lastInsertIdValid = reader.lastRow[0] != nil
+			// the appended select reports a row count of its own,
+			// unless SET NOCOUNT ON is in effect
+			if reader.lastRowCounted {
+				result.rowsAffected--
+				if counts := reader.outs.rowCounts; counts != nil && len(*counts) > 0 {
+					*counts = (*counts)[:len(*counts)-1]
+				}
+			}
+		}
+	}
+	return result, nil
 }
 
 // Rows represents the non-experimental data/sql model for Query and QueryContext
@@ -862,6 +1392,9 @@ func (rc *Rows) Columns() (res []string) {
 	return
 }
 
+// Next reads the next row into dest. The []byte values of dest are allocated
+// for the row, read straight from the network buffer, and not modified by the
+// driver afterwards. Scanning them into *sql.RawBytes needs no copy.
 func (rc *Rows) Next(dest []driver.Value) error {
 	if !rc.stmt.c.connectionGood {
 		return driver.ErrBadConn
@@ -881,9 +1414,13 @@ func (rc *Rows) Next(dest []driver.Value) error {
 					rc.nextCols = tokdata
 					return io.EOF
 				case []interface{}:
+					if err := rc.reader.checkLimits(tokdata); err != nil {
+						return err
+					}
 					for i := range dest {
 						dest[i] = tokdata[i]
 					}
+					rc.stmt.c.convertRow(rc.cols, dest)
 					return nil
 				case doneStruct:
 					if tokdata.isError() {
@@ -919,7 +1456,7 @@ func (rc *Rows) NextResultSet() error {
 // the value type that can be used to scan types into. For example, the database
 // column type "bigint" this should return "reflect.TypeOf(int64(0))".
 func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
-	return makeGoLangScanType(r.cols[index].originalTypeInfo())
+	return r.stmt.c.scanType(r.cols[index].originalTypeInfo())
 }
 
 // RowsColumnTypeDatabaseTypeName may be implemented by Rows. It should return the
@@ -968,6 +1505,14 @@ func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
 	return
 }
 
+func makeHandleParam(handle int32) (res param) {
+	res.ti.TypeId = typeIntN
+	res.ti.Size = 4
+	res.buffer = make([]byte, 4)
+	binary.LittleEndian.PutUint32(res.buffer, uint32(handle))
+	return
+}
+
 func makeStrParam(val string) (res param) {
 	res.ti.TypeId = typeNVarChar
 	res.buffer = str2ucs2(val)
@@ -1028,9 +1573,11 @@ func (s *Stmt) makeParam(val driver.Value) (res param, err error) {
 			binary.LittleEndian.PutUint64(res.buffer, uint64(val))
 		}
 	case int8:
+		// tinyint is unsigned, a smallint holds the negative values
 		res.ti.TypeId = typeIntN
-		res.buffer = []byte{byte(val)}
-		res.ti.Size = 1
+		res.buffer = make([]byte, 2)
+		res.ti.Size = 2
+		binary.LittleEndian.PutUint16(res.buffer, uint16(val))
 	case int16:
 		res.ti.TypeId = typeIntN
 		res.buffer = make([]byte, 2)
@@ -1100,6 +1647,7 @@ func (s *Stmt) makeParam(val driver.Value) (res param, err error) {
 		res.buffer = []byte{}
 
 	case time.Time:
+		val = s.c.toServerTimezone(val)
 		if s.c.sess.loginAck.TDSVersion >= verTDS73 {
 			res.ti.TypeId = typeDateTimeOffsetN
 			res.ti.Scale = 7
@@ -1119,6 +1667,11 @@ func (s *Stmt) makeParam(val driver.Value) (res param, err error) {
 type Result struct {
 	c            *Conn
 	rowsAffected int64
+
+	// set when LastInsertId was passed to Exec
+	hasLastInsertId   bool
+	lastInsertId      int64
+	lastInsertIdValid bool
 }
 
 func (r *Result) RowsAffected() (int64, error) {
@@ -1132,7 +1685,7 @@ func (c *Conn) Ping(ctx context.Context) error {
 	if !c.connectionGood {
 		return driver.ErrBadConn
 	}
-	stmt := &Stmt{c, `select 1;`, 0, nil, true}
+	stmt := &Stmt{c: c, query: `select 1;`, skipEncryption: true}
 	_, err := stmt.ExecContext(ctx, nil)
 	return err
 }
@@ -1304,9 +1857,13 @@ func (rc *Rowsq) Next(dest []driver.Value) error {
 				}
 				switch tokdata := tok.(type) {
 				case []interface{}:
+					if err := rc.reader.checkLimits(tokdata); err != nil {
+						return err
+					}
 					for i := range dest {
 						dest[i] = tokdata[i]
 					}
+					rc.stmt.c.convertRow(rc.cols, dest)
 					return nil
 				case doneStruct:
 					if tokdata.Status&doneMore == 0 {
@@ -1409,7 +1966,7 @@ scan:
 // the value type that can be used to scan types into. For example, the database
 // column type "bigint" this should return "reflect.TypeOf(int64(0))".
 func (r *Rowsq) ColumnTypeScanType(index int) reflect.Type {
-	return makeGoLangScanType(r.cols[index].originalTypeInfo())
+	return r.stmt.c.scanType(r.cols[index].originalTypeInfo())
 }
 
 // RowsColumnTypeDatabaseTypeName may be implemented by Rows. It should return the
@@ -6,38 +6,82 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
+	"strings"
+	"time"
 )
 
 var _ driver.Connector = &Connector{}
 var _ driver.SessionResetter = &Conn{}
 
 func (c *Conn) ResetSession(ctx context.Context) error {
-	if !c.connectionGood {
+	if err := c.resetSessionState(ctx); err != nil {
+		return err
+	}
+	if err := c.onReset(ctx); err != nil {
+		c.logEvent(ctx, Event{Type: EventWarning, Err: err, Message: "OnReset failed: " + err.Error()})
 		return driver.ErrBadConn
 	}
-	c.resetSession = true
+	return nil
+}
 
-	if c.connector == nil || len(c.connector.SessionInitSQL) == 0 {
-		return nil
+// resetSessionState marks the session to be reset by the next request
+// and executes the session init SQL.
+func (c *Conn) resetSessionState(ctx context.Context) error {
+	if !c.connectionGood {
+		return driver.ErrBadConn
 	}
-
-	s, err := c.prepareContext(ctx, c.connector.SessionInitSQL)
-	if err != nil {
+	if c.expired(time.Now()) {
 		return driver.ErrBadConn
 	}
-	_, err = s.exec(ctx, nil)
-	if err != nil {
+	if err := c.checkHealth(ctx); err != nil {
 		return driver.ErrBadConn
 	}
+	c.resetSession = true
+	c.resets++
 
+	initSQL := c.sessionInitSQL()
+	if len(initSQL) > 0 {
+		s, err := c.prepareContext(ctx, initSQL)
+		if err != nil {
+			return driver.ErrBadConn
+		}
+		_, err = s.exec(ctx, nil)
+		if err != nil {
+			return driver.ErrBadConn
+		}
+	}
 	return nil
 }
 
+// sessionInitSQL returns the SET statements for the session options
+// of the connection string followed by the Connector's SessionInitSQL.
+func (c *Conn) sessionInitSQL() string {
+	if c.connector == nil {
+		return ""
+	}
+	stmts := c.connector.params.SessionOptions
+	if len(c.connector.SessionInitSQL) > 0 {
+		stmts = append(stmts[:len(stmts):len(stmts)], c.connector.SessionInitSQL)
+	}
+	return strings.Join(stmts, ";\n")
+}
+
 // Connect to the server and return a TDS connection.
 func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
-	conn, err := c.driver.connect(ctx, c, c.params)
+	params, err := c.loginParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.driver.connect(ctx, c, params)
 	if err == nil {
-		err = conn.ResetSession(ctx)
+		c.passwordChanged(params)
+		err = conn.resetSessionState(ctx)
+	}
+	if err == nil {
+		err = conn.onConnect(ctx)
+	}
+	if err == nil {
+		conn.checkElevateToSnapshot(ctx)
 	}
 	return conn, err
 }
@@ -47,6 +91,14 @@ func (c *Connector) Driver() driver.Driver {
 	return c.driver
 }
 
+// LastInsertId returns the SCOPE_IDENTITY() of the statement when
+// a LastInsertId argument was passed to Exec.
 func (r *Result) LastInsertId() (int64, error) {
-	return -1, errors.New("LastInsertId is not supported. Please use the OUTPUT clause or add `select ID = convert(bigint, SCOPE_IDENTITY())` to the end of your query")
+	if !r.hasLastInsertId {
+		return -1, errors.New("LastInsertId is not supported. Please pass mssql.LastInsertId{} to Exec, use the OUTPUT clause or add `select ID = convert(bigint, SCOPE_IDENTITY())` to the end of your query")
+	}
+	if !r.lastInsertIdValid {
+		return -1, errors.New("LastInsertId is not available, the statement did not insert into a table with an identity column")
+	}
+	return r.lastInsertId, nil
 }
@@ -8,10 +8,12 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"time"
 
 	"github.com/golang-sql/sqlexp"
+	"github.com/microsoft/go-mssqldb/internal/cp"
 
 	// "github.com/cockroachdb/apd"
 	"github.com/golang-sql/civil"
@@ -29,7 +31,8 @@ type MssqlStmt = Stmt               // Deprecated: users should transition to th
 
 var _ driver.NamedValueChecker = &Conn{}
 
-// VarChar is used to encode a string parameter as VarChar instead of a sized NVarChar
+// VarChar is used to encode a string parameter as VarChar instead of a sized NVarChar.
+// The value is sized in bytes and sent with the database collation when that is a UTF-8 collation.
 type VarChar string
 
 // NVarCharMax is used to encode a string parameter as NVarChar(max) instead of a sized NVarChar
@@ -53,8 +56,22 @@ func convertInputParameter(val interface{}) (interface{}, error) {
 		return val, nil
 	case byte:
 		return val, nil
+	case uint16:
+		return int32(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint:
+		return convertUint(uint64(v))
+	case uint64:
+		return convertUint(v)
 	case VarChar:
 		return val, nil
+	case LikeContains:
+		return "%" + EscapeLike(string(v), 0) + "%", nil
+	case LikeStartsWith:
+		return EscapeLike(string(v), 0) + "%", nil
+	case LikeEndsWith:
+		return "%" + EscapeLike(string(v), 0), nil
 	case NVarCharMax:
 		return val, nil
 	case VarCharMax:
@@ -65,6 +82,8 @@ func convertInputParameter(val interface{}) (interface{}, error) {
 		return val, nil
 	case DateTimeOffset:
 		return val, nil
+	case TypedValue:
+		return val, nil
 	case civil.Date:
 		return val, nil
 	case civil.DateTime:
@@ -82,6 +101,14 @@ func convertInputParameter(val interface{}) (interface{}, error) {
 	}
 }
 
+// convertUint converts an unsigned parameter to a bigint, if it is in its range.
+func convertUint(v uint64) (interface{}, error) {
+	if v > math.MaxInt64 {
+		return nil, fmt.Errorf("mssql: an unsigned value above %d is out of the range of bigint, pass it as a decimal", int64(math.MaxInt64))
+	}
+	return int64(v), nil
+}
+
 func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
 	switch v := nv.Value.(type) {
 	case sql.Out:
@@ -131,6 +158,66 @@ func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
 		*v = 0 // By default the return value should be zero.
 		c.outs.returnStatus = v
 		return driver.ErrRemoveArgument
+	case LastInsertId:
+		c.outs.lastInsertId = true
+		return driver.ErrRemoveArgument
+	case NoQueryTimeout:
+		c.outs.noTimeout = true
+		return driver.ErrRemoveArgument
+	case NonIdempotent:
+		c.outs.nonIdempotent = true
+		return driver.ErrRemoveArgument
+	case RetryConflicts:
+		c.outs.retryConflicts = true
+		return driver.ErrRemoveArgument
+	case *Canceler:
+		c.outs.canceler = v
+		return driver.ErrRemoveArgument
+	case DecodeColumns:
+		c.outs.decodeColumns = v
+		return driver.ErrRemoveArgument
+	case ReadAhead:
+		if v < 1 {
+			return fmt.Errorf("mssql: invalid ReadAhead %d", v)
+		}
+		c.outs.readAhead = int(v)
+		return driver.ErrRemoveArgument
+	case MaxRows:
+		if v < 1 {
+			return fmt.Errorf("mssql: invalid MaxRows %d", v)
+		}
+		c.outs.maxRows = int64(v)
+		return driver.ErrRemoveArgument
+	case MaxBytes:
+		if v < 1 {
+			return fmt.Errorf("mssql: invalid MaxBytes %d", v)
+		}
+		c.outs.maxBytes = int64(v)
+		return driver.ErrRemoveArgument
+	case *QueryStatistics:
+		*v = QueryStatistics{}
+		c.outs.statistics = v
+		return driver.ErrRemoveArgument
+	case *ColumnAttributes:
+		*v = ColumnAttributes{}
+		c.outs.columnAttrs = v
+		return driver.ErrRemoveArgument
+	case *backupMonitor:
+		c.outs.backup = v
+		return driver.ErrRemoveArgument
+	case QueryHints:
+		c.outs.queryHints = &v
+		return driver.ErrRemoveArgument
+	case QueryNotification:
+		c.outs.notifSub = newQueryNotifSub(v.ID, v.Options, v.Timeout)
+		return driver.ErrRemoveArgument
+	case *RowCounts:
+		*v = (*v)[:0]
+		c.outs.rowCounts = v
+		return driver.ErrRemoveArgument
+	case *Batch:
+		c.outs.batch = v
+		return driver.ErrRemoveArgument
 	case TVP:
 		return nil
 	case *sqlexp.ReturnMessage:
@@ -144,14 +231,27 @@ func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
 	}
 }
 
+// varCharCollation returns the collation sent with VarChar parameters.
+// The bytes of a Go string are UTF-8, so they can be tagged with the
+// session collation as is when the database uses a UTF-8 collation.
+func (s *Stmt) varCharCollation() cp.Collation {
+	if s.c != nil && s.c.sess != nil && s.c.sess.collation.IsUTF8() {
+		return s.c.sess.collation
+	}
+	return cp.Collation{}
+}
+
 func (s *Stmt) makeParamExtra(val driver.Value) (res param, err error) {
 	switch val := val.(type) {
 	case VarChar:
 		res.ti.TypeId = typeBigVarChar
+		res.ti.Collation = s.varCharCollation()
 		res.buffer = []byte(val)
+		// varchar(n) is sized in bytes, not characters, also for UTF-8 collations
 		res.ti.Size = len(res.buffer)
 	case VarCharMax:
 		res.ti.TypeId = typeBigVarChar
+		res.ti.Collation = s.varCharCollation()
 		res.buffer = []byte(val)
 		res.ti.Size = 0 // currently zero forces varchar(max)
 	case NVarCharMax:
@@ -163,7 +263,7 @@ func (s *Stmt) makeParamExtra(val driver.Value) (res param, err error) {
 		res.buffer = str2ucs2(string(val))
 		res.ti.Size = len(res.buffer)
 	case DateTime1:
-		t := time.Time(val)
+		t := s.c.toServerTimezone(time.Time(val))
 		res.ti.TypeId = typeDateTimeN
 		res.buffer = encodeDateTime(t)
 		res.ti.Size = len(res.buffer)
@@ -172,6 +272,8 @@ func (s *Stmt) makeParamExtra(val driver.Value) (res param, err error) {
 		res.ti.Scale = 7
 		res.buffer = encodeDateTimeOffset(time.Time(val), int(res.ti.Scale))
 		res.ti.Size = len(res.buffer)
+	case TypedValue:
+		res, err = val.makeParam(s.c, s.varCharCollation())
 	case civil.Date:
 		res.ti.TypeId = typeDateN
 		res.buffer = encodeDate(val.In(time.UTC))
@@ -8,9 +8,11 @@ import (
 	"io"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
 )
 
 func TestBadOpen(t *testing.T) {
@@ -40,6 +42,10 @@ func TestIsProc(t *testing.T) {
 		{"schema.[proc name]", true},
 		{"db.schema.[proc name]", true},
 		{"db..[proc name]", true},
+		{"srv.db.dbo.proc", true},
+		{`[srv\inst].[db].[dbo].[proc]`, true},
+		{"[srv].[db]..[proc]", true},
+		{"[srv]]1]...[proc]", true},
 		{"#temp_@_proc", true},
 		{"_temp.[_proc]", true},
 		{"raiserror(13000,1,1)", false},
@@ -111,6 +117,8 @@ func TestCheckBadConn(t *testing.T) {
 	streamErr := StreamError{InnerError: fmt.Errorf("fake StreamError")}
 	serverErr := ServerError{sqlError: Error{Message: "fake ServerError"}}
 	goodConnErr := fmt.Errorf("fake error that leaves connection good")
+	sqlErr := Error{Class: 16, Message: "fake Error"}
+	fatalErr := Error{Class: 20, Message: "fake fatal Error"}
 
 	testInputs := []struct {
 		err              error
@@ -139,6 +147,11 @@ func TestCheckBadConn(t *testing.T) {
 		{serverErr, true, false, newRetryableError(serverErr), false},
 		{serverErr, false, true, serverErr, false},
 		{serverErr, true, true, serverErr, false},
+		{sqlErr, false, false, sqlErr, true},
+		{sqlErr, true, false, sqlErr, true},
+		{fatalErr, false, false, fatalErr, false},
+		{fatalErr, true, false, newRetryableError(fatalErr), false},
+		{fatalErr, false, true, fatalErr, false},
 		{goodConnErr, false, false, goodConnErr, true},
 		{goodConnErr, true, false, goodConnErr, true},
 		{goodConnErr, false, true, goodConnErr, true},
@@ -247,3 +260,277 @@ func TestBadConnRejection(t *testing.T) {
 	}
 
 }
+
+func TestColumnTypeFlags(t *testing.T) {
+	cols := []columnStruct{
+		{ColName: "id", Flags: colFlagIdentity, ti: typeInfo{TypeId: typeInt4, Size: 4}},
+		{ColName: "total", Flags: colFlagNullable | colFlagComputed, ti: typeInfo{TypeId: typeDecimalN, Prec: 18, Scale: 2}},
+		{ColName: "key", Flags: colFlagHidden, ti: typeInfo{TypeId: typeInt4, Size: 4}},
+	}
+	r := &Rows{cols: cols}
+	for i, nullable := range []bool{false, true, false} {
+		if got, _ := r.ColumnTypeNullable(i); got != nullable {
+			t.Errorf("column %d: nullable %v, want %v", i, got, nullable)
+		}
+	}
+	if name := r.ColumnTypeDatabaseTypeName(1); name != "DECIMAL" {
+		t.Errorf("expected DECIMAL, got %s", name)
+	}
+	if prec, scale, ok := r.ColumnTypePrecisionScale(1); !ok || prec != 18 || scale != 2 {
+		t.Errorf("expected decimal(18, 2), got (%d, %d, %v)", prec, scale, ok)
+	}
+
+	c := &Conn{}
+	attrs := &ColumnAttributes{Columns: []ColumnAttribute{{Name: "old"}}}
+	if err := c.CheckNamedValue(&driver.NamedValue{Value: attrs}); err != driver.ErrRemoveArgument {
+		t.Fatalf("expected ErrRemoveArgument, got %v", err)
+	}
+	if c.outs.columnAttrs != attrs || attrs.Columns != nil {
+		t.Fatal("expected the attributes to be reset and received")
+	}
+	attrs.set(cols)
+	expected := []ColumnAttribute{
+		{Name: "id", Identity: true},
+		{Name: "total", Computed: true},
+		{Name: "key", Hidden: true},
+	}
+	if !reflect.DeepEqual(attrs.Columns, expected) {
+		t.Errorf("unexpected attributes %+v", attrs.Columns)
+	}
+
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select id, name from t", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"id", "name"}, Rows: [][]interface{}{{int64(1), "a"}}}}})
+	rows, err := db.Query("select id, name from t", attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if len(attrs.Columns) != 2 || attrs.Columns[0].Name != "id" || attrs.Columns[1].Name != "name" {
+		t.Errorf("expected the attributes of the result set, got %+v", attrs.Columns)
+	}
+}
+
+func TestResultLastInsertId(t *testing.T) {
+	c := &Conn{}
+	if err := c.CheckNamedValue(&driver.NamedValue{Value: LastInsertId{}}); err != driver.ErrRemoveArgument {
+		t.Fatalf("expected ErrRemoveArgument, got %v", err)
+	}
+	if !c.outs.lastInsertId {
+		t.Error("expected LastInsertId to be requested")
+	}
+
+	if _, err := (&Result{}).LastInsertId(); err == nil {
+		t.Error("expected an error when LastInsertId was not requested")
+	}
+	if _, err := (&Result{hasLastInsertId: true}).LastInsertId(); err == nil {
+		t.Error("expected an error when there is no identity value")
+	}
+	id, err := (&Result{hasLastInsertId: true, lastInsertId: 42, lastInsertIdValid: true}).LastInsertId()
+	if err != nil || id != 42 {
+		t.Errorf("expected 42, got %d, %v", id, err)
+	}
+}
+
+func TestExecLastInsertIdRowCount(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=identityhost;user id=sa;password=pwd;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	var noCount bool
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if !strings.HasSuffix(query, lastInsertIdQuery) {
+			return mssqltest.Response{Err: &mssqltest.Error{Number: 208, Message: "unexpected query " + query}}
+		}
+		return mssqltest.Response{Results: []mssqltest.Result{
+			{RowsAffected: 2, NoCount: noCount},
+			{Columns: []string{""}, Rows: [][]interface{}{{int64(7)}}, NoCount: noCount},
+		}}
+	})
+
+	for _, noCount = range []bool{false, true} {
+		var counts RowCounts
+		res, err := db.Exec("insert into t (a) values (1), (2)", LastInsertId{}, &counts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, err := res.LastInsertId(); err != nil || id != 7 {
+			t.Errorf("nocount %v: expected id 7, got %d, %v", noCount, id, err)
+		}
+		affected, _ := res.RowsAffected()
+		if noCount && (affected != 0 || len(counts) != 0) {
+			t.Errorf("nocount: expected no row counts, got %d, %v", affected, counts)
+		}
+		if !noCount && (affected != 2 || len(counts) != 1 || counts[0] != 2) {
+			t.Errorf("expected 2 rows affected, got %d, %v", affected, counts)
+		}
+	}
+}
+
+func TestConnSessionInitSQL(t *testing.T) {
+	c := &Conn{}
+	if sql := c.sessionInitSQL(); sql != "" {
+		t.Errorf("expected no session init sql without a connector, got %q", sql)
+	}
+
+	params, err := msdsn.Parse("server=localhost;lock timeout=1000;arithabort=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.connector = newConnector(params, nil)
+	if sql := c.sessionInitSQL(); sql != "SET ARITHABORT ON;\nSET LOCK_TIMEOUT 1000" {
+		t.Errorf("unexpected session init sql %q", sql)
+	}
+
+	c.connector.SessionInitSQL = "SET XACT_ABORT ON"
+	if sql := c.sessionInitSQL(); sql != "SET ARITHABORT ON;\nSET LOCK_TIMEOUT 1000;\nSET XACT_ABORT ON" {
+		t.Errorf("unexpected session init sql %q", sql)
+	}
+	if len(params.SessionOptions) != 2 {
+		t.Errorf("SessionOptions of the connection string were modified: %v", params.SessionOptions)
+	}
+}
+
+func TestStmtQueryRPCPrepared(t *testing.T) {
+	c := &Conn{sess: &tdsSession{}, prepareStatements: true}
+	s := &Stmt{c: c, query: "select @p1"}
+	const decls = "@p1 bigint"
+	params := func() []param { return make([]param, 3) }
+
+	proc, p := s.queryRPC(params(), decls)
+	if proc != sp_ExecuteSql || len(p) != 3 {
+		t.Fatalf("expected the first execution to use sp_executesql, got %v with %d params", proc, len(p))
+	}
+	if c.outs.prepareHandle != nil {
+		t.Fatal("the first execution must not ask for a handle")
+	}
+
+	proc, p = s.queryRPC(params(), decls)
+	if proc != sp_PrepExec || len(p) != 4 {
+		t.Fatalf("expected the second execution to use sp_prepexec, got %v with %d params", proc, len(p))
+	}
+	if p[0].Flags&fByRevValue == 0 || len(p[0].buffer) != 0 {
+		t.Error("expected the handle to be a null output parameter")
+	}
+	if c.outs.prepareHandle != &s.handle {
+		t.Fatal("expected the handle to be received into the statement")
+	}
+	c.clearOuts()
+	s.handle = 5
+
+	proc, p = s.queryRPC(params(), decls)
+	if proc != sp_Execute || len(p) != 2 {
+		t.Fatalf("expected the prepared statement to use sp_execute, got %v with %d params", proc, len(p))
+	}
+	if p[0].buffer[0] != 5 {
+		t.Errorf("expected handle 5 to be sent, got %v", p[0].buffer)
+	}
+
+	proc, _ = s.queryRPC(params(), "@p1 nvarchar(4000)")
+	if proc != sp_ExecuteSql {
+		t.Errorf("expected other parameter types to use sp_executesql, got %v", proc)
+	}
+
+	c.resets++
+	proc, _ = s.queryRPC(params(), decls)
+	if proc != sp_PrepExec {
+		t.Errorf("expected the statement to be prepared again after a session reset, got %v", proc)
+	}
+	c.clearOuts()
+
+	s.handle = 5
+	for _, err := range []error{
+		Error{Number: 208},
+		Error{Number: 50000, Message: "the table schema changed"},
+		// raised during the execution, after statements may have had effects
+		Error{Number: 16943, Message: "Could not complete cursor operation because the table schema changed"},
+	} {
+		if s.resetInvalidHandle(err) || s.handle != 5 {
+			t.Errorf("expected the handle to be kept after %v", err)
+		}
+	}
+	if !s.resetInvalidHandle(Error{Number: errPreparedHandleNotFound}) || s.handle != 0 {
+		t.Error("expected an unknown handle to be forgotten")
+	}
+	s.handle = 5
+	if !s.resetInvalidHandle(Error{Number: errPreparedHandleInvalid}) || s.handle != 0 {
+		t.Error("expected an invalid handle to be forgotten")
+	}
+
+	c.prepareStatements = false
+	s = &Stmt{c: c, query: "select @p1"}
+	for i := 0; i < 3; i++ {
+		if proc, _ = s.queryRPC(params(), decls); proc != sp_ExecuteSql {
+			t.Fatalf("expected sp_executesql without prepared statements, got %v", proc)
+		}
+	}
+}
+
+func TestStmtPreparedAgain(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=preparehost;encrypt=disable;preparestatements=true", nil)
+	defer srv.Close()
+	defer db.Close()
+	var executeErr int32
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if query == "sp_execute" && executeErr != 0 {
+			e := &mssqltest.Error{Number: executeErr, Message: fmt.Sprintf("error %d", executeErr)}
+			executeErr = 0
+			return mssqltest.Response{Err: e}
+		}
+		return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"n"}, Rows: [][]interface{}{{int64(1)}}}}}
+	})
+	// the statement is executed on a connection of its own, the reset of
+	// a connection of the pool drops the handles of its statements
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	stmt, err := conn.PrepareContext(ctx, "select n from t where id = @p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	var n int64
+	for i := 0; i < 2; i++ {
+		if err = stmt.QueryRow(1).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an unknown or invalid handle is prepared again
+	executeErr = errPreparedHandleNotFound
+	if err = stmt.QueryRow(1).Scan(&n); err != nil {
+		t.Fatalf("expected the query to succeed after preparing it again, got %v", err)
+	}
+	executeErr = errPreparedHandleInvalid
+	if _, err = stmt.Exec(1); err != nil {
+		t.Fatalf("expected the statement to succeed after preparing it again, got %v", err)
+	}
+	// a schema change during the execution is not retried
+	executeErr = 16943
+	if err = stmt.QueryRow(1).Scan(&n); err == nil {
+		t.Error("expected the error of the query")
+	}
+	executeErr = 16943
+	if _, err = stmt.Exec(1); err == nil {
+		t.Error("expected the error of the statement")
+	}
+	if _, err = stmt.Exec(1); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"select n from t where id = @p1",
+		"sp_prepexec",
+		"sp_execute",
+		"sp_prepexec",
+		"sp_execute",
+		"sp_prepexec",
+		"sp_execute",
+		"sp_execute",
+		"sp_execute",
+	}
+	if got := srv.Queries(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected queries\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
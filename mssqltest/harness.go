@@ -0,0 +1,204 @@
+package mssqltest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultImage is the SQL Server container image that StartServer runs
+// when the environment variable MSSQLTEST_IMAGE is not set.
+const DefaultImage = "mcr.microsoft.com/mssql/server:2022-latest"
+
+// readyTimeout limits the time a server takes to accept connections.
+const readyTimeout = 2 * time.Minute
+
+// container is the SQL Server container started by StartServer for the process.
+var container struct {
+	sync.Mutex
+	id  string
+	dsn string
+	err error
+}
+
+// StartServer returns a new, empty database on a real SQL Server for the
+// test t, unlike NewServer. The database is dropped when t ends.
+//
+// The server is the one of the connection string in the environment variable
+// SQLSERVER_DSN. Without it a SQL Server container is started with docker,
+// from the image in MSSQLTEST_IMAGE or DefaultImage, and shared by the tests
+// of the process; call StopServer from TestMain to remove it. Without
+// SQLSERVER_DSN and docker the test is skipped.
+//
+// StartServer waits until the server accepts connections. It opens the
+// database with the "sqlserver" driver, which the test has to import:
+//
+//	import _ "github.com/microsoft/go-mssqldb"
+func StartServer(t testing.TB) (db *sql.DB, dsn string) {
+	t.Helper()
+	serverDSN := os.Getenv("SQLSERVER_DSN")
+	if serverDSN == "" {
+		var err error
+		if serverDSN, err = startContainer(); err != nil {
+			if err == errNoDocker {
+				t.Skip("mssqltest: set SQLSERVER_DSN or install docker to test with a SQL Server")
+			}
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+	server, err := sql.Open("sqlserver", serverDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if err = waitReady(ctx, server); err != nil {
+		t.Fatal(err)
+	}
+	name := "mssqltest_" + randomHex(8)
+	if _, err = server.ExecContext(ctx, "create database "+quote(name)); err != nil {
+		t.Fatal(err)
+	}
+	dsn = withDatabase(serverDSN, name)
+	if db, err = sql.Open("sqlserver", dsn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		dropDatabase(t, serverDSN, name)
+	})
+	return db, dsn
+}
+
+// StopServer removes the container started by StartServer, if any.
+func StopServer() error {
+	container.Lock()
+	defer container.Unlock()
+	if container.id == "" {
+		return nil
+	}
+	err := exec.Command("docker", "rm", "--force", container.id).Run()
+	container.id, container.dsn, container.err = "", "", nil
+	return err
+}
+
+var errNoDocker = fmt.Errorf("mssqltest: docker not found")
+
+// startContainer starts the container of the process once and returns its connection string.
+func startContainer() (string, error) {
+	container.Lock()
+	defer container.Unlock()
+	if container.dsn != "" || container.err != nil {
+		return container.dsn, container.err
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", errNoDocker
+	}
+	image := os.Getenv("MSSQLTEST_IMAGE")
+	if image == "" {
+		image = DefaultImage
+	}
+	// the password has to meet the complexity policy of SQL Server
+	password := "Aa1!" + randomHex(12)
+	out, err := exec.Command("docker", "run", "--detach", "--rm",
+		"--env", "ACCEPT_EULA=Y", "--env", "MSSQL_SA_PASSWORD="+password,
+		"--label", "mssqltest", "--publish", "127.0.0.1::1433", image).Output()
+	if err != nil {
+		container.err = fmt.Errorf("mssqltest: docker run %s: %v", image, commandError(err))
+		return "", container.err
+	}
+	container.id = strings.TrimSpace(string(out))
+	out, err = exec.Command("docker", "port", container.id, "1433/tcp").Output()
+	if err != nil {
+		container.err = fmt.Errorf("mssqltest: docker port: %v", commandError(err))
+		return "", container.err
+	}
+	// e.g. 127.0.0.1:49153, one line per address
+	addr := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	u := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword("sa", password),
+		Host:     addr,
+		RawQuery: "trustservercertificate=true",
+	}
+	container.dsn = u.String()
+	return container.dsn, nil
+}
+
+func commandError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
+
+// waitReady pings db until the server accepts connections.
+func waitReady(ctx context.Context, db *sql.DB) error {
+	delay := 100 * time.Millisecond
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mssqltest: the server is not ready: %v", err)
+		case <-time.After(delay):
+		}
+		if delay < 2*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+func dropDatabase(t testing.TB, serverDSN, name string) {
+	server, err := sql.Open("sqlserver", serverDSN)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	// close the connections of the test that are still open
+	_, err = server.ExecContext(ctx, "alter database "+quote(name)+" set single_user with rollback immediate;\ndrop database "+quote(name))
+	if err != nil {
+		t.Errorf("mssqltest: drop database %s: %v", name, err)
+	}
+}
+
+// withDatabase returns the connection string dsn with the database name.
+func withDatabase(dsn, name string) string {
+	if strings.HasPrefix(dsn, "sqlserver://") {
+		if u, err := url.Parse(dsn); err == nil {
+			q := u.Query()
+			q.Set("database", name)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	// in ADO and ODBC connection strings the last value of a keyword counts
+	return strings.TrimRight(dsn, "; ") + ";database=" + name
+}
+
+func quote(name string) string {
+	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,654 @@
+// Package mssqltest implements a minimal in-process TDS server to test
+// code that uses the driver without a SQL Server.
+//
+// The server logs in every client and answers queries with the canned
+// responses registered with Handle or computed by HandleFunc:
+//
+//	srv, err := mssqltest.NewServer()
+//	...
+//	defer srv.Close()
+//	srv.Handle("select id, name from dbo.users", mssqltest.Response{
+//		Results: []mssqltest.Result{{
+//			Columns: []string{"id", "name"},
+//			Rows:    [][]interface{}{{1, "alice"}, {2, "bob"}},
+//		}},
+//	})
+//	db, err := sql.Open("sqlserver", srv.URL())
+//
+// Queries with parameters are matched by their text, the parameter values
+// are not checked. The calls of an RPC request with several calls, like a
+// mssql.Batch, are answered in order. Transactions are acknowledged but have no effect.
+// Statements prepared on the server are reported as sp_prepexec and
+// sp_execute, sp_prepexec calls return a new handle with the response.
+// Encryption and bulk copy are not supported.
+package mssqltest
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is a result set of a response, or a row count when it has no columns.
+type Result struct {
+	Columns []string
+	// Rows hold the values of the columns, which can be nil, integers,
+	// floats, bool, string, []byte or time.Time. The type of a column
+	// is the type of its first value that is not nil.
+	Rows [][]interface{}
+	// RowsAffected is the row count of a result without columns.
+	RowsAffected int64
+	// NoCount sends the result without a row count, like SET NOCOUNT ON.
+	NoCount bool
+}
+
+// Error is an error sent by the server.
+type Error struct {
+	Number int32
+	// Class is the severity of the error, 16 if it is 0.
+	Class uint8
+	// State is the state of the error, 1 if it is 0.
+	State   uint8
+	Message string
+}
+
+// Response is the response to a query.
+type Response struct {
+	// Results are sent in order.
+	Results []Result
+	// Err, when set, is sent after the results.
+	Err *Error
+	// Delay is the time the server waits before it responds,
+	// the query can be cancelled while it waits.
+	Delay time.Duration
+	// Database, when set, reports that the query changed the database of
+	// the session to Database, like a USE statement.
+	Database string
+	// Messages are informational messages sent before the results, such as
+	// PRINT output.
+	Messages []Error
+}
+
+// Server is an in-process TDS server.
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	handlers map[string]Response
+	handler  func(query string) Response
+	queries  []string
+	conns    map[net.Conn]struct{}
+	spid     uint16
+	tranid   uint64
+	closed   bool
+	logins   int
+	route    string
+	port     uint16
+
+	wg sync.WaitGroup
+}
+
+// errNoResponse is the error number of a query without a response,
+// SQL Server's "Could not find stored procedure".
+const errNoResponse = 2812
+
+// NewServer starts a server listening on a local TCP port.
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: l,
+		handlers: make(map[string]Response),
+		conns:    make(map[net.Conn]struct{}),
+		spid:     50,
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// URL returns a connection string for the server.
+func (s *Server) URL() string {
+	u := &url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword("sa", "mssqltest"),
+		Host:     s.listener.Addr().String(),
+		RawQuery: "encrypt=disable&protocol=tcp",
+	}
+	return u.String()
+}
+
+// DialContext connects to the server through an in-memory pipe, so the
+// Server can be used as the Dialer of a Connector.
+func (s *Server) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+	if !s.serve(server) {
+		client.Close()
+		return nil, errors.New("mssqltest: server is closed")
+	}
+	return client, nil
+}
+
+// HostName returns the host name of the server, Connectors with the Server as
+// their Dialer use it instead of resolving the host of the connection string.
+func (s *Server) HostName() string {
+	return "mssqltest"
+}
+
+// Handle registers the response to query. The query is compared
+// with the received queries after trimming white space.
+func (s *Server) Handle(query string, response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[strings.TrimSpace(query)] = response
+}
+
+// HandleFunc sets the function that computes the response to the queries
+// that have no response registered with Handle.
+func (s *Server) HandleFunc(f func(query string) Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = f
+}
+
+// Queries returns the queries received so far. A stored procedure call
+// is reported with the procedure name.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// Route makes the server route logins to server and port, like an
+// availability group listener routes read-only logins to a secondary
+// replica. An empty server stops the routing.
+func (s *Server) Route(server string, port uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.route, s.port = server, port
+}
+
+// Logins returns the number of logins the server received.
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// login counts a login and returns the route of the server.
+func (s *Server) login() (string, uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logins++
+	return s.route, s.port
+}
+
+// Close stops the server and closes all connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		if !s.serve(conn) {
+			conn.Close()
+			return
+		}
+	}
+}
+
+// serve starts serving conn, it returns false when the server is closed.
+func (s *Server) serve(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.spid++
+	c := &serverConn{srv: s, conn: conn, spid: s.spid, packetSize: 512}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		c.run()
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+	return true
+}
+
+func (s *Server) response(query string) Response {
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	r, ok := s.handlers[strings.TrimSpace(query)]
+	handler := s.handler
+	s.mu.Unlock()
+	if ok {
+		return r
+	}
+	if handler != nil {
+		return handler(query)
+	}
+	return Response{Err: &Error{Number: errNoResponse, Message: fmt.Sprintf("mssqltest: no response for query %q", query)}}
+}
+
+func (s *Server) nextTranID() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tranid++
+	return s.tranid
+}
+
+// message is a request message of the client, the payload of all its packets.
+type message struct {
+	packetType byte
+	payload    []byte
+}
+
+type serverConn struct {
+	srv        *Server
+	conn       net.Conn
+	spid       uint16
+	packetSize int
+	// handle is the last handle returned by sp_prepexec
+	handle int32
+}
+
+func (c *serverConn) run() {
+	messages := make(chan message)
+	go func() {
+		defer close(messages)
+		for {
+			m, err := readMessage(c.conn)
+			if err != nil {
+				return
+			}
+			messages <- m
+		}
+	}()
+	defer func() {
+		// let the reader finish
+		c.conn.Close()
+		for range messages {
+		}
+	}()
+
+	m, ok := <-messages
+	if !ok || m.packetType != packPrelogin {
+		return
+	}
+	if c.writePrelogin() != nil {
+		return
+	}
+	m, ok = <-messages
+	if !ok || m.packetType != packLogin7 {
+		return
+	}
+	if c.writeLoginResponse(m.payload) != nil {
+		return
+	}
+	for m := range messages {
+		var err error
+		switch m.packetType {
+		case packSQLBatch:
+			err = c.respond([]string{parseSQLBatch(m.payload)}, messages)
+		case packRPCRequest:
+			err = c.respond(parseRPC(m.payload), messages)
+		case packTransMgrReq:
+			err = c.writeTransaction(m.payload)
+		case packAttention:
+			w := &tokenWriter{}
+			w.done(doneAttn, 0)
+			err = c.write(w.Bytes())
+		default:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readMessage reads the packets of a message up to the last one.
+func readMessage(r io.Reader) (m message, err error) {
+	header := make([]byte, 8)
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			return
+		}
+		size := int(binary.BigEndian.Uint16(header[2:]))
+		if size < 8 {
+			return m, errors.New("mssqltest: invalid packet size")
+		}
+		payload := make([]byte, size-8)
+		if _, err = io.ReadFull(r, payload); err != nil {
+			return
+		}
+		m.packetType = header[0]
+		m.payload = append(m.payload, payload...)
+		if header[1]&1 != 0 {
+			return
+		}
+	}
+}
+
+// write sends a reply message in packets of the negotiated size.
+func (c *serverConn) write(data []byte) error {
+	max := c.packetSize - 8
+	packetNo := byte(1)
+	for {
+		n := len(data)
+		status := byte(1)
+		if n > max {
+			n = max
+			status = 0
+		}
+		packet := make([]byte, 8+n)
+		packet[0] = packReply
+		packet[1] = status
+		binary.BigEndian.PutUint16(packet[2:], uint16(8+n))
+		binary.BigEndian.PutUint16(packet[4:], c.spid)
+		packet[6] = packetNo
+		copy(packet[8:], data[:n])
+		if _, err := c.conn.Write(packet); err != nil {
+			return err
+		}
+		data = data[n:]
+		packetNo++
+		if status == 1 {
+			return nil
+		}
+	}
+}
+
+func (c *serverConn) writePrelogin() error {
+	// VERSION and ENCRYPTION options, encryption is not supported
+	return c.write([]byte{
+		0x00, 0x00, 0x0B, 0x00, 0x06,
+		0x01, 0x00, 0x11, 0x00, 0x01,
+		0xFF,
+		16, 0, 0, 0, 0, 0,
+		0x02,
+	})
+}
+
+func (c *serverConn) writeLoginResponse(login []byte) error {
+	if len(login) < 72 {
+		return errors.New("mssqltest: invalid login packet")
+	}
+	if size := int(binary.LittleEndian.Uint32(login[8:])); size >= 512 && size <= 32767 {
+		c.packetSize = size
+	}
+	database := "master"
+	offset := int(binary.LittleEndian.Uint16(login[68:]))
+	length := int(binary.LittleEndian.Uint16(login[70:]))
+	if length > 0 && offset+2*length <= len(login) {
+		database = fromUcs2(login[offset : offset+2*length])
+	}
+	w := &tokenWriter{}
+	w.envDatabase(database)
+	w.envChange(envSQLCollation, collation, nil)
+	if server, port := c.srv.login(); server != "" {
+		w.envRouting(server, port)
+	}
+	w.loginAck()
+	w.done(doneFinal, 0)
+	return c.write(w.Bytes())
+}
+
+func (c *serverConn) writeTransaction(payload []byte) error {
+	payload = skipAllHeaders(payload)
+	if len(payload) < 2 {
+		return errors.New("mssqltest: invalid transaction manager request")
+	}
+	w := &tokenWriter{}
+	tranid := make([]byte, 8)
+	switch binary.LittleEndian.Uint16(payload) {
+	case 5: // begin
+		binary.LittleEndian.PutUint64(tranid, c.srv.nextTranID())
+		w.envChange(envBeginTran, tranid, nil)
+	case 7: // commit
+		w.envChange(envCommitTran, nil, tranid)
+	case 8: // rollback
+		w.envChange(envRollbackTran, nil, tranid)
+	}
+	w.done(doneFinal, 0)
+	return c.write(w.Bytes())
+}
+
+// respond sends the responses to the queries of a request in order. While it
+// waits for the delay of a response an attention cancels the request.
+func (c *serverConn) respond(queries []string, messages <-chan message) error {
+	w := &tokenWriter{}
+	for i, query := range queries {
+		r := c.srv.response(query)
+		if r.Delay > 0 {
+			timer := time.NewTimer(r.Delay)
+			select {
+			case <-timer.C:
+			case m, ok := <-messages:
+				timer.Stop()
+				if !ok {
+					return io.EOF
+				}
+				if m.packetType != packAttention {
+					return errors.New("mssqltest: unexpected request while the query runs")
+				}
+				w.Reset()
+				w.done(doneAttn, 0)
+				return c.write(w.Bytes())
+			}
+		}
+		if query == "sp_prepexec" {
+			c.handle++
+			w.returnHandle(c.handle)
+		}
+		if err := w.response(r, i < len(queries)-1); err != nil {
+			return c.writeError(Error{Number: 50000, Message: err.Error()})
+		}
+	}
+	return c.write(w.Bytes())
+}
+
+func (c *serverConn) writeError(e Error) error {
+	w := &tokenWriter{}
+	w.error(e)
+	w.done(doneError, 0)
+	return c.write(w.Bytes())
+}
+
+func skipAllHeaders(payload []byte) []byte {
+	if len(payload) < 4 {
+		return nil
+	}
+	length := int(binary.LittleEndian.Uint32(payload))
+	if length > len(payload) {
+		return nil
+	}
+	return payload[length:]
+}
+
+func parseSQLBatch(payload []byte) string {
+	return fromUcs2(skipAllHeaders(payload))
+}
+
+// procedures that are called by id
+var procNames = map[uint16]string{
+	10: "sp_executesql",
+	11: "sp_prepare",
+	12: "sp_execute",
+	13: "sp_prepexec",
+	15: "sp_unprepare",
+}
+
+// batchFlag separates the calls of an RPC request.
+const batchFlag = 0xFF
+
+// parseRPC returns the statements of the calls of an RPC request.
+// Calls after a parameter of a type that is not parsed are ignored.
+func parseRPC(payload []byte) []string {
+	p := skipAllHeaders(payload)
+	var queries []string
+	for {
+		query, rest := parseRPCCall(p)
+		queries = append(queries, query)
+		if len(rest) == 0 || rest[0] != batchFlag {
+			return queries
+		}
+		p = rest[1:]
+	}
+}
+
+// parseRPCCall returns the statement of a sp_executesql call, or the name
+// of the called stored procedure, and the rest of p after the call.
+func parseRPCCall(p []byte) (string, []byte) {
+	if len(p) < 2 {
+		return "", nil
+	}
+	nameLength := int(binary.LittleEndian.Uint16(p))
+	p = p[2:]
+	var name string
+	if nameLength != 0xFFFF {
+		if len(p) < 2*nameLength {
+			return "", nil
+		}
+		name = fromUcs2(p[:2*nameLength])
+		p = p[2*nameLength:]
+	} else {
+		if len(p) < 2 {
+			return "", nil
+		}
+		id := binary.LittleEndian.Uint16(p)
+		p = p[2:]
+		var ok bool
+		if name, ok = procNames[id]; !ok {
+			name = fmt.Sprintf("procedure %d", id)
+		}
+	}
+	// option flags
+	if len(p) < 2 {
+		return name, nil
+	}
+	p = p[2:]
+	for i := 0; len(p) > 0 && p[0] != batchFlag; i++ {
+		value, rest, ok := parseParam(p)
+		if !ok {
+			return name, nil
+		}
+		if i == 0 && name == "sp_executesql" {
+			name = value
+		}
+		p = rest
+	}
+	return name, p
+}
+
+// parseParam parses a parameter and returns the value of a nvarchar
+// parameter and the rest of p after the parameter.
+func parseParam(p []byte) (value string, rest []byte, ok bool) {
+	if len(p) < 1 || len(p) < 1+2*int(p[0])+1+1 {
+		return "", nil, false
+	}
+	// name and status
+	p = p[1+2*int(p[0])+1:]
+	typ := p[0]
+	p = p[1:]
+	switch typ {
+	case typeIntN, typeBitN, typeFltN, typeDateTime2, typeDateTimeOffset, typeTime, typeDateN:
+		// length or scale of the type, then the length of the value
+		if typ != typeDateN {
+			if len(p) < 1 {
+				return "", nil, false
+			}
+			p = p[1:]
+		}
+		if len(p) < 1 || len(p) < 1+int(p[0]) {
+			return "", nil, false
+		}
+		return "", p[1+int(p[0]):], true
+	case typeNVarChar, typeBigVarChar, typeBigVarBin:
+	default:
+		return "", nil, false
+	}
+	if len(p) < 2 {
+		return "", nil, false
+	}
+	maxLength := binary.LittleEndian.Uint16(p)
+	p = p[2:]
+	if typ != typeBigVarBin {
+		// collation
+		if len(p) < 5 {
+			return "", nil, false
+		}
+		p = p[5:]
+	}
+	var data []byte
+	if maxLength != 0xFFFF {
+		if len(p) < 2 {
+			return "", nil, false
+		}
+		length := int(binary.LittleEndian.Uint16(p))
+		p = p[2:]
+		if length != 0xFFFF {
+			if len(p) < length {
+				return "", nil, false
+			}
+			data, p = p[:length], p[length:]
+		}
+	} else {
+		// PLP chunks after the total length
+		if len(p) < 8 {
+			return "", nil, false
+		}
+		null := binary.LittleEndian.Uint64(p) == 0xFFFFFFFFFFFFFFFF
+		p = p[8:]
+		for !null {
+			if len(p) < 4 {
+				return "", nil, false
+			}
+			length := int(binary.LittleEndian.Uint32(p))
+			p = p[4:]
+			if length == 0 {
+				break
+			}
+			if len(p) < length {
+				return "", nil, false
+			}
+			data = append(data, p[:length]...)
+			p = p[length:]
+		}
+	}
+	if typ == typeNVarChar {
+		value = fromUcs2(data)
+	}
+	return value, p, true
+}
@@ -0,0 +1,417 @@
+package mssqltest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// packet types of the TDS protocol
+const (
+	packSQLBatch    = 1
+	packRPCRequest  = 3
+	packReply       = 4
+	packAttention   = 6
+	packTransMgrReq = 14
+	packLogin7      = 16
+	packPrelogin    = 18
+)
+
+// tokens of the TDS protocol
+const (
+	tokenColMetadata = 0x81
+	tokenError       = 0xAA
+	tokenInfo        = 0xAB
+	tokenReturnValue = 0xAC
+	tokenLoginAck    = 0xAD
+	tokenRow         = 0xD1
+	tokenEnvChange   = 0xE3
+	tokenDone        = 0xFD
+)
+
+// status flags of DONE tokens
+const (
+	doneFinal = 0x00
+	doneMore  = 0x01
+	doneError = 0x02
+	doneCount = 0x10
+	doneAttn  = 0x20
+)
+
+// ENVCHANGE types
+const (
+	envDatabase     = 1
+	envSQLCollation = 7
+	envBeginTran    = 8
+	envCommitTran   = 9
+	envRollbackTran = 10
+	envRouting      = 20
+)
+
+// data types of result columns
+const (
+	typeIntN      = 0x26
+	typeBitN      = 0x68
+	typeFltN      = 0x6D
+	typeDateTime2 = 0x2A
+	typeBigVarBin = 0xA5
+	typeNVarChar  = 0xE7
+)
+
+// data types of parameters, which are skipped
+const (
+	typeDateN          = 0x28
+	typeTime           = 0x29
+	typeDateTimeOffset = 0x2B
+	typeBigVarChar     = 0xA7
+)
+
+// collation is SQL_Latin1_General_CP1_CI_AS
+var collation = []byte{0x09, 0x04, 0xD0, 0x00, 0x34}
+
+func ucs2(s string) []byte {
+	runes := utf16.Encode([]rune(s))
+	res := make([]byte, 2*len(runes))
+	for i, r := range runes {
+		binary.LittleEndian.PutUint16(res[2*i:], r)
+	}
+	return res
+}
+
+func fromUcs2(b []byte) string {
+	runes := make([]uint16, len(b)/2)
+	for i := range runes {
+		runes[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(runes))
+}
+
+// tokenWriter builds a token stream of a response.
+type tokenWriter struct {
+	bytes.Buffer
+}
+
+func (w *tokenWriter) uint16(v uint16) {
+	binary.Write(w, binary.LittleEndian, v)
+}
+
+func (w *tokenWriter) uint32(v uint32) {
+	binary.Write(w, binary.LittleEndian, v)
+}
+
+func (w *tokenWriter) uint64(v uint64) {
+	binary.Write(w, binary.LittleEndian, v)
+}
+
+func (w *tokenWriter) bVarChar(s string) {
+	b := ucs2(s)
+	w.WriteByte(byte(len(b) / 2))
+	w.Write(b)
+}
+
+func (w *tokenWriter) usVarChar(s string) {
+	b := ucs2(s)
+	w.uint16(uint16(len(b) / 2))
+	w.Write(b)
+}
+
+// withLength writes a token with a 2 byte length of the data written by f.
+func (w *tokenWriter) withLength(token byte, f func(body *tokenWriter)) {
+	body := &tokenWriter{}
+	f(body)
+	w.WriteByte(token)
+	w.uint16(uint16(body.Len()))
+	w.Write(body.Bytes())
+}
+
+func (w *tokenWriter) loginAck() {
+	w.withLength(tokenLoginAck, func(b *tokenWriter) {
+		b.WriteByte(1) // SQL interface
+		b.Write([]byte{0x74, 0x00, 0x00, 0x04})
+		b.bVarChar("Microsoft SQL Server")
+		b.Write([]byte{16, 0, 0, 0})
+	})
+}
+
+func (w *tokenWriter) envChange(envType byte, newValue, oldValue []byte) {
+	w.withLength(tokenEnvChange, func(b *tokenWriter) {
+		b.WriteByte(envType)
+		b.WriteByte(byte(len(newValue)))
+		b.Write(newValue)
+		b.WriteByte(byte(len(oldValue)))
+		b.Write(oldValue)
+	})
+}
+
+func (w *tokenWriter) envDatabase(database string) {
+	w.withLength(tokenEnvChange, func(b *tokenWriter) {
+		b.WriteByte(envDatabase)
+		b.bVarChar(database)
+		b.bVarChar("master")
+	})
+}
+
+func (w *tokenWriter) envRouting(server string, port uint16) {
+	w.withLength(tokenEnvChange, func(b *tokenWriter) {
+		b.WriteByte(envRouting)
+		value := &tokenWriter{}
+		value.WriteByte(0) // TCP
+		value.uint16(port)
+		value.usVarChar(server)
+		b.uint16(uint16(value.Len()))
+		b.Write(value.Bytes())
+		b.uint16(0)
+	})
+}
+
+// response writes the results and the error of r. With more set
+// the response is followed by the response of another call.
+func (w *tokenWriter) response(r Response, more bool) error {
+	var last uint16
+	if more {
+		last = doneMore
+	}
+	if r.Database != "" {
+		w.envDatabase(r.Database)
+	}
+	for _, m := range r.Messages {
+		w.info(m)
+	}
+	for i, result := range r.Results {
+		status := uint16(doneCount)
+		if result.NoCount {
+			status = 0
+		}
+		if i < len(r.Results)-1 || r.Err != nil {
+			status |= doneMore
+		} else {
+			status |= last
+		}
+		count := result.RowsAffected
+		if len(result.Columns) > 0 {
+			if err := w.result(result); err != nil {
+				return err
+			}
+			count = int64(len(result.Rows))
+		}
+		w.done(status, count)
+	}
+	if r.Err != nil {
+		w.error(*r.Err)
+		w.done(doneError|last, 0)
+	} else if len(r.Results) == 0 {
+		w.done(doneFinal|last, 0)
+	}
+	return nil
+}
+
+// returnHandle writes the handle output parameter of sp_prepexec.
+func (w *tokenWriter) returnHandle(handle int32) {
+	w.WriteByte(tokenReturnValue)
+	w.uint16(0) // ordinal
+	w.bVarChar("@handle")
+	w.WriteByte(1) // status, output parameter
+	w.uint32(0)    // user type
+	w.uint16(1)    // flags, nullable
+	w.WriteByte(typeIntN)
+	w.WriteByte(4)
+	w.WriteByte(4)
+	w.uint32(uint32(handle))
+}
+
+func (w *tokenWriter) done(status uint16, rowCount int64) {
+	w.WriteByte(tokenDone)
+	w.uint16(status)
+	w.uint16(0) // current command
+	w.uint64(uint64(rowCount))
+}
+
+func (w *tokenWriter) error(e Error) {
+	class := e.Class
+	if class == 0 {
+		class = 16
+	}
+	w.message(tokenError, class, e)
+}
+
+// info writes the informational message e, of severity 0 unless e has a Class.
+func (w *tokenWriter) info(e Error) {
+	w.message(tokenInfo, e.Class, e)
+}
+
+func (w *tokenWriter) message(token, class byte, e Error) {
+	state := e.State
+	if state == 0 {
+		state = 1
+	}
+	w.withLength(token, func(b *tokenWriter) {
+		b.uint32(uint32(e.Number))
+		b.WriteByte(state)
+		b.WriteByte(class)
+		b.usVarChar(e.Message)
+		b.bVarChar("mssqltest")
+		b.bVarChar("")
+		b.uint32(1)
+	})
+}
+
+// columnType returns the type of the column i of the rows,
+// from the first value that is not nil.
+func columnType(rows [][]interface{}, i int) (byte, error) {
+	for _, row := range rows {
+		if i >= len(row) {
+			return 0, fmt.Errorf("mssqltest: row has %d values, expected at least %d", len(row), i+1)
+		}
+		switch row[i].(type) {
+		case nil:
+			continue
+		case int, int8, int16, int32, int64, uint8, uint16, uint32:
+			return typeIntN, nil
+		case float32, float64:
+			return typeFltN, nil
+		case bool:
+			return typeBitN, nil
+		case string:
+			return typeNVarChar, nil
+		case []byte:
+			return typeBigVarBin, nil
+		case time.Time:
+			return typeDateTime2, nil
+		default:
+			return 0, fmt.Errorf("mssqltest: unsupported value type %T", row[i])
+		}
+	}
+	return typeNVarChar, nil
+}
+
+func (w *tokenWriter) result(r Result) error {
+	types := make([]byte, len(r.Columns))
+	for i := range r.Columns {
+		t, err := columnType(r.Rows, i)
+		if err != nil {
+			return err
+		}
+		types[i] = t
+	}
+	w.WriteByte(tokenColMetadata)
+	w.uint16(uint16(len(r.Columns)))
+	for i, name := range r.Columns {
+		w.uint32(0) // user type
+		w.uint16(1) // flags, nullable
+		w.WriteByte(types[i])
+		switch types[i] {
+		case typeIntN, typeFltN:
+			w.WriteByte(8)
+		case typeBitN:
+			w.WriteByte(1)
+		case typeDateTime2:
+			w.WriteByte(7) // scale
+		case typeNVarChar:
+			w.uint16(0xFFFF)
+			w.Write(collation)
+		case typeBigVarBin:
+			w.uint16(0xFFFF)
+		}
+		w.bVarChar(name)
+	}
+	for _, row := range r.Rows {
+		if len(row) != len(r.Columns) {
+			return fmt.Errorf("mssqltest: row has %d values for %d columns", len(row), len(r.Columns))
+		}
+		w.WriteByte(tokenRow)
+		for i, v := range row {
+			w.value(types[i], v)
+		}
+	}
+	return nil
+}
+
+func (w *tokenWriter) value(typ byte, v interface{}) {
+	switch typ {
+	case typeIntN, typeFltN, typeBitN, typeDateTime2:
+		if v == nil {
+			w.WriteByte(0)
+			return
+		}
+	case typeNVarChar, typeBigVarBin:
+		if v == nil {
+			w.uint64(math.MaxUint64) // PLP NULL
+			return
+		}
+	}
+	switch typ {
+	case typeIntN:
+		w.WriteByte(8)
+		w.uint64(uint64(toInt64(v)))
+	case typeFltN:
+		w.WriteByte(8)
+		f, ok := v.(float64)
+		if !ok {
+			f = float64(v.(float32))
+		}
+		w.uint64(math.Float64bits(f))
+	case typeBitN:
+		w.WriteByte(1)
+		if v.(bool) {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case typeDateTime2:
+		w.WriteByte(8)
+		w.Write(encodeDateTime2(v.(time.Time)))
+	case typeNVarChar:
+		w.plp(ucs2(v.(string)))
+	case typeBigVarBin:
+		w.plp(v.([]byte))
+	}
+}
+
+func (w *tokenWriter) plp(b []byte) {
+	w.uint64(uint64(len(b)))
+	if len(b) > 0 {
+		w.uint32(uint32(len(b)))
+		w.Write(b)
+	}
+	w.uint32(0) // terminator
+}
+
+func toInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	}
+	return 0
+}
+
+// encodeDateTime2 encodes the wall clock of t as a datetime2(7)
+func encodeDateTime2(t time.Time) []byte {
+	res := make([]byte, 8)
+	ticks := (int64(t.Hour())*3600+int64(t.Minute())*60+int64(t.Second()))*10000000 + int64(t.Nanosecond())/100
+	for i := 0; i < 5; i++ {
+		res[i] = byte(ticks >> (8 * i))
+	}
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	days := (date.Unix() - time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC).Unix()) / (24 * 60 * 60)
+	for i := 0; i < 3; i++ {
+		res[5+i] = byte(days >> (8 * i))
+	}
+	return res
+}
@@ -0,0 +1,297 @@
+// Package mssqltypes provides null-safe types for common SQL Server columns
+// that implement sql.Scanner and driver.Valuer, so they can be used as query
+// parameters and Scan destinations with the go-mssqldb driver.
+package mssqltypes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-sql/civil"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// NullUniqueIdentifier is a uniqueidentifier that may be NULL.
+type NullUniqueIdentifier = mssql.NullUniqueIdentifier
+
+// JSONText is the text of a JSON document in a nvarchar column. It is sent
+// as nvarchar, SQL Server has no JSON type before SQL Server 2025. An empty
+// JSONText is NULL.
+type JSONText []byte
+
+var errNotJSON = errors.New("mssqltypes: invalid JSON text")
+
+// Scan copies the JSON text of the column, NULL is an empty JSONText.
+func (j *JSONText) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*j = nil
+	case string:
+		*j = append((*j)[:0], v...)
+	case []byte:
+		*j = append((*j)[:0], v...)
+	default:
+		return fmt.Errorf("mssqltypes: cannot scan %T into JSONText", v)
+	}
+	return nil
+}
+
+// Value returns the JSON text as a string, an empty JSONText is NULL.
+// It fails if j is not valid JSON.
+func (j JSONText) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	if !json.Valid(j) {
+		return nil, errNotJSON
+	}
+	return string(j), nil
+}
+
+// MarshalJSON returns j, or null when j is empty.
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON sets j to a copy of data.
+func (j *JSONText) UnmarshalJSON(data []byte) error {
+	if !json.Valid(data) {
+		return errNotJSON
+	}
+	*j = append((*j)[:0], data...)
+	return nil
+}
+
+// Unmarshal unmarshals the JSON text into v.
+func (j JSONText) Unmarshal(v interface{}) error {
+	if len(j) == 0 {
+		return json.Unmarshal([]byte("null"), v)
+	}
+	return json.Unmarshal(j, v)
+}
+
+func (j JSONText) String() string {
+	return string(j)
+}
+
+// GzippedText is text that is stored gzip compressed in a varbinary column,
+// in the format of the COMPRESS and DECOMPRESS functions of SQL Server 2016.
+// The text is compressed when it is sent and decompressed when it is scanned.
+// An empty GzippedText is NULL.
+type GzippedText []byte
+
+// Scan decompresses the value of the column, NULL is an empty GzippedText.
+func (g *GzippedText) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case []byte:
+		r, err := gzip.NewReader(bytes.NewReader(v))
+		if err != nil {
+			return fmt.Errorf("mssqltypes: %v", err)
+		}
+		defer r.Close()
+		text, err := ioutil.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("mssqltypes: %v", err)
+		}
+		*g = text
+		return nil
+	}
+	return fmt.Errorf("mssqltypes: cannot scan %T into GzippedText", v)
+}
+
+// Value returns the compressed text, an empty GzippedText is NULL.
+func (g GzippedText) Value() (driver.Value, error) {
+	if len(g) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(g); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (g GzippedText) String() string {
+	return string(g)
+}
+
+// BitBool is a bool that scans from bit and integer columns, and from the
+// strings "0", "1", "true" and "false". NULL is false.
+type BitBool bool
+
+func (b *BitBool) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*b = false
+	case bool:
+		*b = BitBool(v)
+	case int64:
+		*b = v != 0
+	case []byte:
+		return b.Scan(string(v))
+	case string:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("mssqltypes: cannot scan %q into BitBool", v)
+		}
+		*b = BitBool(parsed)
+	default:
+		return fmt.Errorf("mssqltypes: cannot scan %T into BitBool", v)
+	}
+	return nil
+}
+
+// Value returns the bool, it is sent as a bit.
+func (b BitBool) Value() (driver.Value, error) {
+	return bool(b), nil
+}
+
+// NullDecimal is a decimal or numeric value that may be NULL. Decimal holds
+// the exact text of the value, e.g. "1234.5600", as the driver returns it.
+type NullDecimal struct {
+	Decimal string
+	Valid   bool // Valid is true if Decimal is not NULL
+}
+
+func (n *NullDecimal) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*n = NullDecimal{}
+		return nil
+	case []byte:
+		*n = NullDecimal{Decimal: string(v), Valid: true}
+	case string:
+		*n = NullDecimal{Decimal: v, Valid: true}
+	case int64:
+		*n = NullDecimal{Decimal: strconv.FormatInt(v, 10), Valid: true}
+	case float64:
+		*n = NullDecimal{Decimal: strconv.FormatFloat(v, 'f', -1, 64), Valid: true}
+	default:
+		return fmt.Errorf("mssqltypes: cannot scan %T into NullDecimal", v)
+	}
+	if _, ok := n.Rat(); !ok {
+		decimal := n.Decimal
+		*n = NullDecimal{}
+		return fmt.Errorf("mssqltypes: invalid decimal %q", decimal)
+	}
+	return nil
+}
+
+// Value returns the text of the decimal, which the server converts exactly.
+func (n NullDecimal) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	if _, ok := n.Rat(); !ok {
+		return nil, fmt.Errorf("mssqltypes: invalid decimal %q", n.Decimal)
+	}
+	return n.Decimal, nil
+}
+
+// Rat returns the value as a big.Rat, ok is false if n is NULL or not a decimal.
+func (n NullDecimal) Rat() (r *big.Rat, ok bool) {
+	if !n.Valid {
+		return nil, false
+	}
+	return new(big.Rat).SetString(n.Decimal)
+}
+
+func (n NullDecimal) String() string {
+	if !n.Valid {
+		return "NULL"
+	}
+	return n.Decimal
+}
+
+// CivilDate is a date column without a time zone that may be NULL.
+type CivilDate struct {
+	Date  civil.Date
+	Valid bool // Valid is true if Date is not NULL
+}
+
+func (d *CivilDate) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*d = CivilDate{}
+	case time.Time:
+		*d = CivilDate{Date: civil.DateOf(v), Valid: true}
+	case []byte:
+		return d.Scan(string(v))
+	case string:
+		date, err := civil.ParseDate(v)
+		if err != nil {
+			return fmt.Errorf("mssqltypes: %v", err)
+		}
+		*d = CivilDate{Date: date, Valid: true}
+	default:
+		return fmt.Errorf("mssqltypes: cannot scan %T into CivilDate", v)
+	}
+	return nil
+}
+
+// Value returns the date as a string in the yyyy-mm-dd format,
+// which the server converts independently of the DATEFORMAT setting.
+func (d CivilDate) Value() (driver.Value, error) {
+	if !d.Valid {
+		return nil, nil
+	}
+	return d.Date.String(), nil
+}
+
+// CivilTime is a time of day column that may be NULL.
+type CivilTime struct {
+	Time  civil.Time
+	Valid bool // Valid is true if Time is not NULL
+}
+
+func (t *CivilTime) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*t = CivilTime{}
+	case time.Time:
+		*t = CivilTime{Time: civil.TimeOf(v), Valid: true}
+	case []byte:
+		return t.Scan(string(v))
+	case string:
+		parsed, err := civil.ParseTime(v)
+		if err != nil {
+			return fmt.Errorf("mssqltypes: %v", err)
+		}
+		*t = CivilTime{Time: parsed, Valid: true}
+	default:
+		return fmt.Errorf("mssqltypes: cannot scan %T into CivilTime", v)
+	}
+	return nil
+}
+
+// Value returns the time as a string in the hh:mm:ss.fffffffff format.
+func (t CivilTime) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	s := t.Time.String()
+	// the time type has 7 fractional digits, civil.Time formats nanoseconds
+	if dot := strings.IndexByte(s, '.'); dot >= 0 && len(s)-dot-1 > 7 {
+		s = s[:dot+8]
+	}
+	return s, nil
+}
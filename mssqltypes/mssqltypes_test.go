@@ -0,0 +1,134 @@
+package mssqltypes
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/golang-sql/civil"
+)
+
+func TestJSONText(t *testing.T) {
+	var j JSONText
+	if err := j.Scan([]byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	var v struct{ A int }
+	if err := j.Unmarshal(&v); err != nil || v.A != 1 {
+		t.Errorf("unexpected %+v, %v", v, err)
+	}
+	if val, err := j.Value(); err != nil || val != `{"a":1}` {
+		t.Errorf("unexpected value %v, %v", val, err)
+	}
+	if err := j.Scan(nil); err != nil || j != nil {
+		t.Errorf("expected a NULL JSONText, got %v, %v", j, err)
+	}
+	if val, err := j.Value(); err != nil || val != nil {
+		t.Errorf("expected NULL, got %v, %v", val, err)
+	}
+	if _, err := JSONText(`{"a":`).Value(); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestGzippedText(t *testing.T) {
+	val, err := GzippedText("hello, world").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g GzippedText
+	if err = g.Scan(val); err != nil {
+		t.Fatal(err)
+	}
+	if g.String() != "hello, world" {
+		t.Errorf("unexpected text %q", g)
+	}
+	if err = g.Scan([]byte("not gzip")); err == nil {
+		t.Error("expected an error for text that is not compressed")
+	}
+}
+
+func TestBitBool(t *testing.T) {
+	tests := []struct {
+		in       interface{}
+		expected BitBool
+	}{
+		{true, true},
+		{int64(0), false},
+		{int64(1), true},
+		{[]byte("1"), true},
+		{"false", false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		b := !test.expected
+		if err := b.Scan(test.in); err != nil {
+			t.Errorf("%v: %v", test.in, err)
+		} else if b != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.in, test.expected, b)
+		}
+	}
+	var b BitBool
+	if err := b.Scan("yes please"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestNullDecimal(t *testing.T) {
+	var n NullDecimal
+	if err := n.Scan([]byte("1234.5600")); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := n.Value(); err != nil || val != "1234.5600" {
+		t.Errorf("unexpected value %v, %v", val, err)
+	}
+	if r, ok := n.Rat(); !ok || r.FloatString(2) != "1234.56" {
+		t.Errorf("unexpected rat %v", r)
+	}
+	if err := n.Scan(nil); err != nil || n.Valid {
+		t.Errorf("expected NULL, got %v, %v", n, err)
+	}
+	if err := n.Scan("abc"); err == nil || n.Valid {
+		t.Errorf("expected an error, got %v", n)
+	}
+	if _, err := (NullDecimal{Decimal: "1e", Valid: true}).Value(); err == nil {
+		t.Error("expected an error for an invalid decimal")
+	}
+}
+
+func TestCivilDateTime(t *testing.T) {
+	var d CivilDate
+	if err := d.Scan(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Valid || d.Date != (civil.Date{Year: 2024, Month: 2, Day: 29}) {
+		t.Errorf("unexpected date %+v", d)
+	}
+	if val, err := d.Value(); err != nil || val != "2024-02-29" {
+		t.Errorf("unexpected value %v, %v", val, err)
+	}
+	if err := d.Scan(nil); err != nil || d.Valid {
+		t.Errorf("expected NULL, got %+v", d)
+	}
+
+	var tm CivilTime
+	if err := tm.Scan("13:14:15.123456789"); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := tm.Value(); err != nil || val != "13:14:15.1234567" {
+		t.Errorf("unexpected value %v, %v", val, err)
+	}
+	if val, err := (CivilTime{}).Value(); err != nil || val != nil {
+		t.Errorf("expected NULL, got %v, %v", val, err)
+	}
+}
+
+var (
+	_ driver.Valuer = JSONText(nil)
+	_ driver.Valuer = GzippedText(nil)
+	_ driver.Valuer = BitBool(false)
+	_ driver.Valuer = NullDecimal{}
+	_ driver.Valuer = CivilDate{}
+	_ driver.Valuer = CivilTime{}
+	_ driver.Valuer = NullUniqueIdentifier{}
+)
@@ -0,0 +1,88 @@
+package mssql
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// serverFailures records when the servers of multi-host connection strings
+// last failed to connect. It is global because connections opened with
+// sql.Open do not share a Connector.
+var serverFailures = struct {
+	sync.Mutex
+	m map[string]time.Time
+}{m: map[string]time.Time{}}
+
+func serverFailed(server string, now time.Time) {
+	serverFailures.Lock()
+	serverFailures.m[server] = now
+	serverFailures.Unlock()
+}
+
+func serverSucceeded(server string) {
+	serverFailures.Lock()
+	delete(serverFailures.m, server)
+	serverFailures.Unlock()
+}
+
+// inCooldown reports if server failed less than cooldown before now.
+func inCooldown(server string, now time.Time, cooldown time.Duration) bool {
+	serverFailures.Lock()
+	defer serverFailures.Unlock()
+	failed, ok := serverFailures.m[server]
+	return ok && now.Sub(failed) < cooldown
+}
+
+// serverCandidates returns the configurations of the servers of a multi-host
+// connection string in the order they are tried. Servers that failed within the
+// cooldown are tried last, so a connection can still be made when all servers failed.
+func serverCandidates(p msdsn.Config, now time.Time) []msdsn.Config {
+	servers := make([]msdsn.ServerAddress, len(p.Servers))
+	copy(servers, p.Servers)
+	if p.RandomServerOrder {
+		rand.Shuffle(len(servers), func(i, j int) { servers[i], servers[j] = servers[j], servers[i] })
+	}
+	ready := make([]msdsn.Config, 0, len(servers))
+	var cooling []msdsn.Config
+	for _, s := range servers {
+		c := p
+		c.Host = s.Host
+		c.Instance = s.Instance
+		c.Port = s.Port
+		if !c.HostInCertificateProvided && c.TLSConfig != nil {
+			c.TLSConfig = c.TLSConfig.Clone()
+			c.TLSConfig.ServerName = c.Host
+		}
+		if inCooldown(serverName(c), now, p.ServerCooldown) {
+			cooling = append(cooling, c)
+		} else {
+			ready = append(ready, c)
+		}
+	}
+	return append(ready, cooling...)
+}
+
+// connectServers connects to the first available server of a multi-host connection string.
+func (d *Driver) connectServers(ctx context.Context, c *Connector, params msdsn.Config) (sess *tdsSession, err error) {
+	for i, p := range serverCandidates(params, time.Now()) {
+		if i > 0 {
+			if ctx.Err() != nil {
+				break
+			}
+			c.logEvent(ctx, Event{Type: EventFailover, Server: serverName(p), Message: "connecting to the next server"})
+		}
+		start := time.Now()
+		sess, err = connect(ctx, c, d.logger, p)
+		if err == nil {
+			serverSucceeded(serverName(p))
+			return sess, nil
+		}
+		serverFailed(serverName(p), time.Now())
+		c.logEvent(ctx, Event{Type: EventConnectFailed, Server: serverName(p), Duration: time.Since(start), Err: err, Message: err.Error()})
+	}
+	return nil, err
+}
@@ -9,6 +9,10 @@ import (
 type timeoutConn struct {
 	c       net.Conn
 	timeout time.Duration
+	// queryDeadline extends the timeout of reads up to the deadline of the running query
+	queryDeadline time.Time
+	// noTimeout disables the timeout of reads for the running query
+	noTimeout bool
 }
 
 func newTimeoutConn(conn net.Conn, timeout time.Duration) *timeoutConn {
@@ -20,7 +24,7 @@ func newTimeoutConn(conn net.Conn, timeout time.Duration) *timeoutConn {
 
 func (c *timeoutConn) Read(b []byte) (n int, err error) {
 	if c.timeout > 0 {
-		err = c.c.SetDeadline(time.Now().Add(c.timeout))
+		err = c.c.SetDeadline(c.readDeadline())
 		if err != nil {
 			return
 		}
@@ -28,6 +32,29 @@ func (c *timeoutConn) Read(b []byte) (n int, err error) {
 	return c.c.Read(b)
 }
 
+// readDeadline returns the deadline of the next read. A query that waits,
+// like WAITFOR, must not fail on the connection timeout while its context
+// allows it to run longer, the context cancels it with an attention instead.
+// The connection timeout then still limits the wait for the attention acknowledgment.
+func (c *timeoutConn) readDeadline() time.Time {
+	if c.noTimeout {
+		return time.Time{}
+	}
+	deadline := time.Now().Add(c.timeout)
+	if !c.queryDeadline.IsZero() {
+		if queryDeadline := c.queryDeadline.Add(c.timeout); queryDeadline.After(deadline) {
+			deadline = queryDeadline
+		}
+	}
+	return deadline
+}
+
+// setQuery sets the deadline of the query whose response is read next.
+func (c *timeoutConn) setQuery(deadline time.Time, noTimeout bool) {
+	c.queryDeadline = deadline
+	c.noTimeout = noTimeout
+}
+
 func (c *timeoutConn) Write(b []byte) (n int, err error) {
 	if c.timeout > 0 {
 		err = c.c.SetDeadline(time.Now().Add(c.timeout))
@@ -5,6 +5,7 @@ package mssql
 
 import (
 	"context"
+	"database/sql/driver"
 	"net"
 	"testing"
 	"time"
@@ -177,3 +178,43 @@ func TestPassthroughConn(t *testing.T) {
 		}
 	})
 }
+
+func TestTimeoutConnQueryDeadline(t *testing.T) {
+	_, conn := net.Pipe()
+	tconn := newTimeoutConn(conn, time.Second)
+
+	before := time.Now()
+	if d := tconn.readDeadline(); d.Before(before.Add(time.Second)) || d.After(time.Now().Add(time.Second)) {
+		t.Errorf("expected the connection timeout without a query deadline, got %v", d.Sub(before))
+	}
+
+	queryDeadline := time.Now().Add(time.Hour)
+	tconn.setQuery(queryDeadline, false)
+	if d := tconn.readDeadline(); !d.Equal(queryDeadline.Add(time.Second)) {
+		t.Errorf("expected the query deadline plus the connection timeout, got %v", d)
+	}
+
+	tconn.setQuery(time.Now().Add(-time.Hour), false)
+	if d := tconn.readDeadline(); d.Before(before.Add(time.Second)) {
+		t.Errorf("expected a past query deadline to keep the connection timeout, got %v", d.Sub(before))
+	}
+
+	tconn.setQuery(time.Time{}, true)
+	if d := tconn.readDeadline(); !d.IsZero() {
+		t.Errorf("expected no deadline with NoQueryTimeout, got %v", d)
+	}
+
+	c := &Conn{}
+	if err := c.CheckNamedValue(&driver.NamedValue{Value: NoQueryTimeout{}}); err != driver.ErrRemoveArgument {
+		t.Fatalf("expected ErrRemoveArgument, got %v", err)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), queryDeadline)
+	defer cancel()
+	reader := startReading(&tdsSession{buf: makeReplyBuf(makeDoneToken(0, 0)), conn: tconn}, ctx, c.outs)
+	if err := reader.iterateResponse(); err != nil {
+		t.Fatal(err)
+	}
+	if !tconn.noTimeout || !tconn.queryDeadline.Equal(queryDeadline) {
+		t.Errorf("expected startReading to set the query deadline and NoQueryTimeout, got %v %v", tconn.queryDeadline, tconn.noTimeout)
+	}
+}
@@ -0,0 +1,277 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang-sql/civil"
+	"github.com/microsoft/go-mssqldb/internal/jsonfields"
+)
+
+const openJSONTag = "openjson"
+
+// OpenJSONRows are the rows of a slice of structs that are sent as a single
+// JSON parameter and selected with OPENJSON. It is a portable alternative to
+// a TVP for set-based statements on servers where table types cannot be created:
+//
+//	type orderItem struct {
+//		OrderID int64   `json:"order_id"`
+//		SKU     string  `json:"sku" openjson:"varchar(20)"`
+//		Price   float64 `json:"price" openjson:"decimal(18,2)"`
+//	}
+//
+//	items, err := mssql.OpenJSON(orderItems)
+//	...
+//	_, err = db.ExecContext(ctx, "insert into dbo.order_items (order_id, sku, price) select order_id, sku, price from "+
+//		items.Source("@items"), sql.Named("items", items))
+//
+// The columns of the rowset are the exported fields of the struct, named by
+// their json tag like encoding/json names the keys of the document, or by
+// their db tag. The SQL type of a column is derived from the Go type of its
+// field, the openjson tag overrides it. Fields of other struct, map and slice
+// types are nvarchar(max) columns with their JSON, as are the fields with the
+// json option of the db tag, as in db:"payload,json", whatever their type.
+// Fields with the json or db tag "-" are skipped.
+//
+// OPENJSON requires SQL Server 2016 or newer and database compatibility level 130.
+type OpenJSONRows struct {
+	doc     string
+	columns []openJSONColumn
+}
+
+type openJSONColumn struct {
+	name    string
+	sqlType string
+	asJSON  bool
+	// identity is set for a field with the tvp tag "@identity"
+	identity bool
+	// index and omitEmpty describe the field of the column
+	index     []int
+	omitEmpty bool
+}
+
+// OpenJSON returns the OpenJSONRows of rows, a slice of structs or of pointers to structs.
+func OpenJSON(rows interface{}) (OpenJSONRows, error) {
+	t := reflect.TypeOf(rows)
+	if t == nil || t.Kind() != reflect.Slice {
+		return OpenJSONRows{}, fmt.Errorf("mssql: OpenJSON expects a slice of structs, got %T", rows)
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return OpenJSONRows{}, fmt.Errorf("mssql: OpenJSON expects a slice of structs, got %T", rows)
+	}
+	columns, err := openJSONColumns(elem)
+	if err != nil {
+		return OpenJSONRows{}, err
+	}
+	if len(columns) == 0 {
+		return OpenJSONRows{}, fmt.Errorf("mssql: %s has no exported fields", elem)
+	}
+	doc, err := openJSONDocument(reflect.ValueOf(rows), columns)
+	if err != nil {
+		return OpenJSONRows{}, err
+	}
+	return OpenJSONRows{doc: doc, columns: columns}, nil
+}
+
+func openJSONColumns(t reflect.Type) ([]openJSONColumn, error) {
+	fields := jsonfields.Fields(t, nil)
+	columns := make([]openJSONColumn, 0, len(fields))
+	for _, field := range fields {
+		tvpTagValue, _ := field.Tag.Lookup(tvpTag)
+		column := openJSONColumn{name: field.Name, identity: tvpTagValue == tvpIdentity, index: field.Index, omitEmpty: field.OmitEmpty}
+		if field.JSON {
+			// OPENJSON returns objects and arrays only as json, in nvarchar(max)
+			column.sqlType, column.asJSON = "nvarchar(max)", true
+		} else if sqlType, ok := field.Tag.Lookup(openJSONTag); ok {
+			column.sqlType = sqlType
+		} else {
+			var err error
+			if column.sqlType, column.asJSON, err = openJSONType(field.Type); err != nil {
+				return nil, fmt.Errorf("mssql: field %s: %v", field.StructField.Name, err)
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Layouts of the date and time values in the document, with the 7 fractional
+// digits of datetimeoffset, datetime2 and time. encoding/json writes up to 9
+// digits, which OPENJSON fails to convert.
+const (
+	openJSONTimeLayout     = "2006-01-02T15:04:05.0000000Z07:00"
+	openJSONDateTimeLayout = "2006-01-02T15:04:05.0000000"
+	openJSONClockLayout    = "15:04:05.0000000"
+)
+
+// openJSONDocument returns the JSON array of the rows, an object with the
+// columns of every row.
+func openJSONDocument(rows reflect.Value, columns []openJSONColumn) (string, error) {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < rows.Len(); i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		row := rows.Index(i)
+		if row.Kind() == reflect.Ptr {
+			if row.IsNil() {
+				sb.WriteString("null")
+				continue
+			}
+			row = row.Elem()
+		}
+		sb.WriteByte('{')
+		first := true
+		for _, column := range columns {
+			v := row.FieldByIndex(column.index)
+			if column.omitEmpty && jsonfields.IsEmpty(v) {
+				continue
+			}
+			value, err := openJSONValue(v, column.asJSON)
+			if err != nil {
+				return "", fmt.Errorf("mssql: column %s: %v", column.name, err)
+			}
+			if !first {
+				sb.WriteByte(',')
+			}
+			first = false
+			name, _ := json.Marshal(column.name)
+			sb.Write(name)
+			sb.WriteByte(':')
+			sb.Write(value)
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteByte(']')
+	return sb.String(), nil
+}
+
+// openJSONValue returns the JSON of the value of a column, asJSON
+// columns hold the JSON of the value as it is.
+func openJSONValue(v reflect.Value, asJSON bool) ([]byte, error) {
+	if asJSON {
+		return json.Marshal(v.Interface())
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return []byte("null"), nil
+		}
+		v = v.Elem()
+	}
+	switch v.Type() {
+	case timeType:
+		return json.Marshal(v.Interface().(time.Time).Format(openJSONTimeLayout))
+	case civilDateTimeType:
+		return json.Marshal(v.Interface().(civil.DateTime).In(time.UTC).Format(openJSONDateTimeLayout))
+	case civilTimeType:
+		t := v.Interface().(civil.Time)
+		return json.Marshal(time.Date(1, 1, 1, t.Hour, t.Minute, t.Second, t.Nanosecond, time.UTC).Format(openJSONClockLayout))
+	}
+	return json.Marshal(v.Interface())
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	civilDateType       = reflect.TypeOf(civil.Date{})
+	civilDateTimeType   = reflect.TypeOf(civil.DateTime{})
+	civilTimeType       = reflect.TypeOf(civil.Time{})
+	uniqueIdentiferType = reflect.TypeOf(UniqueIdentifier{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// openJSONType returns the SQL type of a column with the JSON of a value of t.
+func openJSONType(t reflect.Type) (sqlType string, asJSON bool, err error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return "datetimeoffset", false, nil
+	case civilDateType:
+		return "date", false, nil
+	case civilDateTimeType:
+		return "datetime2", false, nil
+	case civilTimeType:
+		return "time", false, nil
+	case uniqueIdentiferType:
+		return "uniqueidentifier", false, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bit", false, nil
+	case reflect.Uint8:
+		return "tinyint", false, nil
+	case reflect.Int8, reflect.Int16:
+		return "smallint", false, nil
+	case reflect.Int32, reflect.Uint16:
+		return "int", false, nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint", false, nil
+	case reflect.Uint, reflect.Uint64:
+		return "decimal(20, 0)", false, nil
+	case reflect.Float32:
+		return "real", false, nil
+	case reflect.Float64:
+		return "float", false, nil
+	case reflect.String:
+		return "nvarchar(max)", false, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "", false, errors.New("[]byte is sent as base64, set the SQL type with the openjson tag")
+		}
+		return "nvarchar(max)", true, nil
+	case reflect.Struct, reflect.Map, reflect.Array:
+		if reflect.PtrTo(t).Implements(textMarshalerType) || t.Implements(textMarshalerType) {
+			return "nvarchar(max)", false, nil
+		}
+		return "nvarchar(max)", true, nil
+	}
+	return "", false, fmt.Errorf("unsupported type %s, set the SQL type with the openjson tag", t)
+}
+
+// Value returns the JSON array of the rows.
+func (r OpenJSONRows) Value() (driver.Value, error) {
+	return r.doc, nil
+}
+
+// Source returns the OPENJSON rowset of the rows that were passed
+// as the parameter param, e.g. "@p1" or "@items":
+//
+//	openjson(@items) with ([order_id] bigint '$."order_id"', ...)
+func (r OpenJSONRows) Source(param string) string {
+	return openJSONSource(param, r.columns)
+}
+
+func openJSONSource(param string, columns []openJSONColumn) string {
+	var sb strings.Builder
+	sb.WriteString("openjson(")
+	sb.WriteString(param)
+	sb.WriteString(") with (")
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(TSQLQuoter{}.ID(column.name))
+		sb.WriteByte(' ')
+		sb.WriteString(column.sqlType)
+		sb.WriteByte(' ')
+		path := `$."` + strings.Replace(strings.Replace(column.name, `\`, `\\`, -1), `"`, `\"`, -1) + `"`
+		sb.WriteString(sqlString(path))
+		if column.asJSON {
+			sb.WriteString(" as json")
+		}
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+package mssql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-sql/civil"
+)
+
+type openJSONBase struct {
+	ID int64 `json:"id"`
+}
+
+type openJSONItem struct {
+	openJSONBase
+	SKU      string            `json:"sku" openjson:"varchar(20)"`
+	Price    float64           `json:"price,omitempty" openjson:"decimal(18,2)"`
+	Quantity *int16            `json:"quantity"`
+	Shipped  time.Time         `json:"shipped"`
+	Due      civil.Date        `json:"due"`
+	Tags     []string          `json:"tags"`
+	Note     string            `json:"-"`
+	Extra    map[string]string `json:"it's \"odd\""`
+	Plain    bool
+	internal int
+}
+
+func TestOpenJSON(t *testing.T) {
+	t.Parallel()
+	qty := int16(2)
+	shipped := time.Date(2023, 5, 6, 7, 8, 9, 123456789, time.FixedZone("", 2*60*60))
+	rows, err := OpenJSON([]*openJSONItem{{openJSONBase: openJSONBase{ID: 1}, SKU: "a-1", Quantity: &qty, Shipped: shipped, Due: civil.Date{Year: 2023, Month: 5, Day: 20}, Note: "x", internal: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `openjson(@items) with ([id] bigint '$."id"', [sku] varchar(20) '$."sku"', [price] decimal(18,2) '$."price"', ` +
+		`[quantity] smallint '$."quantity"', [shipped] datetimeoffset '$."shipped"', [due] date '$."due"', ` +
+		`[tags] nvarchar(max) '$."tags"' as json, [it's "odd"] nvarchar(max) '$."it''s \"odd\""' as json, [Plain] bit '$."Plain"')`
+	if got := rows.Source("@items"); got != expected {
+		t.Errorf("unexpected source\n%s\nexpected\n%s", got, expected)
+	}
+	v, err := rows.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := v.(string)
+	// the time has the 7 fractional digits of datetimeoffset
+	expectedDoc := `[{"id":1,"sku":"a-1","quantity":2,"shipped":"2023-05-06T07:08:09.1234567+02:00","due":"2023-05-20",` +
+		`"tags":null,"it's \"odd\"":null,"Plain":false}]`
+	if doc != expectedDoc {
+		t.Errorf("unexpected document\n%s\nexpected\n%s", doc, expectedDoc)
+	}
+
+	empty, err := OpenJSON([]openJSONItem(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ = empty.Value(); v != "[]" {
+		t.Errorf("expected an empty array for a nil slice, got %v", v)
+	}
+
+	for _, invalid := range []interface{}{nil, openJSONItem{}, []int{1}, []struct{ B []byte }{}, []struct{ C chan int }{}, []struct{ x int }{}} {
+		if _, err = OpenJSON(invalid); err == nil {
+			t.Errorf("expected an error for %T", invalid)
+		}
+	}
+}
+
+func TestOpenJSONJSONOption(t *testing.T) {
+	t.Parallel()
+	type document struct {
+		ID      int64             `db:"id"`
+		Payload map[string]int    `db:"payload,json"`
+		Owner   *openJSONBase     `json:"owner" db:",json"`
+		Labels  map[string]string `db:"-"`
+	}
+	rows, err := OpenJSON([]document{{ID: 1, Payload: map[string]int{"a": 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `openjson(@p1) with ([id] bigint '$."id"', [payload] nvarchar(max) '$."payload"' as json, [owner] nvarchar(max) '$."owner"' as json)`
+	if got := rows.Source("@p1"); got != expected {
+		t.Errorf("unexpected source\n%s\nexpected\n%s", got, expected)
+	}
+	if v, _ := rows.Value(); v != `[{"id":1,"payload":{"a":1},"owner":null}]` {
+		t.Errorf("unexpected document %v", v)
+	}
+}
@@ -0,0 +1,61 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Login errors of SQL logins whose password has to be changed.
+const (
+	errPasswordExpired    = 18487
+	errPasswordMustChange = 18488
+)
+
+// loginParams returns the connection parameters for a new connection of
+// the connector, with the password of the PasswordProvider. Once the
+// password was changed at a login, the connections log in with the new
+// password instead of changing it again.
+func (c *Connector) loginParams(ctx context.Context) (msdsn.Config, error) {
+	p := c.params
+	if c.PasswordProvider != nil {
+		password, err := c.PasswordProvider(ctx)
+		if err != nil {
+			return p, fmt.Errorf("mssql: password provider failed: %w", err)
+		}
+		p.Password = password
+	}
+	c.passwordMu.Lock()
+	defer c.passwordMu.Unlock()
+	switch {
+	case c.changedPassword != "":
+		// the provider may return a password that was rotated since
+		if p.Password == c.replacedPassword {
+			p.Password = c.changedPassword
+		}
+		p.ChangePassword = ""
+	case c.NewPassword != "":
+		p.ChangePassword = c.NewPassword
+	}
+	return p, nil
+}
+
+// passwordChanged records that a login with p changed the password.
+func (c *Connector) passwordChanged(p msdsn.Config) {
+	if p.ChangePassword == "" {
+		return
+	}
+	c.passwordMu.Lock()
+	c.replacedPassword, c.changedPassword = p.Password, p.ChangePassword
+	c.passwordMu.Unlock()
+}
+
+// passwordChangeHint returns the error err of a login, wrapped with how to
+// change the password when the password expired or must be changed.
+func passwordChangeHint(err Error, p msdsn.Config) error {
+	if (err.Number == errPasswordExpired || err.Number == errPasswordMustChange) && p.ChangePassword == "" {
+		return fmt.Errorf("%w (set the new password with the change password connection parameter or Connector.NewPassword)", err)
+	}
+	return err
+}
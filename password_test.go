@@ -0,0 +1,118 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+func TestConnectorNewPassword(t *testing.T) {
+	c, err := NewConnector("server=test;user id=app;password=old;change password=fromdsn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := mustLoginParams(t, c); p.Password != "old" || p.ChangePassword != "fromdsn" {
+		t.Errorf("expected the change password of the connection string, got %q and %q", p.Password, p.ChangePassword)
+	}
+	c.NewPassword = "new"
+	p := mustLoginParams(t, c)
+	if p.Password != "old" || p.ChangePassword != "new" {
+		t.Errorf("expected NewPassword to be used, got %q and %q", p.Password, p.ChangePassword)
+	}
+	c.passwordChanged(p)
+	if p = mustLoginParams(t, c); p.Password != "new" || p.ChangePassword != "" {
+		t.Errorf("expected a login with the changed password, got %q and %q", p.Password, p.ChangePassword)
+	}
+	if c.params.Password != "old" {
+		t.Error("expected the connection string parameters not to be changed")
+	}
+}
+
+func mustLoginParams(t *testing.T, c *Connector) msdsn.Config {
+	t.Helper()
+	p, err := c.loginParams(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestConnectorPasswordProvider(t *testing.T) {
+	c, err := NewConnector("server=test;user id=app;password=static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := "v1"
+	c.PasswordProvider = func(ctx context.Context) (string, error) {
+		if secret == "" {
+			return "", errors.New("vault sealed")
+		}
+		return secret, nil
+	}
+	if p := mustLoginParams(t, c); p.Password != "v1" {
+		t.Errorf("expected the password of the provider, got %q", p.Password)
+	}
+	secret = "v2"
+	if p := mustLoginParams(t, c); p.Password != "v2" {
+		t.Errorf("expected the rotated password, got %q", p.Password)
+	}
+
+	// a password changed at login is used until the provider returns a new one
+	c.NewPassword = "v3"
+	p := mustLoginParams(t, c)
+	c.passwordChanged(p)
+	if p = mustLoginParams(t, c); p.Password != "v3" || p.ChangePassword != "" {
+		t.Errorf("expected the changed password, got %q and %q", p.Password, p.ChangePassword)
+	}
+	secret = "v4"
+	if p = mustLoginParams(t, c); p.Password != "v4" || p.ChangePassword != "" {
+		t.Errorf("expected the rotated password, got %q and %q", p.Password, p.ChangePassword)
+	}
+
+	secret = ""
+	if _, err = c.loginParams(context.Background()); err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("expected the provider error, got %v", err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	if err = db.Ping(); err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("expected the connection to fail with the provider error, got %v", err)
+	}
+}
+
+func TestPrepareLoginChangePassword(t *testing.T) {
+	p, err := msdsn.Parse("server=test;user id=app;password=old;change password=new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := prepareLogin(context.Background(), &Connector{}, p, optionalLogger{}, nil, &featureExtFedAuth{}, defaultPacketSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.ChangePassword != "new" {
+		t.Errorf("expected the password change to be requested, got %q", l.ChangePassword)
+	}
+}
+
+func TestPasswordChangeHint(t *testing.T) {
+	loginErr := Error{Number: errPasswordMustChange, Message: "login error: Login failed for user 'app'. Reason: The password of the account must be changed."}
+	err := passwordChangeHint(loginErr, msdsn.Config{})
+	if !strings.Contains(err.Error(), "change password") {
+		t.Errorf("expected a hint in %q", err)
+	}
+	var sqlErr Error
+	if !errors.As(err, &sqlErr) || sqlErr.Message != loginErr.Message {
+		t.Errorf("expected the error of the server to be wrapped unchanged, got %#v", sqlErr)
+	}
+	if _, ok := passwordChangeHint(loginErr, msdsn.Config{ChangePassword: "new"}).(Error); !ok {
+		t.Error("unexpected hint when the password is changed")
+	}
+	loginErr = Error{Number: 18456, Message: "login error: Login failed for user 'app'."}
+	if _, ok := passwordChangeHint(loginErr, msdsn.Config{}).(Error); !ok {
+		t.Error("unexpected hint for another login error")
+	}
+}
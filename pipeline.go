@@ -0,0 +1,163 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPipelineSent is returned when a query is added to a Pipeline
+// that was sent already, or when the Pipeline is sent again.
+var ErrPipelineSent = errors.New("mssql: the pipeline was sent already")
+
+// Pipeline sends independent queries to the server in a single round trip
+// and returns their results through a Future per query, so that a
+// high-latency link is not crossed once per query.
+//
+//	var p mssql.Pipeline
+//	orders := p.Query("select id, total from dbo.orders where customer_id = @p1", id)
+//	customer := p.Query("select name, email from dbo.customers where id = @p1", id)
+//	if err := p.Send(ctx, db); err != nil {
+//		...
+//	}
+//	res, err := customer.Await(ctx)
+//
+// The queries are sent as a Batch and run in order on a single connection.
+// Every query must return exactly one result set, the result sets are
+// correlated to the queries by their order. The results are read in the
+// background and held in memory, a Future is resolved as soon as its result
+// set was read. When a query fails the queries after it fail as well. The
+// end of a result set is only seen when the next one starts, so the error
+// of a query that fails before it returns a result set is also returned
+// for the query before it.
+//
+// Queries can be added to a Pipeline from several goroutines,
+// and a Future can be awaited from any goroutine.
+type Pipeline struct {
+	mu      sync.Mutex
+	batch   Batch
+	futures []*Future
+	sent    bool
+}
+
+// PipelineResult is the result set of a query of a Pipeline.
+type PipelineResult struct {
+	Columns []string
+	// Rows hold the values of the columns as they are scanned into an interface{}.
+	Rows [][]interface{}
+}
+
+// Future is the pending result of a query of a Pipeline.
+type Future struct {
+	done   chan struct{}
+	result *PipelineResult
+	err    error
+}
+
+// Query adds a query with its arguments to the pipeline.
+func (p *Pipeline) Query(query string, args ...interface{}) *Future {
+	f := &Future{done: make(chan struct{})}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sent {
+		f.resolve(nil, ErrPipelineSent)
+		return f
+	}
+	p.batch.Queue(query, args...)
+	p.futures = append(p.futures, f)
+	return f
+}
+
+// Send sends the queries of the pipeline with q and starts reading their
+// results. ctx limits the time it takes to send the queries and read the results.
+func (p *Pipeline) Send(ctx context.Context, q Querier) error {
+	p.mu.Lock()
+	if p.sent {
+		p.mu.Unlock()
+		return ErrPipelineSent
+	}
+	p.sent = true
+	futures := p.futures
+	p.mu.Unlock()
+	if len(futures) == 0 {
+		return nil
+	}
+	rows, err := q.QueryContext(ctx, "", &p.batch)
+	if err != nil {
+		for _, f := range futures {
+			f.resolve(nil, err)
+		}
+		return err
+	}
+	go readPipeline(rows, futures)
+	return nil
+}
+
+// readPipeline resolves the futures with the result sets of rows in order.
+func readPipeline(rows *sql.Rows, futures []*Future) {
+	defer rows.Close()
+	for i, f := range futures {
+		var err error
+		if i > 0 && !rows.NextResultSet() {
+			if err = rows.Err(); err == nil {
+				err = errors.New("mssql: a query of the pipeline returned no result set")
+			}
+		}
+		var res *PipelineResult
+		if err == nil {
+			res, err = readPipelineResult(rows)
+		}
+		f.resolve(res, err)
+		if err != nil {
+			for _, f := range futures[i+1:] {
+				f.resolve(nil, fmt.Errorf("mssql: an earlier query of the pipeline failed: %w", err))
+			}
+			return
+		}
+	}
+}
+
+func readPipelineResult(rows *sql.Rows) (*PipelineResult, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	res := &PipelineResult{Columns: cols}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		res.Rows = append(res.Rows, values)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (f *Future) resolve(res *PipelineResult, err error) {
+	f.result, f.err = res, err
+	close(f.done)
+}
+
+// Done returns a channel that is closed when the result of the query is available.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the result of the query, or until ctx is done.
+func (f *Future) Await(ctx context.Context) (*PipelineResult, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,74 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestPipeline(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=pipelinehost;user id=sa;password=pwd;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select name from dbo.customers where id = @p1", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"name"}, Rows: [][]interface{}{{"alice"}}}}})
+	srv.Handle("select id from dbo.orders where customer_id = @p1", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"id"}, Rows: [][]interface{}{{1}, {2}}}}})
+	srv.Handle("select missing", mssqltest.Response{Err: &mssqltest.Error{Number: 207, Message: "Invalid column name 'missing'."}})
+	ctx := context.Background()
+
+	var p Pipeline
+	var wg sync.WaitGroup
+	futures := make([]*Future, 2)
+	for i, query := range []string{"select name from dbo.customers where id = @p1", "select id from dbo.orders where customer_id = @p1"} {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			futures[i] = p.Query(query, 7)
+		}(i, query)
+	}
+	wg.Wait()
+	if err := p.Send(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	orders, err := futures[1].Await(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Rows) != 2 || orders.Columns[0] != "id" {
+		t.Errorf("unexpected orders %v", orders)
+	}
+	customer, err := futures[0].Await(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customer.Rows) != 1 || customer.Rows[0][0] != "alice" {
+		t.Errorf("unexpected customer %v", customer)
+	}
+	if err = p.Send(ctx, db); err != ErrPipelineSent {
+		t.Errorf("expected ErrPipelineSent, got %v", err)
+	}
+	if _, err = p.Query("select 1").Await(ctx); err != ErrPipelineSent {
+		t.Errorf("expected ErrPipelineSent, got %v", err)
+	}
+
+	var failing Pipeline
+	first := failing.Query("select name from dbo.customers where id = @p1", 7)
+	second := failing.Query("select missing")
+	third := failing.Query("select id from dbo.orders where customer_id = @p1", 7)
+	if err = failing.Send(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	<-third.Done()
+	var sqlErr Error
+	if _, err = first.Await(ctx); !errors.As(err, &sqlErr) || sqlErr.Number != 207 {
+		t.Errorf("expected the error to end the result set before the failed query, got %v", err)
+	}
+	if _, err = second.Await(ctx); !errors.As(err, &sqlErr) || sqlErr.Number != 207 {
+		t.Errorf("expected the error of the query, got %v", err)
+	}
+	if _, err = third.Await(ctx); !errors.As(err, &sqlErr) {
+		t.Errorf("expected the queries after the failed query to fail, got %v", err)
+	}
+}
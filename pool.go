@@ -0,0 +1,117 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+const defaultHealthCheckSQL = "select 1"
+
+// connectionExpiry returns the end of the lifetime of a connection opened at now,
+// the connection lifetime plus a random part of the lifetime jitter.
+func connectionExpiry(now time.Time, p msdsn.Config) time.Time {
+	if p.ConnectionLifetime <= 0 {
+		return time.Time{}
+	}
+	lifetime := p.ConnectionLifetime
+	if p.LifetimeJitter > 0 {
+		lifetime += time.Duration(rand.Int63n(int64(p.LifetimeJitter)))
+	}
+	return now.Add(lifetime)
+}
+
+func (c *Conn) expired(now time.Time) bool {
+	return !c.expires.IsZero() && !now.Before(c.expires)
+}
+
+// checkHealth runs the health check query when the connection was idle
+// longer than the health check interval of the connection string.
+// Load balancers and gateways, such as the Azure SQL gateway, drop
+// idle connections silently, the check finds them before the
+// connection is used by the application.
+func (c *Conn) checkHealth(ctx context.Context) error {
+	if c.connector == nil || c.connector.params.HealthCheckInterval <= 0 {
+		return nil
+	}
+	idle := time.Since(c.sess.buf.lastRead)
+	if idle < c.connector.params.HealthCheckInterval {
+		return nil
+	}
+	query := c.connector.HealthCheckSQL
+	if query == "" {
+		query = defaultHealthCheckSQL
+	}
+	stmt := &Stmt{c: c, query: query, skipEncryption: true}
+	_, err := stmt.ExecContext(ctx, nil)
+	if err != nil {
+		c.logEvent(ctx, Event{Type: EventWarning, Err: err, Message: "health check of a connection idle for " + idle.Round(time.Second).String() + " failed"})
+	}
+	return err
+}
+
+// WarmPool opens n connections of db at the same time and returns them
+// to the pool, so the first requests of an application do not wait for
+// logins. The pool keeps only as many idle connections as allowed by
+// db.SetMaxIdleConns, which defaults to 2, set it to at least n.
+func WarmPool(ctx context.Context, db *sql.DB, n int) error {
+	conns := make([]*sql.Conn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i], errs[i] = db.Conn(ctx)
+		}(i)
+	}
+	wg.Wait()
+	var err error
+	for i := range conns {
+		if conns[i] != nil {
+			conns[i].Close()
+		}
+		if err == nil {
+			err = errs[i]
+		}
+	}
+	return err
+}
+
+// drainPollInterval is the interval in which Drain checks the open
+// connections of the pool.
+const drainPollInterval = 10 * time.Millisecond
+
+// Drain closes db for a deployment: it stops handing out connections,
+// closes the idle connections and waits until the connections in use are
+// returned and closed, or until ctx is done. It returns an error with the
+// number of connections still in use when ctx is done first, these are
+// closed when the application returns them.
+//
+// Connections are closed cleanly with the OnClose callback of the
+// Connector. TDS 7 has no logout message, the server ends the session
+// and rolls back its open transaction when the connection is closed, so
+// no session is left holding locks.
+func Drain(ctx context.Context, db *sql.DB) error {
+	if err := db.Close(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		open := db.Stats().OpenConnections
+		if open == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mssql: drain: %d connections still in use: %w", open, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestConnectionExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if e := connectionExpiry(now, msdsn.Config{}); !e.IsZero() {
+		t.Errorf("expected no expiry without a lifetime, got %v", e)
+	}
+	p := msdsn.Config{ConnectionLifetime: time.Minute}
+	if e := connectionExpiry(now, p); !e.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected expiry after a minute, got %v", e)
+	}
+	p.LifetimeJitter = 10 * time.Second
+	for i := 0; i < 100; i++ {
+		e := connectionExpiry(now, p)
+		if e.Before(now.Add(time.Minute)) || !e.Before(now.Add(time.Minute+10*time.Second)) {
+			t.Fatalf("expiry %v is out of the jitter range", e)
+		}
+	}
+
+	c := &Conn{connectionGood: true, expires: now.Add(time.Minute)}
+	if c.expired(now) {
+		t.Error("the connection expired before its lifetime")
+	}
+	if !c.expired(now.Add(time.Minute)) {
+		t.Error("the connection did not expire after its lifetime")
+	}
+	if (&Conn{connectionGood: true}).expired(now.Add(24 * time.Hour)) {
+		t.Error("a connection without a lifetime expired")
+	}
+}
+
+func TestConnectionLifetime(t *testing.T) {
+	var dials int32
+	faults := &FaultInjector{Dial: func(ctx context.Context, server string) error {
+		atomic.AddInt32(&dials, 1)
+		return nil
+	}}
+	db, srv := newFaultTestDB(t, "sqlserver://sa:pwd@db?encrypt=disable&connection+lifetime=3600", faults)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.Raw(func(driverConn interface{}) error {
+		c := driverConn.(*Conn)
+		if !c.IsValid() {
+			t.Error("a new connection is not valid")
+		}
+		c.expires = time.Now().Add(-time.Second)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if _, err = db.ExecContext(ctx, "select 1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&dials); n != 2 {
+		t.Errorf("expected the expired connection to be replaced, got %d dials", n)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	db, srv := newFaultTestDB(t, "sqlserver://sa:pwd@db?encrypt=disable&health+check+interval=60", nil)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "select 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "select 1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Queries()); n != 2 {
+		t.Fatalf("expected no health check of a connection in use, got %d queries", n)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Raw(func(driverConn interface{}) error {
+		driverConn.(*Conn).sess.buf.lastRead = time.Now().Add(-2 * time.Minute)
+		return nil
+	})
+	conn.Close()
+
+	if _, err = db.ExecContext(ctx, "select 1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Queries()); n != 4 {
+		t.Errorf("expected a health check of the idle connection, got %d queries", n)
+	}
+}
+
+func TestWarmPool(t *testing.T) {
+	var dials int32
+	faults := &FaultInjector{Dial: func(ctx context.Context, server string) error {
+		atomic.AddInt32(&dials, 1)
+		return nil
+	}}
+	db, srv := newFaultTestDB(t, "sqlserver://sa:pwd@db?encrypt=disable", faults)
+	defer srv.Close()
+	defer db.Close()
+	db.SetMaxIdleConns(4)
+
+	ctx := context.Background()
+	if err := WarmPool(ctx, db, 4); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&dials); n != 4 {
+		t.Fatalf("expected 4 connections, got %d", n)
+	}
+	if idle := db.Stats().Idle; idle != 4 {
+		t.Errorf("expected 4 idle connections, got %d", idle)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := db.ExecContext(ctx, "select 1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&dials); n != 4 {
+		t.Errorf("expected the warm connections to be used, got %d dials", n)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.Handle("select 1", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{""}, Rows: [][]interface{}{{1}}}}})
+	var closed int32
+	connector, err := NewConnector("sqlserver://sa:pwd@db?encrypt=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector.Dialer = srv
+	connector.OnClose = func(ctx context.Context, s *Session) error {
+		atomic.AddInt32(&closed, 1)
+		return nil
+	}
+	db := sql.OpenDB(connector)
+	ctx := context.Background()
+	if err = WarmPool(ctx, db, 2); err != nil {
+		t.Fatal(err)
+	}
+	inUse, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	err = Drain(short, db)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "1 connections still in use") {
+		t.Fatalf("expected the connection in use to time out the drain, got %v", err)
+	}
+	if n := atomic.LoadInt32(&closed); n != 1 {
+		t.Errorf("expected the idle connection to be closed, got %d closed", n)
+	}
+	if _, err = db.ExecContext(ctx, "select 1"); err == nil {
+		t.Error("expected a draining pool to refuse new queries")
+	}
+	if _, err = inUse.ExecContext(ctx, "select 1"); err != nil {
+		t.Errorf("expected the connection in use to keep working, got %v", err)
+	}
+
+	go inUse.Close()
+	if err = Drain(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&closed); n != 2 {
+		t.Errorf("expected both connections to be closed, got %d closed", n)
+	}
+}
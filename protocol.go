@@ -2,15 +2,40 @@ package mssql
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/microsoft/go-mssqldb/msdsn"
 )
 
+var errDialSkipped = errors.New("mssql: dial skipped after a connection succeeded")
+
+// minDialTimeout is the shortest time an address gets when the dial timeout
+// is split between the addresses that are dialed one after another.
+const minDialTimeout = 2 * time.Second
+
+// addressDialContext returns the context to dial the next of remaining
+// addresses with, which gets its share of the time left until the deadline
+// of ctx, but at least minDialTimeout, so an unreachable address does not
+// use up the time of the addresses after it.
+func addressDialContext(ctx context.Context, remaining int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || remaining <= 1 {
+		return context.WithCancel(ctx)
+	}
+	timeout := time.Until(deadline) / time.Duration(remaining)
+	if timeout < minDialTimeout {
+		timeout = minDialTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 type MssqlProtocolDialer interface {
 	// DialSqlConnection creates a net.Conn from a Connector based on the Config
 	DialSqlConnection(ctx context.Context, c *Connector, p *msdsn.Config) (conn net.Conn, err error)
@@ -80,20 +105,23 @@ func (t tcpDialer) DialSqlConnection(ctx context.Context, c *Connector, p *msdsn
 			return d.DialContext(ctx, "tcp", addr)
 		}
 
-		ips, err = net.LookupIP(p.Host)
+		ips, err = lookupIP(ctx, p.Host, p.DNSCacheTTL)
 		if err != nil {
 			return
 		}
 	} else {
 		ips = []net.IP{ip}
 	}
+	ips = sortIPs(ips, p.IPPreference)
 
 	if len(ips) == 1 || !p.MultiSubnetFailover {
 		// Try to connect to IPs sequentially until one is successful per MultiSubnetFailover false rules
-		for _, ipaddress := range ips {
+		for i, ipaddress := range ips {
 			d := c.getDialer(p)
 			addr := net.JoinHostPort(ipaddress.String(), portStr)
-			conn, err = d.DialContext(ctx, "tcp", addr)
+			addrCtx, cancel := addressDialContext(ctx, len(ips)-i)
+			conn, err = d.DialContext(addrCtx, "tcp", addr)
+			cancel()
 			if err == nil {
 				break
 			}
@@ -102,17 +130,45 @@ func (t tcpDialer) DialSqlConnection(ctx context.Context, c *Connector, p *msdsn
 		//Try Dials in parallel to avoid waiting for timeouts.
 		connChan := make(chan net.Conn, len(ips))
 		errChan := make(chan error, len(ips))
+		// connected is closed when a connection succeeded, delayed dials are skipped then
+		connected := make(chan struct{})
+		hasPreferred := preferred(ips[0], p.IPPreference)
+		// preferredFailed is closed when the dials of all preferred addresses
+		// failed, the other addresses are dialed without further delay then
+		preferredFailed := make(chan struct{})
+		var preferredPending int32
+		for _, ip := range ips {
+			if preferred(ip, p.IPPreference) {
+				preferredPending++
+			}
+		}
 
 		for _, ip := range ips {
 			go func(ip net.IP) {
+				isFallback := hasPreferred && !preferred(ip, p.IPPreference)
+				if isFallback {
+					select {
+					case <-time.After(fallbackDelay):
+					case <-preferredFailed:
+					case <-connected:
+						errChan <- errDialSkipped
+						return
+					case <-ctx.Done():
+						errChan <- ctx.Err()
+						return
+					}
+				}
 				d := c.getDialer(p)
 				addr := net.JoinHostPort(ip.String(), portStr)
 				conn, err := d.DialContext(ctx, "tcp", addr)
 				if err == nil {
 					connChan <- conn
-				} else {
-					errChan <- err
+					return
+				}
+				if hasPreferred && !isFallback && atomic.AddInt32(&preferredPending, -1) == 0 {
+					close(preferredFailed)
 				}
+				errChan <- err
 			}(ip)
 		}
 		// Wait for either the *first* successful connection, or all the errors
@@ -120,6 +176,7 @@ func (t tcpDialer) DialSqlConnection(ctx context.Context, c *Connector, p *msdsn
 		for i := range ips {
 			select {
 			case conn = <-connChan:
+				close(connected)
 				// Got a connection to use, close any others
 				go func(n int) {
 					for i := 0; i < n; i++ {
@@ -139,6 +196,9 @@ func (t tcpDialer) DialSqlConnection(ctx context.Context, c *Connector, p *msdsn
 	}
 	// Can't do the usual err != nil check, as it is possible to have gotten an error before a successful connection
 	if conn == nil {
+		if p.DNSCacheTTL > 0 {
+			forgetIP(p.Host)
+		}
 		return nil, wrapConnErr(p, err)
 	}
 	if p.ServerSPN == "" {
@@ -0,0 +1,55 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryHints may be passed as an argument to Query or Exec to add an OPTION
+// clause with query hints to the end of the query text, and a label to the
+// comment of the labels of WithQueryLabels at its start. The hints apply to the last statement of the query, which
+// must not be a stored procedure name.
+//
+//	rows, err := db.QueryContext(ctx, "select * from dbo.orders where customer_id = @p1", id,
+//		mssql.QueryHints{MaxDop: 1, Recompile: true, Label: "orders-by-customer"})
+//
+// https://learn.microsoft.com/sql/t-sql/queries/hints-transact-sql-query
+type QueryHints struct {
+	// MaxDop limits the number of processors used by the query, 0 does not set MAXDOP.
+	MaxDop int
+	// Recompile compiles a new plan for every execution.
+	Recompile bool
+	// UseHints are the names of the USE HINT hints, e.g. "DISABLE_PARAMETER_SNIFFING".
+	UseHints []string
+	// Label is added to the comment of the labels at the start of the query
+	// text, in front of the labels of WithQueryLabels and escaped like them.
+	// It shows up in Query Store and in the dynamic management views.
+	Label string
+}
+
+// apply returns query with the OPTION clause, the label is sent by
+// Conn.queryLabel.
+func (h QueryHints) apply(query string) (string, error) {
+	if h.MaxDop < 0 {
+		return "", fmt.Errorf("mssql: invalid MAXDOP %d", h.MaxDop)
+	}
+	var options []string
+	if h.MaxDop > 0 {
+		options = append(options, fmt.Sprintf("maxdop %d", h.MaxDop))
+	}
+	if h.Recompile {
+		options = append(options, "recompile")
+	}
+	if len(h.UseHints) > 0 {
+		names := make([]string, len(h.UseHints))
+		for i, name := range h.UseHints {
+			names[i] = sqlString(name)
+		}
+		options = append(options, "use hint("+strings.Join(names, ", ")+")")
+	}
+	if len(options) > 0 {
+		// the OPTION clause has to follow the statement, before a terminating semicolon
+		query = strings.TrimRight(query, "; \t\r\n") + "\noption (" + strings.Join(options, ", ") + ")"
+	}
+	return query, nil
+}
@@ -0,0 +1,59 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestQueryHintsApply(t *testing.T) {
+	values := []struct {
+		hints    QueryHints
+		query    string
+		expected string
+	}{
+		{QueryHints{}, "select 1", "select 1"},
+		{QueryHints{MaxDop: 2}, "select 1;\n", "select 1\noption (maxdop 2)"},
+		{QueryHints{Recompile: true, UseHints: []string{"DISABLE_PARAMETER_SNIFFING", "it's"}}, "select 1",
+			"select 1\noption (recompile, use hint('DISABLE_PARAMETER_SNIFFING', 'it''s'))"},
+		{QueryHints{MaxDop: 1, Label: "orders"}, "select 1", "select 1\noption (maxdop 1)"},
+	}
+	for _, v := range values {
+		query, err := v.hints.apply(v.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != v.expected {
+			t.Errorf("expected %q, got %q", v.expected, query)
+		}
+	}
+
+	if _, err := (QueryHints{MaxDop: -1}).apply("select 1"); err == nil {
+		t.Error("expected an error for a negative MAXDOP")
+	}
+}
+
+func TestQueryHintsArgument(t *testing.T) {
+	c := &Conn{}
+	if err := c.CheckNamedValue(&driver.NamedValue{Value: QueryHints{Recompile: true}}); err != driver.ErrRemoveArgument {
+		t.Fatalf("expected ErrRemoveArgument, got %v", err)
+	}
+	s := &Stmt{c: c, query: "select 1", handle: 5, executions: 3}
+	withHints, err := s.withQueryHints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withHints.query != "select 1\noption (recompile)" || withHints.handle != 0 || withHints.executions != 0 {
+		t.Errorf("unexpected statement %q with handle %d and %d executions", withHints.query, withHints.handle, withHints.executions)
+	}
+	if s.query != "select 1" {
+		t.Errorf("expected the statement to be unchanged, got %q", s.query)
+	}
+
+	s.query = "dbo.proc"
+	if _, err = s.withQueryHints(); err == nil {
+		t.Error("expected an error for a stored procedure name")
+	}
+	if c.outs.queryHints != nil {
+		t.Error("expected the outputs to be cleared")
+	}
+}
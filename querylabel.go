@@ -0,0 +1,93 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+)
+
+// queryLabelsKey is the context key of the labels of WithQueryLabels.
+type queryLabelsKey struct{}
+
+// WithQueryLabels returns a copy of ctx with the labels keyvals, pairs of
+// keys and values, added to the labels of ctx. The statements executed
+// with the context start with a comment of the labels, such as
+//
+//	/* app=checkout trace_id=4bf92f3577b34da6 */ select ...
+//
+// so traces of the server, sys.dm_exec_requests and Query Store show them
+// and can be correlated with the traces of the application. A label that is
+// set again replaces the value of the label.
+//
+// The labels are part of the query text, the server caches a plan for
+// every distinct text of a statement. Labels with many values, such as
+// trace ids, compile a plan for every statement. Statements prepared with
+// the prepare statements option of the connection string keep the labels
+// they were prepared with, executions of the prepared statement only send
+// its handle. Stored procedures called by name are not labeled.
+func WithQueryLabels(ctx context.Context, keyvals ...string) context.Context {
+	parent, _ := ctx.Value(queryLabelsKey{}).([]string)
+	labels := make([]string, len(parent), len(parent)+len(keyvals)+1)
+	copy(labels, parent)
+	labels = appendQueryLabels(labels, keyvals)
+	return context.WithValue(ctx, queryLabelsKey{}, labels)
+}
+
+// appendQueryLabels appends the pairs of keyvals to labels, replacing the
+// value of a key that is already in labels. A key without a value gets an
+// empty value.
+func appendQueryLabels(labels, keyvals []string) []string {
+	if len(keyvals)%2 == 1 {
+		keyvals = append(keyvals[:len(keyvals):len(keyvals)], "")
+	}
+next:
+	for i := 0; i < len(keyvals); i += 2 {
+		for j := 0; j < len(labels); j += 2 {
+			if labels[j] == keyvals[i] {
+				labels[j+1] = keyvals[i+1]
+				continue next
+			}
+		}
+		labels = append(labels, keyvals[i], keyvals[i+1])
+	}
+	return labels
+}
+
+// queryLabelEscaper escapes the characters that would end the comment of
+// the labels or separate them. T-SQL comments nest, so both /* and */ would
+// end it, every asterisk is escaped.
+var queryLabelEscaper = strings.NewReplacer("%", "%25", "*", "%2A", " ", "%20", "=", "%3D", "\r", "%0D", "\n", "%0A")
+
+// queryLabel returns the comment of the labels sent in front of a query,
+// the label of its QueryHints followed by the labels of the Connector and
+// of ctx, or an empty string when there are no labels. Both are escaped by
+// queryLabelEscaper.
+func (c *Conn) queryLabel(ctx context.Context) string {
+	var labels []string
+	if c.connector != nil && c.connector.QueryLabels != nil {
+		labels = appendQueryLabels(labels, c.connector.QueryLabels(ctx))
+	}
+	if ctxLabels, ok := ctx.Value(queryLabelsKey{}).([]string); ok {
+		labels = appendQueryLabels(labels, ctxLabels)
+	}
+	var hintLabel string
+	if c.outs.queryHints != nil {
+		hintLabel = c.outs.queryHints.Label
+	}
+	if len(labels) == 0 && hintLabel == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("/*")
+	if hintLabel != "" {
+		b.WriteByte(' ')
+		b.WriteString(queryLabelEscaper.Replace(hintLabel))
+	}
+	for i := 0; i < len(labels); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(queryLabelEscaper.Replace(labels[i]))
+		b.WriteByte('=')
+		b.WriteString(queryLabelEscaper.Replace(labels[i+1]))
+	}
+	b.WriteString(" */ ")
+	return b.String()
+}
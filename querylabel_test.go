@@ -0,0 +1,72 @@
+package mssql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestQueryLabel(t *testing.T) {
+	c := &Conn{connector: &Connector{}}
+	ctx := context.Background()
+	if q := c.queryLabel(ctx); q != "" {
+		t.Errorf("expected no comment without labels, got %q", q)
+	}
+	c.connector.QueryLabels = func(ctx context.Context) []string {
+		return []string{"app", "checkout", "env", "prod"}
+	}
+	ctx = WithQueryLabels(ctx, "trace_id", "4bf92f35")
+	child := WithQueryLabels(ctx, "env", "test", "user", "a */ drop table x; /*")
+	if q := c.queryLabel(ctx) + "select 1"; q != "/* app=checkout env=prod trace_id=4bf92f35 */ select 1" {
+		t.Errorf("unexpected labeled query %q", q)
+	}
+	want := "/* app=checkout env=test trace_id=4bf92f35 user=a%20%2A/%20drop%20table%20x;%20/%2A */ select 1"
+	if q := c.queryLabel(child) + "select 1"; q != want {
+		t.Errorf("expected %q, got %q", want, q)
+	}
+	if q := c.queryLabel(ctx) + "select 1"; q != "/* app=checkout env=prod trace_id=4bf92f35 */ select 1" {
+		t.Errorf("expected the labels of the parent context to be unchanged, got %q", q)
+	}
+	if q := (&Conn{}).queryLabel(WithQueryLabels(context.Background(), "odd")) + "select 1"; q != "/* odd= */ select 1" {
+		t.Errorf("unexpected label without a value %q", q)
+	}
+
+	// the label of QueryHints comes first and is escaped like the others
+	c.outs.queryHints = &QueryHints{Label: "orders */ drop table x; /*"}
+	want = "/* orders%20%2A/%20drop%20table%20x;%20/%2A app=checkout env=prod trace_id=4bf92f35 */ "
+	if q := c.queryLabel(ctx); q != want {
+		t.Errorf("expected %q, got %q", want, q)
+	}
+	c.connector.QueryLabels = nil
+	if q := c.queryLabel(context.Background()); q != "/* orders%20%2A/%20drop%20table%20x;%20/%2A */ " {
+		t.Errorf("unexpected label of QueryHints %q", q)
+	}
+}
+
+func TestQueryLabelsSent(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.HandleFunc(func(query string) mssqltest.Response { return mssqltest.Response{} })
+	ctx := WithQueryLabels(context.Background(), "app", "checkout")
+	if _, err := db.ExecContext(ctx, "update t set a = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "update t set a = @p1", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "update t set a = @p1", 3, QueryHints{MaxDop: 1, Label: "orders"}); err != nil {
+		t.Fatal(err)
+	}
+	queries := srv.Queries()
+	expected := []string{
+		"/* app=checkout */ update t set a = 1",
+		"/* app=checkout */ update t set a = @p1",
+		"/* orders app=checkout */ update t set a = @p1\noption (maxdop 1)",
+	}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("expected the labels in the batch and the RPC, got %q", queries)
+	}
+}
@@ -0,0 +1,108 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/internal/sqlexec"
+)
+
+// QueryNotification subscribes to a notification that is sent to a Service Broker
+// service when the results of the query change. Pass it as an argument to
+// Query or Exec; it is removed from the query parameters.
+// Receive the notifications with ReceiveQueryNotification.
+//
+//	rows, err := db.QueryContext(ctx, "select id, name from dbo.products",
+//		mssql.QueryNotification{ID: "products", Options: "service=ProductCache", Timeout: time.Hour})
+//
+// The query must meet the requirements for query notifications:
+// https://learn.microsoft.com/sql/relational-databases/native-client/features/working-with-query-notifications
+type QueryNotification struct {
+	// ID is the text of the notification message, use it to identify the subscription.
+	ID string
+	// Options are the Service Broker options, "service=<service name>"
+	// optionally followed by ";local database=<database name>".
+	Options string
+	// Timeout is how long the subscription stays active.
+	Timeout time.Duration
+}
+
+func newQueryNotifSub(id, options string, timeout time.Duration) *queryNotifSub {
+	// 2.2.5.3.1 Query Notifications Header
+	// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-tds/e168d373-a7b7-41aa-b6ca-25985466a7e0
+	// Timeout in milliseconds in TDS protocol.
+	to := uint32(timeout / time.Millisecond)
+	if to < 1 {
+		to = 1
+	}
+	return &queryNotifSub{id, options, to}
+}
+
+// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Querier = sqlexec.Querier
+
+// QueryNotificationEvent is a query notification message.
+// https://learn.microsoft.com/sql/relational-databases/native-client/features/working-with-query-notifications
+type QueryNotificationEvent struct {
+	// Type is "change" when the results changed or "subscribe" when the subscription failed.
+	Type string `xml:"type,attr"`
+	// Source is the reason of the notification, for example "data" or "timeout".
+	Source string `xml:"source,attr"`
+	// Info describes the change, for example "insert", "update" or "delete".
+	Info string `xml:"info,attr"`
+	// ID is the ID of the QueryNotification that subscribed to the notification.
+	ID string `xml:"Message"`
+}
+
+const queryNotificationMessageType = "http://schemas.microsoft.com/SQL/Notifications/QueryNotification"
+
+// ReceiveQueryNotification waits up to timeout for the next query notification
+// on the Service Broker queue of the service that was passed in the QueryNotification
+// options, named like the queue of Receive. It returns nil if no notification arrived before the timeout.
+// A negative timeout waits until a notification arrives or ctx is done.
+// Other messages on the queue are removed and skipped.
+//
+// Cancelling ctx stops waiting.
+func ReceiveQueryNotification(ctx context.Context, q Querier, queue string, timeout time.Duration) (*QueryNotificationEvent, error) {
+	query, err := receiveQuery("message_type_name, cast(message_body as xml)", queue, timeout)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		messageType, body, err := receiveNotification(ctx, q, query)
+		if err != nil || len(messageType) == 0 {
+			return nil, err
+		}
+		if messageType != queryNotificationMessageType || !body.Valid {
+			continue
+		}
+		return parseQueryNotification(body.String)
+	}
+}
+
+// receiveNotification runs the waitfor receive query of ReceiveQueryNotification.
+// The message type is empty if no message arrived before the timeout.
+func receiveNotification(ctx context.Context, q Querier, query string) (messageType string, body sql.NullString, err error) {
+	rows, err := q.QueryContext(ctx, query, NoQueryTimeout{})
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	if rows.Next() {
+		err = rows.Scan(&messageType, &body)
+		return
+	}
+	err = rows.Err()
+	return
+}
+
+func parseQueryNotification(body string) (*QueryNotificationEvent, error) {
+	ev := &QueryNotificationEvent{}
+	if err := xml.Unmarshal([]byte(body), ev); err != nil {
+		return nil, fmt.Errorf("mssql: invalid query notification: %v", err)
+	}
+	return ev, nil
+}
@@ -0,0 +1,42 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestQueryNotificationArgument(t *testing.T) {
+	c := &Conn{}
+	nv := &driver.NamedValue{Value: QueryNotification{ID: "products", Options: "service=ProductCache", Timeout: time.Minute}}
+	if err := c.CheckNamedValue(nv); err != driver.ErrRemoveArgument {
+		t.Fatalf("expected ErrRemoveArgument, got %v", err)
+	}
+	sub := c.outs.notifSub
+	if sub == nil {
+		t.Fatal("expected a query notification subscription")
+	}
+	if sub.msgText != "products" || sub.options != "service=ProductCache" || sub.timeout != 60000 {
+		t.Errorf("unexpected subscription %+v", *sub)
+	}
+
+	if sub = newQueryNotifSub("products", "service=ProductCache", 0); sub.timeout != 1 {
+		t.Errorf("expected the timeout to be at least 1ms, got %d", sub.timeout)
+	}
+}
+
+func TestParseQueryNotification(t *testing.T) {
+	body := `<qn:QueryNotification xmlns:qn="http://schemas.microsoft.com/SQL/Notifications/QueryNotification" id="7" type="change" source="data" info="update" database_id="5" sid="0x01"><qn:Message>products</qn:Message></qn:QueryNotification>`
+	ev, err := parseQueryNotification(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := QueryNotificationEvent{Type: "change", Source: "data", Info: "update", ID: "products"}
+	if *ev != expected {
+		t.Errorf("expected %+v, got %+v", expected, *ev)
+	}
+
+	if _, err = parseQueryNotification("not xml"); err == nil {
+		t.Error("expected an error for an invalid message")
+	}
+}
@@ -0,0 +1,139 @@
+package mssql
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Numbers of the informational messages of SET STATISTICS TIME and IO.
+const (
+	msgStatisticsTime = 3612
+	msgStatisticsIO   = 3615
+)
+
+// QueryStatistics receives the statistics of a single query measured with
+// SET STATISTICS IO and TIME, e.g. to check the logical reads of queries
+// in CI. Pass a pointer to it as an argument of the query:
+//
+//	var stats mssql.QueryStatistics
+//	rows, err := db.QueryContext(ctx, "select * from orders where customer_id = @p1", id, &stats)
+//	...
+//	rows.Close()
+//	fmt.Println(stats.LogicalReads, stats.CPUTime)
+//
+// The statistics are complete once the rows were read or closed. They are
+// parsed from the messages of the server, which are only understood in
+// English, the default language of a login.
+type QueryStatistics struct {
+	// Tables are the reads of the tables of the query, in the order the
+	// server reported them. A table read by several statements is listed
+	// for every statement.
+	Tables []TableIO
+	// LogicalReads and PhysicalReads are the sums of the reads of Tables,
+	// including the LOB reads.
+	LogicalReads  int64
+	PhysicalReads int64
+	// CPUTime and ElapsedTime are the execution times of the statements.
+	CPUTime     time.Duration
+	ElapsedTime time.Duration
+	// CompileCPUTime and CompileElapsedTime are the times the server
+	// spent to parse and compile the statements.
+	CompileCPUTime     time.Duration
+	CompileElapsedTime time.Duration
+}
+
+// TableIO are the reads of a table in a statement reported by SET STATISTICS IO.
+type TableIO struct {
+	Table            string
+	ScanCount        int64
+	LogicalReads     int64
+	PhysicalReads    int64
+	ReadAheadReads   int64
+	LobLogicalReads  int64
+	LobPhysicalReads int64
+}
+
+// statisticsTimeRe matches the times of a SET STATISTICS TIME message.
+var statisticsTimeRe = regexp.MustCompile(`CPU time = (\d+) ms,\s*elapsed time = (\d+) ms`)
+
+// add adds the statistics of the informational message msg.
+func (s *QueryStatistics) add(msg Error) {
+	switch msg.Number {
+	case msgStatisticsTime:
+		m := statisticsTimeRe.FindStringSubmatch(msg.Message)
+		if m == nil {
+			return
+		}
+		cpu, _ := strconv.ParseInt(m[1], 10, 64)
+		elapsed, _ := strconv.ParseInt(m[2], 10, 64)
+		if strings.Contains(msg.Message, "parse and compile") {
+			s.CompileCPUTime += time.Duration(cpu) * time.Millisecond
+			s.CompileElapsedTime += time.Duration(elapsed) * time.Millisecond
+		} else {
+			s.CPUTime += time.Duration(cpu) * time.Millisecond
+			s.ElapsedTime += time.Duration(elapsed) * time.Millisecond
+		}
+	case msgStatisticsIO:
+		if io, ok := parseTableIO(msg.Message); ok {
+			s.Tables = append(s.Tables, io)
+			s.LogicalReads += io.LogicalReads + io.LobLogicalReads
+			s.PhysicalReads += io.PhysicalReads + io.LobPhysicalReads
+		}
+	}
+}
+
+// parseTableIO parses a SET STATISTICS IO message such as
+//
+//	Table 'orders'. Scan count 1, logical reads 3, physical reads 0, ...
+func parseTableIO(message string) (TableIO, bool) {
+	const prefix = "Table '"
+	end := strings.LastIndex(message, "'. ")
+	if !strings.HasPrefix(message, prefix) || end < len(prefix) {
+		return TableIO{}, false
+	}
+	io := TableIO{Table: message[len(prefix):end]}
+	for _, counter := range strings.Split(strings.TrimSuffix(message[end+3:], "."), ",") {
+		counter = strings.TrimSpace(counter)
+		i := strings.LastIndexByte(counter, ' ')
+		if i < 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(counter[i+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch counter[:i] {
+		case "Scan count":
+			io.ScanCount = n
+		case "logical reads":
+			io.LogicalReads = n
+		case "physical reads":
+			io.PhysicalReads = n
+		case "read-ahead reads":
+			io.ReadAheadReads = n
+		case "lob logical reads":
+			io.LobLogicalReads = n
+		case "lob physical reads":
+			io.LobPhysicalReads = n
+		}
+	}
+	return io, true
+}
+
+// withStatistics returns a copy of the statement that switches on
+// SET STATISTICS IO and TIME for its query.
+func (s *Stmt) withStatistics() (*Stmt, error) {
+	if isProc(s.query) {
+		s.c.clearOuts()
+		return nil, errors.New("mssql: QueryStatistics cannot be used with a stored procedure name")
+	}
+	withStats := *s
+	withStats.query = "set statistics io, time on; " + s.query + "\nset statistics io, time off"
+	// the copy is executed once, it must neither use nor prepare a handle
+	withStats.handle = 0
+	withStats.executions = 0
+	return &withStats, nil
+}
@@ -0,0 +1,68 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestParseTableIO(t *testing.T) {
+	io, ok := parseTableIO("Table 'order''s'. Scan count 2, logical reads 30, physical reads 1, page server reads 0, read-ahead reads 7, " +
+		"page server read-ahead reads 0, lob logical reads 4, lob physical reads 2, lob page server reads 0, lob read-ahead reads 0, lob page server read-ahead reads 0.")
+	want := TableIO{Table: "order''s", ScanCount: 2, LogicalReads: 30, PhysicalReads: 1, ReadAheadReads: 7, LobLogicalReads: 4, LobPhysicalReads: 2}
+	if !ok || io != want {
+		t.Errorf("expected %+v, got %+v", want, io)
+	}
+	if _, ok = parseTableIO("Tabelle 'orders'. Scananzahl 1"); ok {
+		t.Error("expected a message in another language not to be parsed")
+	}
+}
+
+func TestQueryStatistics(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if !strings.HasPrefix(query, "set statistics io, time on; select id from orders") {
+			return mssqltest.Response{Err: &mssqltest.Error{Number: 208, Message: "unexpected query " + query}}
+		}
+		return mssqltest.Response{
+			Messages: []mssqltest.Error{
+				{Number: msgStatisticsTime, Message: "SQL Server parse and compile time: \n   CPU time = 2 ms, elapsed time = 5 ms."},
+				{Number: msgStatisticsIO, Message: "Table 'orders'. Scan count 1, logical reads 3, physical reads 1, read-ahead reads 0, lob logical reads 0, lob physical reads 0."},
+				{Number: msgStatisticsTime, Message: "\n SQL Server Execution Times:\n   CPU time = 16 ms,  elapsed time = 21 ms."},
+			},
+			Results: []mssqltest.Result{{Columns: []string{"id"}, Rows: [][]interface{}{{1}}}},
+		}
+	})
+	ctx := context.Background()
+	var stats QueryStatistics
+	rows, err := db.QueryContext(ctx, "select id from orders where customer_id = @p1", 42, &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if len(stats.Tables) != 1 || stats.Tables[0].Table != "orders" || stats.LogicalReads != 3 || stats.PhysicalReads != 1 {
+		t.Errorf("unexpected reads %+v", stats)
+	}
+	if stats.CPUTime != 16*time.Millisecond || stats.ElapsedTime != 21*time.Millisecond ||
+		stats.CompileCPUTime != 2*time.Millisecond || stats.CompileElapsedTime != 5*time.Millisecond {
+		t.Errorf("unexpected times %+v", stats)
+	}
+
+	// the statistics are reset for every query
+	if _, err = db.ExecContext(ctx, "select id from orders", &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Tables) != 1 || stats.LogicalReads != 3 {
+		t.Errorf("expected the statistics of the second query only, got %+v", stats)
+	}
+	if _, err = db.ExecContext(ctx, "sp_who", &stats); err == nil {
+		t.Error("expected an error for a stored procedure")
+	}
+}
@@ -1,15 +1,22 @@
 package mssql
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"unicode/utf16"
 )
 
+// maxIdentifierLength is the longest name of a database or another object
+// of SQL Server in characters.
+const maxIdentifierLength = 128
+
 // TSQLQuoter implements sqlexp.Quoter
 type TSQLQuoter struct {
 }
 
 // ID quotes identifiers such as schema, table, or column names.
-// This implementation handles multi-part names.
+// Multi-part names have to be quoted part by part, see QuoteSchemaObject.
 func (TSQLQuoter) ID(name string) string {
 	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
 }
@@ -38,3 +45,158 @@ func (TSQLQuoter) Value(v interface{}) string {
 func sqlString(v string) string {
 	return "'" + strings.Replace(string(v), "'", "''", -1) + "'"
 }
+
+// QuoteIdentifier returns name as a delimited identifier in brackets, with
+// the closing brackets in it doubled, like the QUOTENAME function of T-SQL,
+// so a table or column name that cannot be passed as a parameter can be
+// used in dynamic SQL safely:
+//
+//	column, err := mssql.QuoteIdentifier(sortColumn)
+//	...
+//	rows, err := db.QueryContext(ctx, "select id from orders order by "+column)
+//
+// Like QUOTENAME it rejects names longer than 128 characters, which SQL
+// Server does not accept, and it rejects empty names and names with a NUL
+// character.
+func QuoteIdentifier(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("mssql: the identifier is empty")
+	}
+	if n := len(utf16.Encode([]rune(name))); n > maxIdentifierLength {
+		return "", fmt.Errorf("mssql: the identifier of %d characters is longer than %d characters", n, maxIdentifierLength)
+	}
+	if strings.ContainsRune(name, 0) {
+		return "", errors.New("mssql: the identifier contains a NUL character")
+	}
+	return TSQLQuoter{}.ID(name), nil
+}
+
+// QuoteSchemaObject returns the two-part name of object in schema with
+// both parts quoted by QuoteIdentifier, or only object when schema is
+// empty, so the default schema of the user is used.
+func QuoteSchemaObject(schema, object string) (string, error) {
+	quotedObject, err := QuoteIdentifier(object)
+	if err != nil || schema == "" {
+		return quotedObject, err
+	}
+	quotedSchema, err := QuoteIdentifier(schema)
+	if err != nil {
+		return "", err
+	}
+	return quotedSchema + "." + quotedObject, nil
+}
+
+// splitSchemaObject splits name, "object" or "schema.object", into its
+// parts. A part in brackets, such as [order.queue], may contain dots and
+// has its closing brackets doubled.
+func splitSchemaObject(name string) (schema, object string, err error) {
+	var parts []string
+	for rest := name; ; {
+		var part string
+		if strings.HasPrefix(rest, "[") {
+			end := 1
+			for {
+				closing := strings.IndexByte(rest[end:], ']')
+				if closing < 0 {
+					return "", "", fmt.Errorf("mssql: the name %s has no closing bracket", name)
+				}
+				end += closing + 1
+				if !strings.HasPrefix(rest[end:], "]") {
+					break
+				}
+				end++
+			}
+			part = strings.Replace(rest[1:end-1], "]]", "]", -1)
+			rest = rest[end:]
+			if rest != "" && rest[0] != '.' {
+				return "", "", fmt.Errorf("mssql: unexpected %q after a bracketed part of the name %s", rest, name)
+			}
+		} else if dot := strings.IndexByte(rest, '.'); dot >= 0 {
+			part, rest = rest[:dot], rest[dot:]
+		} else {
+			part, rest = rest, ""
+		}
+		parts = append(parts, part)
+		if rest == "" {
+			break
+		}
+		rest = rest[1:]
+	}
+	switch len(parts) {
+	case 1:
+		return "", parts[0], nil
+	case 2:
+		if parts[0] == "" {
+			return "", "", fmt.Errorf("mssql: the schema of the name %s is empty", name)
+		}
+		return parts[0], parts[1], nil
+	}
+	return "", "", fmt.Errorf("mssql: the name %s has more than two parts, schema and object", name)
+}
+
+// maxNameParts is the number of parts of a four-part name, server,
+// database, schema and object.
+const maxNameParts = 4
+
+// QuoteMultiPartName returns the name of up to four parts, such as
+// server, database, schema and object of a table on a linked server, with
+// every part quoted by QuoteIdentifier and joined by dots:
+//
+//	table, err := mssql.QuoteMultiPartName("HR-SRV", "hr", "dbo", "employees")
+//	// [HR-SRV].[hr].[dbo].[employees]
+//
+// The parts between the first and the last may be empty to use their
+// default, e.g. the default schema in [srv].[db]..[object].
+func QuoteMultiPartName(parts ...string) (string, error) {
+	if len(parts) == 0 || len(parts) > maxNameParts {
+		return "", fmt.Errorf("mssql: a name has 1 to %d parts, got %d", maxNameParts, len(parts))
+	}
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		if part == "" && i > 0 && i < len(parts)-1 {
+			continue
+		}
+		var err error
+		if quoted[i], err = QuoteIdentifier(part); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(quoted, "."), nil
+}
+
+// OpenQuery returns an OPENQUERY table source that runs query on the
+// linked server linkedServer, with the single quotes of query doubled:
+//
+//	source, err := mssql.OpenQuery("ORA1", "select ename from emp where job = 'CLERK'")
+//	// openquery([ORA1], N'select ename from emp where job = ''CLERK''')
+//	rows, err := db.QueryContext(ctx, "select * from "+source)
+//
+// query is sent to the linked server as is, in its SQL dialect. OPENQUERY
+// takes no parameters and the server limits query to 8 KB, so values have
+// to be quoted for the linked server first, e.g. with QuoteVarCharString
+// for another SQL Server.
+func OpenQuery(linkedServer, query string) (string, error) {
+	server, err := QuoteIdentifier(linkedServer)
+	if err != nil {
+		return "", err
+	}
+	if query == "" {
+		return "", errors.New("mssql: the query of OPENQUERY is empty")
+	}
+	return "openquery(" + server + ", " + QuoteString(query) + ")", nil
+}
+
+// QuoteString returns s as an nvarchar string literal, N'...' with the
+// single quotes in it doubled, for statements that cannot take parameters.
+// Prefer parameters wherever the statement accepts them.
+func QuoteString(s string) string {
+	return "N" + sqlString(s)
+}
+
+// QuoteVarCharString returns s as a varchar string literal, '...' with
+// the single quotes in it doubled. Characters that are not in the code
+// page of the collation of the database are converted to question marks
+// by the server.
+func QuoteVarCharString(s string) string {
+	return sqlString(s)
+}
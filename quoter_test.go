@@ -0,0 +1,91 @@
+package mssql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	for name, want := range map[string]string{
+		"orders":            "[orders]",
+		"order details":     "[order details]",
+		"a]b":               "[a]]b]",
+		"x]; drop table y;": "[x]]; drop table y;]",
+		"[t]":               "[[t]]]",
+	} {
+		if got, err := QuoteIdentifier(name); err != nil || got != want {
+			t.Errorf("QuoteIdentifier(%q) = %q, %v, expected %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"", strings.Repeat("x", 129), "a\x00b"} {
+		if _, err := QuoteIdentifier(name); err == nil {
+			t.Errorf("expected an error for %q", name)
+		}
+	}
+	// the length is counted in UTF-16 code units like SQL Server does
+	if _, err := QuoteIdentifier(strings.Repeat("😀", 65)); err == nil {
+		t.Error("expected an error for 130 UTF-16 code units")
+	}
+}
+
+func TestQuoteSchemaObject(t *testing.T) {
+	if got, err := QuoteSchemaObject("sales", "order]s"); err != nil || got != "[sales].[order]]s]" {
+		t.Errorf("unexpected %q, %v", got, err)
+	}
+	if got, err := QuoteSchemaObject("", "orders"); err != nil || got != "[orders]" {
+		t.Errorf("unexpected %q, %v", got, err)
+	}
+	if _, err := QuoteSchemaObject("sales", ""); err == nil {
+		t.Error("expected an error for an empty object name")
+	}
+	if _, err := QuoteSchemaObject(strings.Repeat("s", 129), "orders"); err == nil {
+		t.Error("expected an error for a long schema name")
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	if got := QuoteString("O'Brien"); got != "N'O''Brien'" {
+		t.Errorf("unexpected %q", got)
+	}
+	if got := QuoteVarCharString("it's"); got != "'it''s'" {
+		t.Errorf("unexpected %q", got)
+	}
+}
+
+func TestQuoteMultiPartName(t *testing.T) {
+	for want, parts := range map[string][]string{
+		"[orders]":                        {"orders"},
+		"[HR-SRV].[hr].[dbo].[employees]": {"HR-SRV", "hr", "dbo", "employees"},
+		"[srv\\inst].[db]..[t]":           {`srv\inst`, "db", "", "t"},
+		"[srv]...[t]":                     {"srv", "", "", "t"},
+		"[db].[sales].[order]]s]":         {"db", "sales", "order]s"},
+	} {
+		if got, err := QuoteMultiPartName(parts...); err != nil || got != want {
+			t.Errorf("QuoteMultiPartName(%q) = %q, %v, expected %q", parts, got, err, want)
+		}
+	}
+	for _, parts := range [][]string{nil, {"a", "b", "c", "d", "e"}, {"", "db", "dbo", "t"}, {"srv", "db", "dbo", ""}} {
+		if _, err := QuoteMultiPartName(parts...); err == nil {
+			t.Errorf("expected an error for %q", parts)
+		}
+	}
+}
+
+func TestOpenQuery(t *testing.T) {
+	got, err := OpenQuery("ORA1", "select ename from emp where job = 'CLERK'")
+	if want := "openquery([ORA1], N'select ename from emp where job = ''CLERK''')"; err != nil || got != want {
+		t.Errorf("expected %q, got %q, %v", want, got, err)
+	}
+	// a literal inside the remote query is nested once more
+	inner := "select * from t where name = " + QuoteVarCharString("O'Brien")
+	got, err = OpenQuery("srv", inner)
+	if want := "openquery([srv], N'select * from t where name = ''O''''Brien''')"; err != nil || got != want {
+		t.Errorf("expected %q, got %q, %v", want, got, err)
+	}
+	if _, err = OpenQuery("srv", ""); err == nil {
+		t.Error("expected an error for an empty query")
+	}
+	if _, err = OpenQuery("", "select 1"); err == nil {
+		t.Error("expected an error for an empty linked server name")
+	}
+}
@@ -0,0 +1,48 @@
+package mssql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// truncatedLength is the number of characters of a value that the truncate
+// param redaction keeps.
+const truncatedLength = 4
+
+// redactParam returns the text of a parameter value for the log and errors,
+// redacted by the param redaction setting of the connection:
+//
+//	none      42, alice@example.com
+//	hash      int64:sha256:73475cb40a568e8d, string:sha256:ff8d9819fc0e12bf
+//	truncate  42, alic...(17 characters)
+//	full      int64, string
+//
+// The hash is not salted so that equal values can be correlated across
+// the logs of several processes, short values like numbers can be found
+// from their hash by trying all of them.
+func (c *Conn) redactParam(v interface{}) string {
+	if v == nil {
+		return "<nil>"
+	}
+	redaction := msdsn.ParamRedactionNone
+	if c != nil {
+		redaction = c.paramRedaction
+	}
+	switch redaction {
+	case msdsn.ParamRedactionHash:
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%v", v)))
+		return fmt.Sprintf("%T:sha256:%s", v, hex.EncodeToString(sum[:8]))
+	case msdsn.ParamRedactionTruncate:
+		s := []rune(fmt.Sprintf("%v", v))
+		if len(s) <= truncatedLength {
+			return string(s)
+		}
+		return fmt.Sprintf("%s...(%d characters)", string(s[:truncatedLength]), len(s))
+	case msdsn.ParamRedactionFull:
+		return fmt.Sprintf("%T", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
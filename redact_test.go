@@ -0,0 +1,73 @@
+package mssql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/internal/cp"
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+func TestRedactParam(t *testing.T) {
+	tests := []struct {
+		redaction string
+		value     interface{}
+		expected  string
+	}{
+		{msdsn.ParamRedactionNone, "alice@example.com", "alice@example.com"},
+		{msdsn.ParamRedactionHash, "alice@example.com", "string:sha256:ff8d9819fc0e12bf"},
+		{msdsn.ParamRedactionHash, int64(42), "int64:sha256:73475cb40a568e8d"},
+		{msdsn.ParamRedactionTruncate, "alice@example.com", "alic...(17 characters)"},
+		{msdsn.ParamRedactionTruncate, int64(42), "42"},
+		{msdsn.ParamRedactionFull, "alice@example.com", "string"},
+		{msdsn.ParamRedactionFull, nil, "<nil>"},
+	}
+	for _, test := range tests {
+		c := &Conn{paramRedaction: test.redaction}
+		if got := c.redactParam(test.value); got != test.expected {
+			t.Errorf("%s %v: expected %q, got %q", test.redaction, test.value, test.expected, got)
+		}
+	}
+	if got := (*Conn)(nil).redactParam(1); got != "1" {
+		t.Errorf("expected no redaction without a connection, got %q", got)
+	}
+}
+
+func TestBulkErrorsAreRedacted(t *testing.T) {
+	b := &Bulk{cn: &Conn{paramRedaction: msdsn.ParamRedactionFull}}
+	_, err := b.makeParam("secret", columnStruct{ti: typeInfo{TypeId: typeBitN, Size: 1}})
+	if err == nil || err.Error() != "mssql: invalid type for bit column: string string" {
+		t.Errorf("expected a redacted error, got %v", err)
+	}
+
+	decimalCol := columnStruct{ti: typeInfo{TypeId: typeDecimalN, Prec: 5, Scale: 2}}
+	for _, test := range []struct {
+		value interface{}
+		col   columnStruct
+	}{
+		{"12345.678", decimalCol},
+		{"not a number 123", decimalCol},
+		{1e30, decimalCol},
+		{[]int{123}, decimalCol},
+		{"secret 2023", columnStruct{ti: typeInfo{TypeId: typeDateN}}},
+		{"secret 2023", columnStruct{ti: typeInfo{TypeId: typeDateTime2N}}},
+		{"secret 2023", columnStruct{ti: typeInfo{TypeId: typeDateTimeOffsetN}}},
+		{"secret 2023", columnStruct{ti: typeInfo{TypeId: typeDateTimeN, Size: 8}}},
+	} {
+		_, err := b.makeParam(test.value, test.col)
+		if err == nil {
+			t.Errorf("expected an error for %v", test.value)
+			continue
+		}
+		if msg := err.Error(); strings.Contains(msg, "secret") || strings.Contains(msg, "123") || strings.Contains(msg, "e+30") {
+			t.Errorf("expected the value to be redacted, got %q", msg)
+		}
+	}
+
+	c := &Conn{paramRedaction: msdsn.ParamRedactionFull}
+	for _, v := range []interface{}{"123456789.5", "12.345"} {
+		if _, err := TypedDecimal(v, 5, 2).makeParam(c, cp.Collation{}); err == nil || strings.Contains(err.Error(), "12") {
+			t.Errorf("expected a redacted error for %v, got %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package mssql
+
+import "context"
+
+// NonIdempotent may be passed as an argument to Query to exclude a query
+// from the retry reads setting of the connection, for queries that change
+// data, e.g. a SELECT that calls a procedure with side effects or an
+// UPDATE with an OUTPUT clause:
+//
+//	rows, err := db.QueryContext(ctx, "update dbo.jobs set taken = 1 output inserted.id where id = @p1", id, mssql.NonIdempotent{})
+type NonIdempotent struct{}
+
+// mayRetryRead reports whether a query that failed before its
+// first result set arrived may be retried on another connection. Only
+// queries on a connection with retry reads, that broke the connection
+// outside of a transaction and are not NonIdempotent, are retried.
+func (s *Stmt) mayRetryRead(ctx context.Context, outs outputs) bool {
+	c := s.c
+	if c.connector == nil || !c.connector.params.RetryReads || outs.nonIdempotent {
+		return false
+	}
+	return !c.connectionGood && c.sess.tranid == 0 && ctx.Err() == nil
+}
@@ -0,0 +1,66 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestRetryReads(t *testing.T) {
+	var injectError int32
+	faults := &FaultInjector{
+		Response: func() *Error {
+			if atomic.CompareAndSwapInt32(&injectError, 1, 0) {
+				return &Error{Number: 10054, Class: SeverityFatal, Message: "injected connection reset"}
+			}
+			return nil
+		},
+	}
+	db, srv := newFaultTestDB(t, "server=localhost;encrypt=disable;retry reads=true", faults)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select name from dbo.users", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"name"}, Rows: [][]interface{}{{"alice"}}}}})
+	ctx := context.Background()
+
+	var name string
+	if err := db.QueryRowContext(ctx, "select 1").Scan(new(int)); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&injectError, 1)
+	if err := db.QueryRowContext(ctx, "select name from dbo.users").Scan(&name); err != nil || name != "alice" {
+		t.Fatalf("expected the read to be retried, got %q and %v", name, err)
+	}
+
+	atomic.StoreInt32(&injectError, 1)
+	var sqlErr Error
+	err := db.QueryRowContext(ctx, "select name from dbo.users", NonIdempotent{}).Scan(&name)
+	if !errors.As(err, &sqlErr) || sqlErr.Number != 10054 {
+		t.Fatalf("expected the injected error for a non-idempotent query, got %v", err)
+	}
+}
+
+func TestRetryReadsDisabled(t *testing.T) {
+	var injectError int32
+	faults := &FaultInjector{
+		Response: func() *Error {
+			if atomic.CompareAndSwapInt32(&injectError, 1, 0) {
+				return &Error{Number: 10054, Class: SeverityFatal, Message: "injected connection reset"}
+			}
+			return nil
+		},
+	}
+	db, srv := newFaultTestDB(t, "server=localhost;encrypt=disable", faults)
+	defer srv.Close()
+	defer db.Close()
+	ctx := context.Background()
+	if err := db.QueryRowContext(ctx, "select 1").Scan(new(int)); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&injectError, 1)
+	if err := db.QueryRowContext(ctx, "select 1").Scan(new(int)); err == nil {
+		t.Fatal("expected the injected error without retry reads")
+	}
+}
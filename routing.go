@@ -0,0 +1,118 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// route is a server that an availability group listener routed read-only
+// logins to.
+type route struct {
+	server  string
+	port    uint16
+	expires time.Time
+}
+
+// routingCache caches the routes of the read-only logins of a Connector
+// for the routing cache ttl of the connection string.
+type routingCache struct {
+	sync.Mutex
+	m map[string]route
+}
+
+// routeKey returns the key of the route of logins with p, the listener and
+// the database, or "" when routes of p are not cached.
+func routeKey(p msdsn.Config) string {
+	if !p.ReadOnlyIntent || p.RoutingCacheTTL <= 0 {
+		return ""
+	}
+	return strings.ToLower(serverName(p) + "/" + p.Database)
+}
+
+// cacheRoute records that the listener of p routed the login to server and port.
+func (c *Connector) cacheRoute(p msdsn.Config, server string, port uint16) {
+	key := routeKey(p)
+	if c == nil || key == "" {
+		return
+	}
+	c.routes.Lock()
+	if c.routes.m == nil {
+		c.routes.m = make(map[string]route)
+	}
+	c.routes.m[key] = route{server: server, port: port, expires: time.Now().Add(p.RoutingCacheTTL)}
+	c.routes.Unlock()
+}
+
+// cachedRoute returns the route of logins with p that has not expired.
+func (c *Connector) cachedRoute(p msdsn.Config) (route, bool) {
+	key := routeKey(p)
+	if c == nil || key == "" {
+		return route{}, false
+	}
+	c.routes.Lock()
+	defer c.routes.Unlock()
+	r, ok := c.routes.m[key]
+	if ok && !time.Now().Before(r.expires) {
+		delete(c.routes.m, key)
+		ok = false
+	}
+	return r, ok
+}
+
+// forgetRoute removes the route of logins with p.
+func (c *Connector) forgetRoute(p msdsn.Config) {
+	if c == nil {
+		return
+	}
+	c.routes.Lock()
+	delete(c.routes.m, routeKey(p))
+	c.routes.Unlock()
+}
+
+// FlushRouting removes the routes of read-only logins that the Connector
+// cached for the routing cache ttl of the connection string, so new
+// connections log in through the availability group listener again. Call
+// it after a failover, when the cached secondary may have become the
+// primary or may no longer be readable. Connections in the pool are not
+// closed.
+func (c *Connector) FlushRouting() {
+	c.routes.Lock()
+	c.routes.m = nil
+	c.routes.Unlock()
+}
+
+// routedConfig returns p for a connection to the server and port that the
+// server of p routed the login to.
+func routedConfig(p msdsn.Config, server string, port uint16) msdsn.Config {
+	// Need to handle case when routedServer is in "host\instance" format.
+	routedParts := strings.SplitN(server, "\\", 2)
+	p.Host = routedParts[0]
+	if len(routedParts) == 2 {
+		p.Instance = routedParts[1]
+	}
+	p.Port = uint64(port)
+	if !p.HostInCertificateProvided && p.TLSConfig != nil {
+		p.TLSConfig = p.TLSConfig.Clone()
+		p.TLSConfig.ServerName = p.Host
+	}
+	return p
+}
+
+// connect logs in to the server of p. A read-only login whose route was
+// cached connects to the routed server directly, and through the listener
+// again when that fails.
+func connect(ctx context.Context, c *Connector, logger ContextLogger, p msdsn.Config) (*tdsSession, error) {
+	if r, ok := c.cachedRoute(p); ok {
+		sess, err := dialAndLogin(ctx, c, logger, routedConfig(p, r.server, r.port))
+		if err == nil {
+			return sess, nil
+		}
+		c.forgetRoute(p)
+		c.logEvent(ctx, Event{Type: EventWarning, Server: serverName(p), Err: err, Message: "cached route to " + r.server + " failed, connecting to the listener: " + err.Error()})
+	}
+	return dialAndLogin(ctx, c, logger, p)
+}
@@ -0,0 +1,108 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+// hostsDialer dials the test server of the host of an address.
+type hostsDialer struct {
+	mu      sync.Mutex
+	servers map[string]*mssqltest.Server
+	dials   []string
+}
+
+func (d *hostsDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.dials = append(d.dials, host)
+	srv, ok := d.servers[host]
+	d.mu.Unlock()
+	if !ok {
+		return nil, errors.New("unknown host " + host)
+	}
+	return srv.DialContext(ctx, network, addr)
+}
+
+func (d *hostsDialer) HostName() string {
+	return "mssqltest"
+}
+
+func TestRoutingCache(t *testing.T) {
+	listener, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	secondary, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secondary.Close()
+	listener.Route("secondary", 1433)
+	dialer := &hostsDialer{servers: map[string]*mssqltest.Server{"listener": listener, "secondary": secondary}}
+
+	config, err := msdsn.Parse("server=listener;database=sales;applicationintent=ReadOnly;routing cache ttl=60;encrypt=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := NewConnectorConfig(config)
+	connector.Dialer = dialer
+	connect := func() {
+		t.Helper()
+		conn, err := connector.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	connect()
+	connect()
+	if listener.Logins() != 1 || secondary.Logins() != 2 {
+		t.Errorf("expected the second connection to use the cached route, got %d listener and %d secondary logins", listener.Logins(), secondary.Logins())
+	}
+
+	connector.FlushRouting()
+	connect()
+	if listener.Logins() != 2 {
+		t.Errorf("expected a login through the listener after the routes were flushed, got %d", listener.Logins())
+	}
+
+	// the secondary is gone, the connection falls back to the listener
+	delete(dialer.servers, "secondary")
+	dialer.servers["tertiary"] = secondary
+	listener.Route("tertiary", 1433)
+	connect()
+	if listener.Logins() != 3 || secondary.Logins() != 4 {
+		t.Errorf("expected a login through the listener when the cached route failed, got %d listener and %d secondary logins", listener.Logins(), secondary.Logins())
+	}
+	if last := dialer.dials[len(dialer.dials)-3:]; last[0] != "secondary" || last[1] != "listener" || last[2] != "tertiary" {
+		t.Errorf("unexpected dials %v", dialer.dials)
+	}
+}
+
+func TestRoutingCacheDisabled(t *testing.T) {
+	for _, dsn := range []string{
+		"server=listener;applicationintent=ReadOnly;database=sales",
+		"server=listener;routing cache ttl=60",
+	} {
+		p, err := msdsn.Parse(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewConnectorConfig(p)
+		c.cacheRoute(p, "secondary", 1433)
+		if _, ok := c.cachedRoute(p); ok {
+			t.Errorf("%s: expected routes not to be cached", dsn)
+		}
+	}
+}
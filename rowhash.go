@@ -0,0 +1,87 @@
+package mssql
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"hash"
+)
+
+// ResultSetHash is the hash of a result set returned by HashRows.
+type ResultSetHash struct {
+	// Rows is the number of rows of the result set.
+	Rows int64
+	// Sum is the SHA-256 hash of the number of columns and the hashes of the
+	// rows in order.
+	Sum [sha256.Size]byte
+}
+
+// HashRows streams the rows of the current result set of rows and returns
+// the hash of the result set, e.g. to compare a table after a migration or
+// on a replica with the source without holding either in memory:
+//
+//	rows, err := db.QueryContext(ctx, "select * from dbo.orders order by id")
+//	...
+//	defer rows.Close()
+//	sum, err := mssql.HashRows(rows, nil)
+//
+// Every value is serialized in the text form of WriteCSV, so a value hashes
+// the same from both sides when the column has the same type, and NULL
+// differs from an empty string. The hash of the set depends on the order of
+// the rows, the query needs an ORDER BY on a unique key.
+//
+// When onRow is not nil it is called with the number, counting from 1, and
+// the SHA-256 hash of every row, to find the rows that differ. The hash is
+// only valid during the call.
+func HashRows(rows *sql.Rows, onRow func(row int64, sum []byte) error) (ResultSetHash, error) {
+	var result ResultSetHash
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return result, err
+	}
+	values := make([]interface{}, len(types))
+	scan := make([]interface{}, len(types))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	set := sha256.New()
+	row := sha256.New()
+	var buf [binary.MaxVarintLen64]byte
+	set.Write(buf[:binary.PutUvarint(buf[:], uint64(len(types)))])
+	sum := make([]byte, 0, sha256.Size)
+	for rows.Next() {
+		if err = rows.Scan(scan...); err != nil {
+			return result, err
+		}
+		row.Reset()
+		for i, typ := range types {
+			writeHashValue(row, buf[:], typ, values[i])
+		}
+		sum = row.Sum(sum[:0])
+		set.Write(sum)
+		result.Rows++
+		if onRow != nil {
+			if err = onRow(result.Rows, sum); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return result, err
+	}
+	copy(result.Sum[:], set.Sum(nil))
+	return result, nil
+}
+
+// writeHashValue writes v to h as a 0 byte for NULL, or as a 1 byte and
+// the length and bytes of its text form.
+func writeHashValue(h hash.Hash, buf []byte, typ *sql.ColumnType, v interface{}) {
+	if v == nil {
+		h.Write([]byte{0})
+		return
+	}
+	text := formatCSVValue(typ, v, CSVOptions{})
+	h.Write([]byte{1})
+	h.Write(buf[:binary.PutUvarint(buf, uint64(len(text)))])
+	h.Write([]byte(text))
+}
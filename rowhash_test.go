@@ -0,0 +1,56 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestHashRows(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	result := func(rows ...[]interface{}) mssqltest.Response {
+		return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"id", "name"}, Rows: rows}}}
+	}
+	srv.Handle("select source", result([]interface{}{1, "a"}, []interface{}{2, nil}))
+	srv.Handle("select replica", result([]interface{}{1, "a"}, []interface{}{2, nil}))
+	srv.Handle("select empty name", result([]interface{}{1, "a"}, []interface{}{2, ""}))
+	srv.Handle("select swapped", result([]interface{}{2, nil}, []interface{}{1, "a"}))
+
+	hashQuery := func(query string) (ResultSetHash, [][]byte) {
+		rows, err := db.QueryContext(context.Background(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var sums [][]byte
+		h, err := HashRows(rows, func(row int64, sum []byte) error {
+			if row != int64(len(sums)+1) {
+				t.Errorf("expected row %d, got %d", len(sums)+1, row)
+			}
+			sums = append(sums, append([]byte(nil), sum...))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h, sums
+	}
+	source, sourceRows := hashQuery("select source")
+	if source.Rows != 2 || len(sourceRows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", source.Rows)
+	}
+	if replica, _ := hashQuery("select replica"); replica != source {
+		t.Error("expected equal result sets to have the same hash")
+	}
+	empty, emptyRows := hashQuery("select empty name")
+	if empty.Sum == source.Sum || !bytes.Equal(emptyRows[0], sourceRows[0]) || bytes.Equal(emptyRows[1], sourceRows[1]) {
+		t.Error("expected NULL and an empty string to hash differently")
+	}
+	if swapped, _ := hashQuery("select swapped"); swapped.Sum == source.Sum {
+		t.Error("expected the hash to depend on the order of the rows")
+	}
+}
@@ -0,0 +1,104 @@
+package mssql
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// RowVersion is the 8 byte value of a rowversion (timestamp) column.
+// It is sent as varbinary(8), so it can be used in a WHERE clause to check
+// that a row was not modified since it was read.
+type RowVersion [8]byte
+
+// ErrRowVersionConflict is returned by CheckRowVersion when the row was
+// changed or deleted since its row version was read.
+var ErrRowVersionConflict = errors.New("mssql: the row was changed or deleted since its rowversion was read")
+
+// CheckRowVersion returns the error of an UPDATE or DELETE of a single row
+// that checks its row version in the WHERE clause, or ErrRowVersionConflict
+// when it affected no row:
+//
+//	err := mssql.CheckRowVersion(db.ExecContext(ctx,
+//		"update dbo.orders set status = @p1 where id = @p2 and version = @p3", status, id, version))
+//	if errors.Is(err, mssql.ErrRowVersionConflict) {
+//		// read the row again
+//	}
+func CheckRowVersion(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRowVersionConflict
+	}
+	return nil
+}
+
+// Scan sets rv to a rowversion value. A NULL, e.g. of an outer join, sets
+// the zero RowVersion, which is older than the version of every row.
+func (rv *RowVersion) Scan(v interface{}) error {
+	switch vt := v.(type) {
+	case nil:
+		*rv = RowVersion{}
+		return nil
+	case []byte:
+		if len(vt) != len(rv) {
+			return fmt.Errorf("mssql: invalid RowVersion length %d", len(vt))
+		}
+		copy(rv[:], vt)
+		return nil
+	default:
+		return fmt.Errorf("mssql: cannot convert %T to RowVersion", v)
+	}
+}
+
+func (rv RowVersion) Value() (driver.Value, error) {
+	raw := make([]byte, len(rv))
+	copy(raw, rv[:])
+	return raw, nil
+}
+
+// Uint64 returns the row version as a number, rowversion values
+// are stored big endian and increase with every change in the database.
+func (rv RowVersion) Uint64() uint64 {
+	return binary.BigEndian.Uint64(rv[:])
+}
+
+// Compare returns -1, 0 or 1 when rv is older than, the same as or newer than other.
+func (rv RowVersion) Compare(other RowVersion) int {
+	return bytes.Compare(rv[:], other[:])
+}
+
+// String returns the row version the way SQL Server displays it, e.g. 0x00000000000007D1.
+func (rv RowVersion) String() string {
+	return fmt.Sprintf("0x%X", rv[:])
+}
+
+// MarshalText converts the RowVersion to its hexadecimal string representation.
+func (rv RowVersion) MarshalText() (text []byte, err error) {
+	text = []byte(rv.String())
+	return
+}
+
+// UnmarshalText parses a RowVersion from its hexadecimal string representation,
+// with or without the 0x prefix.
+func (rv *RowVersion) UnmarshalText(text []byte) error {
+	text = bytes.TrimPrefix(bytes.TrimPrefix(text, []byte("0x")), []byte("0X"))
+	var raw RowVersion
+	if hex.DecodedLen(len(text)) != len(raw) {
+		return fmt.Errorf("mssql: invalid RowVersion string length %d", len(text))
+	}
+	if _, err := hex.Decode(raw[:], text); err != nil {
+		return err
+	}
+	*rv = raw
+	return nil
+}
@@ -0,0 +1,99 @@
+package mssql
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRowVersionScan(t *testing.T) {
+	t.Parallel()
+	var rv RowVersion
+	if err := rv.Scan([]byte{0, 0, 0, 0, 0, 0, 0x07, 0xd1}); err != nil {
+		t.Fatal(err)
+	}
+	if rv.Uint64() != 2001 {
+		t.Errorf("expected 2001, got %d", rv.Uint64())
+	}
+	if err := rv.Scan([]byte{1, 2, 3}); err == nil {
+		t.Error("expected an error for a short value")
+	}
+	if err := rv.Scan(nil); err != nil || rv != (RowVersion{}) {
+		t.Errorf("expected NULL to scan as the zero RowVersion, got %v and %v", rv, err)
+	}
+	if err := rv.Scan("0x01"); err == nil {
+		t.Error("expected an error for a string")
+	}
+}
+
+type rowVersionResult int64
+
+func (r rowVersionResult) LastInsertId() (int64, error) { return 0, nil }
+func (r rowVersionResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestCheckRowVersion(t *testing.T) {
+	t.Parallel()
+	if err := CheckRowVersion(rowVersionResult(1), nil); err != nil {
+		t.Errorf("expected no error for an updated row, got %v", err)
+	}
+	if err := CheckRowVersion(rowVersionResult(0), nil); !errors.Is(err, ErrRowVersionConflict) {
+		t.Errorf("expected ErrRowVersionConflict, got %v", err)
+	}
+	failed := errors.New("failed")
+	if err := CheckRowVersion(nil, failed); err != failed {
+		t.Errorf("expected the error of the statement, got %v", err)
+	}
+}
+
+func TestRowVersionValue(t *testing.T) {
+	t.Parallel()
+	rv := RowVersion{0, 0, 0, 0, 0, 0, 0x07, 0xd1}
+	v, err := rv.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok := v.([]byte)
+	if !ok || !bytes.Equal(b, rv[:]) {
+		t.Fatalf("unexpected value %v", v)
+	}
+
+	s := &Stmt{}
+	p, err := s.makeParam(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decl := makeDecl(p.ti); decl != "varbinary(8)" {
+		t.Errorf("expected varbinary(8), got %s", decl)
+	}
+}
+
+func TestRowVersionCompare(t *testing.T) {
+	t.Parallel()
+	older := RowVersion{0, 0, 0, 0, 0, 0, 0x00, 0xff}
+	newer := RowVersion{0, 0, 0, 0, 0, 0, 0x01, 0x00}
+	if older.Compare(newer) != -1 || newer.Compare(older) != 1 || older.Compare(older) != 0 {
+		t.Error("rowversion values compare incorrectly")
+	}
+}
+
+func TestRowVersionText(t *testing.T) {
+	t.Parallel()
+	rv := RowVersion{0, 0, 0, 0, 0, 0, 0x07, 0xd1}
+	text, err := rv.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "0x00000000000007D1" {
+		t.Errorf("unexpected text %s", text)
+	}
+	var got RowVersion
+	if err := got.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if got != rv {
+		t.Errorf("expected %s, got %s", rv, got)
+	}
+	if err := got.UnmarshalText([]byte("0x07D1")); err == nil {
+		t.Error("expected an error for a short string")
+	}
+}
@@ -0,0 +1,158 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Defaults of RowVersionPoller.
+const (
+	defaultPollBatchSize = 1000
+	defaultPollInterval  = 5 * time.Second
+)
+
+// RowVersionPoller polls a table for rows that were inserted or updated
+// since the last poll, by the rowversion column of the table, as a simple
+// alternative to change data capture for sync jobs. Deleted rows are not
+// seen. The rowversion column should be indexed:
+//
+//	p := &mssql.RowVersionPoller{Schema: "dbo", Table: "orders", RowVersionColumn: "rv", Last: saved}
+//	err := p.Run(ctx, db, func(ctx context.Context, batch []map[string]interface{}) error {
+//		...
+//		return save(p.Last)
+//	})
+//
+// Rows changed by transactions that are still open have lower row versions
+// than rows committed after them. A poll reads only rows with a row version
+// below MIN_ACTIVE_ROWVERSION(), so the watermark does not pass the rows of
+// open transactions, they are read once the transactions committed.
+type RowVersionPoller struct {
+	// Schema and Table are the names of the table, an empty Schema uses the
+	// default schema of the user.
+	Schema string
+	Table  string
+	// Columns are the columns to read, all columns when empty. The rowversion
+	// column is read in any case.
+	Columns []string
+	// RowVersionColumn is the name of the rowversion column.
+	RowVersionColumn string
+	// BatchSize is the maximum number of rows of a batch, 1000 by default.
+	BatchSize int
+	// Interval is the time Run waits after a poll that read all changes, 5
+	// seconds by default.
+	Interval time.Duration
+	// Last is the watermark, the row version of the last row that was
+	// passed to the callback. Set it to continue after a restart, the zero
+	// value reads all rows of the table.
+	Last RowVersion
+}
+
+// query returns the query of a batch.
+func (p *RowVersionPoller) query() (string, error) {
+	table, err := QuoteSchemaObject(p.Schema, p.Table)
+	if err != nil {
+		return "", err
+	}
+	if p.RowVersionColumn == "" {
+		return "", errors.New("mssql: RowVersionPoller needs the RowVersionColumn")
+	}
+	rv, err := QuoteIdentifier(p.RowVersionColumn)
+	if err != nil {
+		return "", err
+	}
+	columns := "*"
+	if len(p.Columns) > 0 {
+		quoted := make([]string, 0, len(p.Columns)+1)
+		hasRV := false
+		for _, c := range p.Columns {
+			q, err := QuoteIdentifier(c)
+			if err != nil {
+				return "", err
+			}
+			quoted = append(quoted, q)
+			hasRV = hasRV || strings.EqualFold(c, p.RowVersionColumn)
+		}
+		if !hasRV {
+			quoted = append(quoted, rv)
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+	return fmt.Sprintf("select top (@p1) %s from %s where %s > @p2 and %s < min_active_rowversion() order by %s",
+		columns, table, rv, rv, rv), nil
+}
+
+// Poll reads the rows changed since Last in batches of BatchSize and calls
+// fn with every batch, until all changes were read. Last is advanced to the
+// last row of a batch after fn returned without error, so a failed batch
+// is read again by the next poll. Poll returns the number of rows read.
+func (p *RowVersionPoller) Poll(ctx context.Context, q Querier, fn func(ctx context.Context, batch []map[string]interface{}) error) (int, error) {
+	query, err := p.query()
+	if err != nil {
+		return 0, err
+	}
+	size := p.BatchSize
+	if size <= 0 {
+		size = defaultPollBatchSize
+	}
+	n := 0
+	for {
+		var batch []map[string]interface{}
+		if err = SelectMaps(ctx, q, &batch, query, size, p.Last); err != nil {
+			return n, err
+		}
+		if len(batch) == 0 {
+			return n, nil
+		}
+		var last RowVersion
+		if err = last.Scan(mapValue(batch[len(batch)-1], p.RowVersionColumn)); err != nil {
+			return n, fmt.Errorf("mssql: reading the rowversion column %s: %w", p.RowVersionColumn, err)
+		}
+		if err = fn(ctx, batch); err != nil {
+			return n, err
+		}
+		p.Last = last
+		n += len(batch)
+		if len(batch) < size {
+			return n, nil
+		}
+	}
+}
+
+// Run polls the table until ctx is done, waiting Interval after every poll
+// that read all changes. It returns the error of a poll or of ctx.
+func (p *RowVersionPoller) Run(ctx context.Context, q Querier, fn func(ctx context.Context, batch []map[string]interface{}) error) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if _, err := p.Poll(ctx, q, fn); err != nil {
+			return err
+		}
+		timer.Reset(interval)
+	}
+}
+
+// mapValue returns the value of the column name of row, the names of
+// columns are case insensitive like in the default collations.
+func mapValue(row map[string]interface{}, name string) interface{} {
+	if v, ok := row[name]; ok {
+		return v
+	}
+	for k, v := range row {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestRowVersionPollerQuery(t *testing.T) {
+	p := &RowVersionPoller{Schema: "dbo", Table: "orders", Columns: []string{"id", "total"}, RowVersionColumn: "rv"}
+	query, err := p.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "select top (@p1) [id], [total], [rv] from [dbo].[orders] where [rv] > @p2 and [rv] < min_active_rowversion() order by [rv]"
+	if query != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, query)
+	}
+	if _, err = (&RowVersionPoller{Table: "orders"}).query(); err == nil {
+		t.Error("expected an error without a rowversion column")
+	}
+}
+
+func TestRowVersionPollerPoll(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	rv := func(n byte) []byte { return []byte{0, 0, 0, 0, 0, 0, 0, n} }
+	batches := [][][]interface{}{
+		{{1, rv(3)}, {2, rv(5)}},
+		{{3, rv(9)}},
+	}
+	polls := 0
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		var rows [][]interface{}
+		if polls < len(batches) {
+			rows = batches[polls]
+		}
+		polls++
+		return mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"id", "RV"}, Rows: rows}}}
+	})
+	p := &RowVersionPoller{Table: "orders", RowVersionColumn: "rv", BatchSize: 2}
+	ctx := context.Background()
+	failed := errors.New("sync failed")
+	_, err := p.Poll(ctx, db, func(ctx context.Context, batch []map[string]interface{}) error { return failed })
+	if err != failed || p.Last != (RowVersion{}) {
+		t.Fatalf("expected the watermark to stay at a failed batch, got %v, %s", err, p.Last)
+	}
+
+	polls = 0
+	var ids []int64
+	n, err := p.Poll(ctx, db, func(ctx context.Context, batch []map[string]interface{}) error {
+		for _, row := range batch {
+			ids = append(ids, row["id"].(int64))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || len(ids) != 3 || polls != 2 {
+		t.Errorf("expected 3 rows in 2 batches, got %d rows %v in %d polls", n, ids, polls)
+	}
+	if p.Last.Uint64() != 9 {
+		t.Errorf("expected the watermark of the last row, got %s", p.Last)
+	}
+}
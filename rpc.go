@@ -37,15 +37,46 @@ var (
 	sp_CursorClose     = procId{9, ""}
 	sp_ExecuteSql      = procId{10, ""}
 	sp_Prepare         = procId{11, ""}
+	sp_Execute         = procId{12, ""}
 	sp_PrepExec        = procId{13, ""}
 	sp_PrepExecRpc     = procId{14, ""}
 	sp_Unprepare       = procId{15, ""}
 )
 
+// rpcCall is a call of an RPC request.
+type rpcCall struct {
+	proc   procId
+	flags  uint16
+	params []param
+}
+
+// batchFlag separates the calls of an RPC request with more than one call.
+const batchFlag = 0xff
+
 // http://msdn.microsoft.com/en-us/library/dd357576.aspx
 func sendRpc(buf *tdsBuffer, headers []headerStruct, proc procId, flags uint16, params []param, resetSession bool) (err error) {
+	return sendRpcs(buf, headers, []rpcCall{{proc, flags, params}}, resetSession)
+}
+
+// sendRpcs sends the calls in a single RPC request, the server runs them in order.
+func sendRpcs(buf *tdsBuffer, headers []headerStruct, calls []rpcCall, resetSession bool) (err error) {
 	buf.BeginPacket(packRPCRequest, resetSession)
 	writeAllHeaders(buf, headers)
+	for i, call := range calls {
+		if i > 0 {
+			if err = buf.WriteByte(batchFlag); err != nil {
+				return
+			}
+		}
+		if err = writeRpcCall(buf, call); err != nil {
+			return
+		}
+	}
+	return buf.FinishPacket()
+}
+
+func writeRpcCall(buf *tdsBuffer, call rpcCall) (err error) {
+	proc, flags, params := call.proc, call.flags, call.params
 	if len(proc.name) == 0 {
 		var idswitch uint16 = 0xffff
 		err = binary.Write(buf, binary.LittleEndian, &idswitch)
@@ -91,5 +122,5 @@ func sendRpc(buf *tdsBuffer, headers []headerStruct, proc procId, flags uint16,
 			}
 		}
 	}
-	return buf.FinishPacket()
+	return
 }
@@ -0,0 +1,46 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// convertRow applies the scan settings of the connection to the values of a row.
+func (c *Conn) convertRow(cols []columnStruct, dest []driver.Value) {
+	if c.bitScan == msdsn.BitScanInt {
+		convertBits(cols, dest)
+	}
+	c.convertDateTimes(cols, dest)
+}
+
+// convertBits returns the values of bit columns as the int64 0 or 1.
+func convertBits(cols []columnStruct, dest []driver.Value) {
+	for i, v := range dest {
+		b, ok := v.(bool)
+		if !ok || i >= len(cols) || !isBitType(cols[i].originalTypeInfo()) {
+			continue
+		}
+		if b {
+			dest[i] = int64(1)
+		} else {
+			dest[i] = int64(0)
+		}
+	}
+}
+
+func isBitType(ti typeInfo) bool {
+	return ti.TypeId == typeBit || ti.TypeId == typeBitN
+}
+
+// scanType returns the type the values of the column are scanned as.
+func (c *Conn) scanType(ti typeInfo) reflect.Type {
+	switch {
+	case c.dateTimeScan == msdsn.DateTimeScanString && isDateTimeType(ti):
+		return reflect.TypeOf("")
+	case c.bitScan == msdsn.BitScanInt && isBitType(ti):
+		return reflect.TypeOf(int64(0))
+	}
+	return makeGoLangScanType(ti)
+}
@@ -0,0 +1,83 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestBitScanInt(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable;bit scan=int", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select active, deleted from dbo.users", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"active", "deleted"},
+		Rows:    [][]interface{}{{true, false}},
+	}}})
+	rows, err := db.QueryContext(context.Background(), "select active, deleted from dbo.users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := types[0].ScanType(); typ != reflect.TypeOf(int64(0)) {
+		t.Errorf("expected the scan type int64, got %v", typ)
+	}
+	if !rows.Next() {
+		t.Fatal(rows.Err())
+	}
+	var active, deleted interface{}
+	if err = rows.Scan(&active, &deleted); err != nil {
+		t.Fatal(err)
+	}
+	if active != int64(1) || deleted != int64(0) {
+		t.Errorf("expected 1 and 0, got %v and %v", active, deleted)
+	}
+}
+
+func TestUnsignedParameters(t *testing.T) {
+	tests := []struct {
+		in       interface{}
+		expected driver.Value
+	}{
+		{uint8(255), uint8(255)},
+		{uint16(math.MaxUint16), int32(math.MaxUint16)},
+		{uint32(math.MaxUint32), int64(math.MaxUint32)},
+		{uint(42), int64(42)},
+		{uint64(math.MaxInt64), int64(math.MaxInt64)},
+	}
+	for _, test := range tests {
+		v, err := convertInputParameter(test.in)
+		if err != nil {
+			t.Errorf("%T %v: %v", test.in, test.in, err)
+			continue
+		}
+		if v != test.expected {
+			t.Errorf("%T %v: expected %T %v, got %T %v", test.in, test.in, test.expected, test.expected, v, v)
+		}
+	}
+	_, err := convertInputParameter(uint64(math.MaxInt64 + 1))
+	if err == nil || !strings.Contains(err.Error(), "out of the range of bigint") {
+		t.Errorf("expected a range error, got %v", err)
+	}
+}
+
+func TestInt8ParameterIsSigned(t *testing.T) {
+	s := &Stmt{}
+	p, err := s.makeParam(int8(-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a tinyint is unsigned, -1 has to be sent as a smallint
+	if p.ti.TypeId != typeIntN || p.ti.Size != 2 || !reflect.DeepEqual(p.buffer, []byte{0xff, 0xff}) {
+		t.Errorf("unexpected parameter %+v", p)
+	}
+}
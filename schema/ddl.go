@@ -0,0 +1,308 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang-sql/civil"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/internal/jsonfields"
+)
+
+const mssqlTag = "mssql"
+
+// column is the definition of a column of a struct field.
+type column struct {
+	name       string
+	sqlType    string
+	nullable   bool
+	identity   bool
+	primaryKey bool
+	def        string
+}
+
+// CreateTable returns the CREATE TABLE statement of the table name with
+// a column for every field of v, a struct or a pointer to a struct.
+// name is used as is, e.g. "dbo.orders" or "[sales].[order lines]".
+//
+// The column of a field is described by the mssql tag, a comma
+// separated list of options:
+//
+//	type Order struct {
+//		ID       int64     `json:"id" mssql:"identity,pk"`
+//		Customer string    `json:"customer" mssql:"size=100"`
+//		Total    float64   `json:"total" mssql:"type=decimal(18,2)"`
+//		Created  time.Time `json:"created" mssql:"type=datetime2,default=sysutcdatetime()"`
+//		Note     *string   `json:"note"`
+//		Internal string    `json:"-"`
+//	}
+//
+// The column is named like the key of the field in encoding/json or by its
+// db tag, fields with the json, db or mssql tag "-" have no column. A field
+// with the json option of the db tag, as in db:"payload,json", is a
+// nvarchar column with its JSON. The options are:
+//
+//	type=T      the SQL type of the column, instead of the type derived from the Go type
+//	size=N      the length of a string or []byte column, instead of max, or of
+//	            450 characters and 900 bytes in the primary key
+//	identity    an identity(1,1) column, also set by the tvp tag "@identity"
+//	pk          a column of the primary key, in the order of the fields
+//	null        a nullable column, pointers and the sql.Null types are nullable
+//	default=E   the default value expression
+func CreateTable(name string, v interface{}) (string, error) {
+	columns, err := structColumns(v)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "create table %s (\n", name)
+	var pk []string
+	for i, c := range columns {
+		if i > 0 {
+			sb.WriteString(",\n")
+		}
+		sb.WriteString("\t" + c.definition())
+		if c.primaryKey {
+			pk = append(pk, quote(c.name))
+		}
+	}
+	if len(pk) > 0 {
+		fmt.Fprintf(&sb, ",\n\tprimary key (%s)", strings.Join(pk, ", "))
+	}
+	sb.WriteString("\n)")
+	return sb.String(), nil
+}
+
+// AlterTable returns the ALTER TABLE statements that add the columns of
+// the fields of v that table does not have. Columns are neither changed
+// nor dropped. A column that is added to a table with rows must be
+// nullable or have a default.
+func AlterTable(table *Table, v interface{}) ([]string, error) {
+	columns, err := structColumns(v)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(table.Columns))
+	for _, c := range table.Columns {
+		// column names are compared like the default case insensitive collations
+		existing[strings.ToLower(c.Name)] = true
+	}
+	var res []string
+	for _, c := range columns {
+		if existing[strings.ToLower(c.name)] {
+			continue
+		}
+		res = append(res, fmt.Sprintf("alter table %s.%s add %s", quote(table.Schema), quote(table.Name), c.definition()))
+	}
+	return res, nil
+}
+
+// Apply runs the statements in order with e.
+func Apply(ctx context.Context, e mssql.Execer, statements ...string) error {
+	for _, s := range statements {
+		if _, err := e.ExecContext(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quote(name string) string {
+	return mssql.TSQLQuoter{}.ID(name)
+}
+
+func (c column) definition() string {
+	def := quote(c.name) + " " + c.sqlType
+	if c.identity {
+		def += " identity(1,1)"
+	}
+	if c.nullable {
+		def += " null"
+	} else {
+		def += " not null"
+	}
+	if c.def != "" {
+		def += " default " + c.def
+	}
+	return def
+}
+
+func structColumns(v interface{}) ([]column, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: expected a struct, got %T", v)
+	}
+	columns, err := fieldColumns(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("schema: %s has no exported fields", t)
+	}
+	return columns, nil
+}
+
+func fieldColumns(t reflect.Type) ([]column, error) {
+	var columns []column
+	fields := jsonfields.Fields(t, func(field reflect.StructField) bool {
+		return field.Tag.Get(mssqlTag) == "-"
+	})
+	for _, field := range fields {
+		tag := field.Tag.Get(mssqlTag)
+		c := column{name: field.Name, identity: field.Tag.Get("tvp") == "@identity"}
+		size := ""
+		for _, option := range splitOptions(tag) {
+			key, value := option, ""
+			if eq := strings.IndexByte(option, '='); eq >= 0 {
+				key, value = option[:eq], option[eq+1:]
+			}
+			switch key {
+			case "type":
+				c.sqlType = value
+			case "size":
+				size = value
+			case "identity":
+				c.identity = true
+			case "pk":
+				c.primaryKey = true
+			case "null":
+				c.nullable = true
+			case "default":
+				c.def = value
+			default:
+				return nil, fmt.Errorf("schema: field %s: unknown option %q", field.StructField.Name, option)
+			}
+		}
+		if size == "" {
+			size = "max"
+			if c.primaryKey {
+				size = keySize(field.Type)
+			}
+		}
+		sqlType, nullable, ok := goType(field.Type, size)
+		if field.JSON {
+			switch field.Type.Kind() {
+			case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+				nullable = true
+			}
+			sqlType, ok = "nvarchar("+size+")", true
+		}
+		if c.sqlType == "" {
+			if !ok {
+				return nil, fmt.Errorf("schema: field %s: unsupported type %s, set the SQL type with the type option", field.StructField.Name, field.Type)
+			}
+			c.sqlType = sqlType
+		}
+		c.nullable = c.nullable || nullable
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// keySize returns the size of a string or []byte column of type t in the
+// primary key without the size option. Index keys are at most 900 bytes,
+// max columns cannot be in a key.
+func keySize(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		return "900"
+	}
+	// 450 characters of nvarchar
+	return "450"
+}
+
+// splitOptions splits the options of a tag at the commas
+// that are not inside parentheses, like in type=decimal(18,2).
+func splitOptions(tag string) []string {
+	var options []string
+	depth, start := 0, 0
+	for i, r := range tag {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				options = append(options, strings.TrimSpace(tag[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(tag[start:]); last != "" || len(options) > 0 {
+		options = append(options, last)
+	}
+	return options
+}
+
+var sqlTypes = map[reflect.Type]string{
+	reflect.TypeOf(time.Time{}):                  "datetimeoffset",
+	reflect.TypeOf(civil.Date{}):                 "date",
+	reflect.TypeOf(civil.DateTime{}):             "datetime2",
+	reflect.TypeOf(civil.Time{}):                 "time",
+	reflect.TypeOf(mssql.UniqueIdentifier{}):     "uniqueidentifier",
+	reflect.TypeOf(mssql.NullUniqueIdentifier{}): "uniqueidentifier",
+	reflect.TypeOf(mssql.RowVersion{}):           "rowversion",
+	reflect.TypeOf(sql.NullBool{}):               "bit",
+	reflect.TypeOf(sql.NullByte{}):               "tinyint",
+	reflect.TypeOf(sql.NullInt16{}):              "smallint",
+	reflect.TypeOf(sql.NullInt32{}):              "int",
+	reflect.TypeOf(sql.NullInt64{}):              "bigint",
+	reflect.TypeOf(sql.NullFloat64{}):            "float",
+	reflect.TypeOf(sql.NullTime{}):               "datetimeoffset",
+}
+
+// goType returns the SQL type of a column for a field of type t,
+// and if the column is nullable.
+func goType(t reflect.Type, size string) (sqlType string, nullable bool, ok bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+	if t == reflect.TypeOf(sql.NullString{}) {
+		return "nvarchar(" + size + ")", true, true
+	}
+	if sqlType, ok = sqlTypes[t]; ok {
+		if strings.HasPrefix(t.Name(), "Null") {
+			nullable = true
+		}
+		return sqlType, nullable, true
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		sqlType = "bit"
+	case reflect.Uint8:
+		sqlType = "tinyint"
+	case reflect.Int8, reflect.Int16:
+		sqlType = "smallint"
+	case reflect.Int32, reflect.Uint16:
+		sqlType = "int"
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		sqlType = "bigint"
+	case reflect.Uint, reflect.Uint64:
+		sqlType = "decimal(20, 0)"
+	case reflect.Float32:
+		sqlType = "real"
+	case reflect.Float64:
+		sqlType = "float"
+	case reflect.String:
+		sqlType = "nvarchar(" + size + ")"
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return "", false, false
+		}
+		sqlType = "varbinary(" + size + ")"
+	default:
+		return "", false, false
+	}
+	return sqlType, nullable, true
+}
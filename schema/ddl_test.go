@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+type ddlAudit struct {
+	Created time.Time `json:"created" mssql:"type=datetime2,default=sysutcdatetime()"`
+}
+
+type ddlOrder struct {
+	ID       int64   `json:"id" mssql:"identity,pk"`
+	Customer string  `json:"customer" mssql:"size=100"`
+	Total    float64 `json:"total" mssql:"type=decimal(18,2)"`
+	Note     *string `json:"note,omitempty"`
+	Ref      mssql.UniqueIdentifier
+	Shipped  sql.NullTime `json:"shipped"`
+	Data     []byte       `json:"data" mssql:"size=16,null"`
+	Internal string       `json:"-"`
+	Skipped  int          `mssql:"-"`
+	ddlAudit
+	private int
+}
+
+func TestCreateTable(t *testing.T) {
+	got, err := CreateTable("dbo.orders", &ddlOrder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "create table dbo.orders (\n" +
+		"\t[id] bigint identity(1,1) not null,\n" +
+		"\t[customer] nvarchar(100) not null,\n" +
+		"\t[total] decimal(18,2) not null,\n" +
+		"\t[note] nvarchar(max) null,\n" +
+		"\t[Ref] uniqueidentifier not null,\n" +
+		"\t[shipped] datetimeoffset null,\n" +
+		"\t[data] varbinary(16) null,\n" +
+		"\t[created] datetime2 not null default sysutcdatetime(),\n" +
+		"\tprimary key ([id])\n" +
+		")"
+	if got != expected {
+		t.Errorf("unexpected statement\n%s\nexpected\n%s", got, expected)
+	}
+
+	// max columns cannot be in a key
+	got, err = CreateTable("dbo.codes", struct {
+		Code  string `json:"code" mssql:"pk"`
+		Hash  []byte `json:"hash" mssql:"pk"`
+		Short string `json:"short" mssql:"pk,size=10"`
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "create table dbo.codes (\n" +
+		"\t[code] nvarchar(450) not null,\n" +
+		"\t[hash] varbinary(900) not null,\n" +
+		"\t[short] nvarchar(10) not null,\n" +
+		"\tprimary key ([code], [hash], [short])\n" +
+		")"
+	if got != expected {
+		t.Errorf("unexpected statement\n%s\nexpected\n%s", got, expected)
+	}
+
+	got, err = CreateTable("dbo.documents", struct {
+		Key     string            `db:"key" mssql:"pk"`
+		Payload map[string]string `db:"payload,json"`
+		Owner   struct{ ID int }  `db:"owner,json" mssql:"size=4000"`
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "create table dbo.documents (\n" +
+		"\t[key] nvarchar(450) not null,\n" +
+		"\t[payload] nvarchar(max) null,\n" +
+		"\t[owner] nvarchar(4000) not null,\n" +
+		"\tprimary key ([key])\n" +
+		")"
+	if got != expected {
+		t.Errorf("unexpected statement\n%s\nexpected\n%s", got, expected)
+	}
+
+	for _, invalid := range []interface{}{nil, 1, struct{ x int }{}, struct{ C chan int }{}, struct {
+		A int `mssql:"primary"`
+	}{}} {
+		if _, err = CreateTable("t", invalid); err == nil {
+			t.Errorf("expected an error for %T", invalid)
+		}
+	}
+}
+
+func TestAlterTable(t *testing.T) {
+	table := &Table{Schema: "dbo", Name: "orders", Columns: []Column{{Name: "ID"}, {Name: "customer"}, {Name: "total"}}}
+	got, err := AlterTable(table, ddlOrder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"alter table [dbo].[orders] add [note] nvarchar(max) null",
+		"alter table [dbo].[orders] add [Ref] uniqueidentifier not null",
+		"alter table [dbo].[orders] add [shipped] datetimeoffset null",
+		"alter table [dbo].[orders] add [data] varbinary(16) null",
+		"alter table [dbo].[orders] add [created] datetime2 not null default sysutcdatetime()",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements %q", got)
+	}
+}
+
+func TestSplitOptions(t *testing.T) {
+	got := splitOptions("type=decimal(18, 2), pk ,default=(coalesce(1,2))")
+	expected := []string{"type=decimal(18, 2)", "pk", "default=(coalesce(1,2))"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected options %q", got)
+	}
+	if len(splitOptions("")) != 0 {
+		t.Error("expected no options for an empty tag")
+	}
+}
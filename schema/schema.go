@@ -0,0 +1,266 @@
+// Package schema reads the tables of a database from the sys catalog views:
+// their columns with types, nullability and defaults, primary keys,
+// foreign keys and indexes.
+//
+//	tables, err := schema.Tables(ctx, db)
+//	...
+//	for _, t := range tables {
+//		fmt.Println(t.Schema, t.Name, t.PrimaryKey)
+//	}
+//
+// The catalog views only show the objects the user has a permission on.
+//
+// CreateTable and AlterTable generate the DDL of a table from an annotated
+// struct, for test fixtures and prototypes.
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Table is a user table, from sys.tables.
+type Table struct {
+	Schema  string
+	Name    string
+	Columns []Column
+	// PrimaryKey are the columns of the primary key in key order,
+	// empty when the table has no primary key.
+	PrimaryKey  []string
+	ForeignKeys []ForeignKey
+	// Indexes are the indexes of the table, including the index of
+	// the primary key and of unique constraints. Heaps have no index.
+	Indexes []Index
+}
+
+// Column is a column of a table, from sys.columns.
+type Column struct {
+	Name string
+	// Type is the name of the data type, e.g. "nvarchar" or "decimal",
+	// or the name of an alias data type.
+	Type string
+	// MaxLength is the maximum length of a string or binary column,
+	// in characters for nchar and nvarchar, -1 for max.
+	MaxLength int
+	Precision int
+	Scale     int
+	Nullable  bool
+	Identity  bool
+	Computed  bool
+	// Default is the definition of the default constraint, e.g. "(getdate())",
+	// empty when the column has no default.
+	Default   string
+	Collation string
+}
+
+// ForeignKey is a foreign key of a table, from sys.foreign_keys.
+type ForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedSchema  string
+	ReferencedTable   string
+	ReferencedColumns []string
+	// OnDelete and OnUpdate are the referential actions, e.g. "NO_ACTION" or "CASCADE".
+	OnDelete string
+	OnUpdate string
+}
+
+// Index is an index of a table, from sys.indexes.
+type Index struct {
+	Name string
+	// Type is "CLUSTERED", "NONCLUSTERED", "CLUSTERED COLUMNSTORE", ...
+	Type             string
+	Unique           bool
+	PrimaryKey       bool
+	UniqueConstraint bool
+	Columns          []IndexColumn
+	// Included are the included columns of a nonclustered index.
+	Included []string
+}
+
+// IndexColumn is a key column of an index.
+type IndexColumn struct {
+	Name       string
+	Descending bool
+}
+
+const filter = `t.is_ms_shipped = 0 and (@schema = '' or s.name = @schema) and (@table = '' or t.name = @table)`
+
+const tablesQuery = `select t.object_id, s.name, t.name
+from sys.tables t
+join sys.schemas s on s.schema_id = t.schema_id
+where ` + filter + `
+order by s.name, t.name`
+
+const columnsQuery = `select c.object_id, c.name, ty.name, c.max_length, c.precision, c.scale,
+	c.is_nullable, c.is_identity, c.is_computed, isnull(dc.definition, ''), isnull(c.collation_name, '')
+from sys.columns c
+join sys.tables t on t.object_id = c.object_id
+join sys.schemas s on s.schema_id = t.schema_id
+join sys.types ty on ty.user_type_id = c.user_type_id
+left join sys.default_constraints dc on dc.object_id = c.default_object_id
+where ` + filter + `
+order by c.object_id, c.column_id`
+
+const indexesQuery = `select i.object_id, i.index_id, i.name, i.type_desc, i.is_unique, i.is_primary_key, i.is_unique_constraint,
+	c.name, ic.is_descending_key, ic.is_included_column
+from sys.indexes i
+join sys.index_columns ic on ic.object_id = i.object_id and ic.index_id = i.index_id
+join sys.columns c on c.object_id = ic.object_id and c.column_id = ic.column_id
+join sys.tables t on t.object_id = i.object_id
+join sys.schemas s on s.schema_id = t.schema_id
+where i.type > 0 and ` + filter + `
+order by i.object_id, i.index_id, ic.is_included_column, ic.key_ordinal, ic.index_column_id`
+
+const foreignKeysQuery = `select fk.parent_object_id, fk.name, pc.name, rs.name, rt.name, rc.name,
+	fk.delete_referential_action_desc, fk.update_referential_action_desc
+from sys.foreign_keys fk
+join sys.foreign_key_columns fkc on fkc.constraint_object_id = fk.object_id
+join sys.columns pc on pc.object_id = fkc.parent_object_id and pc.column_id = fkc.parent_column_id
+join sys.tables rt on rt.object_id = fkc.referenced_object_id
+join sys.schemas rs on rs.schema_id = rt.schema_id
+join sys.columns rc on rc.object_id = fkc.referenced_object_id and rc.column_id = fkc.referenced_column_id
+join sys.tables t on t.object_id = fk.parent_object_id
+join sys.schemas s on s.schema_id = t.schema_id
+where ` + filter + `
+order by fk.parent_object_id, fk.name, fkc.constraint_column_id`
+
+// Tables returns the user tables of the current database ordered by schema and name.
+func Tables(ctx context.Context, q mssql.Querier) ([]Table, error) {
+	return tables(ctx, q, "", "")
+}
+
+// DescribeTable returns the table name of schemaName.
+func DescribeTable(ctx context.Context, q mssql.Querier, schemaName, name string) (*Table, error) {
+	if name == "" {
+		return nil, fmt.Errorf("schema: empty table name")
+	}
+	res, err := tables(ctx, q, schemaName, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("schema: table %s.%s not found", schemaName, name)
+	}
+	return &res[0], nil
+}
+
+func tables(ctx context.Context, q mssql.Querier, schemaName, name string) ([]Table, error) {
+	args := []interface{}{sql.Named("schema", schemaName), sql.Named("table", name)}
+	var res []Table
+	var ids []int
+	err := query(ctx, q, tablesQuery, args, func(rows *sql.Rows) error {
+		var id int
+		var t Table
+		if err := rows.Scan(&id, &t.Schema, &t.Name); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		res = append(res, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*Table, len(res))
+	for i := range res {
+		byID[ids[i]] = &res[i]
+	}
+	err = query(ctx, q, columnsQuery, args, func(rows *sql.Rows) error {
+		var id int
+		var c Column
+		err := rows.Scan(&id, &c.Name, &c.Type, &c.MaxLength, &c.Precision, &c.Scale,
+			&c.Nullable, &c.Identity, &c.Computed, &c.Default, &c.Collation)
+		if err != nil {
+			return err
+		}
+		if (c.Type == "nchar" || c.Type == "nvarchar") && c.MaxLength > 0 {
+			c.MaxLength /= 2
+		}
+		if t := byID[id]; t != nil {
+			t.Columns = append(t.Columns, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var lastIndex, lastIndexID int
+	err = query(ctx, q, indexesQuery, args, func(rows *sql.Rows) error {
+		var id, indexID int
+		var i Index
+		var column string
+		var descending, included bool
+		err := rows.Scan(&id, &indexID, &i.Name, &i.Type, &i.Unique, &i.PrimaryKey, &i.UniqueConstraint,
+			&column, &descending, &included)
+		if err != nil {
+			return err
+		}
+		t := byID[id]
+		if t == nil {
+			return nil
+		}
+		if id != lastIndex || indexID != lastIndexID || len(t.Indexes) == 0 {
+			t.Indexes = append(t.Indexes, i)
+			lastIndex, lastIndexID = id, indexID
+		}
+		index := &t.Indexes[len(t.Indexes)-1]
+		if included {
+			index.Included = append(index.Included, column)
+			return nil
+		}
+		index.Columns = append(index.Columns, IndexColumn{Name: column, Descending: descending})
+		if index.PrimaryKey {
+			t.PrimaryKey = append(t.PrimaryKey, column)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var lastFKTable int
+	err = query(ctx, q, foreignKeysQuery, args, func(rows *sql.Rows) error {
+		var id int
+		var fk ForeignKey
+		var column, referenced string
+		err := rows.Scan(&id, &fk.Name, &column, &fk.ReferencedSchema, &fk.ReferencedTable, &referenced,
+			&fk.OnDelete, &fk.OnUpdate)
+		if err != nil {
+			return err
+		}
+		t := byID[id]
+		if t == nil {
+			return nil
+		}
+		if id != lastFKTable || len(t.ForeignKeys) == 0 || t.ForeignKeys[len(t.ForeignKeys)-1].Name != fk.Name {
+			t.ForeignKeys = append(t.ForeignKeys, fk)
+			lastFKTable = id
+		}
+		last := &t.ForeignKeys[len(t.ForeignKeys)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, referenced)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// query calls scan for every row of the result of query.
+func query(ctx context.Context, q mssql.Querier, query string, args []interface{}, scan func(rows *sql.Rows) error) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,101 @@
+package schema_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+	"github.com/microsoft/go-mssqldb/schema"
+)
+
+func catalog(query string) mssqltest.Response {
+	result := func(columns string, rows ...[]interface{}) mssqltest.Response {
+		return mssqltest.Response{Results: []mssqltest.Result{{Columns: strings.Split(columns, ","), Rows: rows}}}
+	}
+	switch {
+	case strings.Contains(query, "from sys.tables t\n"):
+		return result("object_id,schema,name",
+			[]interface{}{1, "dbo", "customers"},
+			[]interface{}{2, "dbo", "orders"})
+	case strings.Contains(query, "from sys.columns c"):
+		return result("object_id,name,type,max_length,precision,scale,is_nullable,is_identity,is_computed,default,collation",
+			[]interface{}{1, "id", "int", 4, 10, 0, false, true, false, "", ""},
+			[]interface{}{1, "name", "nvarchar", 200, 0, 0, true, false, false, "", "Latin1_General_CI_AS"},
+			[]interface{}{2, "id", "int", 4, 10, 0, false, true, false, "", ""},
+			[]interface{}{2, "customer_id", "int", 4, 10, 0, false, false, false, "", ""},
+			[]interface{}{2, "created", "datetime2", 8, 27, 7, false, false, false, "(sysutcdatetime())", ""},
+			[]interface{}{2, "notes", "nvarchar", -1, 0, 0, true, false, false, "", "Latin1_General_CI_AS"})
+	case strings.Contains(query, "from sys.indexes i"):
+		return result("object_id,index_id,name,type,is_unique,is_primary_key,is_unique_constraint,column,is_descending_key,is_included_column",
+			[]interface{}{1, 1, "pk_customers", "CLUSTERED", true, true, false, "id", false, false},
+			[]interface{}{2, 1, "pk_orders", "CLUSTERED", true, true, false, "id", false, false},
+			[]interface{}{2, 2, "ix_orders_customer", "NONCLUSTERED", false, false, false, "customer_id", false, false},
+			[]interface{}{2, 2, "ix_orders_customer", "NONCLUSTERED", false, false, false, "created", true, false},
+			[]interface{}{2, 2, "ix_orders_customer", "NONCLUSTERED", false, false, false, "notes", false, true})
+	case strings.Contains(query, "from sys.foreign_keys fk"):
+		return result("parent_object_id,name,column,referenced_schema,referenced_table,referenced_column,on_delete,on_update",
+			[]interface{}{2, "fk_orders_customers", "customer_id", "dbo", "customers", "id", "CASCADE", "NO_ACTION"})
+	}
+	return mssqltest.Response{Err: &mssqltest.Error{Number: 50000, Message: "unexpected query"}}
+}
+
+func TestTables(t *testing.T) {
+	srv, err := mssqltest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.HandleFunc(catalog)
+	config, err := msdsn.Parse(srv.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := mssql.NewConnectorConfig(config)
+	connector.Dialer = srv
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	tables, err := schema.Tables(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[0].Name != "customers" || tables[1].Name != "orders" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	orders := tables[1]
+	if len(orders.Columns) != 4 || orders.Columns[3].MaxLength != -1 || !orders.Columns[0].Identity {
+		t.Errorf("unexpected columns %+v", orders.Columns)
+	}
+	if c := tables[0].Columns[1]; c.MaxLength != 100 || !c.Nullable {
+		t.Errorf("expected the length of nvarchar in characters, got %+v", c)
+	}
+	if orders.Columns[2].Default != "(sysutcdatetime())" {
+		t.Errorf("unexpected default %q", orders.Columns[2].Default)
+	}
+	if !reflect.DeepEqual(orders.PrimaryKey, []string{"id"}) {
+		t.Errorf("unexpected primary key %v", orders.PrimaryKey)
+	}
+	expected := []schema.Index{
+		{Name: "pk_orders", Type: "CLUSTERED", Unique: true, PrimaryKey: true, Columns: []schema.IndexColumn{{Name: "id"}}},
+		{Name: "ix_orders_customer", Type: "NONCLUSTERED",
+			Columns:  []schema.IndexColumn{{Name: "customer_id"}, {Name: "created", Descending: true}},
+			Included: []string{"notes"}},
+	}
+	if !reflect.DeepEqual(orders.Indexes, expected) {
+		t.Errorf("unexpected indexes %+v", orders.Indexes)
+	}
+	fk := schema.ForeignKey{Name: "fk_orders_customers", Columns: []string{"customer_id"},
+		ReferencedSchema: "dbo", ReferencedTable: "customers", ReferencedColumns: []string{"id"},
+		OnDelete: "CASCADE", OnUpdate: "NO_ACTION"}
+	if len(orders.ForeignKeys) != 1 || !reflect.DeepEqual(orders.ForeignKeys[0], fk) {
+		t.Errorf("unexpected foreign keys %+v", orders.ForeignKeys)
+	}
+	if len(tables[0].ForeignKeys) != 0 {
+		t.Errorf("unexpected foreign keys of customers %+v", tables[0].ForeignKeys)
+	}
+}
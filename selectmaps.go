@@ -0,0 +1,60 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+)
+
+// SelectMaps runs query with q and appends a map per row to dest, with the
+// names of the columns as keys, for queries whose columns are only known at
+// runtime:
+//
+//	var rows []map[string]interface{}
+//	err := mssql.SelectMaps(ctx, db, &rows, "select * from dbo.reports where owner = @p1", owner)
+//
+// The values are the values of the columns as they are scanned into an
+// interface{}, NULL is nil. The driver returns decimal and money values as
+// []byte, they are stored as strings so that the maps encode to JSON as the
+// numbers instead of base64. Of several columns with the same name, the map
+// holds the last one.
+func SelectMaps(ctx context.Context, q Querier, dest *[]map[string]interface{}, query string, args ...interface{}) error {
+	if dest == nil {
+		return errors.New("mssql: SelectMaps needs a non-nil destination")
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	numeric := make([]bool, len(types))
+	for i, typ := range types {
+		switch typ.DatabaseTypeName() {
+		case "DECIMAL", "MONEY", "SMALLMONEY":
+			numeric[i] = true
+		}
+	}
+	values := make([]interface{}, len(types))
+	scan := make([]interface{}, len(types))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(scan...); err != nil {
+			return err
+		}
+		row := make(map[string]interface{}, len(types))
+		for i, typ := range types {
+			v := values[i]
+			if b, ok := v.([]byte); ok && numeric[i] {
+				v = string(b)
+			}
+			row[typ.Name()] = v
+		}
+		*dest = append(*dest, row)
+	}
+	return rows.Err()
+}
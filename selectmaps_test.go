@@ -0,0 +1,36 @@
+package mssql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestSelectMaps(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.Handle("select * from dbo.reports", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "name", "active"},
+		Rows: [][]interface{}{
+			{int64(1), "daily", true},
+			{int64(2), nil, false},
+		},
+	}}})
+	var rows []map[string]interface{}
+	if err := SelectMaps(context.Background(), db, &rows, "select * from dbo.reports"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]interface{}{
+		{"id": int64(1), "name": "daily", "active": true},
+		{"id": int64(2), "name": nil, "active": false},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %v, got %v", expected, rows)
+	}
+	if err := SelectMaps(context.Background(), db, nil, "select * from dbo.reports"); err == nil {
+		t.Error("expected an error for a nil destination")
+	}
+}
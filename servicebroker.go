@@ -0,0 +1,124 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message types sent by Service Broker itself.
+const (
+	EndDialogMessageType = "http://schemas.microsoft.com/SQL/ServiceBroker/EndDialog"
+	ErrorMessageType     = "http://schemas.microsoft.com/SQL/ServiceBroker/Error"
+	DefaultMessageType   = "DEFAULT"
+)
+
+// BrokerMessage is a message received from a Service Broker queue.
+type BrokerMessage struct {
+	Conversation UniqueIdentifier
+	MessageType  string
+	Body         []byte
+}
+
+// BeginDialog starts a conversation from fromService to toService on contract
+// and returns its handle. An empty contract uses the DEFAULT contract.
+//
+// https://learn.microsoft.com/sql/t-sql/statements/begin-dialog-conversation-transact-sql
+func BeginDialog(ctx context.Context, q Querier, fromService, toService, contract string, encryption bool) (UniqueIdentifier, error) {
+	var handle UniqueIdentifier
+	quoter := TSQLQuoter{}
+	b := &strings.Builder{}
+	b.WriteString("declare @handle uniqueidentifier;\n")
+	fmt.Fprintf(b, "begin dialog conversation @handle from service %s to service %s", quoter.ID(fromService), quoter.Value(toService))
+	if len(contract) > 0 {
+		fmt.Fprintf(b, " on contract %s", quoter.ID(contract))
+	}
+	if encryption {
+		b.WriteString(" with encryption = on")
+	} else {
+		b.WriteString(" with encryption = off")
+	}
+	b.WriteString(";\nselect @handle;")
+	rows, err := q.QueryContext(ctx, b.String())
+	if err != nil {
+		return handle, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = fmt.Errorf("mssql: begin dialog returned no conversation handle")
+		}
+		return handle, err
+	}
+	err = rows.Scan(&handle)
+	return handle, err
+}
+
+// Send sends body on the conversation. An empty messageType sends a message
+// of the DEFAULT message type.
+//
+// https://learn.microsoft.com/sql/t-sql/statements/send-transact-sql
+func Send(ctx context.Context, e Execer, conversation UniqueIdentifier, messageType string, body []byte) error {
+	if len(messageType) == 0 {
+		messageType = DefaultMessageType
+	}
+	query := fmt.Sprintf("send on conversation @p1 message type %s (@p2)", TSQLQuoter{}.ID(messageType))
+	_, err := e.ExecContext(ctx, query, conversation, body)
+	return err
+}
+
+// EndConversation ends the local side of the conversation.
+func EndConversation(ctx context.Context, e Execer, conversation UniqueIdentifier) error {
+	_, err := e.ExecContext(ctx, "end conversation @p1", conversation)
+	return err
+}
+
+// Receive waits up to timeout for the next message on queue and removes it from
+// the queue. queue is the name of the queue, or its schema and name such as
+// dbo.orders, with names that contain dots in brackets.
+// It returns nil if no message arrived before the timeout.
+// A negative timeout waits until a message arrives or ctx is done.
+//
+// The connection timeout does not apply, cancelling ctx sends an attention
+// to the server, which stops waiting.
+// To not lose a message when its processing fails, pass a *sql.Tx and
+// commit it after the message was processed.
+//
+// https://learn.microsoft.com/sql/t-sql/statements/receive-transact-sql
+func Receive(ctx context.Context, q Querier, queue string, timeout time.Duration) (*BrokerMessage, error) {
+	query, err := receiveQuery("conversation_handle, message_type_name, message_body", queue, timeout)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, query, NoQueryTimeout{})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	msg := &BrokerMessage{}
+	if err = rows.Scan(&msg.Conversation, &msg.MessageType, &msg.Body); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// receiveQuery returns a waitfor receive query for a single message from
+// queue, the name of the queue or its schema and name, such as dbo.orders.
+func receiveQuery(columns, queue string, timeout time.Duration) (string, error) {
+	schema, name, err := splitSchemaObject(queue)
+	if err != nil {
+		return "", err
+	}
+	if queue, err = QuoteSchemaObject(schema, name); err != nil {
+		return "", err
+	}
+	query := fmt.Sprintf("waitfor (receive top (1) %s from %s)", columns, queue)
+	if timeout >= 0 {
+		query += fmt.Sprintf(", timeout %d", timeout/time.Millisecond)
+	}
+	return query, nil
+}
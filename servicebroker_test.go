@@ -0,0 +1,31 @@
+package mssql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReceiveQuery(t *testing.T) {
+	tests := []struct {
+		queue    string
+		timeout  time.Duration
+		expected string
+	}{
+		{"orders", 5 * time.Second, "waitfor (receive top (1) m from [orders]), timeout 5000"},
+		{"orders", 0, "waitfor (receive top (1) m from [orders]), timeout 0"},
+		{"order]queue", -1, "waitfor (receive top (1) m from [order]]queue])"},
+		{"dbo.orders", -1, "waitfor (receive top (1) m from [dbo].[orders])"},
+		{"[sales.eu].[order.queue]]s]", -1, "waitfor (receive top (1) m from [sales.eu].[order.queue]]s])"},
+	}
+	for _, tt := range tests {
+		got, err := receiveQuery("m", tt.queue, tt.timeout)
+		if err != nil || got != tt.expected {
+			t.Errorf("expected %q, got %q, %v", tt.expected, got, err)
+		}
+	}
+	for _, invalid := range []string{"", "a.b.c", ".orders", "[orders", "[dbo]x.orders"} {
+		if _, err := receiveQuery("m", invalid, 0); err == nil {
+			t.Errorf("expected an error for the queue %q", invalid)
+		}
+	}
+}
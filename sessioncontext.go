@@ -0,0 +1,32 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/microsoft/go-mssqldb/internal/sqlexec"
+)
+
+// Execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer = sqlexec.Execer
+
+// SetSessionContext sets key to value in the SESSION_CONTEXT of the session
+// with sp_set_session_context, e.g. to drive Row-Level Security predicates
+// with the identity of the application user.
+//
+// The session context belongs to a single connection, so use a *sql.Conn
+// or a *sql.Tx, not a *sql.DB. A key set with readOnly cannot be changed
+// for the rest of the session; the connection pool resets the session
+// context when the connection is reused.
+//
+//	conn, err := db.Conn(ctx)
+//	...
+//	err = mssql.SetSessionContext(ctx, conn, "tenant_id", tenantID, true)
+func SetSessionContext(ctx context.Context, e Execer, key string, value interface{}, readOnly bool) error {
+	_, err := e.ExecContext(ctx, "sp_set_session_context",
+		sql.Named("key", key),
+		sql.Named("value", value),
+		sql.Named("read_only", readOnly),
+	)
+	return err
+}
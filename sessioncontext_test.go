@@ -0,0 +1,45 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+type recordingExecer struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (r *recordingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	r.args = append(r.args, args)
+	return driverResultStub{}, nil
+}
+
+type driverResultStub struct{}
+
+func (driverResultStub) LastInsertId() (int64, error) { return 0, nil }
+func (driverResultStub) RowsAffected() (int64, error) { return 0, nil }
+
+func TestSetSessionContext(t *testing.T) {
+	e := &recordingExecer{}
+	if err := SetSessionContext(context.Background(), e, "tenant_id", int64(42), true); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.queries) != 1 || e.queries[0] != "sp_set_session_context" {
+		t.Fatalf("unexpected queries %v", e.queries)
+	}
+	want := []interface{}{
+		sql.Named("key", "tenant_id"),
+		sql.Named("value", int64(42)),
+		sql.Named("read_only", true),
+	}
+	if !reflect.DeepEqual(e.args[0], want) {
+		t.Errorf("unexpected arguments %v", e.args[0])
+	}
+	if !isProc(e.queries[0]) {
+		t.Error("sp_set_session_context should be sent as an RPC call")
+	}
+}
@@ -0,0 +1,116 @@
+package mssql
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Statistics are counters of the TDS traffic and the requests of a connection,
+// or of all connections of a Connector. They are similar to the statistics of
+// SqlConnection in ADO.NET.
+type Statistics struct {
+	BytesSent       int64
+	BytesReceived   int64
+	PacketsSent     int64
+	PacketsReceived int64
+	// ServerRoundtrips is the number of requests sent to the server,
+	// including the login and attention requests.
+	ServerRoundtrips int64
+	// ExecutionTime is the time from sending a request until the last
+	// packet of its response was received, summed over all requests.
+	ExecutionTime time.Duration
+	// Connections is the number of connections opened by a Connector,
+	// it is 0 in the statistics of a connection.
+	Connections int64
+}
+
+// statsCounters counts the traffic of a tdsBuffer, the counters are updated
+// atomically because a connection and its Connector may be read while in use.
+type statsCounters struct {
+	bytesSent       int64
+	bytesReceived   int64
+	packetsSent     int64
+	packetsReceived int64
+	roundtrips      int64
+	executionTime   int64
+	connections     int64
+	// requestStart is the UnixNano time the last request was sent
+	requestStart int64
+	// parent are the counters of the Connector of the connection
+	parent *statsCounters
+}
+
+func (s *statsCounters) sent(bytes int, final bool) {
+	for ; s != nil; s = s.parent {
+		atomic.AddInt64(&s.bytesSent, int64(bytes))
+		atomic.AddInt64(&s.packetsSent, 1)
+		if final {
+			atomic.AddInt64(&s.roundtrips, 1)
+		}
+	}
+}
+
+func (s *statsCounters) requestSent(now time.Time) {
+	if s != nil {
+		atomic.StoreInt64(&s.requestStart, now.UnixNano())
+	}
+}
+
+func (s *statsCounters) received(bytes int, final bool, now time.Time) {
+	var elapsed int64
+	if final && s != nil {
+		if start := atomic.SwapInt64(&s.requestStart, 0); start != 0 {
+			elapsed = now.UnixNano() - start
+		}
+	}
+	for ; s != nil; s = s.parent {
+		atomic.AddInt64(&s.bytesReceived, int64(bytes))
+		atomic.AddInt64(&s.packetsReceived, 1)
+		atomic.AddInt64(&s.executionTime, elapsed)
+	}
+}
+
+func (s *statsCounters) snapshot() Statistics {
+	return Statistics{
+		BytesSent:        atomic.LoadInt64(&s.bytesSent),
+		BytesReceived:    atomic.LoadInt64(&s.bytesReceived),
+		PacketsSent:      atomic.LoadInt64(&s.packetsSent),
+		PacketsReceived:  atomic.LoadInt64(&s.packetsReceived),
+		ServerRoundtrips: atomic.LoadInt64(&s.roundtrips),
+		ExecutionTime:    time.Duration(atomic.LoadInt64(&s.executionTime)),
+		Connections:      atomic.LoadInt64(&s.connections),
+	}
+}
+
+func (s *statsCounters) reset() {
+	atomic.StoreInt64(&s.bytesSent, 0)
+	atomic.StoreInt64(&s.bytesReceived, 0)
+	atomic.StoreInt64(&s.packetsSent, 0)
+	atomic.StoreInt64(&s.packetsReceived, 0)
+	atomic.StoreInt64(&s.roundtrips, 0)
+	atomic.StoreInt64(&s.executionTime, 0)
+	atomic.StoreInt64(&s.connections, 0)
+}
+
+// Statistics returns the counters of the connection since it was opened
+// or since the last ResetStatistics. Use it through sql.Conn.Raw.
+func (c *Conn) Statistics() Statistics {
+	return c.sess.buf.stats.snapshot()
+}
+
+// ResetStatistics sets the counters of the connection to zero.
+// The counters of its Connector are not changed.
+func (c *Conn) ResetStatistics() {
+	c.sess.buf.stats.reset()
+}
+
+// Statistics returns the counters of all connections opened by the Connector,
+// including connections that are already closed.
+func (c *Connector) Statistics() Statistics {
+	return c.stats.snapshot()
+}
+
+// ResetStatistics sets the counters of the Connector to zero.
+func (c *Connector) ResetStatistics() {
+	c.stats.reset()
+}
@@ -0,0 +1,68 @@
+package mssql
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestStatisticsCounters(t *testing.T) {
+	connector := &Connector{}
+	// a response of two packets, the second one is the last
+	response := []byte{
+		byte(packReply), 0, 0, 10, 0, 0, 1, 0, 1, 2,
+		byte(packReply), 1, 0, 9, 0, 0, 2, 0, 3,
+	}
+	transport := closableBuffer{bytes.NewBuffer(response)}
+	buf := newTdsBuffer(16, &transport)
+	buf.stats = &statsCounters{parent: &connector.stats}
+	c := &Conn{sess: &tdsSession{buf: buf}}
+
+	// a request of two packets
+	buf.BeginPacket(packSQLBatch, false)
+	if _, err := buf.Write(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.FinishPacket(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := buf.BeginRead(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.ReadByte(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.ReadByte(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.ReadByte(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.Statistics()
+	if stats.BytesSent != 26 || stats.PacketsSent != 2 || stats.ServerRoundtrips != 1 {
+		t.Errorf("unexpected sent statistics %+v", stats)
+	}
+	if stats.BytesReceived != 19 || stats.PacketsReceived != 2 {
+		t.Errorf("unexpected received statistics %+v", stats)
+	}
+	if stats.ExecutionTime < time.Millisecond {
+		t.Errorf("expected an execution time of at least 1ms, got %v", stats.ExecutionTime)
+	}
+	if connector.Statistics() != stats {
+		t.Errorf("expected the Connector statistics %+v to equal the connection statistics %+v", connector.Statistics(), stats)
+	}
+
+	c.ResetStatistics()
+	if c.Statistics() != (Statistics{}) {
+		t.Errorf("expected the statistics to be reset, got %+v", c.Statistics())
+	}
+	if connector.Statistics().BytesSent != 26 {
+		t.Error("expected the Connector statistics to be kept")
+	}
+	connector.ResetStatistics()
+	if connector.Statistics() != (Statistics{}) {
+		t.Errorf("expected the Connector statistics to be reset, got %+v", connector.Statistics())
+	}
+}
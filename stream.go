@@ -0,0 +1,118 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// defaultReadAhead is the number of tokens the driver decodes ahead of the reader.
+const defaultReadAhead = 5
+
+// ReadAhead may be passed as an argument to Query to set the number of
+// decoded tokens, mostly rows, that the driver reads from the connection
+// ahead of Rows.Next, instead of 5. The driver reads a response one TDS
+// packet at a time and decodes its rows one by one. While the read-ahead is
+// full it stops reading from the connection, so the server is throttled by
+// the network and the memory for a result set stays flat however many rows
+// it has. A larger read-ahead evens out a reader that processes rows in bursts.
+//
+//	rows, err := db.QueryContext(ctx, "select * from dbo.events", mssql.ReadAhead(100))
+type ReadAhead int
+
+// RowStream yields the rows of the first result set of a query on a channel,
+// as the driver decodes them:
+//
+//	s, err := mssql.Stream(ctx, db, "select id, payload from dbo.events", mssql.ReadAhead(100))
+//	if err != nil {
+//		...
+//	}
+//	defer s.Close()
+//	for row := range s.Rows() {
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// The channel is unbuffered, a row is scanned from the connection only when
+// the previous one was received, so a slow receiver throttles the query.
+type RowStream struct {
+	columns []string
+	rows    chan []interface{}
+	stop    chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// Stream runs query with q and returns the stream of its rows. The
+// connection of the query is held until the stream ends or is closed.
+func Stream(ctx context.Context, q Querier, query string, args ...interface{}) (*RowStream, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	s := &RowStream{
+		columns: cols,
+		rows:    make(chan []interface{}),
+		stop:    make(chan struct{}),
+	}
+	go s.read(rows)
+	return s, nil
+}
+
+func (s *RowStream) read(rows *sql.Rows) {
+	defer close(s.rows)
+	defer rows.Close()
+	for rows.Next() {
+		values := make([]interface{}, len(s.columns))
+		dest := make([]interface{}, len(s.columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			s.err = err
+			return
+		}
+		select {
+		case s.rows <- values:
+		case <-s.stop:
+			return
+		}
+	}
+	s.err = rows.Err()
+}
+
+// Columns returns the names of the columns of the rows.
+func (s *RowStream) Columns() []string {
+	return s.columns
+}
+
+// Rows returns the channel of the rows, it is closed when the result set
+// ends, the query fails or the stream is closed. The values of a row are
+// the values of its columns as they are scanned into an interface{}.
+func (s *RowStream) Rows() <-chan []interface{} {
+	return s.rows
+}
+
+// Err returns the error that ended the stream. It is only valid after the
+// channel of Rows was closed.
+func (s *RowStream) Err() error {
+	return s.err
+}
+
+// Close stops the stream and cancels the rest of the query, if it still runs.
+// It waits until the connection of the query was released.
+func (s *RowStream) Close() error {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	for range s.rows {
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package mssql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestStream(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	var rows [][]interface{}
+	for i := 1; i <= 1000; i++ {
+		rows = append(rows, []interface{}{i, strings.Repeat("x", 100)})
+	}
+	srv.Handle("select id, payload from dbo.events", mssqltest.Response{Results: []mssqltest.Result{{
+		Columns: []string{"id", "payload"},
+		Rows:    rows,
+	}}})
+
+	ctx := context.Background()
+	s, err := Stream(ctx, db, "select id, payload from dbo.events", ReadAhead(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !reflect.DeepEqual(s.Columns(), []string{"id", "payload"}) {
+		t.Errorf("unexpected columns %v", s.Columns())
+	}
+	n := int64(0)
+	for row := range s.Rows() {
+		n++
+		if row[0] != n {
+			t.Fatalf("row %d has id %v", n, row[0])
+		}
+	}
+	if err = s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1000 {
+		t.Errorf("expected 1000 rows, got %d", n)
+	}
+
+	// closing a stream early releases the connection
+	s, err = Stream(ctx, db, "select id, payload from dbo.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-s.Rows()
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var one int
+	if err = db.QueryRowContext(ctx, "select 1").Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadAheadInvalid(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=test;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	_, err := db.QueryContext(context.Background(), "select 1", ReadAhead(0))
+	if err == nil || !strings.Contains(err.Error(), "invalid ReadAhead 0") {
+		t.Errorf("expected an invalid ReadAhead error, got %v", err)
+	}
+}
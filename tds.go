@@ -9,25 +9,43 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"os"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unicode/utf16"
 	"unicode/utf8"
 
 	"github.com/microsoft/go-mssqldb/aecmk"
 	"github.com/microsoft/go-mssqldb/integratedauth"
+	"github.com/microsoft/go-mssqldb/internal/cp"
 	"github.com/microsoft/go-mssqldb/msdsn"
 )
 
 func parseDAC(msg []byte, instance string) msdsn.BrowserData {
 	results := msdsn.BrowserData{}
+	// SVR_RESP_DAC: 0x05, size, protocol version and the DAC port
 	if len(msg) == 6 && msg[0] == 5 {
-		results[strings.ToUpper(instance)]["tcp"] = fmt.Sprint(binary.LittleEndian.Uint16(msg[5:]))
+		name := strings.ToUpper(instance)
+		results[name] = map[string]string{
+			"InstanceName": name,
+			"tcp":          fmt.Sprint(binary.LittleEndian.Uint16(msg[4:])),
+		}
 	}
 	return results
 }
 
+// dacRequest returns the CLNT_UCAST_DAC message asking the SQL Server Browser
+// for the port of the dedicated administrator connection of instance.
+func dacRequest(instance string) []byte {
+	bmsg := make([]byte, 3+len(instance))
+	bmsg[0] = byte(msdsn.BrowserDAC)
+	bmsg[1] = 1 // protocol version
+	copy(bmsg[2:], instance)
+	return bmsg
+}
+
 func parseInstances(msg []byte) msdsn.BrowserData {
 	results := msdsn.BrowserData{}
 	if len(msg) > 3 && msg[0] == 5 {
@@ -62,10 +80,7 @@ func getInstances(ctx context.Context, d Dialer, address string, browserMsg msds
 	var bmsg []byte
 	var resp []byte
 	if browserMsg == msdsn.BrowserDAC {
-		bmsg = make([]byte, 3+len(instance))
-		bmsg[0] = byte(msdsn.BrowserDAC)
-		bmsg[1] = 1
-		_ = copy(bmsg[3:], instance)
+		bmsg = dacRequest(instance)
 		resp = make([]byte, 6)
 	} else { // default to AllInstances
 		bmsg = []byte{byte(msdsn.BrowserAllInstances)}
@@ -161,8 +176,11 @@ const (
 
 type tdsSession struct {
 	buf             *tdsBuffer
+	conn            *timeoutConn
 	loginAck        loginAckStruct
 	database        string
+	language        string
+	collation       cp.Collation
 	partner         string
 	columns         []columnStruct
 	tranid          uint64
@@ -171,7 +189,14 @@ type tdsSession struct {
 	routedServer    string
 	routedPort      uint16
 	alwaysEncrypted bool
+	utf8Support     bool
 	aeSettings      *alwaysEncryptedSettings
+	messageHandler  func(ctx context.Context, msg Error)
+	databaseChanged func(ctx context.Context, oldDatabase, newDatabase string)
+	// connector receives the events of the session, it may be nil
+	connector *Connector
+	// server is the server of the session for events
+	server string
 }
 
 type alwaysEncryptedSettings struct {
@@ -193,6 +218,8 @@ type columnStruct struct {
 	ColName    string
 	ti         typeInfo
 	cryptoMeta *cryptoMetadata
+	// skip is set for a column that is not in the DecodeColumns of the query
+	skip bool
 }
 
 func (c columnStruct) isEncrypted() bool {
@@ -444,9 +471,16 @@ func (e featureExts) toBytes() []byte {
 	if len(e.features) == 0 {
 		return nil
 	}
+	// sort the features so that the login is the same for every connection
+	ids := make([]int, 0, len(e.features))
+	for featureID := range e.features {
+		ids = append(ids, int(featureID))
+	}
+	sort.Ints(ids)
 	var d []byte
-	for featureID, f := range e.features {
-		featureData := f.toBytes()
+	for _, id := range ids {
+		featureID := byte(id)
+		featureData := e.features[featureID].toBytes()
 
 		hdr := make([]byte, 5)
 		hdr[0] = featureID                                               // FedAuth feature extension BYTE
@@ -577,6 +611,39 @@ func str2ucs2(s string) []byte {
 	return ucs2
 }
 
+// utf16le2str decodes little endian UTF-16 into a string with a single
+// allocation. Unpaired surrogates are replaced by U+FFFD, like utf16.Decode does.
+func utf16le2str(s []byte) string {
+	n := 0
+	for i := 0; i+1 < len(s); {
+		r, size := utf16leRune(s[i:])
+		n += utf8.RuneLen(r)
+		i += size
+	}
+	var b strings.Builder
+	b.Grow(n)
+	for i := 0; i+1 < len(s); {
+		r, size := utf16leRune(s[i:])
+		b.WriteRune(r)
+		i += size
+	}
+	return b.String()
+}
+
+// utf16leRune decodes the first rune of s and returns it with its size in bytes.
+func utf16leRune(s []byte) (rune, int) {
+	r := rune(binary.LittleEndian.Uint16(s))
+	if !utf16.IsSurrogate(r) {
+		return r, 2
+	}
+	if len(s) >= 4 {
+		if dec := utf16.DecodeRune(r, rune(binary.LittleEndian.Uint16(s[2:]))); dec != utf8.RuneError {
+			return dec, 4
+		}
+	}
+	return utf8.RuneError, 2
+}
+
 const (
 	mask64 uint64 = 0xFF80FF80FF80FF80
 	mask32 uint32 = 0xFF80FF80
@@ -1083,8 +1150,14 @@ func prepareLogin(ctx context.Context, c *Connector, p msdsn.Config, logger Cont
 		TypeFlags:      typeFlags,
 		CtlIntName:     "go-mssqldb",
 		ClientProgVer:  getDriverVersion(driverVersion),
+		ClientPID:      uint32(os.Getpid()),
 		ChangePassword: p.ChangePassword,
+		AtchDBFile:     p.AttachDBFilename,
+	}
+	if p.UserInstance {
+		l.OptionFlags3 |= fUserInstance
 	}
+	_ = l.FeatureExt.Add(&featureExtUTF8Support{})
 	if p.ColumnEncryption {
 		_ = l.FeatureExt.Add(&featureExtColumnEncryption{})
 	}
@@ -1133,7 +1206,7 @@ func prepareLogin(ctx context.Context, c *Connector, p msdsn.Config, logger Cont
 	return l, nil
 }
 
-func getTLSConn(conn *timeoutConn, p msdsn.Config, alpnSeq string) (tlsConn *tls.Conn, err error) {
+func getTLSConn(c *Connector, conn *timeoutConn, p msdsn.Config, alpnSeq string) (tlsConn *tls.Conn, err error) {
 	var config *tls.Config
 	if pc := p.TLSConfig; pc != nil {
 		config = pc
@@ -1144,6 +1217,7 @@ func getTLSConn(conn *timeoutConn, p msdsn.Config, alpnSeq string) (tlsConn *tls
 			return nil, err
 		}
 	}
+	config = c.withClientCertificate(config)
 	//Set ALPN Sequence
 	config.NextProtos = []string{alpnSeq}
 	tlsConn = tls.Client(conn.c, config)
@@ -1154,7 +1228,9 @@ func getTLSConn(conn *timeoutConn, p msdsn.Config, alpnSeq string) (tlsConn *tls
 	return tlsConn, nil
 }
 
-func connect(ctx context.Context, c *Connector, logger ContextLogger, p msdsn.Config) (res *tdsSession, err error) {
+// dialAndLogin connects to the server of p and logs in, following the
+// routing of the server to another server.
+func dialAndLogin(ctx context.Context, c *Connector, logger ContextLogger, p msdsn.Config) (res *tdsSession, err error) {
 	isTransportEncrypted := false
 	// if instance is specified use instance resolution service
 	if len(p.Instance) > 0 && p.Port != 0 && uint64(p.LogFlags)&logDebug != 0 {
@@ -1163,6 +1239,9 @@ func connect(ctx context.Context, c *Connector, logger ContextLogger, p msdsn.Co
 		// you should not provide instance name when you provide port
 		logger.Log(ctx, msdsn.LogDebug, "WARN: You specified both instance name and port in the connection string, port will be used and instance name will be ignored")
 	}
+	if len(p.Instance) > 0 && p.Port != 0 {
+		c.logEvent(ctx, Event{Type: EventWarning, Server: serverName(p), Message: "both instance name and port are specified, the instance name is ignored"})
+	}
 
 	packetSize := p.PacketSize
 	if packetSize == 0 {
@@ -1189,26 +1268,43 @@ initiate_connection:
 		dialCtx, cancel = context.WithTimeout(ctx, dt)
 		defer cancel()
 	}
+	if c != nil && c.FaultInjector != nil && c.FaultInjector.Dial != nil {
+		if err = c.FaultInjector.Dial(dialCtx, serverName(p)); err != nil {
+			return nil, err
+		}
+	}
 	conn, err := dialConnection(dialCtx, c, &p, logger)
 	if err != nil {
 		return nil, err
 	}
+	if c != nil && c.FaultInjector != nil {
+		conn = faultConn{Conn: conn, faults: c.FaultInjector}
+	}
 
 	toconn := newTimeoutConn(conn, p.ConnTimeout)
 	outbuf := newTdsBuffer(packetSize, toconn)
+	outbuf.stats = &statsCounters{parent: &c.stats}
+	atomic.AddInt64(&c.stats.connections, 1)
+	outbuf.trace = newTracer(c.TraceWriter, c.TraceLevel, atomic.AddInt64(&tracedConnections, 1),
+		p.ParamRedaction != "" && p.ParamRedaction != msdsn.ParamRedactionNone)
 
 	if p.Encryption == msdsn.EncryptionStrict {
-		outbuf.transport, err = getTLSConn(toconn, p, "tds/8.0")
+		outbuf.transport, err = getTLSConn(c, toconn, p, "tds/8.0")
 		if err != nil {
 			return nil, err
 		}
 		isTransportEncrypted = true
 	}
 	sess := tdsSession{
-		buf:        outbuf,
-		logger:     logger,
-		logFlags:   uint64(p.LogFlags),
-		aeSettings: &alwaysEncryptedSettings{keyProviders: aecmk.GetGlobalCekProviders()},
+		buf:             outbuf,
+		conn:            toconn,
+		logger:          logger,
+		logFlags:        uint64(p.LogFlags),
+		aeSettings:      &alwaysEncryptedSettings{keyProviders: aecmk.GetGlobalCekProviders()},
+		messageHandler:  c.MessageHandler,
+		databaseChanged: c.DatabaseChanged,
+		connector:       c,
+		server:          serverName(p),
 	}
 
 	for i, p := range c.keyProviders {
@@ -1262,6 +1358,7 @@ initiate_connection:
 				}
 
 			}
+			config = c.withClientCertificate(config)
 
 			// setting up connection handler which will allow wrapping of TLS handshake packets inside TDS stream
 			handshakeConn := tlsHandshakeConn{buf: outbuf}
@@ -1369,6 +1466,8 @@ initiate_connection:
 			case featureExtAck:
 				for _, v := range token {
 					switch v := v.(type) {
+					case utf8SupportAck:
+						sess.utf8Support = bool(v)
 					case colAckStruct:
 						if v.Version <= 2 && v.Version > 0 {
 							sess.alwaysEncrypted = true
@@ -1382,7 +1481,7 @@ initiate_connection:
 				if token.isError() {
 					tokenErr := token.getError()
 					tokenErr.Message = "login error: " + tokenErr.Message
-					return nil, tokenErr
+					return nil, passwordChangeHint(tokenErr, p)
 				}
 			case error:
 				return nil, fmt.Errorf("login error: %s", token.Error())
@@ -1391,18 +1490,10 @@ initiate_connection:
 	}
 
 	if sess.routedServer != "" {
+		c.logEvent(ctx, Event{Type: EventRedirect, Server: serverName(p), Message: fmt.Sprintf("routed to %s:%d", sess.routedServer, sess.routedPort)})
 		toconn.Close()
-		// Need to handle case when routedServer is in "host\instance" format.
-		routedParts := strings.SplitN(sess.routedServer, "\\", 2)
-		p.Host = routedParts[0]
-		if len(routedParts) == 2 {
-			p.Instance = routedParts[1]
-		}
-		p.Port = uint64(sess.routedPort)
-		if !p.HostInCertificateProvided && p.TLSConfig != nil {
-			p.TLSConfig = p.TLSConfig.Clone()
-			p.TLSConfig.ServerName = p.Host
-		}
+		c.cacheRoute(p, sess.routedServer, sess.routedPort)
+		p = routedConfig(p, sess.routedServer, sess.routedPort)
 		goto initiate_connection
 	}
 	return &sess, nil
@@ -1425,3 +1516,29 @@ func (f *featureExtColumnEncryption) toBytes() []byte {
 	*/
 	return []byte{0x01}
 }
+
+// featureExtUTF8Support tells the server that the client can read varchar
+// values of UTF-8 collations, so it sends them as UTF-8 instead of
+// converting them to the code page of the collation.
+type featureExtUTF8Support struct {
+}
+
+func (f *featureExtUTF8Support) featureID() byte {
+	return featExtUTF8SUPPORT
+}
+
+func (f *featureExtUTF8Support) toBytes() []byte {
+	// the feature has no data
+	return nil
+}
+
+// withClientCertificate returns config with the ClientCertificate of the
+// connector. config is cloned, it is shared by the connections.
+func (c *Connector) withClientCertificate(config *tls.Config) *tls.Config {
+	if c == nil || c.ClientCertificate == nil {
+		return config
+	}
+	config = config.Clone()
+	config.Certificates = []tls.Certificate{*c.ClientCertificate}
+	return config
+}
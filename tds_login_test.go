@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"regexp"
 	"sync/atomic"
 	"testing"
@@ -140,16 +141,17 @@ func TestLoginWithSQLServerAuth(t *testing.T) {
 	defer tl.StopLogging()
 	SetLogger(&tl)
 	v := versionToHexString(getDriverVersion(driverVersion))
+	pid := versionToHexString(uint32(os.Getpid()))
 	mock := NewMockTransportDialer(
 		[]string{
 			fmt.Sprintf("12 01 00 2f 00 00 01 00  00 00 1a 00 06 01 00 20\n"+
 				"00 01 02 00 21 00 01 03  00 22 00 04 04 00 26 00\n"+
 				"01 ff %s             00 00  00 00 00 00 00 00 00\n", v),
-			fmt.Sprintf("10 01 00 c6 00 00 01 00  be 00 00 00 04 00 00 74\n"+
-				"00 10 00 00 %s           00 00 00 00 00 00 00 00\n"+
-				"A0 02 00 00 00 00 00 00  00 00 00 00 5e 00 09 00\n"+
+			fmt.Sprintf("10 01 00 d0 00 00 01 00  c8 00 00 00 04 00 00 74\n"+
+				"00 10 00 00 %s           %s 00 00 00 00\n"+
+				"a0 02 00 10 00 00 00 00  00 00 00 00 5e 00 09 00\n"+
 				"70 00 04 00 78 00 06 00  84 00 0a 00 98 00 09 00\n"+
-				"00 00 00 00 aa 00 0a 00  be 00 00 00 be 00 00 00\n"+
+				"be 00 04 00 aa 00 0a 00  be 00 00 00 be 00 00 00\n"+
 				"00 00 00 00 00 00 be 00  00 00 be 00 00 00 be 00\n"+
 				"00 00 00 00 00 00 6c 00  6f 00 63 00 61 00 6c 00\n"+
 				"68 00 6f 00 73 00 74 00  74 00 65 00 73 00 74 00\n"+
@@ -157,7 +159,7 @@ func TestLoginWithSQLServerAuth(t *testing.T) {
 				"2d 00 6d 00 73 00 73 00  71 00 6c 00 64 00 62 00\n"+
 				"6c 00 6f 00 63 00 61 00  6c 00 68 00 6f 00 73 00\n"+
 				"74 00 67 00 6f 00 2d 00  6d 00 73 00 73 00 71 00\n"+
-				"6c 00 64 00 62 00\n", v),
+				"6c 00 64 00 62 00 c2 00  00 00 0a 00 00 00 00 ff\n", v, pid),
 		},
 		[]string{
 			"  04 01 00 20  00 00 01 00   00 00 10 00  06 01 00 16\n" +
@@ -201,25 +203,27 @@ func TestLoginWithSecurityTokenAuth(t *testing.T) {
 	defer tl.StopLogging()
 	SetLogger(&tl)
 	v := versionToHexString(getDriverVersion(driverVersion))
+	pid := versionToHexString(uint32(os.Getpid()))
 	mock := NewMockTransportDialer(
 		[]string{
 			fmt.Sprintf("12 01 00 35 00 00 01 00  00 00 1F 00 06 01 00 25\n"+
 				"00 01 02 00 26 00 01 03  00 27 00 04 04 00 2B 00\n"+
 				"01 06 00 2c 00 01 ff %s           00 00 00 00 00\n"+
 				"00 00 00 00 01\n", v),
-			fmt.Sprintf("10 01 00 CF 00 00 01 00  C7 00 00 00 04 00 00 74\n"+
-				"00 10 00 00 %s           00 00 00 00 00 00 00 00\n"+
-				"A0 02 00 10 00 00 00 00  00 00 00 00 5E 00 09 00\n"+
-				"70 00 00 00 70 00 00 00  70 00 0A 00 84 00 09 00\n"+
-				"AA 00 04 00 96 00 0A 00  AA 00 00 00 AA 00 00 00\n"+
-				"00 00 00 00 00 00 AA 00  00 00 AA 00 00 00 AA 00\n"+
-				"00 00 00 00 00 00 6C 00  6F 00 63 00 61 00 6C 00\n"+
-				"68 00 6F 00 73 00 74 00  67 00 6F 00 2D 00 6D 00\n"+
-				"73 00 73 00 71 00 6C 00  64 00 62 00 6C 00 6F 00\n"+
-				"63 00 61 00 6C 00 68 00  6F 00 73 00 74 00 67 00\n"+
-				"6F 00 2D 00 6D 00 73 00  73 00 71 00 6C 00 64 00\n"+
-				"62 00 AE 00 00 00 02 13  00 00 00 03 0E 00 00 00\n"+
-				"3C 00 74 00 6F 00 6B 00  65 00 6E 00 3E 00 FF\n", v),
+			fmt.Sprintf("10 01 00 d4 00 00 01 00  cc 00 00 00 04 00 00 74\n"+
+				"00 10 00 00 %s           %s 00 00 00 00\n"+
+				"a0 02 00 10 00 00 00 00  00 00 00 00 5e 00 09 00\n"+
+				"70 00 00 00 70 00 00 00  70 00 0a 00 84 00 09 00\n"+
+				"aa 00 04 00 96 00 0a 00  aa 00 00 00 aa 00 00 00\n"+
+				"00 00 00 00 00 00 aa 00  00 00 aa 00 00 00 aa 00\n"+
+				"00 00 00 00 00 00 6c 00  6f 00 63 00 61 00 6c 00\n"+
+				"68 00 6f 00 73 00 74 00  67 00 6f 00 2d 00 6d 00\n"+
+				"73 00 73 00 71 00 6c 00  64 00 62 00 6c 00 6f 00\n"+
+				"63 00 61 00 6c 00 68 00  6f 00 73 00 74 00 67 00\n"+
+				"6f 00 2d 00 6d 00 73 00  73 00 71 00 6c 00 64 00\n"+
+				"62 00 ae 00 00 00 02 13  00 00 00 03 0e 00 00 00\n"+
+				"3c 00 74 00 6f 00 6b 00  65 00 6e 00 3e 00 0a 00\n"+
+				"00 00 00 ff\n", v, pid),
 		},
 		[]string{
 			"  04 01 00 20  00 00 01 00   00 00 10 00  06 01 00 16\n" +
@@ -265,24 +269,26 @@ func TestLoginWithADALUsernamePasswordAuth(t *testing.T) {
 	defer tl.StopLogging()
 	SetLogger(&tl)
 	v := versionToHexString(getDriverVersion(driverVersion))
+	pid := versionToHexString(uint32(os.Getpid()))
 	mock := NewMockTransportDialer(
 		[]string{
 			fmt.Sprintf("12 01 00 35 00 00 01 00  00 00 1F 00 06 01 00 25\n"+
 				"00 01 02 00 26 00 01 03  00 27 00 04 04 00 2B 00\n"+
 				"01 06 00 2C 00 01 ff %s  00 00 00 00 00\n"+
 				"00 00 00 00 01\n", v),
-			fmt.Sprintf("10 01 00 BE 00 00 01 00  b6 00 00 00 04 00 00 74\n"+
-				"00 10 00 00 %s           00 00 00 00 00 00 00 00\n"+
-				"A0 02 00 10 00 00 00 00  00 00 00 00 5e 00 09 00\n"+
+			fmt.Sprintf("10 01 00 c3 00 00 01 00  bb 00 00 00 04 00 00 74\n"+
+				"00 10 00 00 %s           %s 00 00 00 00\n"+
+				"a0 02 00 10 00 00 00 00  00 00 00 00 5e 00 09 00\n"+
 				"70 00 00 00 70 00 00 00  70 00 0a 00 84 00 09 00\n"+
-				"AA 00 04 00 96 00 0A 00  AA 00 00 00 AA 00 00 00\n"+
-				"00 00 00 00 00 00 AA 00  00 00 AA 00 00 00 AA 00\n"+
+				"aa 00 04 00 96 00 0a 00  aa 00 00 00 aa 00 00 00\n"+
+				"00 00 00 00 00 00 aa 00  00 00 aa 00 00 00 aa 00\n"+
 				"00 00 00 00 00 00 6c 00  6f 00 63 00 61 00 6c 00\n"+
 				"68 00 6f 00 73 00 74 00  67 00 6f 00 2d 00 6d 00\n"+
 				"73 00 73 00 71 00 6c 00  64 00 62 00 6c 00 6f 00\n"+
 				"63 00 61 00 6c 00 68 00  6f 00 73 00 74 00 67 00\n"+
 				"6f 00 2d 00 6d 00 73 00  73 00 71 00 6c 00 64 00\n"+
-				"62 00 AE 00 00 00 02 02  00 00  00 05 01 ff\n", v),
+				"62 00 ae 00 00 00 02 02  00 00 00 05 01 0a 00 00\n"+
+				"00 00 ff\n", v, pid),
 			"  08 01 00 1e 00 00 01 00  12 00 00 00 0e 00 00 00\n" +
 				"3c 00 74 00 6f 00 6b 00  65 00 6e 00 3e 00\n",
 		},
@@ -341,24 +347,26 @@ func TestLoginWithADALManagedIdentityAuth(t *testing.T) {
 	SetLogger(&tl)
 
 	v := versionToHexString(getDriverVersion(driverVersion))
+	pid := versionToHexString(uint32(os.Getpid()))
 	mock := NewMockTransportDialer(
 		[]string{
 			fmt.Sprintf("12 01 00 35 00 00 01 00  00 00 1F 00 06 01 00 25\n"+
 				"00 01 02 00 26 00 01 03  00 27 00 04 04 00 2B 00\n"+
 				"01 06 00 2C 00 01 ff %s           00 00 00 00 00\n"+
 				"00 00 00 00 01\n", v),
-			fmt.Sprintf("10 01 00 be 00 00 01 00  b6 00 00 00 04 00 00 74\n"+
-				"00 10 00 00 %s           00 00 00 00 00 00 00 00\n"+
-				"A0 02 00 10 00 00 00 00  00 00 00 00 5e 00 09 00\n"+
+			fmt.Sprintf("10 01 00 c3 00 00 01 00  bb 00 00 00 04 00 00 74\n"+
+				"00 10 00 00 %s           %s 00 00 00 00\n"+
+				"a0 02 00 10 00 00 00 00  00 00 00 00 5e 00 09 00\n"+
 				"70 00 00 00 70 00 00 00  70 00 0a 00 84 00 09 00\n"+
-				"AA 00 04 00 96 00 0A 00  AA 00 00 00 AA 00 00 00\n"+
-				"00 00 00 00 00 00 AA 00  00 00 AA 00 00 00 AA 00\n"+
+				"aa 00 04 00 96 00 0a 00  aa 00 00 00 aa 00 00 00\n"+
+				"00 00 00 00 00 00 aa 00  00 00 aa 00 00 00 aa 00\n"+
 				"00 00 00 00 00 00 6c 00  6f 00 63 00 61 00 6c 00\n"+
 				"68 00 6f 00 73 00 74 00  67 00 6f 00 2d 00 6d 00\n"+
 				"73 00 73 00 71 00 6c 00  64 00 62 00 6c 00 6f 00\n"+
 				"63 00 61 00 6c 00 68 00  6f 00 73 00 74 00 67 00\n"+
 				"6f 00 2d 00 6d 00 73 00  73 00 71 00 6c 00 64 00\n"+
-				"62 00 AE 00 00 00 02 02  00 00 00 05 03 ff\n", v),
+				"62 00 ae 00 00 00 02 02  00 00 00 05 03 0a 00 00\n"+
+				"00 00 ff\n", v, pid),
 			"  08 01 00 1e 00 00 01 00  12 00 00 00 0e 00 00 00\n" +
 				"3c 00 74 00 6f 00 6b 00  65 00 6e 00 3e 00\n",
 		},
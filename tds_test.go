@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"encoding/binary"
 	"encoding/hex"
@@ -36,7 +37,7 @@ func TestConstantsDefined(t *testing.T) {
 	// These constants are part of the spec but not yet used.
 	for _, b := range []byte{
 		featExtSESSIONRECOVERY, featExtGLOBALTRANSACTIONS,
-		featExtAZURESQLSUPPORT, featExtDATACLASSIFICATION, featExtUTF8SUPPORT,
+		featExtAZURESQLSUPPORT, featExtDATACLASSIFICATION,
 	} {
 		if b == 0 {
 			t.Fail()
@@ -886,6 +887,25 @@ func TestUcs22str(t *testing.T) {
 	ExerciseUCS2ToStringFunction("ucs22str", ucs22str, t)
 }
 
+func TestUtf16le2str(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte{}, ""},
+		{str2ucs2("café"), "café"},
+		{str2ucs2("日本語 🍺"), "日本語 🍺"},
+		{[]byte{0x3d, 0xd8, 0x41, 0}, "\ufffdA"},                         // unpaired high surrogate
+		{[]byte{0x41, 0, 0x7a, 0xdf}, "A\ufffd"},                         // unpaired low surrogate
+		{[]byte{0x3d, 0xd8, 0x3d, 0xd8, 0x7a, 0xdf}, "\ufffd\U0001F77A"}, // high surrogate followed by a pair
+	}
+	for _, test := range tests {
+		if got := utf16le2str(test.in); got != test.want {
+			t.Errorf("utf16le2str(%v) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
 var sideeffect_varchar string
 
 // ucs22str benchmarks
@@ -1129,3 +1149,67 @@ func TestDialSqlConnectionCustomDialer(t *testing.T) {
 		t.Error(fmt.Errorf("dialer should not be used to resolve dns if not a host dialer"))
 	}
 }
+
+func TestPrepareLoginClientInfo(t *testing.T) {
+	p, err := msdsn.Parse("server=somehost;app name=inventory-api;workstation id=node-7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := prepareLogin(context.Background(), &Connector{}, p, optionalLogger{}, nil, &featureExtFedAuth{}, defaultPacketSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.AppName != "inventory-api" || l.HostName != "node-7" {
+		t.Errorf("unexpected app name %q and host name %q", l.AppName, l.HostName)
+	}
+	if l.ClientPID != uint32(os.Getpid()) {
+		t.Errorf("expected client process id %d, got %d", os.Getpid(), l.ClientPID)
+	}
+}
+
+func TestPrepareLoginUTF8Support(t *testing.T) {
+	p, err := msdsn.Parse("server=somehost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := prepareLogin(context.Background(), &Connector{}, p, optionalLogger{}, nil, &featureExtFedAuth{}, defaultPacketSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{featExtUTF8SUPPORT, 0, 0, 0, 0, featExtTERMINATOR}
+	if b := l.FeatureExt.toBytes(); !bytes.Equal(b, expected) {
+		t.Errorf("expected the feature extension % x, got % x", expected, b)
+	}
+}
+
+func TestPrepareLoginAttachDBFilename(t *testing.T) {
+	p, err := msdsn.Parse(`server=.\SQLExpress;attachdbfilename=C:\data\app.mdf;user instance=true`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := prepareLogin(context.Background(), &Connector{}, p, optionalLogger{}, nil, &featureExtFedAuth{}, defaultPacketSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.AtchDBFile != `C:\data\app.mdf` {
+		t.Errorf("unexpected attach db file %q", l.AtchDBFile)
+	}
+	if l.OptionFlags3&fUserInstance == 0 {
+		t.Error("expected the user instance flag")
+	}
+}
+
+func TestWithClientCertificate(t *testing.T) {
+	config := &tls.Config{ServerName: "db.example.com"}
+	if got := (&Connector{}).withClientCertificate(config); got != config {
+		t.Error("expected the config to be used as is without a client certificate")
+	}
+	cert := &tls.Certificate{Certificate: [][]byte{{1, 2, 3}}}
+	got := (&Connector{ClientCertificate: cert}).withClientCertificate(config)
+	if got == config || len(config.Certificates) != 0 {
+		t.Error("expected the shared config not to be changed")
+	}
+	if len(got.Certificates) != 1 || got.ServerName != "db.example.com" {
+		t.Errorf("unexpected config with client certificate %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package mssql
+
+import (
+	"time"
+)
+
+// SystemTime is a FOR SYSTEM_TIME clause for a query of a system-versioned
+// temporal table, it is placed after the table name:
+//
+//	query := "select * from dbo.accounts " + mssql.SystemTimeAsOf(t).String() + " where id = @p1"
+//
+// The times are converted to UTC, the time zone of the period columns.
+//
+// https://learn.microsoft.com/sql/relational-databases/tables/querying-data-in-a-system-versioned-temporal-table
+type SystemTime struct {
+	kind     string
+	from, to time.Time
+}
+
+// SystemTimeAsOf selects the rows that were current at t.
+func SystemTimeAsOf(t time.Time) SystemTime {
+	return SystemTime{kind: "as of", from: t}
+}
+
+// SystemTimeFromTo selects the rows that were current at any time from from
+// up to, but not including, to.
+func SystemTimeFromTo(from, to time.Time) SystemTime {
+	return SystemTime{kind: "from", from: from, to: to}
+}
+
+// SystemTimeBetween selects the rows that were current at any time from from
+// up to and including to.
+func SystemTimeBetween(from, to time.Time) SystemTime {
+	return SystemTime{kind: "between", from: from, to: to}
+}
+
+// SystemTimeContainedIn selects the rows that became current and stopped
+// being current between from and to.
+func SystemTimeContainedIn(from, to time.Time) SystemTime {
+	return SystemTime{kind: "contained in", from: from, to: to}
+}
+
+// SystemTimeAll selects all current and history rows.
+func SystemTimeAll() SystemTime {
+	return SystemTime{kind: "all"}
+}
+
+// String returns the clause. The times are formatted by the driver,
+// so the clause is safe to embed in SQL text.
+func (st SystemTime) String() string {
+	switch st.kind {
+	case "as of":
+		return "for system_time as of " + systemTimeLiteral(st.from)
+	case "from":
+		return "for system_time from " + systemTimeLiteral(st.from) + " to " + systemTimeLiteral(st.to)
+	case "between":
+		return "for system_time between " + systemTimeLiteral(st.from) + " and " + systemTimeLiteral(st.to)
+	case "contained in":
+		return "for system_time contained in (" + systemTimeLiteral(st.from) + ", " + systemTimeLiteral(st.to) + ")"
+	case "all":
+		return "for system_time all"
+	}
+	return ""
+}
+
+func systemTimeLiteral(t time.Time) string {
+	return "'" + t.UTC().Format("2006-01-02T15:04:05.0000000") + "'"
+}
+
+// PeriodEndMax is the period end of the current rows of a temporal table.
+var PeriodEndMax = time.Date(9999, 12, 31, 23, 59, 59, 999999900, time.UTC)
+
+// Period holds the period columns of a row of a temporal table.
+// Scan the period start and end columns into Start and End.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Current reports whether the row is the current version of the row,
+// history rows have an end before PeriodEndMax.
+func (p Period) Current() bool {
+	return p.End.Year() == PeriodEndMax.Year()
+}
+
+// Contains reports whether the row was current at t.
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
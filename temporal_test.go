@@ -0,0 +1,43 @@
+package mssql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSystemTime(t *testing.T) {
+	from := time.Date(2024, 1, 2, 3, 4, 5, 123456700, time.FixedZone("", 2*60*60))
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	values := []struct {
+		st       SystemTime
+		expected string
+	}{
+		{SystemTimeAsOf(from), "for system_time as of '2024-01-02T01:04:05.1234567'"},
+		{SystemTimeFromTo(from, to), "for system_time from '2024-01-02T01:04:05.1234567' to '2024-02-01T00:00:00.0000000'"},
+		{SystemTimeBetween(from, to), "for system_time between '2024-01-02T01:04:05.1234567' and '2024-02-01T00:00:00.0000000'"},
+		{SystemTimeContainedIn(from, to), "for system_time contained in ('2024-01-02T01:04:05.1234567', '2024-02-01T00:00:00.0000000')"},
+		{SystemTimeAll(), "for system_time all"},
+		{SystemTime{}, ""},
+	}
+	for _, v := range values {
+		if s := v.st.String(); s != v.expected {
+			t.Errorf("expected %q, got %q", v.expected, s)
+		}
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	history := Period{Start: start, End: end}
+	if history.Current() {
+		t.Error("expected a history row not to be current")
+	}
+	if !history.Contains(start) || history.Contains(end) || !history.Contains(end.Add(-time.Nanosecond)) {
+		t.Error("expected the period to include its start and exclude its end")
+	}
+	current := Period{Start: end, End: time.Date(9999, 12, 31, 23, 59, 59, 999999900, time.UTC)}
+	if !current.Current() || !current.Contains(time.Now()) {
+		t.Error("expected the current row to be current")
+	}
+}
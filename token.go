@@ -12,6 +12,7 @@ import (
 
 	"github.com/golang-sql/sqlexp"
 	"github.com/microsoft/go-mssqldb/aecmk"
+	"github.com/microsoft/go-mssqldb/internal/cp"
 	"github.com/microsoft/go-mssqldb/internal/github.com/swisscom/mssql-always-encrypted/pkg/algorithms"
 	"github.com/microsoft/go-mssqldb/internal/github.com/swisscom/mssql-always-encrypted/pkg/encryption"
 	"github.com/microsoft/go-mssqldb/internal/github.com/swisscom/mssql-always-encrypted/pkg/keys"
@@ -106,7 +107,10 @@ const (
 // https://msdn.microsoft.com/en-us/library/dd357363.aspx
 const (
 	colFlagNullable  = 1
+	colFlagIdentity  = 0x0010
+	colFlagComputed  = 0x0020
 	colFlagEncrypted = 0x0800
+	colFlagHidden    = 0x2000
 	// TODO implement more flags
 )
 
@@ -163,14 +167,16 @@ func processEnvChg(ctx context.Context, sess *tdsSession) {
 			if err != nil {
 				badStreamPanic(err)
 			}
-			_, err = readBVarChar(r)
+			oldDatabase, err := readBVarChar(r)
 			if err != nil {
 				badStreamPanic(err)
 			}
+			if sess.databaseChanged != nil {
+				sess.databaseChanged(ctx, oldDatabase, sess.database)
+			}
 		case envTypLanguage:
-			// currently ignored
 			// new value
-			if _, err = readBVarChar(r); err != nil {
+			if sess.language, err = readBVarChar(r); err != nil {
 				badStreamPanic(err)
 			}
 			// old value
@@ -200,6 +206,9 @@ func processEnvChg(ctx context.Context, sess *tdsSession) {
 			if err != nil {
 				badStreamPanicf("Invalid Packet size value returned from server (%s): %s", packetsize, err.Error())
 			}
+			if packetsizei < 512 || packetsizei > 32767 {
+				badStreamPanicf("Invalid Packet size value returned from server (%s)", packetsize)
+			}
 			sess.buf.ResizeBuffer(packetsizei)
 		case envSortId:
 			// currently ignored
@@ -222,7 +231,6 @@ func processEnvChg(ctx context.Context, sess *tdsSession) {
 				badStreamPanic(err)
 			}
 		case envSqlCollation:
-			// currently ignored
 			var collationSize uint8
 			err = binary.Read(r, binary.LittleEndian, &collationSize)
 			if err != nil {
@@ -248,6 +256,8 @@ func processEnvChg(ctx context.Context, sess *tdsSession) {
 				badStreamPanic(err)
 			}
 
+			sess.collation = cp.Collation{LcidAndFlags: info, SortId: sortID}
+
 			// old value, should be 0
 			if _, err = readBVarChar(r); err != nil {
 				badStreamPanic(err)
@@ -399,6 +409,7 @@ func processEnvChg(ctx context.Context, sess *tdsSession) {
 			if sess.logFlags&logDebug != 0 {
 				sess.logger.Log(ctx, msdsn.LogDebug, fmt.Sprintf("WARN: Unknown ENVCHANGE record detected with type id = %d", envtype))
 			}
+			sess.connector.logEvent(ctx, Event{Type: EventWarning, Server: sess.server, SPID: int(sess.buf.rSpid), Message: fmt.Sprintf("unknown ENVCHANGE type %d", envtype)})
 			return
 		}
 	}
@@ -549,6 +560,9 @@ type colAckStruct struct {
 	EnclaveType string
 }
 
+// utf8SupportAck reports whether the server supports UTF-8 collations.
+type utf8SupportAck bool
+
 type featureExtAck map[byte]interface{}
 
 func parseFeatureExtAck(r *tdsBuffer) featureExtAck {
@@ -588,6 +602,11 @@ func parseFeatureExtAck(r *tdsBuffer) featureExtAck {
 
 			}
 			ack[feature] = colAck
+		case featExtUTF8SUPPORT:
+			if length > 0 {
+				ack[feature] = utf8SupportAck(r.byte()&0x01 != 0)
+				length--
+			}
 		}
 
 		// Skip unprocessed bytes
@@ -787,6 +806,11 @@ func readCekTableEntry(r *tdsBuffer) cekTableEntry {
 // http://msdn.microsoft.com/en-us/library/dd357254.aspx
 func parseRow(ctx context.Context, r *tdsBuffer, s *tdsSession, columns []columnStruct, row []interface{}) error {
 	for i, column := range columns {
+		if column.skip {
+			skipValue(&column.ti, r)
+			row[i] = nil
+			continue
+		}
 		columnContent := column.ti.Reader(&column.ti, r, nil)
 		if columnContent == nil {
 			row[i] = columnContent
@@ -865,13 +889,21 @@ func decryptColumn(ctx context.Context, column columnStruct, s *tdsSession, colu
 // http://msdn.microsoft.com/en-us/library/dd304783.aspx
 func parseNbcRow(ctx context.Context, r *tdsBuffer, s *tdsSession, columns []columnStruct, row []interface{}) error {
 	bitlen := (len(columns) + 7) / 8
-	pres := make([]byte, bitlen)
+	if cap(r.nbcBitmap) < bitlen {
+		r.nbcBitmap = make([]byte, bitlen)
+	}
+	pres := r.nbcBitmap[:bitlen]
 	r.ReadFull(pres)
 	for i, col := range columns {
 		if pres[i/8]&(1<<(uint(i)%8)) != 0 {
 			row[i] = nil
 			continue
 		}
+		if col.skip {
+			skipValue(&col.ti, r)
+			row[i] = nil
+			continue
+		}
 		columnContent := col.ti.Reader(&col.ti, r, nil)
 		if col.isEncrypted() {
 			buffer, err := decryptColumn(ctx, col, s, columnContent)
@@ -985,6 +1017,10 @@ func processSingleResponse(ctx context.Context, sess *tdsSession, ch chan tokenS
 	if packet_type != packReply {
 		badStreamPanic(fmt.Errorf("unexpected packet type in reply: got %v, expected %v", packet_type, packReply))
 	}
+	if injected := sess.injectResponseFault(); injected != nil {
+		ch <- doneStruct{Status: doneError, errors: []Error{*injected}}
+		return
+	}
 	var columns []columnStruct
 	errs := make([]Error, 0, 5)
 	for tokens := 0; ; tokens += 1 {
@@ -1042,9 +1078,7 @@ func processSingleResponse(ctx context.Context, sess *tdsSession, ch chan tokenS
 		case tokenDone, tokenDoneProc:
 			done := parseDone(sess.buf)
 			done.errors = errs
-			if outs.msgq != nil {
-				errs = make([]Error, 0, 5)
-			}
+			errs = make([]Error, 0, 5)
 			if sess.logFlags&logDebug != 0 {
 				sess.logger.Log(ctx, msdsn.LogDebug, fmt.Sprintf("got DONE or DONEPROC status=%d", done.Status))
 			}
@@ -1080,6 +1114,12 @@ func processSingleResponse(ctx context.Context, sess *tdsSession, ch chan tokenS
 			}
 		case tokenColMetadata:
 			columns = parseColMetadata72(sess.buf, sess)
+			if outs.decodeColumns != nil {
+				skipColumns(columns, outs.decodeColumns)
+			}
+			if outs.columnAttrs != nil {
+				outs.columnAttrs.set(columns)
+			}
 			ch <- columns
 			colsReceived = true
 			if outs.msgq != nil {
@@ -1124,12 +1164,27 @@ func processSingleResponse(ctx context.Context, sess *tdsSession, ch chan tokenS
 			if sess.logFlags&logMessages != 0 {
 				sess.logger.Log(ctx, msdsn.LogMessages, info.Message)
 			}
+			if sess.messageHandler != nil {
+				sess.messageHandler(ctx, info)
+			}
 			if outs.msgq != nil {
 				_ = sqlexp.ReturnMessageEnqueue(ctx, outs.msgq, sqlexp.MsgNotice{Message: info})
 			}
+			if outs.statistics != nil {
+				outs.statistics.add(info)
+			}
+			if outs.backup != nil {
+				outs.backup.add(info)
+			}
 		case tokenReturnValue:
 			nv := parseReturnValue(sess.buf, sess)
-			if len(nv.Name) > 0 {
+			if outs.prepareHandle != nil {
+				// the handle is the first output parameter of sp_prepexec
+				if handle, ok := nv.Value.(int64); ok {
+					*outs.prepareHandle = int32(handle)
+				}
+				outs.prepareHandle = nil
+			} else if len(nv.Name) > 0 {
 				name := nv.Name[1:] // Remove the leading "@".
 				if ov, has := outs.params[name]; has {
 					err = scanIntoOut(name, nv.Value, ov)
@@ -1153,12 +1208,27 @@ type tokenProcessor struct {
 	lastRow    []interface{}
 	rowCount   int64
 	firstError error
+	// lastRowCounted is whether the DONE token that ended the result set
+	// of lastRow had a row count, SET NOCOUNT ON sends none
+	lastRowCounted bool
+	lastRowDone    bool
 	// whether to skip sending attention when ctx is done
 	noAttn bool
+	// rowsRead and bytesRead count the rows for MaxRows and MaxBytes
+	rowsRead  int64
+	bytesRead int64
 }
 
 func startReading(sess *tdsSession, ctx context.Context, outs outputs) *tokenProcessor {
-	tokChan := make(chan tokenStruct, 5)
+	if sess.conn != nil {
+		deadline, _ := ctx.Deadline()
+		sess.conn.setQuery(deadline, outs.noTimeout)
+	}
+	readAhead := defaultReadAhead
+	if outs.readAhead > 0 {
+		readAhead = outs.readAhead
+	}
+	tokChan := make(chan tokenStruct, readAhead)
 	go processSingleResponse(ctx, sess, tokChan, outs)
 	return &tokenProcessor{
 		tokChan: tokChan,
@@ -1180,16 +1250,19 @@ func (t *tokenProcessor) iterateResponse() error {
 					t.sess.columns = token
 				case []interface{}:
 					t.lastRow = token
+					t.lastRowDone = false
 				case doneInProcStruct:
+					t.doneRow(token.Status)
 					if token.Status&doneCount != 0 {
-						t.rowCount += int64(token.RowCount)
+						t.addRowCount(int64(token.RowCount))
 					}
 				case doneStruct:
+					t.doneRow(token.Status)
 					if token.Status&doneCount != 0 {
-						t.rowCount += int64(token.RowCount)
+						t.addRowCount(int64(token.RowCount))
 					}
-					if token.isError() && t.firstError == nil {
-						t.firstError = token.getError()
+					if token.isError() {
+						t.addError(token)
 					}
 				case ReturnStatus:
 					if t.outs.returnStatus != nil {
@@ -1207,6 +1280,34 @@ func (t *tokenProcessor) iterateResponse() error {
 	}
 }
 
+// addError keeps the error of the first failed statement,
+// errors of later statements in the batch are added to its All list.
+func (t *tokenProcessor) addError(done doneStruct) {
+	if t.firstError == nil {
+		t.firstError = done.getError()
+		return
+	}
+	if sqlErr, ok := t.firstError.(Error); ok && len(done.errors) > 0 {
+		sqlErr.All = append(sqlErr.All, done.errors...)
+		t.firstError = sqlErr
+	}
+}
+
+// doneRow records whether the first DONE token after lastRow has a row count.
+func (t *tokenProcessor) doneRow(status uint16) {
+	if t.lastRow != nil && !t.lastRowDone {
+		t.lastRowDone = true
+		t.lastRowCounted = status&doneCount != 0
+	}
+}
+
+func (t *tokenProcessor) addRowCount(count int64) {
+	t.rowCount += count
+	if t.outs.rowCounts != nil {
+		*t.outs.rowCounts = append(*t.outs.rowCounts, count)
+	}
+}
+
 func (t tokenProcessor) nextToken() (tokenStruct, error) {
 	// we do this separate non-blocking check on token channel to
 	// prioritize it over cancellation channel
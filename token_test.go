@@ -1,7 +1,13 @@
 package mssql
 
 import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"reflect"
 	"regexp"
 	"testing"
 )
@@ -40,3 +46,255 @@ func TestParseFeatureExtAck(t *testing.T) {
 		parseFeatureExtAck(r)
 	}
 }
+
+func TestParseFeatureExtAckUTF8Support(t *testing.T) {
+	b := []byte{featExtUTF8SUPPORT, 0x01, 0x00, 0x00, 0x00, 0x01, featExtTERMINATOR}
+	r := &tdsBuffer{
+		packetSize: len(b),
+		rbuf:       b,
+		rpos:       0,
+		rsize:      len(b),
+	}
+	ack := parseFeatureExtAck(r)
+	if v, ok := ack[featExtUTF8SUPPORT].(utf8SupportAck); !ok || !bool(v) {
+		t.Errorf("expected the UTF-8 support to be acknowledged, got %v", ack)
+	}
+}
+
+func TestProcessEnvChgCollation(t *testing.T) {
+	// ENVCHANGE with a SQL collation of Latin1_General_100_CI_AS_SC_UTF8
+	b := []byte{
+		0x08, 0x00, // length
+		0x07,                               // type
+		0x05, 0x09, 0x04, 0xd0, 0x24, 0x00, // new value
+		0x00, // old value
+	}
+	sess := &tdsSession{buf: &tdsBuffer{
+		packetSize: len(b),
+		rbuf:       b,
+		rsize:      len(b),
+	}}
+
+	processEnvChg(context.Background(), sess)
+
+	if !sess.collation.IsUTF8() {
+		t.Errorf("expected a UTF-8 session collation, got %+v", sess.collation)
+	}
+
+	s := &Stmt{c: &Conn{sess: sess}}
+	p, err := s.makeParam(VarChar("café"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ti.Collation != sess.collation {
+		t.Errorf("expected VarChar collation %+v, got %+v", sess.collation, p.ti.Collation)
+	}
+	if p.ti.Size != 5 {
+		t.Errorf("expected VarChar size in bytes 5, got %d", p.ti.Size)
+	}
+	if decl := makeDecl(p.ti); decl != "varchar(5)" {
+		t.Errorf("expected varchar(5), got %s", decl)
+	}
+}
+
+func TestIterateResponseRowCounts(t *testing.T) {
+	var counts RowCounts
+	c := &Conn{}
+	if err := c.CheckNamedValue(&driver.NamedValue{Value: &counts}); err != driver.ErrRemoveArgument {
+		t.Fatalf("expected ErrRemoveArgument, got %v", err)
+	}
+
+	ch := make(chan tokenStruct, 5)
+	ch <- doneInProcStruct{Status: doneCount | doneMore, RowCount: 3}
+	ch <- doneInProcStruct{Status: doneMore}
+	ch <- doneInProcStruct{Status: doneCount | doneMore, RowCount: 0}
+	ch <- doneStruct{Status: doneCount, RowCount: 2}
+	close(ch)
+	reader := &tokenProcessor{tokChan: ch, ctx: context.Background(), sess: &tdsSession{}, outs: c.outs}
+	if err := reader.iterateResponse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if reader.rowCount != 5 {
+		t.Errorf("expected total row count 5, got %d", reader.rowCount)
+	}
+	if !reflect.DeepEqual(counts, RowCounts{3, 0, 2}) {
+		t.Errorf("expected row counts [3 0 2], got %v", counts)
+	}
+}
+
+func TestIterateResponseAllErrors(t *testing.T) {
+	ch := make(chan tokenStruct, 5)
+	ch <- doneStruct{Status: doneError | doneMore, errors: []Error{
+		{Number: 547, Class: 16, Message: "conflicted with the FOREIGN KEY constraint", LineNo: 1},
+	}}
+	ch <- doneStruct{Status: doneCount | doneMore, RowCount: 1}
+	ch <- doneStruct{Status: doneError, errors: []Error{
+		{Number: 2627, Class: 14, Message: "Violation of PRIMARY KEY constraint", LineNo: 3},
+	}}
+	close(ch)
+	reader := &tokenProcessor{tokChan: ch, ctx: context.Background(), sess: &tdsSession{}}
+	err := reader.iterateResponse()
+
+	var sqlErr Error
+	if !errors.As(err, &sqlErr) {
+		t.Fatalf("expected an mssql.Error, got %v", err)
+	}
+	if sqlErr.Number != 547 || sqlErr.LineNo != 1 {
+		t.Errorf("expected the first error to be reported, got %+v", sqlErr)
+	}
+	if len(sqlErr.All) != 2 || sqlErr.All[0].Number != 547 || sqlErr.All[1].Number != 2627 {
+		t.Errorf("expected errors 547 and 2627 in All, got %+v", sqlErr.All)
+	}
+}
+
+// makeReplyBuf wraps a token stream into a single reply packet.
+func makeReplyBuf(tokens []byte) *tdsBuffer {
+	packet := make([]byte, 8, 8+len(tokens))
+	packet[0] = byte(packReply)
+	packet[1] = 1 // last packet of the message
+	binary.BigEndian.PutUint16(packet[2:], uint16(8+len(tokens)))
+	return makeBuf(uint16(8+len(tokens)), append(packet, tokens...))
+}
+
+// makeInfoToken encodes an INFO (or ERROR, when tok is tokenError) token.
+func makeInfoToken(tok token, number int32, class uint8, msg string) []byte {
+	body := &bytes.Buffer{}
+	_ = binary.Write(body, binary.LittleEndian, number)
+	body.WriteByte(1) // state
+	body.WriteByte(class)
+	_ = binary.Write(body, binary.LittleEndian, uint16(len(msg)))
+	body.Write(str2ucs2(msg))
+	body.WriteByte(0) // server name
+	body.WriteByte(0) // proc name
+	_ = binary.Write(body, binary.LittleEndian, int32(1))
+
+	res := &bytes.Buffer{}
+	res.WriteByte(byte(tok))
+	_ = binary.Write(res, binary.LittleEndian, uint16(body.Len()))
+	res.Write(body.Bytes())
+	return res.Bytes()
+}
+
+func makeDoneToken(status uint16, rowCount uint64) []byte {
+	res := &bytes.Buffer{}
+	res.WriteByte(byte(tokenDone))
+	_ = binary.Write(res, binary.LittleEndian, status)
+	_ = binary.Write(res, binary.LittleEndian, uint16(0)) // current command
+	_ = binary.Write(res, binary.LittleEndian, rowCount)
+	return res.Bytes()
+}
+
+func TestMessageHandler(t *testing.T) {
+	var tokens []byte
+	tokens = append(tokens, makeInfoToken(tokenInfo, 0, 0, "step 1 done")...)
+	tokens = append(tokens, makeInfoToken(tokenInfo, 50000, 10, "step 2 done")...)
+	tokens = append(tokens, makeDoneToken(0, 0)...)
+
+	var got []Error
+	sess := &tdsSession{
+		buf: makeReplyBuf(tokens),
+		messageHandler: func(ctx context.Context, msg Error) {
+			got = append(got, msg)
+		},
+	}
+	reader := startReading(sess, context.Background(), outputs{})
+	if err := reader.iterateResponse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(got))
+	}
+	if got[0].Message != "step 1 done" || got[1].Number != 50000 || got[1].Class != 10 {
+		t.Errorf("unexpected messages %+v", got)
+	}
+}
+
+// makeEnvChange encodes an ENVCHANGE token body with B_VARCHAR new and old values.
+func makeEnvChange(envtype uint8, newValue, oldValue string) []byte {
+	body := &bytes.Buffer{}
+	body.WriteByte(envtype)
+	body.WriteByte(byte(len(newValue)))
+	body.Write(str2ucs2(newValue))
+	body.WriteByte(byte(len(oldValue)))
+	body.Write(str2ucs2(oldValue))
+
+	res := &bytes.Buffer{}
+	_ = binary.Write(res, binary.LittleEndian, uint16(body.Len()))
+	res.Write(body.Bytes())
+	return res.Bytes()
+}
+
+func TestProcessEnvChgSessionState(t *testing.T) {
+	var b []byte
+	b = append(b, makeEnvChange(envTypDatabase, "tenant1", "master")...)
+	b = append(b, makeEnvChange(envTypLanguage, "us_english", "")...)
+	b = append(b, makeEnvChange(envTypPacketSize, "8000", "4096")...)
+
+	var changes []string
+	sess := &tdsSession{
+		buf: &tdsBuffer{packetSize: len(b), rbuf: b, rsize: len(b)},
+		databaseChanged: func(ctx context.Context, oldDatabase, newDatabase string) {
+			changes = append(changes, oldDatabase+"->"+newDatabase)
+		},
+	}
+	for i := 0; i < 3; i++ {
+		processEnvChg(context.Background(), sess)
+	}
+
+	c := &Conn{sess: sess}
+	if c.Database() != "tenant1" {
+		t.Errorf("expected database tenant1, got %s", c.Database())
+	}
+	if c.Language() != "us_english" {
+		t.Errorf("expected language us_english, got %s", c.Language())
+	}
+	if c.PacketSize() != 8000 {
+		t.Errorf("expected packet size 8000, got %d", c.PacketSize())
+	}
+	if !reflect.DeepEqual(changes, []string{"master->tenant1"}) {
+		t.Errorf("unexpected database changes %v", changes)
+	}
+}
+
+// makeIntReturnValue encodes a RETURNVALUE token of an int output parameter.
+func makeIntReturnValue(name string, value int32) []byte {
+	res := &bytes.Buffer{}
+	res.WriteByte(byte(tokenReturnValue))
+	_ = binary.Write(res, binary.LittleEndian, uint16(0)) // ordinal
+	res.WriteByte(byte(len(name)))
+	res.Write(str2ucs2(name))
+	res.WriteByte(1)                                      // status, output parameter
+	_ = binary.Write(res, binary.LittleEndian, uint32(0)) // user type
+	_ = binary.Write(res, binary.LittleEndian, uint16(0)) // flags
+	res.WriteByte(typeIntN)
+	res.WriteByte(4)
+	res.WriteByte(4)
+	_ = binary.Write(res, binary.LittleEndian, value)
+	return res.Bytes()
+}
+
+func TestPrepareHandleReturnValue(t *testing.T) {
+	var tokens []byte
+	tokens = append(tokens, makeIntReturnValue("", 7)...)
+	tokens = append(tokens, makeIntReturnValue("@count", 42)...)
+	tokens = append(tokens, makeDoneToken(0, 0)...)
+
+	var handle int32
+	var count int32
+	outs := outputs{
+		params:        map[string]interface{}{"count": &count},
+		prepareHandle: &handle,
+	}
+	reader := startReading(&tdsSession{buf: makeReplyBuf(tokens)}, context.Background(), outs)
+	if err := reader.iterateResponse(); err != nil {
+		t.Fatal(err)
+	}
+	if handle != 7 {
+		t.Errorf("expected handle 7, got %d", handle)
+	}
+	if count != 42 {
+		t.Errorf("expected output parameter 42, got %d", count)
+	}
+}
@@ -0,0 +1,113 @@
+package mssql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// TraceLevel selects what is written to Connector.TraceWriter.
+type TraceLevel int
+
+const (
+	// TraceOff writes nothing.
+	TraceOff TraceLevel = iota
+	// TraceErrors writes network errors.
+	TraceErrors
+	// TracePackets also writes the header of every TDS packet.
+	TracePackets
+	// TraceHexDump also writes a hex dump of the payload of every TDS packet.
+	// The payloads of login, authentication, SQL batch, RPC and bulk load
+	// packets are not dumped, they contain passwords, tokens, parameter
+	// values and the text of statements, which can embed values as literals.
+	// Prelogin, attention and transaction manager requests are dumped. The
+	// responses of the server contain rows and output parameters, they are
+	// dumped only when the param redaction of the connection is none.
+	TraceHexDump
+)
+
+var packetTypeNames = map[packetType]string{
+	packSQLBatch:     "SQLBATCH",
+	packRPCRequest:   "RPC",
+	packReply:        "REPLY",
+	packAttention:    "ATTENTION",
+	packBulkLoadBCP:  "BULKLOAD",
+	packFedAuthToken: "FEDAUTHTOKEN",
+	packTransMgrReq:  "TRANSMGR",
+	packLogin7:       "LOGIN7",
+	packSSPIMessage:  "SSPI",
+	packPrelogin:     "PRELOGIN",
+}
+
+func (p packetType) String() string {
+	if name, ok := packetTypeNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("packet type %d", uint8(p))
+}
+
+// redactedPacket reports whether the payload of a packet of type p
+// may contain secrets or parameter values. SQL batches are redacted too,
+// statements such as ALTER LOGIN or queries built with QuoteString
+// embed secrets and values in their text.
+func redactedPacket(p packetType) bool {
+	switch p {
+	case packLogin7, packSSPIMessage, packFedAuthToken, packSQLBatch, packRPCRequest, packBulkLoadBCP:
+		return true
+	}
+	return false
+}
+
+// tracedConnections numbers the connections in the traces
+var tracedConnections int64
+
+// tracer writes the packets and network errors of a connection to a writer.
+// Requests and attentions are written on other goroutines than responses,
+// so writes are serialized by the mutex.
+type tracer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level TraceLevel
+	// conn identifies the connection in the trace
+	conn int64
+	// redactReplies redacts the payloads of the responses, which contain
+	// the values of rows and output parameters.
+	redactReplies bool
+}
+
+func newTracer(w io.Writer, level TraceLevel, conn int64, redactReplies bool) *tracer {
+	if w == nil || level <= TraceOff {
+		return nil
+	}
+	return &tracer{w: w, level: level, conn: conn, redactReplies: redactReplies}
+}
+
+// packet traces a sent or received packet, the packet includes its header.
+func (t *tracer) packet(direction string, packet []byte) {
+	if t.level < TracePackets || len(packet) < headerSize {
+		return
+	}
+	p := packetType(packet[0])
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s conn %d %s %s packet %d status 0x%02x size %d\n",
+		time.Now().Format("15:04:05.000000"), t.conn, direction, p, packet[6], packet[1], len(packet))
+	if t.level < TraceHexDump || len(packet) == headerSize {
+		return
+	}
+	if redactedPacket(p) || p == packReply && t.redactReplies {
+		fmt.Fprintf(t.w, "\t%d bytes redacted\n", len(packet)-headerSize)
+		return
+	}
+	dumper := hex.Dumper(t.w)
+	dumper.Write(packet[headerSize:])
+	dumper.Close()
+}
+
+func (t *tracer) error(op string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s conn %d %s failed: %v\n", time.Now().Format("15:04:05.000000"), t.conn, op, err)
+}
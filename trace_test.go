@@ -0,0 +1,109 @@
+package mssql
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+func TestTracePackets(t *testing.T) {
+	if newTracer(&bytes.Buffer{}, TraceOff, 1, false) != nil || newTracer(nil, TraceHexDump, 1, false) != nil {
+		t.Fatal("expected no tracer when tracing is off")
+	}
+
+	out := &bytes.Buffer{}
+	response := []byte{byte(packReply), 1, 0, 11, 0, 0, 1, 0, 0xfd, 0xab, 0xcd}
+	transport := closableBuffer{bytes.NewBuffer(response)}
+	buf := newTdsBuffer(defaultPacketSize, &transport)
+	buf.trace = newTracer(out, TraceHexDump, 7, false)
+
+	if _, err := buf.BeginRead(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.BeginRead(); err == nil {
+		t.Fatal("expected a read error at the end of the response")
+	}
+	buf.BeginPacket(packSQLBatch, false)
+	buf.Write([]byte("alter login app with password = 'secret'"))
+	if err := buf.FinishPacket(); err != nil {
+		t.Fatal(err)
+	}
+	buf.BeginPacket(packRPCRequest, false)
+	buf.Write([]byte("secret"))
+	if err := buf.FinishPacket(); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := out.String()
+	for _, expected := range []string{
+		"conn 7 sent SQLBATCH packet 1 status 0x01 size 48\n\t40 bytes redacted\n",
+		"conn 7 sent RPC packet 1 status 0x01 size 14\n\t6 bytes redacted\n",
+		"conn 7 received REPLY packet 1 status 0x01 size 11\n",
+		"fd ab cd",
+		"conn 7 read failed: EOF\n",
+	} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("expected %q in the trace:\n%s", expected, trace)
+		}
+	}
+	if strings.Contains(trace, "secret") || strings.Contains(trace, "73 65 63 72 65 74") {
+		t.Errorf("expected the SQL batch and RPC payloads to be redacted:\n%s", trace)
+	}
+
+	out.Reset()
+	buf.trace = newTracer(out, TraceErrors, 7, false)
+	buf.BeginPacket(packSQLBatch, false)
+	if err := buf.FinishPacket(); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no packets at TraceErrors, got %s", out.String())
+	}
+}
+
+func TestTraceRedactsReplies(t *testing.T) {
+	for _, redaction := range []string{msdsn.ParamRedactionNone, msdsn.ParamRedactionFull} {
+		srv, err := mssqltest.NewServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv.Handle("select secret", mssqltest.Response{Results: []mssqltest.Result{{Columns: []string{"v"}, Rows: [][]interface{}{{"secret"}}}}})
+		config, err := msdsn.Parse(srv.URL())
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.ParamRedaction = redaction
+		out := &bytes.Buffer{}
+		connector := NewConnectorConfig(config)
+		connector.Dialer = srv
+		connector.TraceWriter = out
+		connector.TraceLevel = TraceHexDump
+		db := sql.OpenDB(connector)
+		var v string
+		err = db.QueryRow("select secret").Scan(&v)
+		db.Close()
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(out.String(), "\n")
+		replies := 0
+		for i, line := range lines {
+			if !strings.Contains(line, "received REPLY") || i+1 == len(lines) {
+				continue
+			}
+			replies++
+			if redacted := strings.HasSuffix(lines[i+1], "bytes redacted"); redacted != (redaction != msdsn.ParamRedactionNone) {
+				t.Errorf("param redaction %s: expected the reply to be redacted %v, got %q", redaction, !redacted, lines[i+1])
+			}
+		}
+		if replies == 0 {
+			t.Errorf("param redaction %s: expected replies in the trace:\n%s", redaction, out.String())
+		}
+	}
+}
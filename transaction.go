@@ -0,0 +1,55 @@
+package mssql
+
+import (
+	"context"
+)
+
+// SetXactAbort switches XACT_ABORT on or off for the session. With XACT_ABORT ON
+// the server rolls back the whole transaction when a statement fails,
+// Commit then returns ErrTransactionRolledBack.
+//
+// Use a *sql.Tx or a *sql.Conn, to switch it on for every connection
+// use the "xact abort" connection string parameter.
+func SetXactAbort(ctx context.Context, e Execer, on bool) error {
+	query := "set xact_abort off"
+	if on {
+		query = "set xact_abort on"
+	}
+	_, err := e.ExecContext(ctx, query)
+	return err
+}
+
+// TransactionState is the transaction state of a session.
+type TransactionState struct {
+	// Count is @@TRANCOUNT, the number of open transactions.
+	Count int
+	// State is XACT_STATE(), 1 for an active transaction, 0 for no transaction
+	// and -1 for a transaction that cannot be committed anymore.
+	State int
+}
+
+// Active reports whether the session has a transaction that can be committed.
+func (s TransactionState) Active() bool {
+	return s.State == 1
+}
+
+// Doomed reports whether the session has a transaction that can only be rolled back.
+func (s TransactionState) Doomed() bool {
+	return s.State == -1
+}
+
+// GetTransactionState returns @@TRANCOUNT and XACT_STATE() of the session of q,
+// which should be a *sql.Tx or a *sql.Conn.
+func GetTransactionState(ctx context.Context, q Querier) (TransactionState, error) {
+	var st TransactionState
+	rows, err := q.QueryContext(ctx, "select @@trancount, xact_state()")
+	if err != nil {
+		return st, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return st, rows.Err()
+	}
+	err = rows.Scan(&st.Count, &st.State)
+	return st, err
+}
@@ -0,0 +1,34 @@
+package mssql
+
+import (
+	"testing"
+)
+
+func TestCommitRolledBackTransaction(t *testing.T) {
+	c := &Conn{connectionGood: true, sess: &tdsSession{}}
+	if err := c.Commit(); err != ErrTransactionRolledBack {
+		t.Errorf("expected ErrTransactionRolledBack, got %v", err)
+	}
+	if err := c.Rollback(); err != nil {
+		t.Errorf("expected Rollback to succeed, got %v", err)
+	}
+	if !c.connectionGood {
+		t.Error("expected the connection to stay good")
+	}
+}
+
+func TestTransactionState(t *testing.T) {
+	values := []struct {
+		state          TransactionState
+		active, doomed bool
+	}{
+		{TransactionState{Count: 0, State: 0}, false, false},
+		{TransactionState{Count: 1, State: 1}, true, false},
+		{TransactionState{Count: 1, State: -1}, false, true},
+	}
+	for _, v := range values {
+		if v.state.Active() != v.active || v.state.Doomed() != v.doomed {
+			t.Errorf("%+v: expected Active() %v and Doomed() %v", v.state, v.active, v.doomed)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package mssql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/internal/cp"
+)
+
+// TypedValue is a parameter that is sent with an explicit SQL Server type,
+// length, precision and scale instead of the type derived from its Go value.
+// Declaring a parameter with the type of the column it is compared with
+// avoids implicit conversions of the column, which prevent index seeks.
+//
+// Create TypedValues with TypedVarChar, TypedNVarChar, TypedDateTime2 and TypedDecimal.
+type TypedValue struct {
+	typeId    uint8
+	value     interface{}
+	length    int
+	precision int
+	scale     int
+}
+
+// TypedVarChar sends s as varchar(length), length is in bytes.
+// A length of 0 sends s as varchar(max).
+func TypedVarChar(s string, length int) TypedValue {
+	return TypedValue{typeId: typeBigVarChar, value: s, length: length}
+}
+
+// TypedNVarChar sends s as nvarchar(length), length is in UTF-16 code units.
+// A length of 0 sends s as nvarchar(max).
+func TypedNVarChar(s string, length int) TypedValue {
+	return TypedValue{typeId: typeNVarChar, value: s, length: length}
+}
+
+// TypedDateTime2 sends t as datetime2(scale), scale is the number of fractional
+// second digits from 0 to 7.
+func TypedDateTime2(t time.Time, scale int) TypedValue {
+	return TypedValue{typeId: typeDateTime2N, value: t, scale: scale}
+}
+
+// TypedDecimal sends v as decimal(precision, scale).
+// v can be an integer, a float or a string with the decimal representation
+// of the number. Strings are converted exactly, use them for values that
+// cannot be represented as a float.
+// A nil v sends a NULL decimal.
+func TypedDecimal(v interface{}, precision, scale int) TypedValue {
+	return TypedValue{typeId: typeDecimalN, value: v, precision: precision, scale: scale}
+}
+
+// makeParam returns the parameter of v, c redacts the value in errors.
+func (v TypedValue) makeParam(c *Conn, collation cp.Collation) (res param, err error) {
+	res.ti.TypeId = v.typeId
+	switch v.typeId {
+	case typeBigVarChar:
+		if v.length < 0 || v.length > 8000 {
+			return res, fmt.Errorf("mssql: invalid varchar length %d, must be between 0 and 8000", v.length)
+		}
+		res.ti.Collation = collation
+		res.buffer = []byte(v.value.(string))
+		res.ti.Size = v.length
+		if v.length > 0 && len(res.buffer) > v.length {
+			return res, fmt.Errorf("mssql: value of %d bytes is too long for varchar(%d)", len(res.buffer), v.length)
+		}
+	case typeNVarChar:
+		if v.length < 0 || v.length > 4000 {
+			return res, fmt.Errorf("mssql: invalid nvarchar length %d, must be between 0 and 4000", v.length)
+		}
+		res.buffer = str2ucs2(v.value.(string))
+		res.ti.Size = v.length * 2
+		if v.length > 0 && len(res.buffer) > res.ti.Size {
+			return res, fmt.Errorf("mssql: value of %d characters is too long for nvarchar(%d)", len(res.buffer)/2, v.length)
+		}
+	case typeDateTime2N:
+		if v.scale < 0 || v.scale > 7 {
+			return res, fmt.Errorf("mssql: invalid datetime2 scale %d, must be between 0 and 7", v.scale)
+		}
+		res.ti.Scale = uint8(v.scale)
+		res.buffer = encodeDateTime2(v.value.(time.Time), v.scale)
+		res.ti.Size = len(res.buffer)
+	case typeDecimalN:
+		if v.precision < 1 || v.precision > 38 || v.scale < 0 || v.scale > v.precision {
+			return res, fmt.Errorf("mssql: invalid decimal(%d, %d), precision must be between 1 and 38 and scale between 0 and the precision", v.precision, v.scale)
+		}
+		res.ti.Prec = uint8(v.precision)
+		res.ti.Scale = uint8(v.scale)
+		res.ti.Size = decimalLength(res.ti.Prec) + 1
+		if v.value == nil {
+			res.buffer = []byte{}
+			return
+		}
+		res.buffer, err = encodeDecimal(c, v.value, res.ti.Prec, res.ti.Scale)
+	}
+	return
+}
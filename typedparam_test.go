@@ -0,0 +1,90 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/internal/cp"
+)
+
+func TestTypedValueDecl(t *testing.T) {
+	s := &Stmt{c: &Conn{sess: &tdsSession{}}}
+	ts := time.Date(2024, 2, 29, 13, 14, 15, 123456700, time.UTC)
+	values := []struct {
+		val  TypedValue
+		decl string
+	}{
+		{TypedVarChar("ab-12", 50), "varchar(50)"},
+		{TypedVarChar("ab-12", 0), "varchar(max)"},
+		{TypedNVarChar("héllo", 20), "nvarchar(20)"},
+		{TypedNVarChar("héllo", 0), "nvarchar(max)"},
+		{TypedDateTime2(ts, 3), "datetime2(3)"},
+		{TypedDecimal(12.5, 18, 4), "decimal(18, 4)"},
+		{TypedDecimal(nil, 5, 2), "decimal(5, 2)"},
+	}
+	for _, v := range values {
+		nv := &driver.NamedValue{Value: v.val}
+		if err := s.c.CheckNamedValue(nv); err != nil {
+			t.Fatalf("CheckNamedValue of %#v failed: %v", v.val, err)
+		}
+		p, err := s.makeParam(nv.Value)
+		if err != nil {
+			t.Fatalf("makeParam of %#v failed: %v", v.val, err)
+		}
+		if decl := makeDecl(p.ti); decl != v.decl {
+			t.Errorf("expected %s, got %s", v.decl, decl)
+		}
+	}
+}
+
+func TestTypedValueInvalid(t *testing.T) {
+	s := &Stmt{c: &Conn{sess: &tdsSession{}}}
+	values := []TypedValue{
+		TypedVarChar("too long", 3),
+		TypedVarChar("x", 8001),
+		TypedNVarChar("too long", 3),
+		TypedNVarChar("x", -1),
+		TypedDateTime2(time.Now(), 8),
+		TypedDecimal(1, 0, 0),
+		TypedDecimal(1, 5, 6),
+		TypedDecimal("1.234", 5, 2),
+		TypedDecimal(true, 5, 2),
+		TypedDecimal("1234.5", 5, 2),
+		TypedDecimal(-1000, 5, 2),
+		TypedDecimal(1000.0, 5, 2),
+		TypedDecimal("1000000000000000000000000000000000000", 38, 2),
+	}
+	for _, v := range values {
+		if _, err := s.makeParam(v); err == nil {
+			t.Errorf("expected an error for %#v", v)
+		}
+	}
+}
+
+func TestTypedDecimalEncoding(t *testing.T) {
+	values := []struct {
+		val      interface{}
+		expected string
+	}{
+		{"-12.5", "-12.5000"},
+		{int64(7), "7.0000"},
+		{3, "3.0000"},
+		{2.25, "2.2500"},
+		{"123456789012345.1234", "123456789012345.1234"},
+		{"-999999999999999.9999", "-999999999999999.9999"},
+	}
+	for _, v := range values {
+		p, err := TypedDecimal(v.val, 19, 4).makeParam(nil, cp.Collation{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ti.Size != 9 || len(p.buffer) != 9 {
+			t.Fatalf("expected 9 bytes for decimal(19, 4), got size %d with %d bytes", p.ti.Size, len(p.buffer))
+		}
+		got := decodeDecimal(p.ti.Prec, p.ti.Scale, p.buffer)
+		if string(got) != v.expected {
+			t.Errorf("expected %s for %v, got %s", v.expected, v.val, got)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package mssql
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -248,8 +247,12 @@ func writeVarLen(w io.Writer, ti *typeInfo, out bool) (err error) {
 		if err = binary.Write(w, binary.LittleEndian, uint32(ti.Size)); err != nil {
 			return
 		}
-		if err = writeCollation(w, ti.Collation); err != nil {
-			return
+		switch ti.TypeId {
+		case typeText, typeNText:
+			// only the character types carry a collation
+			if err = writeCollation(w, ti.Collation); err != nil {
+				return
+			}
 		}
 		ti.Writer = writeLongLenType
 	default:
@@ -361,6 +364,9 @@ func readByteLenType(ti *typeInfo, r *tdsBuffer, c *cryptoMetadata) interface{}
 	if size == 0 {
 		return nil
 	}
+	if ti.TypeId == typeBinary || ti.TypeId == typeVarBinary {
+		return readBytes(r, int(size))
+	}
 	r.ReadFull(ti.Buffer[:size])
 	buf := ti.Buffer[:size]
 	switch ti.TypeId {
@@ -430,13 +436,6 @@ func readByteLenType(ti *typeInfo, r *tdsBuffer, c *cryptoMetadata) interface{}
 		}
 	case typeChar, typeVarChar:
 		return decodeChar(ti.Collation, buf)
-	case typeBinary, typeVarBinary:
-		// a copy, because the backing array for ti.Buffer is reused
-		// and can be overwritten by the next row while this row waits
-		// in a buffered chan
-		cpy := make([]byte, len(buf))
-		copy(cpy, buf)
-		return cpy
 	default:
 		badStreamPanicf("Invalid typeid")
 	}
@@ -465,18 +464,14 @@ func readShortLenType(ti *typeInfo, r *tdsBuffer, c *cryptoMetadata) interface{}
 	if size == 0xffff {
 		return nil
 	}
+	if ti.TypeId == typeBigVarBin || ti.TypeId == typeBigBinary {
+		return readBytes(r, int(size))
+	}
 	r.ReadFull(ti.Buffer[:size])
 	buf := ti.Buffer[:size]
 	switch ti.TypeId {
 	case typeBigVarChar, typeBigChar:
 		return decodeChar(ti.Collation, buf)
-	case typeBigVarBin, typeBigBinary:
-		// a copy, because the backing array for ti.Buffer is reused
-		// and can be overwritten by the next row while this row waits
-		// in a buffered chan
-		cpy := make([]byte, len(buf))
-		copy(cpy, buf)
-		return cpy
 	case typeNVarChar, typeNChar:
 		return decodeNChar(buf)
 	case typeUdt:
@@ -487,6 +482,16 @@ func readShortLenType(ti *typeInfo, r *tdsBuffer, c *cryptoMetadata) interface{}
 	panic("shoulnd't get here")
 }
 
+// readBytes reads a binary value straight from the packet buffer into a
+// slice of its own. The backing array of ti.Buffer can not be returned,
+// it is reused and can be overwritten by the next row while this row
+// waits in a buffered chan.
+func readBytes(r *tdsBuffer, size int) []byte {
+	buf := make([]byte, size)
+	r.ReadFull(buf)
+	return buf
+}
+
 func writeShortLenType(w io.Writer, ti typeInfo, buf []byte) (err error) {
 	if buf == nil {
 		err = binary.Write(w, binary.LittleEndian, uint16(0xffff))
@@ -535,6 +540,12 @@ func readLongLenType(ti *typeInfo, r *tdsBuffer, c *cryptoMetadata) interface{}
 	panic("shoulnd't get here")
 }
 func writeLongLenType(w io.Writer, ti typeInfo, buf []byte) (err error) {
+	if buf == nil {
+		// a zero length text pointer marks a NULL value,
+		// neither the timestamp nor the data follow it
+		err = binary.Write(w, binary.LittleEndian, byte(0))
+		return
+	}
 	//textptr
 	err = binary.Write(w, binary.LittleEndian, byte(0x10))
 	if err != nil {
@@ -670,31 +681,44 @@ func readVariantType(ti *typeInfo, r *tdsBuffer, c *cryptoMetadata) interface{}
 
 // partially length prefixed stream
 // http://msdn.microsoft.com/en-us/library/dd340469.aspx
+// maxPLPScratch is the largest buffer kept by tdsBuffer for decoding PLP strings,
+// larger values are decoded from a buffer that is garbage collected.
+const maxPLPScratch = 1 << 20
+
 func readPLPType(ti *typeInfo, r *tdsBuffer, c *cryptoMetadata) interface{} {
 	var bytesToDecode []byte
 	if c == nil {
 		size := r.uint64()
-		var buf *bytes.Buffer
-		switch size {
-		case _PLP_NULL:
-			// null
+		if size == _PLP_NULL {
 			return nil
-		case _UNKNOWN_PLP_LEN:
-			// size unknown
-			buf = bytes.NewBuffer(make([]byte, 0, 1000))
+		}
+		// strings are decoded into a new string, the bytes are read into a
+		// scratch buffer that is reused. Binary and UDT values are returned
+		// as they are read and need a buffer of their own.
+		isBinary := ti.TypeId == typeBigVarBin || ti.TypeId == typeBigBinary || ti.TypeId == typeImage || ti.TypeId == typeUdt
+		var buf []byte
+		switch {
+		case !isBinary:
+			buf = r.plpScratch[:0]
+		case size == _UNKNOWN_PLP_LEN:
+			buf = make([]byte, 0, 1000)
 		default:
-			buf = bytes.NewBuffer(make([]byte, 0, size))
+			buf = make([]byte, 0, size)
 		}
 		for {
 			chunksize := r.uint32()
 			if chunksize == 0 {
 				break
 			}
-			if _, err := io.CopyN(buf, r, int64(chunksize)); err != nil {
+			var err error
+			if buf, err = r.appendN(buf, int(chunksize)); err != nil {
 				badStreamPanicf("Reading PLP type failed: %s", err.Error())
 			}
 		}
-		bytesToDecode = buf.Bytes()
+		if !isBinary && cap(buf) <= maxPLPScratch {
+			r.plpScratch = buf[:0]
+		}
+		bytesToDecode = buf
 	} else {
 		bytesToDecode = r.rbuf
 	}
@@ -1250,6 +1274,8 @@ func makeDecl(ti typeInfo) string {
 		return "text"
 	case typeNText:
 		return "ntext"
+	case typeImage:
+		return "image"
 	case typeUdt:
 		return ti.UdtInfo.TypeName
 	case typeGuid:
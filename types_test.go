@@ -1,9 +1,15 @@
 package mssql
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/microsoft/go-mssqldb/internal/cp"
 )
 
 func TestMakeGoLangScanType(t *testing.T) {
@@ -136,6 +142,9 @@ func TestMakeDecl(t *testing.T) {
 		{"varbinary(max)", 0xffff, typeBigVarBin},
 		{"varbinary(8000)", 8000, typeBigVarBin},
 		{"varbinary(4001)", 4001, typeBigVarBin},
+		{"text", 0, typeText},
+		{"ntext", 0, typeNText},
+		{"image", 0, typeImage},
 	}
 
 	for _, tt := range tests {
@@ -151,3 +160,192 @@ func handlePanic(t *testing.T) {
 		t.Errorf("recovered panic")
 	}
 }
+
+func TestReadLongLenType(t *testing.T) {
+	textptr := bytes.Repeat([]byte{0xab}, 16)
+	timestamp := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	makeValue := func(data []byte) []byte {
+		b := []byte{byte(len(textptr))}
+		b = append(b, textptr...)
+		b = append(b, timestamp...)
+		size := make([]byte, 4)
+		binary.LittleEndian.PutUint32(size, uint32(len(data)))
+		b = append(b, size...)
+		return append(b, data...)
+	}
+	makeBuffer := func(b []byte) *tdsBuffer {
+		return &tdsBuffer{packetSize: len(b), rbuf: b, rsize: len(b)}
+	}
+
+	tests := []struct {
+		name     string
+		typeID   uint8
+		data     []byte
+		expected interface{}
+	}{
+		{"text", typeText, []byte("hello"), "hello"},
+		{"ntext", typeNText, str2ucs2("hello"), "hello"},
+		{"image", typeImage, []byte{0xde, 0xad, 0xbe, 0xef}, []byte{0xde, 0xad, 0xbe, 0xef}},
+		{"empty image", typeImage, []byte{}, []byte{}},
+	}
+	for _, tt := range tests {
+		ti := typeInfo{TypeId: tt.typeID}
+		res := readLongLenType(&ti, makeBuffer(makeValue(tt.data)), nil)
+		if !reflect.DeepEqual(res, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, res)
+		}
+	}
+
+	ti := typeInfo{TypeId: typeText}
+	if res := readLongLenType(&ti, makeBuffer([]byte{0}), nil); res != nil {
+		t.Errorf("expected nil for a zero length text pointer, got %v", res)
+	}
+}
+
+func TestWriteLongLenType(t *testing.T) {
+	data := []byte{1, 2, 3}
+	buf := &bytes.Buffer{}
+	if err := writeLongLenType(buf, typeInfo{TypeId: typeImage, Size: len(data)}, data); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	r := &tdsBuffer{packetSize: len(b), rbuf: b, rsize: len(b)}
+	ti := typeInfo{TypeId: typeImage}
+	if res := readLongLenType(&ti, r, nil); !reflect.DeepEqual(res, data) {
+		t.Errorf("expected %v, got %v", data, res)
+	}
+
+	buf.Reset()
+	if err := writeLongLenType(buf, typeInfo{TypeId: typeImage}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{0}) {
+		t.Errorf("expected a zero length text pointer for NULL, got %v", buf.Bytes())
+	}
+}
+
+// makeRowColumns returns the columns and the data of a row with an int,
+// an ASCII and a non-ASCII nvarchar(max), a varchar(max) and a varbinary(max) column.
+func makeRowColumns() ([]columnStruct, []byte) {
+	plpColumn := func(typeId uint8) columnStruct {
+		return columnStruct{ti: typeInfo{TypeId: typeId, Size: 0xffff, Reader: readPLPType, Collation: cp.Collation{LcidAndFlags: 0x0409}}}
+	}
+	columns := []columnStruct{
+		{ti: typeInfo{TypeId: typeIntN, Size: 4, Buffer: make([]byte, 4), Reader: readByteLenType}},
+		plpColumn(typeNVarChar),
+		plpColumn(typeNVarChar),
+		plpColumn(typeBigVarChar),
+		plpColumn(typeBigVarBin),
+	}
+	plp := func(b []byte) []byte {
+		res := make([]byte, 8, 16+len(b))
+		binary.LittleEndian.PutUint64(res, uint64(len(b)))
+		res = append(res, byte(len(b)), byte(len(b)>>8), 0, 0)
+		res = append(res, b...)
+		return append(res, 0, 0, 0, 0)
+	}
+	data := []byte{4, 42, 0, 0, 0}
+	data = append(data, plp(str2ucs2(strings.Repeat("ascii text ", 20)))...)
+	data = append(data, plp(str2ucs2(strings.Repeat("texte accentué ", 20)))...)
+	data = append(data, plp([]byte(strings.Repeat("varchar text ", 20)))...)
+	data = append(data, plp(bytes.Repeat([]byte{1, 2, 3, 4}, 50))...)
+	return columns, data
+}
+
+func TestParseRowPLP(t *testing.T) {
+	columns, data := makeRowColumns()
+	r := makeReplyBuf(data)
+	if _, err := r.BeginRead(); err != nil {
+		t.Fatal(err)
+	}
+	row := make([]interface{}, len(columns))
+	if err := parseRow(context.Background(), r, nil, columns, row); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		int64(42),
+		strings.Repeat("ascii text ", 20),
+		strings.Repeat("texte accentué ", 20),
+		strings.Repeat("varchar text ", 20),
+		bytes.Repeat([]byte{1, 2, 3, 4}, 50),
+	}
+	if !reflect.DeepEqual(row, expected) {
+		t.Errorf("expected %v, got %v", expected, row)
+	}
+}
+
+func BenchmarkParseRow(b *testing.B) {
+	columns, data := makeRowColumns()
+	r := makeReplyBuf(data)
+	if _, err := r.BeginRead(); err != nil {
+		b.Fatal(err)
+	}
+	start := r.rpos
+	row := make([]interface{}, len(columns))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.rpos = start
+		if err := parseRow(context.Background(), r, nil, columns, row); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBinaryValuesNotShared(t *testing.T) {
+	columns := []columnStruct{
+		{ti: typeInfo{TypeId: typeBigVarBin, Size: 8000, Buffer: make([]byte, 8000), Reader: readShortLenType}},
+		{ti: typeInfo{TypeId: typeVarBinary, Size: 255, Buffer: make([]byte, 255), Reader: readByteLenType}},
+	}
+	data := []byte{
+		3, 0, 1, 2, 3, 2, 4, 5, // first row
+		3, 0, 6, 7, 8, 2, 9, 10, // second row
+	}
+	r := makeReplyBuf(data)
+	if _, err := r.BeginRead(); err != nil {
+		t.Fatal(err)
+	}
+	first := make([]interface{}, 2)
+	second := make([]interface{}, 2)
+	if err := parseRow(context.Background(), r, nil, columns, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseRow(context.Background(), r, nil, columns, second); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{[]byte{1, 2, 3}, []byte{4, 5}}
+	if !reflect.DeepEqual(first, expected) {
+		t.Errorf("the values of the first row changed after reading the second row: %v", first)
+	}
+	expected = []interface{}{[]byte{6, 7, 8}, []byte{9, 10}}
+	if !reflect.DeepEqual(second, expected) {
+		t.Errorf("expected %v, got %v", expected, second)
+	}
+}
+
+func TestParseRowUdtNotShared(t *testing.T) {
+	plpColumn := func(typeId uint8) columnStruct {
+		return columnStruct{ti: typeInfo{TypeId: typeId, Size: 0xffff, Reader: readPLPType, Collation: cp.Collation{LcidAndFlags: 0x0409}}}
+	}
+	columns := []columnStruct{plpColumn(typeUdt), plpColumn(typeNVarChar)}
+	plp := func(b []byte) []byte {
+		res := make([]byte, 8, 16+len(b))
+		binary.LittleEndian.PutUint64(res, uint64(len(b)))
+		res = append(res, byte(len(b)), byte(len(b)>>8), 0, 0)
+		res = append(res, b...)
+		return append(res, 0, 0, 0, 0)
+	}
+	udt := bytes.Repeat([]byte{0xe6, 0x10, 1, 0}, 16)
+	data := append(plp(udt), plp(str2ucs2("overwrite"))...)
+	r := makeReplyBuf(data)
+	if _, err := r.BeginRead(); err != nil {
+		t.Fatal(err)
+	}
+	row := make([]interface{}, len(columns))
+	if err := parseRow(context.Background(), r, nil, columns, row); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(row[0].([]byte), udt) {
+		t.Errorf("the UDT value was overwritten by the next column: %x", row[0])
+	}
+}
@@ -0,0 +1,115 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// UpsertResult is the outcome of the MERGE of a row by Upsert.
+type UpsertResult struct {
+	// Action is "INSERT" or "UPDATE".
+	Action string
+	// ID is the value of the identity column of the row,
+	// 0 when the rows have no identity field.
+	ID int64
+}
+
+// Upsert merges rows, a slice of structs or of pointers to structs, into table,
+// the name of the table or its schema and name such as dbo.products, with a
+// single MERGE statement. Rows whose key columns match a row of the table
+// update it, the other rows are inserted:
+//
+//	results, err := mssql.Upsert(ctx, db, "dbo.products", products, "sku")
+//
+// The columns are the fields of the struct as described for OpenJSON, the rows
+// are sent as an OpenJSONRows parameter. A field with the tvp tag "@identity"
+// is the identity column of the table, it is neither inserted nor updated and
+// its value is returned in the UpsertResult of the row. The results are in
+// the order the server merged the rows, which is not necessarily the order of rows.
+//
+// The table is locked with HOLDLOCK while the statement runs,
+// so concurrent upserts of the same key do not both insert it.
+func Upsert(ctx context.Context, q Querier, table string, rows interface{}, keyColumns ...string) ([]UpsertResult, error) {
+	if len(keyColumns) == 0 {
+		return nil, fmt.Errorf("mssql: Upsert into %s needs a key column", table)
+	}
+	source, err := OpenJSON(rows)
+	if err != nil {
+		return nil, err
+	}
+	query, err := upsertQuery(table, source.columns, keyColumns)
+	if err != nil {
+		return nil, err
+	}
+	res, err := q.QueryContext(ctx, query, source)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var results []UpsertResult
+	for res.Next() {
+		var r UpsertResult
+		var id *int64
+		if err = res.Scan(&r.Action, &id); err != nil {
+			return nil, err
+		}
+		if id != nil {
+			r.ID = *id
+		}
+		results = append(results, r)
+	}
+	return results, res.Err()
+}
+
+func upsertQuery(table string, columns []openJSONColumn, keyColumns []string) (string, error) {
+	schema, name, err := splitSchemaObject(table)
+	if err != nil {
+		return "", err
+	}
+	if table, err = QuoteSchemaObject(schema, name); err != nil {
+		return "", err
+	}
+	quote := TSQLQuoter{}.ID
+	isKey := make(map[string]bool, len(keyColumns))
+	for _, key := range keyColumns {
+		isKey[key] = true
+	}
+	identity := "null"
+	var merged []openJSONColumn
+	var match, update, insert, values []string
+	for _, column := range columns {
+		name := quote(column.name)
+		if column.identity {
+			if isKey[column.name] {
+				return "", fmt.Errorf("mssql: the identity column %s cannot be a key column of Upsert", column.name)
+			}
+			identity = "inserted." + name
+			continue
+		}
+		merged = append(merged, column)
+		insert = append(insert, name)
+		values = append(values, "source."+name)
+		if isKey[column.name] {
+			match = append(match, "target."+name+" = source."+name)
+			delete(isKey, column.name)
+		} else {
+			update = append(update, name+" = source."+name)
+		}
+	}
+	for _, key := range keyColumns {
+		if !isKey[key] {
+			continue
+		}
+		return "", fmt.Errorf("mssql: the key column %s of Upsert is not a field of the rows", key)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "merge %s with (holdlock) as target\nusing %s as source\non %s\n",
+		table, openJSONSource("@p1", merged), strings.Join(match, " and "))
+	if len(update) > 0 {
+		fmt.Fprintf(&sb, "when matched then update set %s\n", strings.Join(update, ", "))
+	}
+	fmt.Fprintf(&sb, "when not matched then insert (%s) values (%s)\noutput $action, %s;",
+		strings.Join(insert, ", "), strings.Join(values, ", "), identity)
+	return sb.String(), nil
+}
@@ -0,0 +1,106 @@
+package mssql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/go-mssqldb/mssqltest"
+)
+
+type upsertProduct struct {
+	ID    int64   `json:"id" tvp:"@identity"`
+	SKU   string  `json:"sku" openjson:"varchar(20)"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price" openjson:"decimal(18,2)"`
+}
+
+func TestUpsertQuery(t *testing.T) {
+	t.Parallel()
+	source, err := OpenJSON([]upsertProduct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := upsertQuery("dbo.products", source.columns, []string{"sku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "merge [dbo].[products] with (holdlock) as target\n" +
+		`using openjson(@p1) with ([sku] varchar(20) '$."sku"', [name] nvarchar(max) '$."name"', [price] decimal(18,2) '$."price"') as source` + "\n" +
+		"on target.[sku] = source.[sku]\n" +
+		"when matched then update set [name] = source.[name], [price] = source.[price]\n" +
+		"when not matched then insert ([sku], [name], [price]) values (source.[sku], source.[name], source.[price])\n" +
+		"output $action, inserted.[id];"
+	if query != expected {
+		t.Errorf("unexpected query\n%s\nexpected\n%s", query, expected)
+	}
+
+	if query, err = upsertQuery("dbo.products", source.columns[1:], []string{"sku", "name", "price"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(query, "when matched") || !strings.HasSuffix(query, "output $action, null;") {
+		t.Errorf("expected only inserts without an identity column, got\n%s", query)
+	}
+	for table, quoted := range map[string]string{
+		"products":              "[products]",
+		"[sales.eu].[order]]s]": "[sales.eu].[order]]s]",
+		"dbo.x; drop table y":   "[dbo].[x; drop table y]",
+	} {
+		if query, err = upsertQuery(table, source.columns, []string{"sku"}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(query, "merge "+quoted+" with (holdlock)") {
+			t.Errorf("expected the table %s to be quoted as %s, got\n%s", table, quoted, query)
+		}
+	}
+	type document struct {
+		Key     string            `db:"key"`
+		Payload map[string]string `db:"payload,json"`
+	}
+	documents, err := OpenJSON([]document{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query, err = upsertQuery("dbo.documents", documents.columns, []string{"key"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, `[payload] nvarchar(max) '$."payload"' as json`) || !strings.Contains(query, "update set [payload] = source.[payload]") {
+		t.Errorf("expected the payload to be merged as JSON, got\n%s", query)
+	}
+	if _, err = upsertQuery("srv.db.dbo.products", source.columns, []string{"sku"}); err == nil {
+		t.Error("expected an error for a name of more than two parts")
+	}
+	if _, err = upsertQuery("dbo.products", source.columns, []string{"id"}); err == nil {
+		t.Error("expected an error for an identity key column")
+	}
+	if _, err = upsertQuery("dbo.products", source.columns, []string{"sku", "code"}); err == nil || !strings.Contains(err.Error(), "code") {
+		t.Errorf("expected an error for an unknown key column, got %v", err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db, srv := newFaultTestDB(t, "server=upserthost;user id=sa;password=pwd;encrypt=disable", nil)
+	defer srv.Close()
+	defer db.Close()
+	srv.HandleFunc(func(query string) mssqltest.Response {
+		if !strings.HasPrefix(query, "merge [dbo].[products]") {
+			return mssqltest.Response{Err: &mssqltest.Error{Number: 50000, Message: "unexpected query"}}
+		}
+		return mssqltest.Response{Results: []mssqltest.Result{{
+			Columns: []string{"$action", "id"},
+			Rows:    [][]interface{}{{"INSERT", 7}, {"UPDATE", 3}},
+		}}}
+	})
+	ctx := context.Background()
+	products := []upsertProduct{{SKU: "a", Name: "new"}, {SKU: "b", Name: "changed"}}
+	results, err := Upsert(ctx, db, "dbo.products", products, "sku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0] != (UpsertResult{"INSERT", 7}) || results[1] != (UpsertResult{"UPDATE", 3}) {
+		t.Errorf("unexpected results %v", results)
+	}
+	if _, err = Upsert(ctx, db, "dbo.products", products); err == nil {
+		t.Error("expected an error without key columns")
+	}
+}